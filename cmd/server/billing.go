@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// billingCloser periodically closes the previous billing period, issuing an invoice for each
+// contract which does not have one yet.
+type billingCloser struct {
+	interval time.Duration
+}
+
+func newBillingCloser(interval time.Duration) *billingCloser {
+	return &billingCloser{interval: interval}
+}
+
+// run closes the previous billing period every interval until ctx is done.
+func (b *billingCloser) run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		b.closeOnce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeOnce closes the billing period before the one which contains now.
+func (b *billingCloser) closeOnce(ctx context.Context, now time.Time) {
+	period := contract.PreviousPeriod(now)
+	report, err := contractAccessor.CloseAllPeriods(ctx, period, now)
+	publishInvoices(ctx, report.Issued...)
+	for id, err := range report.Failed {
+		log.Error("could not issue invoice of contract ", id, " for ", contract.FormatPeriod(period), " : ", err)
+		billingFailures.Inc()
+	}
+	if err != nil {
+		log.Error("could not close billing period ", contract.FormatPeriod(period), " : ", err)
+	}
+}
+
+// publishInvoices publishes an event for each newly issued invoice or credit note.
+func publishInvoices(ctx context.Context, invoices ...model.Invoice) {
+	for _, invoice := range invoices {
+		invoicesIssued.WithLabelValues(invoice.Kind).Inc()
+		events.publish(ctx, eventInvoiceIssued, invoice.ContractID, map[string]interface{}{
+			"invoiceId": invoice.ID.String(),
+			"number":    invoice.Number,
+			"kind":      invoice.Kind,
+			"period":    contract.FormatPeriod(invoice.PeriodStart),
+			"currency":  invoice.Currency,
+			"total":     invoice.Total,
+		})
+	}
+}
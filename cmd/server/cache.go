@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+)
+
+// metricsCache records hits and misses of a contract cache.
+type metricsCache struct {
+	contract.Cache
+}
+
+// Get implements contract.Cache.
+func (c metricsCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, ok := c.Cache.Get(ctx, key)
+	if ok {
+		cacheRequests.WithLabelValues("hit").Inc()
+	} else {
+		cacheRequests.WithLabelValues("miss").Inc()
+	}
+	return value, ok
+}
@@ -0,0 +1,698 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	"github.com/openinfradev/tks-contract/pkg/contract/quantity"
+	"github.com/openinfradev/tks-contract/pkg/redact"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// envPrefix is a prefix of environment variables which override the configuration file.
+const envPrefix = "TKS_CONTRACT_"
+
+// Config represents the configuration of tks-contract.
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Info       InfoConfig       `yaml:"info"`
+	Argo       ArgoConfig       `yaml:"argo"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Health     HealthConfig     `yaml:"health"`
+	Expiry     ExpiryConfig     `yaml:"expiry"`
+	Events     EventsConfig     `yaml:"events"`
+	Billing    BillingConfig    `yaml:"billing"`
+	Reconciler ReconcilerConfig `yaml:"reconciler"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Default    DefaultConfig    `yaml:"defaultContract"`
+	Manifests  ManifestsConfig  `yaml:"manifests"`
+}
+
+// ServerConfig represents the configuration of the gRPC server.
+type ServerConfig struct {
+	Port                 int           `yaml:"port"`
+	MetricsPort          int           `yaml:"metricsPort"`
+	MetricsAddress       string        `yaml:"metricsAddress"`
+	GatewayPort          int           `yaml:"gatewayPort"`
+	GatewayAddress       string        `yaml:"gatewayAddress"`
+	TLSEnabled           bool          `yaml:"tlsEnabled"`
+	TLSCertPath          string        `yaml:"tlsCertPath"`
+	TLSKeyPath           string        `yaml:"tlsKeyPath"`
+	ShutdownTimeout      time.Duration `yaml:"shutdownTimeout"`
+	RequestTimeout       time.Duration `yaml:"requestTimeout"`
+	SecretReloadInterval time.Duration `yaml:"secretReloadInterval"`
+	// InternalToken is the token which callers pass as "Authorization: Bearer <token>".
+	// If it is set, all requests except health checks are denied without it. Requests are not
+	// authenticated if it is empty.
+	InternalToken     string `yaml:"internalToken"`
+	InternalTokenFile string `yaml:"internalTokenFile"`
+}
+
+// DatabaseConfig represents the connection options of PostgreSQL.
+type DatabaseConfig struct {
+	Host         string `yaml:"host"`
+	Port         string `yaml:"port"`
+	User         string `yaml:"user"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"passwordFile"`
+	Name         string `yaml:"name"`
+	SSLMode      string `yaml:"sslMode"`
+	SSLRootCert  string `yaml:"sslRootCert"`
+	SSLCert      string `yaml:"sslCert"`
+	SSLKey       string `yaml:"sslKey"`
+	TimeZone     string `yaml:"timeZone"`
+
+	MaxOpenConns            int           `yaml:"maxOpenConns"`
+	MaxIdleConns            int           `yaml:"maxIdleConns"`
+	ConnMaxLifetime         time.Duration `yaml:"connMaxLifetime"`
+	ConnMaxIdleTime         time.Duration `yaml:"connMaxIdleTime"`
+	StatementTimeout        time.Duration `yaml:"statementTimeout"`
+	ConnectTimeout          time.Duration `yaml:"connectTimeout"`
+	ConnectRetries          int           `yaml:"connectRetries"`
+	ConnectRetryInterval    time.Duration `yaml:"connectRetryInterval"`
+	ConnectRetryMaxInterval time.Duration `yaml:"connectRetryMaxInterval"`
+}
+
+// InfoConfig represents the connection options of tks-info.
+type InfoConfig struct {
+	Address           string `yaml:"address"`
+	Port              int    `yaml:"port"`
+	TLSClientCertPath string `yaml:"tlsClientCertPath"`
+}
+
+// ArgoConfig represents the connection options of argo-workflow.
+type ArgoConfig struct {
+	Address  string `yaml:"address"`
+	Port     int    `yaml:"port"`
+	Revision string `yaml:"revision"`
+}
+
+// TracingConfig represents the configuration of OpenTelemetry tracing.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	File        string  `yaml:"file"`
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// HealthConfig represents the configuration of dependency health checks.
+type HealthConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// ExpiryConfig represents the configuration of the job which expires contracts.
+type ExpiryConfig struct {
+	// Interval of checks. 0 disables the job.
+	Interval time.Duration `yaml:"interval"`
+	// NoticePeriod is how long before expiry contracts are flagged as expiring.
+	NoticePeriod time.Duration `yaml:"noticePeriod"`
+	// ExpiredState is the state which expired contracts are changed into, read_only or suspended.
+	ExpiredState string `yaml:"expiredState"`
+}
+
+// EventsConfig represents the configuration of contract events.
+type EventsConfig struct {
+	// WebhookURL receives events as JSON POST requests. Events are only logged if it is empty.
+	WebhookURL string        `yaml:"webhookUrl"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+// BillingConfig represents the configuration of the job which issues invoices.
+type BillingConfig struct {
+	// Interval of closing the previous billing period. 0 disables the job.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// ReconcilerConfig represents the configuration of the job which reconciles contracts with
+// the CSP info of tks-info.
+type ReconcilerConfig struct {
+	// Interval of reconciliations. 0 disables the job.
+	Interval time.Duration `yaml:"interval"`
+	// Policy is report to only report drifts, or repair to repair them.
+	Policy string `yaml:"policy"`
+	// GracePeriod skips contracts and CSP accounts newer than it.
+	GracePeriod time.Duration `yaml:"gracePeriod"`
+}
+
+// CacheConfig represents the configuration of the in-process cache of contracts and quotas.
+type CacheConfig struct {
+	// Size is the maximum number of cached contracts. 0 disables the cache.
+	Size int `yaml:"size"`
+	// TTL is how long a contract is cached. Changes made by other servers are seen after it.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// DefaultConfig represents the default contract which is created at startup if there is none.
+type DefaultConfig struct {
+	// Create enables creating the default contract at startup.
+	Create bool `yaml:"create"`
+	// ContractorName of the default contract. An existing contract of the name is made the default.
+	ContractorName    string   `yaml:"contractorName"`
+	AvailableServices []string `yaml:"availableServices"`
+	// Quota maps quota dimensions to quantities such as 4 or 16Gi.
+	Quota       map[string]string `yaml:"quota"`
+	Description string            `yaml:"description"`
+}
+
+func (c DefaultConfig) quota() (*pb.ContractQuota, error) {
+	values := map[string]int64{}
+	for dimension, s := range c.Quota {
+		v, err := quantity.Parse(dimension, s)
+		if err != nil {
+			return nil, err
+		}
+		values[dimension] = v
+	}
+	return &pb.ContractQuota{
+		Cpu:      values[quantity.Cpu],
+		Memory:   values[quantity.Memory],
+		Block:    values[quantity.Block],
+		BlockSsd: values[quantity.BlockSsd],
+		Fs:       values[quantity.Fs],
+		FsSsd:    values[quantity.FsSsd],
+	}, nil
+}
+
+// ManifestsConfig represents how the quotas of contracts are rendered into Kubernetes manifests.
+// Maps are merged into the defaults, and a dimension can be left out with an empty list of resources.
+type ManifestsConfig struct {
+	// Resources maps quota dimensions to the resources of ResourceQuota.
+	Resources map[string][]string `yaml:"resources"`
+	// DefaultLimits and DefaultRequests are the defaults of containers in LimitRange.
+	DefaultLimits   map[string]string `yaml:"defaultLimits"`
+	DefaultRequests map[string]string `yaml:"defaultRequests"`
+	// Directory of the manifests in the GitOps repository of a contract.
+	Directory string `yaml:"directory"`
+}
+
+func (c ManifestsConfig) mapping() contract.ManifestMapping {
+	return contract.ManifestMapping{
+		Resources:       c.Resources,
+		DefaultLimits:   c.DefaultLimits,
+		DefaultRequests: c.DefaultRequests,
+		Directory:       c.Directory,
+	}
+}
+
+func defaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:            9110,
+			MetricsPort:     9190,
+			MetricsAddress:  "127.0.0.1",
+			GatewayPort:     9180,
+			GatewayAddress:  "127.0.0.1",
+			TLSEnabled:      false,
+			TLSCertPath:     "../../cert/tks-server.crt",
+			TLSKeyPath:      "../../cert/tks-server.key",
+			ShutdownTimeout: 30 * time.Second,
+			RequestTimeout:  30 * time.Second,
+
+			SecretReloadInterval: 30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:     "localhost",
+			Port:     "5432",
+			User:     "postgres",
+			Password: "password",
+			Name:     "tks",
+			SSLMode:  "disable",
+			TimeZone: "Asia/Seoul",
+
+			MaxOpenConns:            20,
+			MaxIdleConns:            5,
+			ConnMaxLifetime:         30 * time.Minute,
+			ConnMaxIdleTime:         5 * time.Minute,
+			StatementTimeout:        30 * time.Second,
+			ConnectTimeout:          5 * time.Second,
+			ConnectRetries:          10,
+			ConnectRetryInterval:    time.Second,
+			ConnectRetryMaxInterval: 30 * time.Second,
+		},
+		Info: InfoConfig{
+			Address:           "localhost",
+			Port:              9111,
+			TLSClientCertPath: "../../cert/tks-ca.crt",
+		},
+		Argo: ArgoConfig{
+			Address:  "localhost",
+			Port:     2746,
+			Revision: "main",
+		},
+		Tracing: TracingConfig{
+			Exporter:    traceExporterNone,
+			Endpoint:    "localhost:4317",
+			SampleRatio: 1.0,
+		},
+		Health: HealthConfig{
+			Interval: 10 * time.Second,
+			Timeout:  3 * time.Second,
+		},
+		Expiry: ExpiryConfig{
+			Interval:     time.Hour,
+			NoticePeriod: 30 * 24 * time.Hour,
+			ExpiredState: string(contract.StateReadOnly),
+		},
+		Events: EventsConfig{
+			Timeout: 5 * time.Second,
+		},
+		Cache: CacheConfig{
+			Size: 1000,
+			TTL:  30 * time.Second,
+		},
+		Default: DefaultConfig{
+			ContractorName: contract.LegacyDefaultContractorName,
+		},
+		Reconciler: ReconcilerConfig{
+			Interval:    time.Hour,
+			Policy:      reconcilePolicyReport,
+			GracePeriod: 10 * time.Minute,
+		},
+		Manifests: ManifestsConfig{
+			Resources: map[string][]string{
+				quantity.Cpu:      {"requests.cpu", "limits.cpu"},
+				quantity.Memory:   {"requests.memory", "limits.memory"},
+				quantity.Block:    {"requests.storage"},
+				quantity.BlockSsd: {"requests.storage"},
+				quantity.Fs:       {"requests.storage"},
+				quantity.FsSsd:    {"requests.storage"},
+			},
+			DefaultLimits:   map[string]string{"cpu": "500m", "memory": "512Mi"},
+			DefaultRequests: map[string]string{"cpu": "100m", "memory": "128Mi"},
+			Directory:       "quota",
+		},
+	}
+}
+
+// option binds a configuration value to a flag and an environment variable.
+type option struct {
+	flag  string
+	env   string
+	usage string
+	value interface{}
+}
+
+func (c *Config) options() []option {
+	return []option{
+		{"port", "PORT", "service port", &c.Server.Port},
+		{"metrics-port", "METRICS_PORT", "port for prometheus metrics endpoint", &c.Server.MetricsPort},
+		{"metrics-address", "METRICS_ADDRESS", "address which prometheus metrics endpoint listens on (empty for all interfaces)", &c.Server.MetricsAddress},
+		{"gateway-port", "GATEWAY_PORT", "port for HTTP/JSON gateway (0 disables the gateway)", &c.Server.GatewayPort},
+		{"gateway-address", "GATEWAY_ADDRESS", "address which HTTP/JSON gateway listens on (empty for all interfaces)", &c.Server.GatewayAddress},
+		{"tlsEnabled", "TLS_ENABLED", "enabled tls", &c.Server.TLSEnabled},
+		{"tls-cert-path", "TLS_CERT_PATH", "path of cert file for tls", &c.Server.TLSCertPath},
+		{"tls-key-path", "TLS_KEY_PATH", "path of key file for tls", &c.Server.TLSKeyPath},
+		{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "deadline for in-flight requests on shutdown", &c.Server.ShutdownTimeout},
+		{"request-timeout", "REQUEST_TIMEOUT", "deadline for requests which have no deadline", &c.Server.RequestTimeout},
+		{"tls-client-cert-path", "TLS_CLIENT_CERT_PATH", "path of ca cert file for tls", &c.Info.TLSClientCertPath},
+		{"info-address", "INFO_ADDRESS", "service address for tks-info", &c.Info.Address},
+		{"info-port", "INFO_PORT", "service port for tks-info", &c.Info.Port},
+		{"argo-address", "ARGO_ADDRESS", "service address for argo-workflow", &c.Argo.Address},
+		{"argo-port", "ARGO_PORT", "service port for argo-workflow", &c.Argo.Port},
+		{"revision", "REVISION", "revision for workflow parameter", &c.Argo.Revision},
+		{"dbhost", "DB_HOST", "host of postgreSQL", &c.Database.Host},
+		{"dbport", "DB_PORT", "port of postgreSQL", &c.Database.Port},
+		{"dbuser", "DB_USER", "postgreSQL user", &c.Database.User},
+		{"dbpassword", "DB_PASSWORD", "password for postgreSQL user", &c.Database.Password},
+		{"dbpassword-file", "DB_PASSWORD_FILE", "path of file holding password for postgreSQL user", &c.Database.PasswordFile},
+		{"internal-token", "INTERNAL_TOKEN", "token which callers must pass to be authenticated", &c.Server.InternalToken},
+		{"internal-token-file", "INTERNAL_TOKEN_FILE", "path of file holding token which callers must pass", &c.Server.InternalTokenFile},
+		{"secret-reload-interval", "SECRET_RELOAD_INTERVAL", "interval to reload secret files", &c.Server.SecretReloadInterval},
+		{"dbname", "DB_NAME", "database name of postgreSQL", &c.Database.Name},
+		{"dbsslmode", "DB_SSLMODE", "sslmode of postgreSQL connection", &c.Database.SSLMode},
+		{"dbsslrootcert", "DB_SSLROOTCERT", "path of root cert file for postgreSQL", &c.Database.SSLRootCert},
+		{"dbsslcert", "DB_SSLCERT", "path of client cert file for postgreSQL", &c.Database.SSLCert},
+		{"dbsslkey", "DB_SSLKEY", "path of client key file for postgreSQL", &c.Database.SSLKey},
+		{"dbtimezone", "DB_TIMEZONE", "timezone of postgreSQL session", &c.Database.TimeZone},
+		{"db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum number of open database connections (0 is unlimited)", &c.Database.MaxOpenConns},
+		{"db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum number of idle database connections", &c.Database.MaxIdleConns},
+		{"db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "maximum lifetime of a database connection (0 is unlimited)", &c.Database.ConnMaxLifetime},
+		{"db-conn-max-idle-time", "DB_CONN_MAX_IDLE_TIME", "maximum idle time of a database connection (0 is unlimited)", &c.Database.ConnMaxIdleTime},
+		{"db-statement-timeout", "DB_STATEMENT_TIMEOUT", "statement_timeout of postgreSQL session (0 is unlimited)", &c.Database.StatementTimeout},
+		{"db-connect-timeout", "DB_CONNECT_TIMEOUT", "timeout of each attempt to connect database on startup", &c.Database.ConnectTimeout},
+		{"db-connect-retries", "DB_CONNECT_RETRIES", "number of retries to connect database on startup", &c.Database.ConnectRetries},
+		{"db-connect-retry-interval", "DB_CONNECT_RETRY_INTERVAL", "initial backoff between retries to connect database", &c.Database.ConnectRetryInterval},
+		{"db-connect-retry-max-interval", "DB_CONNECT_RETRY_MAX_INTERVAL", "maximum backoff between retries to connect database", &c.Database.ConnectRetryMaxInterval},
+		{"trace-exporter", "TRACE_EXPORTER", "exporter for tracing (none, otlp, stdout)", &c.Tracing.Exporter},
+		{"trace-endpoint", "TRACE_ENDPOINT", "endpoint of otlp collector for tracing", &c.Tracing.Endpoint},
+		{"trace-file", "TRACE_FILE", "file path to write spans to with stdout exporter (default stdout)", &c.Tracing.File},
+		{"trace-sample-ratio", "TRACE_SAMPLE_RATIO", "ratio of sampled traces", &c.Tracing.SampleRatio},
+		{"health-check-interval", "HEALTH_CHECK_INTERVAL", "interval of dependency health checks", &c.Health.Interval},
+		{"health-check-timeout", "HEALTH_CHECK_TIMEOUT", "timeout of each dependency health check", &c.Health.Timeout},
+		{"expiry-check-interval", "EXPIRY_CHECK_INTERVAL", "interval of checks for expiring contracts (0 disables the checks)", &c.Expiry.Interval},
+		{"expiry-notice-period", "EXPIRY_NOTICE_PERIOD", "period before expiry in which contracts are flagged as expiring", &c.Expiry.NoticePeriod},
+		{"expired-state", "EXPIRED_STATE", "state of expired contracts (read_only, suspended)", &c.Expiry.ExpiredState},
+		{"event-webhook-url", "EVENT_WEBHOOK_URL", "URL to post contract events to (events are only logged if empty)", &c.Events.WebhookURL},
+		{"event-webhook-timeout", "EVENT_WEBHOOK_TIMEOUT", "timeout of posting an event to the webhook", &c.Events.Timeout},
+		{"billing-interval", "BILLING_INTERVAL", "interval of closing the previous billing period (0 disables the job)", &c.Billing.Interval},
+		{"reconcile-interval", "RECONCILE_INTERVAL", "interval of reconciling contracts with tks-info (0 disables the job)", &c.Reconciler.Interval},
+		{"reconcile-policy", "RECONCILE_POLICY", "policy of drifts found by reconciliation (report, repair)", &c.Reconciler.Policy},
+		{"reconcile-grace-period", "RECONCILE_GRACE_PERIOD", "age under which contracts and CSP accounts are not reconciled", &c.Reconciler.GracePeriod},
+		{"cache-size", "CACHE_SIZE", "maximum number of cached contracts (0 disables the cache)", &c.Cache.Size},
+		{"cache-ttl", "CACHE_TTL", "how long a contract is cached", &c.Cache.TTL},
+		{"default-contract-create", "DEFAULT_CONTRACT_CREATE", "create the default contract at startup if there is none", &c.Default.Create},
+		{"default-contract-name", "DEFAULT_CONTRACT_NAME", "contractor name of the default contract", &c.Default.ContractorName},
+		{"manifest-directory", "MANIFEST_DIRECTORY", "directory of Kubernetes manifests in the GitOps repository of a contract", &c.Manifests.Directory},
+	}
+}
+
+// loadConfig loads the configuration from defaults, a YAML file, TKS_CONTRACT_* environment
+// variables and command line flags, in ascending order of precedence.
+func loadConfig(args []string) (Config, error) {
+	cfg := defaultConfig()
+	options := cfg.options()
+
+	fs := flag.NewFlagSet("tks-contract", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "path of YAML configuration file")
+	flagValues := map[string]*optionFlag{}
+	for _, opt := range options {
+		_, isBool := opt.value.(*bool)
+		flagValues[opt.flag] = &optionFlag{raw: valueString(opt.value), isBool: isBool}
+		fs.Var(flagValues[opt.flag], opt.flag, opt.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *configPath != "" {
+		b, err := ioutil.ReadFile(*configPath)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read config file %s : %s", *configPath, err)
+		}
+		if err := yaml.Unmarshal(b, &cfg); err != nil {
+			return Config{}, fmt.Errorf("failed to parse config file %s : %s", *configPath, err)
+		}
+	}
+
+	for _, opt := range options {
+		if v, ok := os.LookupEnv(envPrefix + opt.env); ok {
+			if err := setValue(opt.value, v); err != nil {
+				return Config{}, fmt.Errorf("invalid value for %s%s : %s", envPrefix, opt.env, err)
+			}
+		}
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, opt := range options {
+			if opt.flag == f.Name && err == nil {
+				if serr := setValue(opt.value, flagValues[opt.flag].raw); serr != nil {
+					err = fmt.Errorf("invalid value for -%s : %s", opt.flag, serr)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Validate returns an error if the configuration is not usable.
+func (c *Config) Validate() error {
+	var errs []string
+	checkPort := func(name string, port int) {
+		if port <= 0 || port > 65535 {
+			errs = append(errs, fmt.Sprintf("%s must be between 1 and 65535", name))
+		}
+	}
+	checkFile := func(name string, path string) {
+		if path == "" {
+			return
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s is not accessible", name, path))
+		}
+	}
+
+	checkPort("server.port", c.Server.Port)
+	checkPort("server.metricsPort", c.Server.MetricsPort)
+	if c.Server.Port == c.Server.MetricsPort {
+		errs = append(errs, "server.metricsPort must differ from server.port")
+	}
+	if c.Server.GatewayPort != 0 {
+		checkPort("server.gatewayPort", c.Server.GatewayPort)
+		if c.Server.GatewayPort == c.Server.Port || c.Server.GatewayPort == c.Server.MetricsPort {
+			errs = append(errs, "server.gatewayPort must differ from server.port and server.metricsPort")
+		}
+	}
+	if c.Server.TLSEnabled {
+		checkFile("server.tlsCertPath", c.Server.TLSCertPath)
+		checkFile("server.tlsKeyPath", c.Server.TLSKeyPath)
+		checkFile("info.tlsClientCertPath", c.Info.TLSClientCertPath)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, "server.shutdownTimeout must be positive")
+	}
+	if c.Server.RequestTimeout <= 0 {
+		errs = append(errs, "server.requestTimeout must be positive")
+	}
+	if c.Server.SecretReloadInterval <= 0 {
+		errs = append(errs, "server.secretReloadInterval must be positive")
+	}
+
+	if c.Database.Host == "" {
+		errs = append(errs, "database.host must be specified")
+	}
+	if port, err := strconv.Atoi(c.Database.Port); err != nil {
+		errs = append(errs, "database.port must be a number")
+	} else {
+		checkPort("database.port", port)
+	}
+	if c.Database.User == "" {
+		errs = append(errs, "database.user must be specified")
+	}
+	if c.Database.Name == "" {
+		errs = append(errs, "database.name must be specified")
+	}
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Sprintf("database.sslMode %s is not supported", c.Database.SSLMode))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, "database.maxOpenConns and database.maxIdleConns must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, "database.maxIdleConns must not exceed database.maxOpenConns")
+	}
+	if c.Database.ConnMaxLifetime < 0 || c.Database.ConnMaxIdleTime < 0 || c.Database.StatementTimeout < 0 {
+		errs = append(errs, "database.connMaxLifetime, connMaxIdleTime and statementTimeout must not be negative")
+	}
+	if c.Database.ConnectTimeout <= 0 {
+		errs = append(errs, "database.connectTimeout must be positive")
+	}
+	if c.Database.ConnectRetries < 0 {
+		errs = append(errs, "database.connectRetries must not be negative")
+	}
+	if c.Database.ConnectRetryInterval <= 0 || c.Database.ConnectRetryMaxInterval < c.Database.ConnectRetryInterval {
+		errs = append(errs, "database.connectRetryInterval must be positive and not exceed connectRetryMaxInterval")
+	}
+	checkFile("server.internalTokenFile", c.Server.InternalTokenFile)
+	checkFile("database.passwordFile", c.Database.PasswordFile)
+	checkFile("database.sslRootCert", c.Database.SSLRootCert)
+	checkFile("database.sslCert", c.Database.SSLCert)
+	checkFile("database.sslKey", c.Database.SSLKey)
+	if c.Database.TimeZone != "" {
+		if _, err := time.LoadLocation(c.Database.TimeZone); err != nil {
+			errs = append(errs, fmt.Sprintf("database.timeZone %s is unknown", c.Database.TimeZone))
+		}
+	}
+
+	if c.Info.Address == "" {
+		errs = append(errs, "info.address must be specified")
+	}
+	checkPort("info.port", c.Info.Port)
+	if c.Argo.Address == "" {
+		errs = append(errs, "argo.address must be specified")
+	}
+	checkPort("argo.port", c.Argo.Port)
+
+	switch c.Tracing.Exporter {
+	case traceExporterNone, traceExporterOtlp, traceExporterStdout:
+	default:
+		errs = append(errs, fmt.Sprintf("tracing.exporter %s is not supported", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, "tracing.sampleRatio must be between 0 and 1")
+	}
+
+	if c.Health.Interval <= 0 || c.Health.Timeout <= 0 {
+		errs = append(errs, "health.interval and health.timeout must be positive")
+	}
+
+	if c.Expiry.Interval < 0 || c.Expiry.NoticePeriod < 0 {
+		errs = append(errs, "expiry.interval and expiry.noticePeriod must not be negative")
+	}
+	if err := contract.ValidateExpiredState(contract.State(c.Expiry.ExpiredState)); err != nil {
+		errs = append(errs, "expiry.expiredState : "+err.Error())
+	}
+	if c.Events.WebhookURL != "" {
+		if u, err := url.Parse(c.Events.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Sprintf("events.webhookUrl %s must be an http or https URL", c.Events.WebhookURL))
+		}
+	}
+	if c.Events.Timeout <= 0 {
+		errs = append(errs, "events.timeout must be positive")
+	}
+	if c.Billing.Interval < 0 {
+		errs = append(errs, "billing.interval must not be negative")
+	}
+	if c.Reconciler.Interval < 0 || c.Reconciler.GracePeriod < 0 {
+		errs = append(errs, "reconciler.interval and reconciler.gracePeriod must not be negative")
+	}
+	if err := validateReconcilePolicy(c.Reconciler.Policy); err != nil {
+		errs = append(errs, "reconciler.policy : "+err.Error())
+	}
+	if c.Cache.Size < 0 {
+		errs = append(errs, "cache.size must not be negative")
+	}
+	if c.Cache.Size > 0 && c.Cache.TTL <= 0 {
+		errs = append(errs, "cache.ttl must be positive if the cache is enabled")
+	}
+	if c.Default.Create && c.Default.ContractorName == "" {
+		errs = append(errs, "defaultContract.contractorName must be specified if the default contract is created")
+	}
+	if _, err := c.Default.quota(); err != nil {
+		errs = append(errs, "defaultContract.quota : "+err.Error())
+	}
+	if err := contract.ValidateManifestMapping(c.Manifests.mapping()); err != nil {
+		errs = append(errs, fmt.Sprintf("manifests : %s", err))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration : %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration whose secrets are masked for logging. Options with
+// a sensitive name are secrets, except the paths of the files holding them.
+func (c Config) Redacted() Config {
+	for _, opt := range c.options() {
+		if v, ok := opt.value.(*string); ok && redact.IsSensitive(opt.flag) && !strings.HasSuffix(opt.flag, "-file") {
+			*v = redact.String(*v)
+		}
+	}
+	return c
+}
+
+// DSN returns a data source name for the postgres driver.
+func (c *DatabaseConfig) DSN() string {
+	params := []struct {
+		key   string
+		value string
+	}{
+		{"host", c.Host},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.Name},
+		{"port", c.Port},
+		{"sslmode", c.SSLMode},
+		{"sslrootcert", c.SSLRootCert},
+		{"sslcert", c.SSLCert},
+		{"sslkey", c.SSLKey},
+		{"TimeZone", c.TimeZone},
+	}
+
+	var kv []string
+	for _, p := range params {
+		if p.value == "" {
+			continue
+		}
+		kv = append(kv, fmt.Sprintf("%s=%s", p.key, quoteDSNValue(p.value)))
+	}
+	return strings.Join(kv, " ")
+}
+
+// quoteDSNValue quotes a value of a key/value connection string if needed.
+func quoteDSNValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// optionFlag is a flag.Value which keeps the raw value given on the command line,
+// so that it can be applied after the configuration file and environment variables.
+type optionFlag struct {
+	raw    string
+	isBool bool
+}
+
+func (f *optionFlag) String() string {
+	return f.raw
+}
+
+func (f *optionFlag) Set(s string) error {
+	f.raw = s
+	return nil
+}
+
+func (f *optionFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case *string:
+		return *v
+	case *int:
+		return strconv.Itoa(*v)
+	case *bool:
+		return strconv.FormatBool(*v)
+	case *float64:
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	case *time.Duration:
+		return v.String()
+	}
+	return ""
+}
+
+func setValue(value interface{}, s string) error {
+	switch v := value.(type) {
+	case *string:
+		*v = s
+	case *int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*v = i
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*v = b
+	case *float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*v = f
+	case *time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*v = d
+	default:
+		return fmt.Errorf("unsupported type %T", value)
+	}
+	return nil
+}
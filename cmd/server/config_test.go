@@ -0,0 +1,145 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	err := ioutil.WriteFile(path, []byte(`
+server:
+  port: 9200
+  shutdownTimeout: 5s
+database:
+  host: db.from.file
+  user: file_user
+  name: contract
+`), 0600)
+	require.NoError(t, err)
+
+	os.Setenv(envPrefix+"DB_HOST", "db.from.env")
+	os.Setenv(envPrefix+"DB_USER", "env_user")
+	defer os.Unsetenv(envPrefix + "DB_HOST")
+	defer os.Unsetenv(envPrefix + "DB_USER")
+
+	cfg, err := loadConfig([]string{"-config", path, "-dbuser", "flag_user", "-tlsEnabled=false"})
+	require.NoError(t, err)
+
+	require.Equal(t, 9200, cfg.Server.Port)
+	require.Equal(t, 5*time.Second, cfg.Server.ShutdownTimeout)
+	require.Equal(t, "db.from.env", cfg.Database.Host)
+	require.Equal(t, "flag_user", cfg.Database.User)
+	require.Equal(t, "contract", cfg.Database.Name)
+	require.Equal(t, "disable", cfg.Database.SSLMode)
+}
+
+func TestLoadConfigManifests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+manifests:
+  resources:
+    block_ssd: [ssd.storageclass.storage.k8s.io/requests.storage]
+    fs: []
+`), 0600))
+	cfg, err := loadConfig([]string{"-config", path, "-manifest-directory", "k8s/quota"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"requests.cpu", "limits.cpu"}, cfg.Manifests.Resources["cpu"])
+	require.Equal(t, []string{"ssd.storageclass.storage.k8s.io/requests.storage"}, cfg.Manifests.Resources["block_ssd"])
+	require.Empty(t, cfg.Manifests.Resources["fs"])
+	require.Equal(t, "k8s/quota", cfg.Manifests.Directory)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+manifests:
+  resources:
+    fs: [requests.cpu]
+`), 0600))
+	_, err = loadConfig([]string{"-config", path})
+	require.Error(t, err)
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	_, err := loadConfig([]string{"-dbsslmode", "sometimes"})
+	require.Error(t, err)
+
+	_, err = loadConfig([]string{"-port", "not_a_number"})
+	require.Error(t, err)
+
+	_, err = loadConfig([]string{"-metrics-port", "9110"})
+	require.Error(t, err)
+
+	_, err = loadConfig([]string{"-gateway-port", "9190"})
+	require.Error(t, err)
+
+	_, err = loadConfig([]string{"-gateway-port", "0"})
+	require.NoError(t, err)
+
+	_, err = loadConfig([]string{"-internal-token-file", "/nonexistent/token"})
+	require.Error(t, err)
+
+	_, err = loadConfig([]string{"-expired-state", "active"})
+	require.Error(t, err)
+
+	_, err = loadConfig([]string{"-event-webhook-url", "ftp://events"})
+	require.Error(t, err)
+
+	_, err = loadConfig([]string{"-billing-interval", "-1h"})
+	require.Error(t, err)
+
+	_, err = loadConfig([]string{"-reconcile-policy", "delete"})
+	require.Error(t, err)
+
+	_, err = loadConfig([]string{"-cache-ttl", "0"})
+	require.Error(t, err)
+	_, err = loadConfig([]string{"-cache-size", "0", "-cache-ttl", "0"})
+	require.NoError(t, err)
+
+	_, err = loadConfig([]string{"-default-contract-create", "-default-contract-name", ""})
+	require.Error(t, err)
+	_, err = loadConfig([]string{"-default-contract-name", ""})
+	require.NoError(t, err)
+
+	cfg, err := loadConfig([]string{"-expired-state", "suspended", "-expiry-check-interval", "0"})
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), cfg.Expiry.Interval)
+	require.Equal(t, 30*24*time.Hour, cfg.Expiry.NoticePeriod)
+	require.Equal(t, reconcilePolicyReport, cfg.Reconciler.Policy)
+	require.Equal(t, "default", cfg.Default.ContractorName)
+	require.Equal(t, "127.0.0.1", cfg.Server.GatewayAddress)
+	require.Equal(t, "127.0.0.1", cfg.Server.MetricsAddress)
+}
+
+func TestDefaultConfigQuota(t *testing.T) {
+	quota, err := DefaultConfig{Quota: map[string]string{"cpu": "4", "memory": "16Gi", "fs_ssd": "1Ti"}}.quota()
+	require.NoError(t, err)
+	require.Equal(t, int64(4000), quota.Cpu)
+	require.Equal(t, int64(16), quota.Memory)
+	require.Equal(t, int64(1024), quota.FsSsd)
+	require.Equal(t, int64(0), quota.Block)
+
+	_, err = DefaultConfig{Quota: map[string]string{"gpu": "1"}}.quota()
+	require.Error(t, err)
+	_, err = DefaultConfig{Quota: map[string]string{"memory": "lots"}}.quota()
+	require.Error(t, err)
+}
+
+func TestDatabaseDSN(t *testing.T) {
+	c := DatabaseConfig{
+		Host:     "localhost",
+		Port:     "5432",
+		User:     "postgres",
+		Password: "pass word's",
+		Name:     "tks",
+		SSLMode:  "verify-full",
+		TimeZone: "Asia/Seoul",
+	}
+	require.Equal(t,
+		`host=localhost user=postgres password='pass word\'s' dbname=tks port=5432 sslmode=verify-full TimeZone=Asia/Seoul`,
+		c.DSN())
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+)
+
+// openDatabase opens a database connection pool and waits until the database is reachable.
+// password is called whenever a new connection is established, so that a rotated password
+// is used for new connections without restarting the server.
+func openDatabase(ctx context.Context, c DatabaseConfig, password func() string) (*gorm.DB, error) {
+	connConfig, err := pgx.ParseConfig(c.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("invalid database configuration : %s", err)
+	}
+	if c.StatementTimeout > 0 {
+		connConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10)
+	}
+
+	sqlDB := stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+		cc.Password = password()
+		return nil
+	}))
+	sqlDB.SetMaxOpenConns(c.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(c.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(c.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+
+	interval := c.ConnectRetryInterval
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, c.ConnectTimeout)
+		err = sqlDB.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			log.Info("database is connected")
+			return db, nil
+		}
+		if attempt >= c.ConnectRetries {
+			break
+		}
+
+		log.Error("failed to connect database (attempt ", attempt+1, "/", c.ConnectRetries+1, ") : ", err,
+			". retrying in ", interval)
+		select {
+		case <-ctx.Done():
+			_ = sqlDB.Close()
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > c.ConnectRetryMaxInterval {
+			interval = c.ConnectRetryMaxInterval
+		}
+	}
+
+	_ = sqlDB.Close()
+	return nil, fmt.Errorf("database is not reachable after %d attempts : %s", c.ConnectRetries+1, err)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenDatabaseRetries(t *testing.T) {
+	c := defaultConfig().Database
+	c.Host = "127.0.0.1"
+	c.Port = "1"
+	c.ConnectTimeout = 100 * time.Millisecond
+	c.ConnectRetries = 2
+	c.ConnectRetryInterval = 10 * time.Millisecond
+	c.ConnectRetryMaxInterval = 20 * time.Millisecond
+
+	_, err := openDatabase(context.Background(), c, func() string { return "password" })
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "after 3 attempts")
+}
+
+func TestOpenDatabaseCancelled(t *testing.T) {
+	c := defaultConfig().Database
+	c.Host = "127.0.0.1"
+	c.Port = "1"
+	c.ConnectTimeout = 100 * time.Millisecond
+	c.ConnectRetryInterval = time.Minute
+	c.ConnectRetryMaxInterval = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := openDatabase(ctx, c, func() string { return "password" })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
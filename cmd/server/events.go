@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+)
+
+// Types of contract events.
+const (
+	eventContractExpiring = "contract.expiring"
+	eventContractExpired  = "contract.expired"
+	eventContractRenewed  = "contract.renewed"
+	eventInvoiceIssued    = "invoice.issued"
+)
+
+// event is a notification about a change of a contract which is not made by a request,
+// or which other services act on.
+type event struct {
+	Type       string      `json:"type"`
+	ContractId string      `json:"contractId"`
+	Time       time.Time   `json:"time"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// eventPublisher writes events to the log and posts them to a webhook if it is configured.
+// Failures to post are logged, and do not fail the change which caused the event.
+type eventPublisher struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func newEventPublisher(webhookURL string, timeout time.Duration) *eventPublisher {
+	return &eventPublisher{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+// events publishes the events of the server. It is replaced by run with the configured one.
+var events = newEventPublisher("", 0)
+
+// publish publishes an event of typ for a contract.
+func (p *eventPublisher) publish(ctx context.Context, typ string, contractId string, data interface{}) {
+	e := event{Type: typ, ContractId: contractId, Time: time.Now().UTC(), Data: data}
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Error("could not encode event ", typ, " for contract ", contractId, " : ", err)
+		return
+	}
+	log.Info("event ", string(b))
+
+	result := "logged"
+	if p.webhookURL != "" {
+		result = "posted"
+		if err := p.post(ctx, b); err != nil {
+			log.Error("could not post event ", typ, " for contract ", contractId, " : ", err)
+			result = "failed"
+		}
+	}
+	contractEvents.WithLabelValues(typ, result).Inc()
+}
+
+func (p *eventPublisher) post(ctx context.Context, b []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.webhookURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook responded %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventPublisher(t *testing.T) {
+	received := make(chan event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var e event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		received <- e
+	}))
+	defer srv.Close()
+
+	p := newEventPublisher(srv.URL, time.Second)
+	posted := testutil.ToFloat64(contractEvents.WithLabelValues(eventContractExpired, "posted"))
+	p.publish(context.Background(), eventContractExpired, "P0123abcd", map[string]interface{}{"state": "read_only"})
+	e := <-received
+	require.Equal(t, eventContractExpired, e.Type)
+	require.Equal(t, "P0123abcd", e.ContractId)
+	require.Equal(t, posted+1, testutil.ToFloat64(contractEvents.WithLabelValues(eventContractExpired, "posted")))
+
+	p = newEventPublisher(srv.URL+"/fail", time.Second)
+	failed := testutil.ToFloat64(contractEvents.WithLabelValues(eventContractExpired, "failed"))
+	p.publish(context.Background(), eventContractExpired, "P0123abcd", nil)
+	require.Equal(t, failed+1, testutil.ToFloat64(contractEvents.WithLabelValues(eventContractExpired, "failed")))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	"github.com/openinfradev/tks-contract/pkg/contract"
+)
+
+// expiryChecker periodically flags contracts nearing expiry and changes expired contracts
+// into the configured state, publishing an event for each of them.
+type expiryChecker struct {
+	interval     time.Duration
+	noticePeriod time.Duration
+	expiredState contract.State
+}
+
+func newExpiryChecker(interval time.Duration, noticePeriod time.Duration, expiredState contract.State) *expiryChecker {
+	return &expiryChecker{
+		interval:     interval,
+		noticePeriod: noticePeriod,
+		expiredState: expiredState,
+	}
+}
+
+// run checks the terms of contracts every interval until ctx is done.
+func (e *expiryChecker) run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.checkOnce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkOnce flags and expires contracts as of now.
+func (e *expiryChecker) checkOnce(ctx context.Context, now time.Time) {
+	expired, err := contractAccessor.Expire(ctx, now, e.expiredState)
+	for _, c := range expired {
+		log.Info("contract ", c.ID, " expired at ", c.ExpiresAt.Format(time.RFC3339), ", state is changed to ", e.expiredState)
+		events.publish(ctx, eventContractExpired, c.ID, map[string]interface{}{
+			"expiresAt": c.ExpiresAt,
+			"state":     e.expiredState,
+		})
+	}
+	if err != nil {
+		log.Error("could not expire contracts : ", err)
+	}
+
+	warned, err := contractAccessor.WarnExpiring(ctx, now, e.noticePeriod)
+	for _, c := range warned {
+		log.Info("contract ", c.ID, " expires at ", c.ExpiresAt.Format(time.RFC3339))
+		events.publish(ctx, eventContractExpiring, c.ID, map[string]interface{}{
+			"expiresAt": c.ExpiresAt,
+		})
+	}
+	if err != nil {
+		log.Error("could not flag expiring contracts : ", err)
+	}
+
+	n, err := contractAccessor.CountExpiring(ctx, now, e.noticePeriod)
+	if err != nil {
+		log.Error("could not count expiring contracts : ", err)
+		return
+	}
+	expiringContracts.Set(float64(n))
+}
@@ -0,0 +1,938 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	"github.com/openinfradev/tks-contract/pkg/api"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+const (
+	// contractServiceName is the full name of ContractService used for RPCs called through the gateway,
+	// so that interceptors see the same method names as for gRPC requests.
+	contractServiceName = "pbgo.ContractService"
+	// apiServiceName is the service name of RPCs which are served only through the gateway.
+	apiServiceName = "tks-contract.api"
+	// maxGatewayBodySize is the maximum size of a request body, which is large enough for
+	// ImportContracts of thousands of contracts.
+	maxGatewayBodySize = 16 << 20
+)
+
+// gatewayHeaders are HTTP headers which are passed to handlers as gRPC metadata. The user id is
+// trusted only along with the internal token, as for gRPC requests.
+var gatewayHeaders = []string{authorizationMetadataKey, userIdMetadataKey, "traceparent", "tracestate", "baggage"}
+
+// pathParams holds the values of path parameters of a matched route.
+type pathParams map[string]string
+
+// route maps an HTTP method and path to an RPC. Requests and responses are either
+// protocol buffer messages of ContractService or messages of the api package.
+type route struct {
+	method  string
+	path    string
+	service string
+	rpc     string
+	summary string
+	// request returns an empty request message of the RPC.
+	request func() interface{}
+	// response is an empty response message of the RPC, used for the OpenAPI document.
+	response interface{}
+	// body is true if the request message is read from the HTTP request body.
+	body bool
+	// quantities is true if the quota in the body may be given in quantities such as "16Gi".
+	quantities bool
+	// bind sets path parameters to the request message.
+	bind func(req interface{}, params pathParams)
+	// metadata maps query parameters to gRPC metadata keys, for the parameters of ContractService
+	// RPCs which their request messages have no field for.
+	metadata map[string]string
+	handler  grpc.UnaryHandler
+}
+
+// fullMethod returns the method name passed to interceptors.
+func (r *route) fullMethod() string {
+	service := r.service
+	if service == "" {
+		service = contractServiceName
+	}
+	return fmt.Sprintf("/%s/%s", service, r.rpc)
+}
+
+// match returns path parameters if path matches the route.
+func (r *route) match(path string) (pathParams, bool) {
+	want := strings.Split(strings.Trim(r.path, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return nil, false
+	}
+	params := pathParams{}
+	for i := range want {
+		if strings.HasPrefix(want[i], "{") && strings.HasSuffix(want[i], "}") {
+			if got[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(want[i], "{}")] = got[i]
+			continue
+		}
+		if want[i] != got[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// contractRoutes returns the routes of the gateway for every ContractService RPC
+// and for the RPCs of the api package.
+func contractRoutes(s *server) []route {
+	return []route{
+		{
+			method: http.MethodPost, path: "/v1/contracts", rpc: "CreateContract",
+			summary:    "Create a contract",
+			request:    func() interface{} { return &pb.CreateContractRequest{} },
+			response:   &pb.CreateContractResponse{},
+			body:       true,
+			quantities: true,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.CreateContract(ctx, req.(*pb.CreateContractRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts", rpc: "GetContracts",
+			summary:  "List contracts",
+			request:  func() interface{} { return &pb.GetContractsRequest{} },
+			response: &pb.GetContractsResponse{},
+			metadata: map[string]string{"labelSelector": labelSelectorMetadataKey},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetContracts(ctx, req.(*pb.GetContractsRequest))
+			},
+		},
+		{
+			method: http.MethodPut, path: "/v1/contracts/default", service: apiServiceName, rpc: "SetDefaultContract",
+			summary:  "Make a contract the default one",
+			request:  func() interface{} { return &api.SetDefaultContractRequest{} },
+			response: &api.SetDefaultContractResponse{},
+			body:     true,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.SetDefaultContract(ctx, req.(*api.SetDefaultContractRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/contracts/default", service: apiServiceName, rpc: "EnsureDefaultContract",
+			summary:  "Create the default contract if there is none",
+			request:  func() interface{} { return &api.EnsureDefaultContractRequest{} },
+			response: &api.EnsureDefaultContractResponse{},
+			body:     true,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.EnsureDefaultContract(ctx, req.(*api.EnsureDefaultContractRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/default", rpc: "GetDefaultContract",
+			summary:  "Get the default contract",
+			request:  func() interface{} { return &empty.Empty{} },
+			response: &pb.GetContractResponse{},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetDefaultContract(ctx, req.(*empty.Empty))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/labels", service: apiServiceName, rpc: "GetLabels",
+			summary:  "Get the labels and annotations of a contract",
+			request:  func() interface{} { return &api.GetLabelsRequest{} },
+			response: &api.GetLabelsResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.GetLabelsRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetLabels(ctx, req.(*api.GetLabelsRequest))
+			},
+		},
+		{
+			method: http.MethodPatch, path: "/v1/contracts/{contractId}/labels", service: apiServiceName, rpc: "SetLabels",
+			summary:  "Set and remove labels and annotations of a contract",
+			request:  func() interface{} { return &api.SetLabelsRequest{} },
+			response: &api.SetLabelsResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.SetLabelsRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.SetLabels(ctx, req.(*api.SetLabelsRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/children", service: apiServiceName, rpc: "ListChildren",
+			summary:  "List the sub-contracts of a contract",
+			request:  func() interface{} { return &api.ListChildrenRequest{} },
+			response: &api.ListChildrenResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.ListChildrenRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ListChildren(ctx, req.(*api.ListChildrenRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/ancestry", service: apiServiceName, rpc: "GetAncestry",
+			summary:  "Get the ancestors of a contract from its parent to the root",
+			request:  func() interface{} { return &api.GetAncestryRequest{} },
+			response: &api.GetAncestryResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.GetAncestryRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetAncestry(ctx, req.(*api.GetAncestryRequest))
+			},
+		},
+		{
+			method: http.MethodPut, path: "/v1/contracts/{contractId}/parent", service: apiServiceName, rpc: "SetParent",
+			summary:  "Attach a contract to a parent contract, or detach it with an empty parentId",
+			request:  func() interface{} { return &api.SetParentRequest{} },
+			response: &api.SetParentResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.SetParentRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.SetParent(ctx, req.(*api.SetParentRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/term", service: apiServiceName, rpc: "GetTerm",
+			summary:  "Get the term and the state of a contract",
+			request:  func() interface{} { return &api.GetTermRequest{} },
+			response: &api.GetTermResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.GetTermRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetTerm(ctx, req.(*api.GetTermRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/contracts/{contractId}/renew", service: apiServiceName, rpc: "RenewContract",
+			summary:  "Extend the term of a contract and make it active again",
+			request:  func() interface{} { return &api.RenewContractRequest{} },
+			response: &api.RenewContractResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.RenewContractRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.RenewContract(ctx, req.(*api.RenewContractRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/charges", service: apiServiceName, rpc: "GetContractCharges",
+			summary:  "Get the charges of a contract for a billing period, prorated by its quota history",
+			request:  func() interface{} { return &api.GetContractChargesRequest{} },
+			response: &api.GetContractChargesResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.GetContractChargesRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetContractCharges(ctx, req.(*api.GetContractChargesRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/manifests", service: apiServiceName, rpc: "RenderManifests",
+			summary:  "Render the quota of a contract into Kubernetes ResourceQuota and LimitRange manifests",
+			request:  func() interface{} { return &api.RenderManifestsRequest{} },
+			response: &api.RenderManifestsResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.RenderManifestsRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.RenderManifests(ctx, req.(*api.RenderManifestsRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/regional-quotas", service: apiServiceName, rpc: "ListRegionalQuotas",
+			summary:  "List the regional quotas of a contract with its total and unallocated quota",
+			request:  func() interface{} { return &api.ListRegionalQuotasRequest{} },
+			response: &api.ListRegionalQuotasResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.ListRegionalQuotasRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ListRegionalQuotas(ctx, req.(*api.ListRegionalQuotasRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/contracts/{contractId}/regional-quotas", service: apiServiceName, rpc: "AllocateRegionalQuota",
+			summary:  "Allocate a slice of the quota of a contract to a region of a CSP",
+			request:  func() interface{} { return &api.AllocateRegionalQuotaRequest{} },
+			response: &api.UpdateRegionalQuotasResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.AllocateRegionalQuotaRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.AllocateRegionalQuota(ctx, req.(*api.AllocateRegionalQuotaRequest))
+			},
+		},
+		{
+			method: http.MethodPut, path: "/v1/contracts/{contractId}/regional-quotas", service: apiServiceName, rpc: "RebalanceRegionalQuotas",
+			summary:  "Replace all the regional quotas of a contract at once",
+			request:  func() interface{} { return &api.RebalanceRegionalQuotasRequest{} },
+			response: &api.UpdateRegionalQuotasResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.RebalanceRegionalQuotasRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.RebalanceRegionalQuotas(ctx, req.(*api.RebalanceRegionalQuotasRequest))
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/v1/contracts/{contractId}/regional-quotas", service: apiServiceName, rpc: "ReleaseRegionalQuota",
+			summary:  "Release the regional quota of a CSP and region back to the contract",
+			request:  func() interface{} { return &api.ReleaseRegionalQuotaRequest{} },
+			response: &api.UpdateRegionalQuotasResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.ReleaseRegionalQuotaRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ReleaseRegionalQuota(ctx, req.(*api.ReleaseRegionalQuotaRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/csps", service: apiServiceName, rpc: "ListCsps",
+			summary:  "List the CSP accounts of a contract",
+			request:  func() interface{} { return &api.ListCspsRequest{} },
+			response: &api.ListCspsResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.ListCspsRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ListCsps(ctx, req.(*api.ListCspsRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/contracts/{contractId}/csps", service: apiServiceName, rpc: "AttachCsp",
+			summary:  "Attach a CSP account to a contract, or change the role of an attached account",
+			request:  func() interface{} { return &api.AttachCspRequest{} },
+			response: &api.AttachCspResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.AttachCspRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.AttachCsp(ctx, req.(*api.AttachCspRequest))
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/v1/contracts/{contractId}/csps/{cspId}", service: apiServiceName, rpc: "DetachCsp",
+			summary:  "Detach a CSP account from a contract",
+			request:  func() interface{} { return &api.DetachCspRequest{} },
+			response: &api.DetachCspResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.DetachCspRequest).ContractId = params["contractId"]
+				req.(*api.DetachCspRequest).CspId = params["cspId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.DetachCsp(ctx, req.(*api.DetachCspRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/members", service: apiServiceName, rpc: "ListMembers",
+			summary:  "List the members of a contract",
+			request:  func() interface{} { return &api.ListMembersRequest{} },
+			response: &api.ListMembersResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.ListMembersRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ListMembers(ctx, req.(*api.ListMembersRequest))
+			},
+		},
+		{
+			method: http.MethodPut, path: "/v1/contracts/{contractId}/members/{userId}", service: apiServiceName, rpc: "AddMember",
+			summary:  "Add a user to a contract, or change the role of a member",
+			request:  func() interface{} { return &api.AddMemberRequest{} },
+			response: &api.AddMemberResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.AddMemberRequest).ContractId = params["contractId"]
+				req.(*api.AddMemberRequest).UserId = params["userId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.AddMember(ctx, req.(*api.AddMemberRequest))
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/v1/contracts/{contractId}/members/{userId}", service: apiServiceName, rpc: "RemoveMember",
+			summary:  "Remove a user from a contract",
+			request:  func() interface{} { return &api.RemoveMemberRequest{} },
+			response: &api.RemoveMemberResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.RemoveMemberRequest).ContractId = params["contractId"]
+				req.(*api.RemoveMemberRequest).UserId = params["userId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.RemoveMember(ctx, req.(*api.RemoveMemberRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/users/{userId}/contracts", service: apiServiceName, rpc: "ListUserContracts",
+			summary:  "List the contracts which a user belongs to",
+			request:  func() interface{} { return &api.ListUserContractsRequest{} },
+			response: &api.ListUserContractsResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.ListUserContractsRequest).UserId = params["userId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ListUserContracts(ctx, req.(*api.ListUserContractsRequest))
+			},
+		},
+		{
+			method: http.MethodPut, path: "/v1/contracts/{contractId}/price-plan", service: apiServiceName, rpc: "SetPricePlan",
+			summary:  "Assign a contract to a price plan",
+			request:  func() interface{} { return &api.SetPricePlanRequest{} },
+			response: &api.SetPricePlanResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.SetPricePlanRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.SetPricePlan(ctx, req.(*api.SetPricePlanRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/price-plans", service: apiServiceName, rpc: "ListPricePlans",
+			summary:  "List the versions of price plans",
+			request:  func() interface{} { return &api.ListPricePlansRequest{} },
+			response: &api.ListPricePlansResponse{},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ListPricePlans(ctx, req.(*api.ListPricePlansRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/price-plans/{name}/versions", service: apiServiceName, rpc: "CreatePricePlan",
+			summary:  "Add a version of a price plan",
+			request:  func() interface{} { return &api.CreatePricePlanRequest{} },
+			response: &api.CreatePricePlanResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.CreatePricePlanRequest).Name = params["name"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.CreatePricePlan(ctx, req.(*api.CreatePricePlanRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/invoices", service: apiServiceName, rpc: "ListInvoices",
+			summary:  "List invoices and credit notes of a contract or of every contract",
+			request:  func() interface{} { return &api.ListInvoicesRequest{} },
+			response: &api.ListInvoicesResponse{},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ListInvoices(ctx, req.(*api.ListInvoicesRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/invoices/close", service: apiServiceName, rpc: "ClosePeriod",
+			summary:  "Issue the invoices of a billing period which is over",
+			request:  func() interface{} { return &api.ClosePeriodRequest{} },
+			response: &api.ClosePeriodResponse{},
+			body:     true,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ClosePeriod(ctx, req.(*api.ClosePeriodRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/invoices/{invoiceId}", service: apiServiceName, rpc: "GetInvoice",
+			summary:  "Get an invoice or a credit note",
+			request:  func() interface{} { return &api.GetInvoiceRequest{} },
+			response: &api.GetInvoiceResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.GetInvoiceRequest).InvoiceId = params["invoiceId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetInvoice(ctx, req.(*api.GetInvoiceRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/invoices/{invoiceId}/credit-notes", service: apiServiceName, rpc: "IssueCreditNote",
+			summary:  "Correct an invoice with a credit note",
+			request:  func() interface{} { return &api.IssueCreditNoteRequest{} },
+			response: &api.IssueCreditNoteResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.IssueCreditNoteRequest).InvoiceId = params["invoiceId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.IssueCreditNote(ctx, req.(*api.IssueCreditNoteRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/reconciliation", service: apiServiceName, rpc: "GetReconcileReport",
+			summary:  "Get the report of the last reconciliation of contracts with tks-info",
+			request:  func() interface{} { return &api.GetReconcileReportRequest{} },
+			response: &api.GetReconcileReportResponse{},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetReconcileReport(ctx, req.(*api.GetReconcileReportRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/reconciliation", service: apiServiceName, rpc: "Reconcile",
+			summary:  "Reconcile contracts with tks-info now",
+			request:  func() interface{} { return &api.ReconcileRequest{} },
+			response: &api.ReconcileResponse{},
+			body:     true,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.Reconcile(ctx, req.(*api.ReconcileRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/doctor", service: apiServiceName, rpc: "DiagnoseDatabase",
+			summary:  "Scan the database for inconsistencies",
+			request:  func() interface{} { return &api.DiagnoseDatabaseRequest{} },
+			response: &api.DiagnoseDatabaseResponse{},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.DiagnoseDatabase(ctx, req.(*api.DiagnoseDatabaseRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/doctor/repair", service: apiServiceName, rpc: "RepairDatabase",
+			summary:  "Repair inconsistencies in the database in a transaction",
+			request:  func() interface{} { return &api.RepairDatabaseRequest{} },
+			response: &api.RepairDatabaseResponse{},
+			body:     true,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.RepairDatabase(ctx, req.(*api.RepairDatabaseRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/export", service: apiServiceName, rpc: "ExportContracts",
+			summary:  "Export contracts with their quota and members as a contract document",
+			request:  func() interface{} { return &api.ExportContractsRequest{} },
+			response: &api.ExportContractsResponse{},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ExportContracts(ctx, req.(*api.ExportContractsRequest))
+			},
+		},
+		{
+			method: http.MethodPost, path: "/v1/contracts/import", service: apiServiceName, rpc: "ImportContracts",
+			summary:  "Import a contract document",
+			request:  func() interface{} { return &api.ImportContractsRequest{} },
+			response: &api.ImportContractsResponse{},
+			body:     true,
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.ImportContracts(ctx, req.(*api.ImportContractsRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}", rpc: "GetContract",
+			summary:  "Get a contract",
+			request:  func() interface{} { return &pb.GetContractRequest{} },
+			response: &pb.GetContractResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*pb.GetContractRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetContract(ctx, req.(*pb.GetContractRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/quota", rpc: "GetQuota",
+			summary:  "Get the resource quota of a contract",
+			request:  func() interface{} { return &pb.GetQuotaRequest{} },
+			response: &pb.GetQuotaResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*pb.GetQuotaRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetQuota(ctx, req.(*pb.GetQuotaRequest))
+			},
+		},
+		{
+			method: http.MethodPatch, path: "/v1/contracts/{contractId}/quota", rpc: "UpdateQuota",
+			summary:    "Update the resource quota of a contract",
+			request:    func() interface{} { return &pb.UpdateQuotaRequest{} },
+			response:   &pb.UpdateQuotaResponse{},
+			body:       true,
+			quantities: true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*pb.UpdateQuotaRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.UpdateQuota(ctx, req.(*pb.UpdateQuotaRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/services", rpc: "GetAvailableServices",
+			summary:  "Get the available services of a contract",
+			request:  func() interface{} { return &pb.GetAvailableServicesRequest{} },
+			response: &pb.GetAvailableServicesResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*pb.GetAvailableServicesRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetAvailableServices(ctx, req.(*pb.GetAvailableServicesRequest))
+			},
+		},
+		{
+			method: http.MethodPatch, path: "/v1/contracts/{contractId}/services", rpc: "UpdateServices",
+			summary:  "Update the available services of a contract",
+			request:  func() interface{} { return &pb.UpdateServicesRequest{} },
+			response: &pb.UpdateServicesResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*pb.UpdateServicesRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.UpdateServices(ctx, req.(*pb.UpdateServicesRequest))
+			},
+		},
+		{
+			method: http.MethodDelete, path: "/v1/contracts/{contractId}", service: apiServiceName, rpc: "DeleteContract",
+			summary:  "Delete a contract",
+			request:  func() interface{} { return &api.DeleteContractRequest{} },
+			response: &api.DeleteContractResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.DeleteContractRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.DeleteContract(ctx, req.(*api.DeleteContractRequest))
+			},
+		},
+		{
+			method: http.MethodPatch, path: "/v1/contracts/{contractId}", service: apiServiceName, rpc: "UpdateContract",
+			summary:  "Change the name, description and contractor profile of a contract",
+			request:  func() interface{} { return &api.UpdateContractRequest{} },
+			response: &api.UpdateContractResponse{},
+			body:     true,
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.UpdateContractRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.UpdateContract(ctx, req.(*api.UpdateContractRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/profile", service: apiServiceName, rpc: "GetProfile",
+			summary:  "Get the contractor profile of a contract",
+			request:  func() interface{} { return &api.GetProfileRequest{} },
+			response: &api.GetProfileResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.GetProfileRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetProfile(ctx, req.(*api.GetProfileRequest))
+			},
+		},
+		{
+			method: http.MethodGet, path: "/v1/contracts/{contractId}/history", service: apiServiceName, rpc: "GetContractHistory",
+			summary:  "List the changes of a contract",
+			request:  func() interface{} { return &api.GetContractHistoryRequest{} },
+			response: &api.GetContractHistoryResponse{},
+			bind: func(req interface{}, params pathParams) {
+				req.(*api.GetContractHistoryRequest).ContractId = params["contractId"]
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.GetContractHistory(ctx, req.(*api.GetContractHistoryRequest))
+			},
+		},
+	}
+}
+
+// gateway serves ContractService as HTTP/JSON. Requests pass through the same interceptors
+// as gRPC requests, so that they are validated, authorized, logged and measured alike.
+type gateway struct {
+	routes      []route
+	interceptor grpc.UnaryServerInterceptor
+	openAPI     []byte
+}
+
+func newGateway(routes []route, interceptors ...grpc.UnaryServerInterceptor) (*gateway, error) {
+	doc, err := json.MarshalIndent(openAPIDocument(routes), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate openapi document : %s", err)
+	}
+	return &gateway{
+		routes:      routes,
+		interceptor: grpc_middleware.ChainUnaryServer(interceptors...),
+		openAPI:     doc,
+	}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == openAPIPath {
+		if r.Method != http.MethodGet {
+			writeGatewayError(w, http.StatusMethodNotAllowed, pb.Code_UNIMPLEMENTED, "method not allowed")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(g.openAPI)
+		return
+	}
+
+	found := false
+	for i := range g.routes {
+		rt := &g.routes[i]
+		params, ok := rt.match(r.URL.Path)
+		if !ok {
+			continue
+		}
+		found = true
+		if rt.method == r.Method {
+			g.serveRoute(w, r, rt, params)
+			return
+		}
+	}
+	if found {
+		writeGatewayError(w, http.StatusMethodNotAllowed, pb.Code_UNIMPLEMENTED, "method not allowed")
+		return
+	}
+	writeGatewayError(w, http.StatusNotFound, pb.Code_NOT_FOUND, fmt.Sprintf("unknown path %s", r.URL.Path))
+}
+
+func (g *gateway) serveRoute(w http.ResponseWriter, r *http.Request, rt *route, params pathParams) {
+	req := rt.request()
+	if rt.body {
+		if r.ContentLength > maxGatewayBodySize {
+			writeGatewayError(w, http.StatusRequestEntityTooLarge, pb.Code_INVALID_ARGUMENT,
+				fmt.Sprintf("body is larger than %d bytes", maxGatewayBodySize))
+			return
+		}
+		b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxGatewayBodySize))
+		if err != nil {
+			writeGatewayError(w, http.StatusBadRequest, pb.Code_INVALID_ARGUMENT, fmt.Sprintf("failed to read body : %s", err))
+			return
+		}
+		if len(b) > 0 && rt.quantities {
+			if b, err = api.ParseQuotaQuantities(b); err != nil {
+				writeGatewayError(w, http.StatusBadRequest, pb.Code_INVALID_ARGUMENT, err.Error())
+				return
+			}
+		}
+		if len(b) > 0 {
+			if err := api.Unmarshal(b, req, false); err != nil {
+				writeGatewayError(w, http.StatusBadRequest, pb.Code_INVALID_ARGUMENT, fmt.Sprintf("invalid body : %s", err))
+				return
+			}
+		}
+	}
+	if err := bindQuery(req, r.URL.Query()); err != nil {
+		writeGatewayError(w, http.StatusBadRequest, pb.Code_INVALID_ARGUMENT, err.Error())
+		return
+	}
+	if rt.bind != nil {
+		rt.bind(req, params)
+	}
+
+	md := metadata.MD{}
+	for _, key := range gatewayHeaders {
+		if v := r.Header.Get(key); v != "" {
+			md.Set(key, v)
+		}
+	}
+	for param, key := range rt.metadata {
+		if v := r.URL.Query().Get(param); v != "" {
+			md.Set(key, v)
+		}
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	info := &grpc.UnaryServerInfo{
+		FullMethod: rt.fullMethod(),
+	}
+	stream := &gatewayStream{method: info.FullMethod}
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+	res, err := g.interceptor(ctx, req, info, rt.handler)
+	for key, values := range stream.header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+
+	if isNilMessage(res) {
+		if err == nil {
+			err = status.Error(codes.Internal, "empty response")
+		}
+		st := status.Convert(err)
+		code := pb.Code(st.Code())
+		writeGatewayError(w, httpStatusFromCode(code), code, st.Message())
+		return
+	}
+
+	code := pb.Code_OK_UNSPECIFIED
+	if cr, ok := res.(codeResponse); ok {
+		code = cr.GetCode()
+	}
+	if code == pb.Code_OK_UNSPECIFIED && err != nil {
+		code = pb.Code(status.Code(err))
+	}
+	b, merr := api.Marshal(res)
+	if merr != nil {
+		log.Error("failed to marshal response of ", rt.rpc, " : ", merr)
+		writeGatewayError(w, http.StatusInternalServerError, pb.Code_INTERNAL, "failed to marshal response")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatusFromCode(code))
+	_, _ = w.Write(b)
+}
+
+// gatewayStream collects the response headers which handlers set with grpc.SetHeader,
+// so that they are returned as HTTP headers.
+type gatewayStream struct {
+	method string
+	header metadata.MD
+}
+
+func (s *gatewayStream) Method() string {
+	return s.method
+}
+
+func (s *gatewayStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *gatewayStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *gatewayStream) SetTrailer(md metadata.MD) error {
+	return nil
+}
+
+// isNilMessage returns true if a handler returned no response.
+func isNilMessage(res interface{}) bool {
+	if res == nil {
+		return true
+	}
+	if m, ok := res.(proto.Message); ok {
+		return !m.ProtoReflect().IsValid()
+	}
+	v := reflect.ValueOf(res)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// bindQuery sets query parameters to the fields of req which have a query tag.
+func bindQuery(req interface{}, query url.Values) error {
+	v := reflect.ValueOf(req)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("query")
+		value := query.Get(name)
+		if name == "" || value == "" {
+			continue
+		}
+		field := v.Field(i)
+		if field.Type() == reflect.TypeOf(time.Time{}) {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("invalid query parameter %s : %s", name, value)
+			}
+			field.Set(reflect.ValueOf(t))
+			continue
+		}
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid query parameter %s : %s", name, value)
+			}
+			field.SetInt(n)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid query parameter %s : %s", name, value)
+			}
+			field.SetBool(b)
+		case reflect.Slice:
+			if field.Type().Elem().Kind() == reflect.String {
+				field.Set(reflect.ValueOf(query[name]))
+			}
+		}
+	}
+	return nil
+}
+
+// writeGatewayError writes an error in the same shape as the error fields of ContractService responses.
+func writeGatewayError(w http.ResponseWriter, httpStatus int, code pb.Code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":  code.String(),
+		"error": map[string]string{"msg": msg},
+	})
+}
+
+// httpStatusFromCode maps a response code to an HTTP status code.
+func httpStatusFromCode(code pb.Code) int {
+	switch code {
+	case pb.Code_OK_UNSPECIFIED:
+		return http.StatusOK
+	case pb.Code_CANCELLED:
+		return 499
+	case pb.Code_INVALID_ARGUMENT, pb.Code_OUT_OF_RANGE:
+		return http.StatusBadRequest
+	case pb.Code_FAILED_PRECONDITION:
+		return http.StatusPreconditionFailed
+	case pb.Code_DEADLINE_EXCEEDED:
+		return http.StatusGatewayTimeout
+	case pb.Code_NOT_FOUND:
+		return http.StatusNotFound
+	case pb.Code_ALREADY_EXISTS, pb.Code_ABORTED:
+		return http.StatusConflict
+	case pb.Code_PERMISSION_DENIED:
+		return http.StatusForbidden
+	case pb.Code_UNAUTHENTICATED:
+		return http.StatusUnauthorized
+	case pb.Code_RESOURCE_EXHAUSTED:
+		return http.StatusTooManyRequests
+	case pb.Code_UNIMPLEMENTED:
+		return http.StatusNotImplemented
+	case pb.Code_UNAVAILABLE:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// startGatewayServer serves the gateway on the given address and port in background.
+func startGatewayServer(address string, port int, tlsEnabled bool, certPath string, keyPath string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(address, strconv.Itoa(port)),
+		Handler: handler,
+	}
+	go func() {
+		log.Info("Starting gateway server on ", srv.Addr)
+		var err error
+		if tlsEnabled {
+			err = srv.ListenAndServeTLS(certPath, keyPath)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Error("failed to serve gateway : ", err)
+		}
+	}()
+	return srv
+}
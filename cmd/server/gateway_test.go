@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestRouteMatch(t *testing.T) {
+	rt := route{path: "/v1/contracts/{contractId}/quota"}
+
+	params, ok := rt.match("/v1/contracts/P0123abcd/quota")
+	require.True(t, ok)
+	require.Equal(t, "P0123abcd", params["contractId"])
+
+	_, ok = rt.match("/v1/contracts//quota")
+	require.False(t, ok)
+	_, ok = rt.match("/v1/contracts/P0123abcd")
+	require.False(t, ok)
+	_, ok = rt.match("/v1/contracts/P0123abcd/services")
+	require.False(t, ok)
+}
+
+func TestBindQuery(t *testing.T) {
+	req := &api.GetContractChargesRequest{}
+	require.NoError(t, bindQuery(req, url.Values{"from": {"2026-06-01T00:00:00Z"}}))
+	require.Equal(t, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), req.From)
+	require.True(t, req.To.IsZero())
+	require.Error(t, bindQuery(req, url.Values{"to": {"2026-06-01"}}))
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	require.Equal(t, http.StatusOK, httpStatusFromCode(pb.Code_OK_UNSPECIFIED))
+	require.Equal(t, http.StatusBadRequest, httpStatusFromCode(pb.Code_INVALID_ARGUMENT))
+	require.Equal(t, http.StatusForbidden, httpStatusFromCode(pb.Code_PERMISSION_DENIED))
+	require.Equal(t, http.StatusNotFound, httpStatusFromCode(pb.Code_NOT_FOUND))
+	require.Equal(t, http.StatusGatewayTimeout, httpStatusFromCode(pb.Code_DEADLINE_EXCEEDED))
+	require.Equal(t, http.StatusInternalServerError, httpStatusFromCode(pb.Code_INTERNAL))
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	routes := contractRoutes(&server{})
+	doc := openAPIDocument(routes)
+
+	paths := doc["paths"].(map[string]interface{})
+	for _, rt := range routes {
+		item, ok := paths[rt.path].(map[string]interface{})
+		require.True(t, ok, rt.path)
+		require.Contains(t, item, strings.ToLower(rt.method))
+	}
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	require.Contains(t, schemas, "GetContractResponse")
+	require.Contains(t, schemas, "Contract")
+	require.Contains(t, schemas, "ContractQuota")
+}
+
+func TestGateway(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	gw, err := newGateway(contractRoutes(&server{}), authServerInterceptor)
+	require.NoError(t, err)
+
+	createdContractId, err := contractAccessor.Create(context.Background(), "gateway", []string{"lma"},
+		&pb.ContractQuota{}, uuid.Nil, "")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		userId     string
+		noToken    bool
+		body       string
+		statusCode int
+		contains   string
+	}{
+		{
+			name:       "OK",
+			method:     http.MethodGet,
+			path:       "/v1/contracts/" + createdContractId,
+			statusCode: http.StatusOK,
+			contains:   createdContractId,
+		},
+		{
+			name:       "UNAUTHENTICATED",
+			method:     http.MethodGet,
+			path:       "/v1/contracts/" + createdContractId,
+			noToken:    true,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "UNAUTHENTICATED_USER",
+			method:     http.MethodGet,
+			path:       "/v1/contracts/" + createdContractId,
+			userId:     uuid.New().String(),
+			noToken:    true,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "INVALID_ARGUMENT",
+			method:     http.MethodGet,
+			path:       "/v1/contracts/invalid_contract_id/quota",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "PERMISSION_DENIED",
+			method:     http.MethodGet,
+			path:       "/v1/contracts/" + createdContractId,
+			userId:     uuid.New().String(),
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "INVALID_BODY",
+			method:     http.MethodPatch,
+			path:       "/v1/contracts/" + createdContractId + "/services",
+			body:       "{",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "BODY_TOO_LARGE",
+			method:     http.MethodPatch,
+			path:       "/v1/contracts/" + createdContractId + "/services",
+			body:       `{"availableServices": ["` + strings.Repeat("a", maxGatewayBodySize) + `"]}`,
+			statusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "QUANTITIES",
+			method:     http.MethodPatch,
+			path:       "/v1/contracts/" + createdContractId + "/quota",
+			body:       `{"quota": {"cpu": "4000m", "memory": "1Ti", "block": 10}}`,
+			statusCode: http.StatusOK,
+			contains:   "1024",
+		},
+		{
+			name:       "INVALID_QUANTITY",
+			method:     http.MethodPatch,
+			path:       "/v1/contracts/" + createdContractId + "/quota",
+			body:       `{"quota": {"memory": "512Mi"}}`,
+			statusCode: http.StatusBadRequest,
+			contains:   "whole number of GiB",
+		},
+		{
+			name:       "INVALID_SELECTOR",
+			method:     http.MethodGet,
+			path:       "/v1/contracts?labelSelector=tier%3Dgold%2C",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "METHOD_NOT_ALLOWED",
+			method:     http.MethodPut,
+			path:       "/v1/contracts/" + createdContractId,
+			statusCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "NOT_FOUND",
+			method:     http.MethodGet,
+			path:       "/v1/unknown",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "OPENAPI",
+			method:     http.MethodGet,
+			path:       openAPIPath,
+			statusCode: http.StatusOK,
+			contains:   "/v1/contracts/{contractId}/quota",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if !tc.noToken {
+				req.Header.Set("Authorization", "Bearer "+testInternalToken)
+			}
+			if tc.userId != "" {
+				req.Header.Set(userIdMetadataKey, tc.userId)
+			}
+			rec := httptest.NewRecorder()
+
+			gw.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.statusCode, rec.Code)
+			require.True(t, json.Valid(rec.Body.Bytes()))
+			require.Contains(t, rec.Body.String(), tc.contains)
+		})
+	}
+
+	t.Run("CSP_IDS_HEADER", func(t *testing.T) {
+		cspId := uuid.New()
+		_, err := contractAccessor.AttachCsp(context.Background(), createdContractId, model.ContractCsp{
+			CspID: cspId, CspName: "aws", Role: string(contract.CspRolePrimary),
+		})
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/contracts/"+createdContractId, nil)
+		req.Header.Set("Authorization", "Bearer "+testInternalToken)
+		gw.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, []string{cspId.String()}, rec.Header().Values(cspIdsMetadataKey))
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"gorm.io/gorm"
+)
+
+// gormCallback returns a gorm callback for the given operation such as create or query.
+type gormCallback func(operation string) func(*gorm.DB)
+
+// registerGormCallbacks registers callbacks which run before and after every gorm operation.
+func registerGormCallbacks(db *gorm.DB, name string, before gormCallback, after gormCallback) error {
+	callbacks := db.Callback()
+	registrations := []func() error{
+		func() error {
+			return callbacks.Create().Before("gorm:create").Register(name+":before_create", before("create"))
+		},
+		func() error {
+			return callbacks.Create().After("gorm:create").Register(name+":after_create", after("create"))
+		},
+		func() error {
+			return callbacks.Query().Before("gorm:query").Register(name+":before_query", before("query"))
+		},
+		func() error {
+			return callbacks.Query().After("gorm:query").Register(name+":after_query", after("query"))
+		},
+		func() error {
+			return callbacks.Update().Before("gorm:update").Register(name+":before_update", before("update"))
+		},
+		func() error {
+			return callbacks.Update().After("gorm:update").Register(name+":after_update", after("update"))
+		},
+		func() error {
+			return callbacks.Delete().Before("gorm:delete").Register(name+":before_delete", before("delete"))
+		},
+		func() error {
+			return callbacks.Delete().After("gorm:delete").Register(name+":after_delete", after("delete"))
+		},
+		func() error {
+			return callbacks.Row().Before("gorm:row").Register(name+":before_row", before("row"))
+		},
+		func() error {
+			return callbacks.Row().After("gorm:row").Register(name+":after_row", after("row"))
+		},
+		func() error {
+			return callbacks.Raw().Before("gorm:raw").Register(name+":before_raw", before("raw"))
+		},
+		func() error {
+			return callbacks.Raw().After("gorm:raw").Register(name+":after_raw", after("raw"))
+		},
+	}
+	for _, register := range registrations {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	"github.com/openinfradev/tks-contract/pkg/contract/quantity"
+	"github.com/openinfradev/tks-contract/pkg/redact"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// createServer creates a grpc server and a listener with the given interceptors.
+func createServer(port int, tlsEnabled bool, certPath string, keyPath string,
+	interceptors ...grpc.UnaryServerInterceptor) (*grpc.Server, net.Listener, error) {
+	log.Info("Starting to listen port ", port)
+	conn, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen port %d : %s", port, err)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(interceptors...)),
+	}
+	if tlsEnabled {
+		creds, err := credentials.NewServerTLSFromFile(certPath, keyPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load tls credentials : %s", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	return grpc.NewServer(opts...), conn, nil
+}
+
+// createCspInfoClient creates a client for tks-info with the given interceptors.
+func createCspInfoClient(address string, port int, tlsEnabled bool, caPath string,
+	interceptors ...grpc.UnaryClientInterceptor) (*grpc.ClientConn, pb.CspInfoServiceClient, error) {
+	opts := []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	}
+	if tlsEnabled {
+		creds, err := credentials.NewClientTLSFromFile(caPath, "")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load tls credentials : %s", err)
+		}
+		opts = append(opts, grpc.WithTransportCredentials(creds))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	cc, err := grpc.Dial(fmt.Sprintf("%s:%d", address, port), opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect %s:%d : %s", address, port, err)
+	}
+	return cc, pb.NewCspInfoServiceClient(cc), nil
+}
+
+// loggingServerInterceptor logs each request with its sensitive fields masked.
+func loggingServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if m, ok := req.(proto.Message); ok {
+		log.Debug("Request ", info.FullMethod, " : ", redact.Message(m))
+	}
+	return handler(ctx, req)
+}
+
+// healthServicePrefix is the prefix of the methods of the gRPC health service, which probes
+// call without credentials.
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+// authServerInterceptor rejects requests without the internal token if the token is configured,
+// so that every method is denied to callers without it.
+func authServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+		return handler(ctx, req)
+	}
+	if _, _, err := callerFromContext(ctx); err != nil {
+		return nil, status.Error(codes.Code(callerErrorCode(err)), err.Error())
+	}
+	return handler(ctx, req)
+}
+
+// actorServerInterceptor passes the caller to the contract accessor, which records it in the history of changes.
+// An invalid caller is rejected by the handlers.
+func actorServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if userId, ok, err := callerFromContext(ctx); ok && err == nil {
+		ctx = contract.WithActor(ctx, userId)
+	}
+	return handler(ctx, req)
+}
+
+// timeoutServerInterceptor applies timeout to requests which have no deadline,
+// so that database queries of a request are bounded even if the client does not set a deadline.
+func timeoutServerInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// grpcServer serves ContractService on gRPC. ContractQuota.cpu of tks-proto is in cores, while
+// the gateway and the database use millicores, so that it converts cpu quotas at the boundary.
+// Cpu quotas which are not whole cores, set through the gateway, are rounded down in responses.
+type grpcServer struct {
+	*server
+}
+
+// CreateContract implements pbgo.ContractService.CreateContract gRPC
+func (s *grpcServer) CreateContract(ctx context.Context, in *pb.CreateContractRequest) (*pb.CreateContractResponse, error) {
+	quota, err := coresToMillicores(in.GetQuota())
+	if err != nil {
+		return &pb.CreateContractResponse{
+			Code:  pb.Code_INVALID_ARGUMENT,
+			Error: &pb.Error{Msg: err.Error()},
+		}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	in.Quota = quota
+	return s.server.CreateContract(ctx, in)
+}
+
+// UpdateQuota implements pbgo.ContractService.UpdateQuota gRPC
+func (s *grpcServer) UpdateQuota(ctx context.Context, in *pb.UpdateQuotaRequest) (*pb.UpdateQuotaResponse, error) {
+	quota, err := coresToMillicores(in.GetQuota())
+	if err != nil {
+		return &pb.UpdateQuotaResponse{
+			Code:  pb.Code_INVALID_ARGUMENT,
+			Error: &pb.Error{Msg: err.Error()},
+		}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	in.Quota = quota
+	res, err := s.server.UpdateQuota(ctx, in)
+	if res != nil {
+		res.PrevQuota = millicoresToCores(res.PrevQuota)
+		res.CurrentQuota = millicoresToCores(res.CurrentQuota)
+	}
+	return res, err
+}
+
+// GetContract implements pbgo.ContractService.GetContract gRPC
+func (s *grpcServer) GetContract(ctx context.Context, in *pb.GetContractRequest) (*pb.GetContractResponse, error) {
+	res, err := s.server.GetContract(ctx, in)
+	if res != nil && res.Contract != nil {
+		res.Contract.Quota = millicoresToCores(res.Contract.Quota)
+	}
+	return res, err
+}
+
+// GetDefaultContract implements pbgo.ContractService.GetDefaultContract gRPC
+func (s *grpcServer) GetDefaultContract(ctx context.Context, in *empty.Empty) (*pb.GetContractResponse, error) {
+	res, err := s.server.GetDefaultContract(ctx, in)
+	if res != nil && res.Contract != nil {
+		res.Contract.Quota = millicoresToCores(res.Contract.Quota)
+	}
+	return res, err
+}
+
+// GetContracts implements pbgo.ContractService.GetContracts gRPC
+func (s *grpcServer) GetContracts(ctx context.Context, in *pb.GetContractsRequest) (*pb.GetContractsResponse, error) {
+	res, err := s.server.GetContracts(ctx, in)
+	if res != nil {
+		for _, c := range res.Contracts {
+			c.Quota = millicoresToCores(c.Quota)
+		}
+	}
+	return res, err
+}
+
+// GetQuota implements pbgo.ContractService.GetQuota gRPC
+func (s *grpcServer) GetQuota(ctx context.Context, in *pb.GetQuotaRequest) (*pb.GetQuotaResponse, error) {
+	res, err := s.server.GetQuota(ctx, in)
+	if res != nil {
+		res.Quota = millicoresToCores(res.Quota)
+	}
+	return res, err
+}
+
+// coresToMillicores returns a quota of the gRPC API with cpu in millicores.
+func coresToMillicores(quota *pb.ContractQuota) (*pb.ContractQuota, error) {
+	if quota == nil {
+		return nil, nil
+	}
+	if quota.Cpu > math.MaxInt64/quantity.MillicoresPerCore || quota.Cpu < math.MinInt64/quantity.MillicoresPerCore {
+		return nil, fmt.Errorf("cpu quota %d is out of range", quota.Cpu)
+	}
+	return &pb.ContractQuota{
+		Cpu:      quota.Cpu * quantity.MillicoresPerCore,
+		Memory:   quota.Memory,
+		Block:    quota.Block,
+		BlockSsd: quota.BlockSsd,
+		Fs:       quota.Fs,
+		FsSsd:    quota.FsSsd,
+	}, nil
+}
+
+// millicoresToCores converts cpu of a quota to cores for the gRPC API.
+func millicoresToCores(quota *pb.ContractQuota) *pb.ContractQuota {
+	if quota != nil {
+		quota.Cpu /= quantity.MillicoresPerCore
+	}
+	return quota
+}
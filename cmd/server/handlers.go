@@ -2,7 +2,12 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/google/uuid"
@@ -10,8 +15,42 @@ import (
 	"github.com/openinfradev/tks-common/pkg/helper"
 	"github.com/openinfradev/tks-common/pkg/log"
 	pb "github.com/openinfradev/tks-proto/tks_pb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
 )
 
+// authorizationMetadataKey is a gRPC metadata key for the credential of the caller, which is
+// "Bearer " followed by the internal token shared with operators and other TKS services.
+const authorizationMetadataKey = "authorization"
+
+// userIdMetadataKey is a gRPC metadata key for the user id of the caller. It is trusted only
+// along with the internal token, as TKS services call on behalf of their users. Requests with
+// the token but without a user id are made by operators and are not filtered by membership.
+const userIdMetadataKey = "tks-user-id"
+
+// labelSelectorMetadataKey is a gRPC metadata key for the label selector of GetContracts,
+// which GetContractsRequest of tks-proto has no field for.
+const labelSelectorMetadataKey = "tks-label-selector"
+
+// cspIdsMetadataKey is a gRPC response header for the ids of the CSP accounts of GetContract,
+// which Contract of tks-proto has no field for. The primary account comes first.
+const cspIdsMetadataKey = "tks-csp-ids"
+
+// internalToken returns the token which every caller must pass. Authentication is disabled while
+// no token is configured, so that callers which do not pass the token keep working until it is
+// configured.
+var internalToken = func() string { return "" }
+
+// errUnauthenticated is returned for a request without the internal token.
+var errUnauthenticated = errors.New("internal token is missing or invalid")
+
 func checkContractId(contractId string) (string, error) {
 	if !helper.ValidateContractId(contractId) {
 		return "", fmt.Errorf("invalid contract ID %s", contractId)
@@ -20,6 +59,131 @@ func checkContractId(contractId string) (string, error) {
 	return contractId, nil
 }
 
+// authenticate returns errUnauthenticated unless the internal token is passed through gRPC metadata.
+// Every request is authenticated if no internal token is configured.
+func authenticate(ctx context.Context) error {
+	token := internalToken()
+	if token == "" {
+		return nil
+	}
+	md, exists := metadata.FromIncomingContext(ctx)
+	if !exists {
+		return errUnauthenticated
+	}
+	for _, v := range md.Get(authorizationMetadataKey) {
+		if !strings.HasPrefix(v, "Bearer ") {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(v, "Bearer ")), []byte(token)) == 1 {
+			return nil
+		}
+	}
+	return errUnauthenticated
+}
+
+// callerFromContext returns the user id of the caller if it is passed through gRPC metadata.
+// It returns errUnauthenticated if the request is not authenticated, so that no request is
+// treated as an internal call without the internal token.
+func callerFromContext(ctx context.Context) (userId uuid.UUID, ok bool, err error) {
+	if err := authenticate(ctx); err != nil {
+		return uuid.Nil, false, err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(userIdMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return uuid.Nil, false, nil
+	}
+	userId, err = uuid.Parse(values[0])
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("invalid user ID %s", values[0])
+	}
+	return userId, true, nil
+}
+
+// callerErrorCode returns the code for an error of callerFromContext.
+func callerErrorCode(err error) pb.Code {
+	if errors.Is(err, errUnauthenticated) {
+		return pb.Code_UNAUTHENTICATED
+	}
+	return pb.Code_INVALID_ARGUMENT
+}
+
+// selectorFromContext returns the label selector if it is passed through gRPC metadata.
+func selectorFromContext(ctx context.Context) (contract.Selector, error) {
+	md, exists := metadata.FromIncomingContext(ctx)
+	if !exists {
+		return nil, nil
+	}
+	values := md.Get(labelSelectorMetadataKey)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return contract.ParseSelector(values[0])
+}
+
+// errorCode returns the code for a failed request. If the request is canceled or its
+// deadline is exceeded, it is reported instead of defaultCode.
+func errorCode(ctx context.Context, defaultCode pb.Code) pb.Code {
+	switch ctx.Err() {
+	case context.Canceled:
+		return pb.Code_CANCELLED
+	case context.DeadlineExceeded:
+		return pb.Code_DEADLINE_EXCEEDED
+	}
+	return defaultCode
+}
+
+// rpcError converts err into a gRPC status error if the request is canceled or its
+// deadline is exceeded.
+func rpcError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return err
+}
+
+// checkMembership returns an error code if the caller is not a member of the contract.
+// If roles are given, the caller must have one of them. Only authenticated internal calls
+// without a user id skip the check.
+func checkMembership(ctx context.Context, contractId string, roles ...contract.Role) (pb.Code, error) {
+	userId, ok, err := callerFromContext(ctx)
+	if err != nil {
+		return callerErrorCode(err), err
+	}
+	if !ok {
+		return pb.Code_OK_UNSPECIFIED, nil
+	}
+	role, err := contractAccessor.GetMemberRole(ctx, contractId, userId)
+	if err != nil {
+		if ctx.Err() != nil {
+			return errorCode(ctx, pb.Code_INTERNAL), rpcError(ctx, err)
+		}
+		return pb.Code_PERMISSION_DENIED, fmt.Errorf("user %s is not a member of contract %s", userId, contractId)
+	}
+	if len(roles) == 0 {
+		return pb.Code_OK_UNSPECIFIED, nil
+	}
+	for _, r := range roles {
+		if role == r {
+			return pb.Code_OK_UNSPECIFIED, nil
+		}
+	}
+	return pb.Code_PERMISSION_DENIED, fmt.Errorf("user %s is %s of contract %s", userId, role, contractId)
+}
+
+// checkActive returns an error code if the contract is not active. Expired contracts
+// cannot be changed until they are renewed.
+func checkActive(ctx context.Context, contractId string) (pb.Code, error) {
+	term, err := contractAccessor.GetTerm(ctx, contractId)
+	if err != nil {
+		return errorCode(ctx, pb.Code_NOT_FOUND), rpcError(ctx, err)
+	}
+	if term.State != contract.StateActive {
+		return pb.Code_FAILED_PRECONDITION, fmt.Errorf("contract %s is %s", contractId, term.State)
+	}
+	return pb.Code_OK_UNSPECIFIED, nil
+}
+
 // CreateContract implements pbgo.ContractService.CreateContract gRPC
 func (s *server) CreateContract(ctx context.Context, in *pb.CreateContractRequest) (*pb.CreateContractResponse, error) {
 	log.Info("Request 'CreateContract' for contract name", in.GetContractorName())
@@ -38,16 +202,17 @@ func (s *server) CreateContract(ctx context.Context, in *pb.CreateContractReques
 		}
 	}
 
-	contractId, err := contractAccessor.Create(in.GetContractorName(), in.GetAvailableServices(), in.GetQuota(), creator, in.GetDescription())
+	contractId, err := contractAccessor.Create(ctx, in.GetContractorName(), in.GetAvailableServices(), in.GetQuota(), creator, in.GetDescription())
 	if err != nil {
 		return &pb.CreateContractResponse{
-			Code: pb.Code_NOT_FOUND,
+			Code: errorCode(ctx, pb.Code_NOT_FOUND),
 			Error: &pb.Error{
 				Msg: err.Error(),
 			},
 		}, nil
 	}
 	log.Info("newly created Contract Id:", contractId)
+	setContractIdAttribute(ctx, contractId)
 
 	res, err := cspInfoClient.CreateCSPInfo(ctx, &pb.CreateCSPInfoRequest{
 		ContractId: contractId,
@@ -56,8 +221,9 @@ func (s *server) CreateContract(ctx context.Context, in *pb.CreateContractReques
 	})
 	log.Info("newly created CSP Id:", res.GetId())
 	if err != nil {
+		rollbackContract(contractId)
 		return &pb.CreateContractResponse{
-			Code: res.GetCode(),
+			Code: errorCode(ctx, res.GetCode()),
 			Error: &pb.Error{
 				Msg: err.Error(),
 			},
@@ -65,6 +231,7 @@ func (s *server) CreateContract(ctx context.Context, in *pb.CreateContractReques
 	}
 
 	if res.GetCode() != pb.Code_OK_UNSPECIFIED {
+		rollbackContract(contractId)
 		return &pb.CreateContractResponse{
 			Code: res.GetCode(),
 			Error: &pb.Error{
@@ -73,23 +240,33 @@ func (s *server) CreateContract(ctx context.Context, in *pb.CreateContractReques
 		}, nil
 	}
 
-	workflowTemplate := "tks-create-contract-repo"
-	nameSpace := "argo"
-	opts := argowf.SubmitOptions{}
-	opts.Parameters = []string{
-		"contract_id=" + contractId,
-		"revision=" + revision,
+	cspID, err := uuid.Parse(res.GetId())
+	if err == nil {
+		_, err = contractAccessor.AttachCsp(ctx, contractId, model.ContractCsp{
+			CspID:   cspID,
+			CspName: in.GetCspName(),
+			Role:    string(contract.CspRolePrimary),
+		})
+	}
+	if err != nil {
+		rollbackContract(contractId)
+		return &pb.CreateContractResponse{
+			Code: errorCode(ctx, pb.Code_INTERNAL),
+			Error: &pb.Error{
+				Msg: fmt.Sprintf("failed to attach CSP account %s : %s", res.GetId(), err),
+			},
+		}, nil
 	}
 
-	workflowName, err := argowfClient.SumbitWorkflowFromWftpl(workflowTemplate, nameSpace, opts)
+	workflowName, err := submitWorkflow(ctx, "tks-create-contract-repo",
+		"contract_id="+contractId,
+		"revision="+cfg.Argo.Revision,
+	)
 	if err != nil {
 		log.Error("failed to submit argo workflow template. err : ", err)
 
 		// 생성된 contract 를 rollback 한다.
-		err := contractAccessor.Delete(contractId)
-		if err != nil {
-			log.Error("Failed to delete contract ", contractId)
-		}
+		rollbackContract(contractId)
 
 		return &pb.CreateContractResponse{
 			Code: pb.Code_INTERNAL,
@@ -112,9 +289,56 @@ func (s *server) CreateContract(ctx context.Context, in *pb.CreateContractReques
 	}, nil
 }
 
+// submitWorkflow submits a workflow from workflowTemplate with parameters, and returns the name of
+// the workflow.
+func submitWorkflow(ctx context.Context, workflowTemplate string, parameters ...string) (string, error) {
+	nameSpace := "argo"
+	opts := argowf.SubmitOptions{}
+	opts.Parameters = parameters
+
+	_, span := tracer.Start(ctx, "argo.SumbitWorkflowFromWftpl", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("argo.workflow_template", workflowTemplate)))
+	workflowName, err := argowfClient.SumbitWorkflowFromWftpl(workflowTemplate, nameSpace, opts)
+	endSpan(span, err)
+	observeArgoSubmission(workflowTemplate, err)
+	return workflowName, err
+}
+
+// cleanupContract returns the cleanup of a deleted contract, which submits a workflow to clean up
+// the repository and the CSP info in tks-info. The contract is kept if the workflow could not be
+// submitted, so that the deletion can be retried.
+func cleanupContract(ctx context.Context, contractID string) func(csps []model.ContractCsp) error {
+	return func(csps []model.ContractCsp) error {
+		cspIDs := make([]string, 0, len(csps))
+		for _, csp := range csps {
+			cspIDs = append(cspIDs, csp.CspID.String())
+		}
+		workflowName, err := submitWorkflow(ctx, "tks-delete-contract-repo",
+			"contract_id="+contractID,
+			"csp_ids="+strings.Join(cspIDs, ","),
+			"revision="+cfg.Argo.Revision,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to call argo workflow : %w", err)
+		}
+		log.Info("submited workflow :", workflowName)
+		return nil
+	}
+}
+
+// rollbackContract deletes a contract whose creation failed halfway, so that no contract is left
+// without its CSP account or repository. CSP info which is already created in tks-info is reported
+// by the reconciler as a CSP account without contract.
+func rollbackContract(contractId string) {
+	if err := contractAccessor.Delete(context.Background(), contractId); err != nil {
+		log.Error("Failed to delete contract ", contractId, " : ", err)
+	}
+}
+
 // UpdateQuota implements pbgo.ContractService.UpdateQuota gRPC
 func (s *server) UpdateQuota(ctx context.Context, in *pb.UpdateQuotaRequest) (*pb.UpdateQuotaResponse, error) {
 	log.Info("Request 'UpdateQuota' for contract id ", in.GetContractId())
+	setContractIdAttribute(ctx, in.GetContractId())
 	contractID, err := checkContractId(in.GetContractId())
 	if err != nil {
 		res := pb.UpdateQuotaResponse{
@@ -125,16 +349,32 @@ func (s *server) UpdateQuota(ctx context.Context, in *pb.UpdateQuotaRequest) (*p
 		}
 		return &res, err
 	}
-	prev, curr, err := contractAccessor.UpdateResourceQuota(contractID, in.GetQuota())
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner, contract.RoleAdmin); err != nil {
+		return &pb.UpdateQuotaResponse{
+			Code:  code,
+			Error: &pb.Error{Msg: err.Error()},
+		}, err
+	}
+	if code, err := checkActive(ctx, contractID); err != nil {
+		return &pb.UpdateQuotaResponse{
+			Code:  code,
+			Error: &pb.Error{Msg: err.Error()},
+		}, err
+	}
+	prev, curr, err := contractAccessor.UpdateResourceQuota(ctx, contractID, in.GetQuota())
 
 	if err != nil {
+		code := pb.Code_INTERNAL
+		if errors.Is(err, contract.ErrQuotaExceeded) || errors.Is(err, contract.ErrRegionalQuotaExceeded) {
+			code = pb.Code_FAILED_PRECONDITION
+		}
 		res := pb.UpdateQuotaResponse{
-			Code: pb.Code_INTERNAL,
+			Code: errorCode(ctx, code),
 			Error: &pb.Error{
 				Msg: err.Error(),
 			},
 		}
-		return &res, err
+		return &res, rpcError(ctx, err)
 	}
 	return &pb.UpdateQuotaResponse{
 		Code:         pb.Code_OK_UNSPECIFIED,
@@ -147,6 +387,7 @@ func (s *server) UpdateQuota(ctx context.Context, in *pb.UpdateQuotaRequest) (*p
 // UpdateServices implements pbgo.ContractService.UpdateServices gRPC
 func (s *server) UpdateServices(ctx context.Context, in *pb.UpdateServicesRequest) (*pb.UpdateServicesResponse, error) {
 	log.Info("Request 'UpdateServices' for contract id ", in.GetContractId())
+	setContractIdAttribute(ctx, in.GetContractId())
 	contractID, err := checkContractId(in.GetContractId())
 	if err != nil {
 		res := pb.UpdateServicesResponse{
@@ -157,15 +398,27 @@ func (s *server) UpdateServices(ctx context.Context, in *pb.UpdateServicesReques
 		}
 		return &res, err
 	}
-	prev, curr, err := contractAccessor.UpdateAvailableServices(contractID, in.GetAvailableServices())
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner, contract.RoleAdmin); err != nil {
+		return &pb.UpdateServicesResponse{
+			Code:  code,
+			Error: &pb.Error{Msg: err.Error()},
+		}, err
+	}
+	if code, err := checkActive(ctx, contractID); err != nil {
+		return &pb.UpdateServicesResponse{
+			Code:  code,
+			Error: &pb.Error{Msg: err.Error()},
+		}, err
+	}
+	prev, curr, err := contractAccessor.UpdateAvailableServices(ctx, contractID, in.GetAvailableServices())
 	if err != nil {
 		res := pb.UpdateServicesResponse{
-			Code: pb.Code_INTERNAL,
+			Code: errorCode(ctx, pb.Code_INTERNAL),
 			Error: &pb.Error{
 				Msg: err.Error(),
 			},
 		}
-		return &res, err
+		return &res, rpcError(ctx, err)
 	}
 	return &pb.UpdateServicesResponse{
 		Code:            pb.Code_OK_UNSPECIFIED,
@@ -178,6 +431,7 @@ func (s *server) UpdateServices(ctx context.Context, in *pb.UpdateServicesReques
 // GetContract implements pbgo.ContractService.GetContract gRPC
 func (s *server) GetContract(ctx context.Context, in *pb.GetContractRequest) (*pb.GetContractResponse, error) {
 	log.Info("Request 'GetContract' for contract id ", in.GetContractId())
+	setContractIdAttribute(ctx, in.GetContractId())
 	contractID, err := checkContractId(in.GetContractId())
 	if err != nil {
 		res := pb.GetContractResponse{
@@ -188,16 +442,26 @@ func (s *server) GetContract(ctx context.Context, in *pb.GetContractRequest) (*p
 		}
 		return &res, err
 	}
-	contract, err := contractAccessor.GetContract(contractID)
-	if err != nil {
+	if code, err := checkMembership(ctx, contractID); err != nil {
 		res := pb.GetContractResponse{
-			Code: pb.Code_NOT_FOUND,
+			Code: code,
 			Error: &pb.Error{
 				Msg: err.Error(),
 			},
 		}
 		return &res, err
 	}
+	contract, err := contractAccessor.GetContract(ctx, contractID)
+	if err != nil {
+		res := pb.GetContractResponse{
+			Code: errorCode(ctx, pb.Code_NOT_FOUND),
+			Error: &pb.Error{
+				Msg: err.Error(),
+			},
+		}
+		return &res, rpcError(ctx, err)
+	}
+	setCspIdsHeader(ctx, contractID)
 	res := pb.GetContractResponse{
 		Code:     pb.Code_OK_UNSPECIFIED,
 		Error:    nil,
@@ -206,20 +470,38 @@ func (s *server) GetContract(ctx context.Context, in *pb.GetContractRequest) (*p
 	return &res, nil
 }
 
+// setCspIdsHeader returns the ids of the CSP accounts of a contract in the response header.
+func setCspIdsHeader(ctx context.Context, contractID string) {
+	csps, err := contractAccessor.ListCsps(ctx, contractID)
+	if err != nil {
+		log.Error("failed to list CSP accounts of contract ", contractID, " : ", err)
+		return
+	}
+	ids := make([]string, 0, len(csps))
+	for _, c := range csps {
+		ids = append(ids, c.CspID.String())
+	}
+	if err := grpc.SetHeader(ctx, metadata.MD{cspIdsMetadataKey: ids}); err != nil {
+		// the handler is called without a gRPC stream, such as in tests
+		log.Debug("failed to set header ", cspIdsMetadataKey, " : ", err)
+	}
+}
+
 // GetDefaultContract implements pbgo.ContractService.GetDefaultContract gRPC
 func (s *server) GetDefaultContract(ctx context.Context, in *empty.Empty) (*pb.GetContractResponse, error) {
 	log.Info("Request 'GetDefaultContract' ")
 
-	contract, err := contractAccessor.GetDefaultContract()
+	contract, err := contractAccessor.GetDefaultContract(ctx)
 	if err != nil {
 		res := pb.GetContractResponse{
-			Code: pb.Code_NOT_FOUND,
+			Code: errorCode(ctx, pb.Code_NOT_FOUND),
 			Error: &pb.Error{
 				Msg: err.Error(),
 			},
 		}
-		return &res, err
+		return &res, rpcError(ctx, err)
 	}
+	setCspIdsHeader(ctx, contract.GetContractId())
 	res := pb.GetContractResponse{
 		Code:     pb.Code_OK_UNSPECIFIED,
 		Error:    nil,
@@ -234,16 +516,41 @@ func (s *server) GetContracts(ctx context.Context, in *pb.GetContractsRequest) (
 
 	const OFFSET = 0
 	const MX_LIMIT = 100
-	contracts, err := contractAccessor.List(OFFSET, MX_LIMIT)
+	userId, ok, err := callerFromContext(ctx)
+	if err != nil {
+		res := pb.GetContractsResponse{
+			Code: callerErrorCode(err),
+			Error: &pb.Error{
+				Msg: err.Error(),
+			},
+		}
+		return &res, err
+	}
+	selector, err := selectorFromContext(ctx)
 	if err != nil {
 		res := pb.GetContractsResponse{
-			Code: pb.Code_NOT_FOUND,
+			Code: pb.Code_INVALID_ARGUMENT,
 			Error: &pb.Error{
 				Msg: err.Error(),
 			},
 		}
 		return &res, err
 	}
+	var contracts []*pb.Contract
+	if ok {
+		contracts, err = contractAccessor.GetUserContracts(ctx, userId, selector)
+	} else {
+		contracts, err = contractAccessor.List(ctx, OFFSET, MX_LIMIT, selector)
+	}
+	if err != nil {
+		res := pb.GetContractsResponse{
+			Code: errorCode(ctx, pb.Code_NOT_FOUND),
+			Error: &pb.Error{
+				Msg: err.Error(),
+			},
+		}
+		return &res, rpcError(ctx, err)
+	}
 	res := pb.GetContractsResponse{
 		Code:      pb.Code_OK_UNSPECIFIED,
 		Error:     nil,
@@ -255,6 +562,7 @@ func (s *server) GetContracts(ctx context.Context, in *pb.GetContractsRequest) (
 // GetQuota implements pbgo.ContractService.GetContract gRPC
 func (s *server) GetQuota(ctx context.Context, in *pb.GetQuotaRequest) (*pb.GetQuotaResponse, error) {
 	log.Info("Request 'GetQuota' for contract id ", in.GetContractId())
+	setContractIdAttribute(ctx, in.GetContractId())
 	contractID, err := checkContractId(in.GetContractId())
 	if err != nil {
 		return &pb.GetQuotaResponse{
@@ -264,15 +572,23 @@ func (s *server) GetQuota(ctx context.Context, in *pb.GetQuotaRequest) (*pb.GetQ
 			},
 		}, err
 	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &pb.GetQuotaResponse{
+			Code: code,
+			Error: &pb.Error{
+				Msg: err.Error(),
+			},
+		}, err
+	}
 
-	quota, err := contractAccessor.GetResourceQuota(contractID)
+	quota, err := contractAccessor.GetResourceQuota(ctx, contractID)
 	if err != nil {
 		return &pb.GetQuotaResponse{
-			Code: pb.Code_NOT_FOUND,
+			Code: errorCode(ctx, pb.Code_NOT_FOUND),
 			Error: &pb.Error{
 				Msg: err.Error(),
 			},
-		}, err
+		}, rpcError(ctx, err)
 	}
 	return &pb.GetQuotaResponse{
 		Code:  pb.Code_OK_UNSPECIFIED,
@@ -291,6 +607,7 @@ func (s *server) GetQuota(ctx context.Context, in *pb.GetQuotaRequest) (*pb.GetQ
 // GetAvailableServices implements pbgo.ContractService.GetAvailableServices gRPC
 func (s *server) GetAvailableServices(ctx context.Context, in *pb.GetAvailableServicesRequest) (*pb.GetAvailableServicesResponse, error) {
 	log.Info("Request 'GetAvailableServices' for contract id ", in.GetContractId())
+	setContractIdAttribute(ctx, in.GetContractId())
 	contractID, err := checkContractId(in.GetContractId())
 	if err != nil {
 		return &pb.GetAvailableServicesResponse{
@@ -300,15 +617,23 @@ func (s *server) GetAvailableServices(ctx context.Context, in *pb.GetAvailableSe
 			},
 		}, err
 	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &pb.GetAvailableServicesResponse{
+			Code: code,
+			Error: &pb.Error{
+				Msg: err.Error(),
+			},
+		}, err
+	}
 
-	contract, err := contractAccessor.GetContract(contractID)
+	contract, err := contractAccessor.GetContract(ctx, contractID)
 	if err != nil {
 		return &pb.GetAvailableServicesResponse{
-			Code: pb.Code_NOT_FOUND,
+			Code: errorCode(ctx, pb.Code_NOT_FOUND),
 			Error: &pb.Error{
 				Msg: err.Error(),
 			},
-		}, err
+		}, rpcError(ctx, err)
 	}
 
 	res := pb.GetAvailableServicesResponse{
@@ -318,3 +643,1870 @@ func (s *server) GetAvailableServices(ctx context.Context, in *pb.GetAvailableSe
 	}
 	return &res, nil
 }
+
+// DeleteContract deletes a contract with its quota and members. Only the owner can delete a contract.
+// CSP info of the contract is kept in tks-info.
+func (s *server) DeleteContract(ctx context.Context, in *api.DeleteContractRequest) (*api.DeleteContractResponse, error) {
+	log.Info("Request 'DeleteContract' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.DeleteContractResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner); err != nil {
+		return &api.DeleteContractResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if _, err := contractAccessor.GetContract(ctx, contractID); err != nil {
+		return &api.DeleteContractResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+
+	if err := contractAccessor.DeleteWith(ctx, contractID, cleanupContract(ctx, contractID)); err != nil {
+		code := pb.Code_INTERNAL
+		if errors.Is(err, contract.ErrHasSubContracts) {
+			code = pb.Code_FAILED_PRECONDITION
+		}
+		return &api.DeleteContractResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.DeleteContractResponse{}, nil
+}
+
+// GetContractHistory returns the changes of a contract, newest first.
+func (s *server) GetContractHistory(ctx context.Context, in *api.GetContractHistoryRequest) (*api.GetContractHistoryResponse, error) {
+	log.Info("Request 'GetContractHistory' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+
+	const MX_LIMIT = 100
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.GetContractHistoryResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if in.Offset < 0 || in.Limit < 0 {
+		err := fmt.Errorf("offset and limit must not be negative")
+		return &api.GetContractHistoryResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.GetContractHistoryResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	limit := in.Limit
+	if limit == 0 || limit > MX_LIMIT {
+		limit = MX_LIMIT
+	}
+	records, err := contractAccessor.GetHistory(ctx, contractID, in.Offset, limit)
+	if err != nil {
+		return &api.GetContractHistoryResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+		}, rpcError(ctx, err)
+	}
+
+	res := api.GetContractHistoryResponse{
+		Records: []*api.HistoryRecord{},
+	}
+	for _, r := range records {
+		record := &api.HistoryRecord{
+			Id:         r.ID.String(),
+			ContractId: r.ContractID,
+			Action:     r.Action,
+			CreatedAt:  r.CreatedAt,
+		}
+		if r.Actor != uuid.Nil {
+			record.Actor = r.Actor.String()
+		}
+		if r.Previous != "" {
+			record.Previous = json.RawMessage(r.Previous)
+		}
+		if r.Current != "" {
+			record.Current = json.RawMessage(r.Current)
+		}
+		res.Records = append(res.Records, record)
+	}
+	return &res, nil
+}
+
+// GetLabels returns the labels and annotations of a contract.
+func (s *server) GetLabels(ctx context.Context, in *api.GetLabelsRequest) (*api.GetLabelsResponse, error) {
+	log.Info("Request 'GetLabels' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.GetLabelsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.GetLabelsResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	labels, annotations, err := contractAccessor.GetLabels(ctx, contractID)
+	if err != nil {
+		return &api.GetLabelsResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.GetLabelsResponse{
+		Labels:      labels,
+		Annotations: annotations,
+	}, nil
+}
+
+// SetLabels sets and removes labels and annotations of a contract. Owners and admins can set them.
+func (s *server) SetLabels(ctx context.Context, in *api.SetLabelsRequest) (*api.SetLabelsResponse, error) {
+	log.Info("Request 'SetLabels' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.SetLabelsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if err := contract.ValidateLabels(in.Labels); err != nil {
+		return &api.SetLabelsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if err := contract.ValidateAnnotations(in.Annotations); err != nil {
+		return &api.SetLabelsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner, contract.RoleAdmin); err != nil {
+		return &api.SetLabelsResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if code, err := checkActive(ctx, contractID); err != nil {
+		return &api.SetLabelsResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	labels, annotations, err := contractAccessor.SetLabels(ctx, contractID, in.Labels, in.RemoveLabels,
+		in.Annotations, in.RemoveAnnotations)
+	if err != nil {
+		return &api.SetLabelsResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.SetLabelsResponse{
+		Labels:      labels,
+		Annotations: annotations,
+	}, nil
+}
+
+// GetProfile returns the contractor profile of a contract.
+func (s *server) GetProfile(ctx context.Context, in *api.GetProfileRequest) (*api.GetProfileResponse, error) {
+	log.Info("Request 'GetProfile' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.GetProfileResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.GetProfileResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	profile, err := contractAccessor.GetProfile(ctx, contractID)
+	if err != nil {
+		return &api.GetProfileResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.GetProfileResponse{Profile: reflectToApiProfile(profile)}, nil
+}
+
+// UpdateContract changes the name, description and contractor profile of a contract. Owners
+// and admins can change them.
+func (s *server) UpdateContract(ctx context.Context, in *api.UpdateContractRequest) (*api.UpdateContractResponse, error) {
+	log.Info("Request 'UpdateContract' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.UpdateContractResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner, contract.RoleAdmin); err != nil {
+		return &api.UpdateContractResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if code, err := checkActive(ctx, contractID); err != nil {
+		return &api.UpdateContractResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	prev, curr, err := contractAccessor.UpdateContract(ctx, contractID, contract.ProfileUpdate{
+		ContractorName:             in.ContractorName,
+		Description:                in.Description,
+		BusinessRegistrationNumber: in.BusinessRegistrationNumber,
+		BillingAddress:             in.BillingAddress,
+		ContactEmail:               in.ContactEmail,
+		ContactPhone:               in.ContactPhone,
+	})
+	if err != nil {
+		code := pb.Code_NOT_FOUND
+		switch {
+		case errors.Is(err, contract.ErrInvalidProfile):
+			code = pb.Code_INVALID_ARGUMENT
+		case errors.Is(err, contract.ErrContractorNameTaken):
+			code = pb.Code_ALREADY_EXISTS
+		}
+		return &api.UpdateContractResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.UpdateContractResponse{
+		PrevProfile: reflectToApiProfile(prev),
+		Profile:     reflectToApiProfile(curr),
+	}, nil
+}
+
+func reflectToApiProfile(profile contract.Profile) *api.ContractProfile {
+	return &api.ContractProfile{
+		ContractorName:             profile.ContractorName,
+		Description:                profile.Description,
+		BusinessRegistrationNumber: profile.BusinessRegistrationNumber,
+		BillingAddress:             profile.BillingAddress,
+		ContactEmail:               profile.ContactEmail,
+		ContactPhone:               profile.ContactPhone,
+	}
+}
+
+// GetTerm returns the term and the state of a contract.
+func (s *server) GetTerm(ctx context.Context, in *api.GetTermRequest) (*api.GetTermResponse, error) {
+	log.Info("Request 'GetTerm' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.GetTermResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.GetTermResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	term, err := contractAccessor.GetTerm(ctx, contractID)
+	if err != nil {
+		return &api.GetTermResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.GetTermResponse{Term: reflectToApiTerm(term)}, nil
+}
+
+// RenewContract extends the term of a contract and makes it active again. Only owners can renew it.
+func (s *server) RenewContract(ctx context.Context, in *api.RenewContractRequest) (*api.RenewContractResponse, error) {
+	log.Info("Request 'RenewContract' for contract id ", in.ContractId, " until ", in.ExpiresAt)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.RenewContractResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if in.ExpiresAt.IsZero() {
+		err := fmt.Errorf("expiresAt must be specified")
+		return &api.RenewContractResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner); err != nil {
+		return &api.RenewContractResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if _, err := contractAccessor.GetTerm(ctx, contractID); err != nil {
+		return &api.RenewContractResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+
+	prev, curr, err := contractAccessor.Renew(ctx, contractID, in.EffectiveFrom, in.ExpiresAt)
+	if err != nil {
+		return &api.RenewContractResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INVALID_ARGUMENT), err),
+		}, rpcError(ctx, err)
+	}
+	events.publish(ctx, eventContractRenewed, contractID, map[string]interface{}{
+		"prevExpiresAt": prev.ExpiresAt,
+		"expiresAt":     curr.ExpiresAt,
+		"prevState":     prev.State,
+	})
+	return &api.RenewContractResponse{
+		PrevTerm:    reflectToApiTerm(prev),
+		CurrentTerm: reflectToApiTerm(curr),
+	}, nil
+}
+
+func reflectToApiTerm(term contract.Term) *api.Term {
+	return &api.Term{
+		EffectiveFrom:  term.EffectiveFrom,
+		ExpiresAt:      term.ExpiresAt,
+		State:          string(term.State),
+		ExpiryWarnedAt: term.ExpiryWarnedAt,
+	}
+}
+
+// GetAncestry returns the ancestors of a contract from its parent to the root.
+func (s *server) GetAncestry(ctx context.Context, in *api.GetAncestryRequest) (*api.GetAncestryResponse, error) {
+	log.Info("Request 'GetAncestry' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.GetAncestryResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.GetAncestryResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	ancestors, err := contractAccessor.GetAncestry(ctx, contractID)
+	if err != nil {
+		return &api.GetAncestryResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.GetAncestryResponse{Ancestors: []*api.ContractSummary{}}
+	for _, c := range ancestors {
+		res.Ancestors = append(res.Ancestors, reflectToApiSummary(c))
+	}
+	return res, nil
+}
+
+// ListChildren returns the sub-contracts of a contract and the quota allocated to them.
+func (s *server) ListChildren(ctx context.Context, in *api.ListChildrenRequest) (*api.ListChildrenResponse, error) {
+	log.Info("Request 'ListChildren' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.ListChildrenResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.ListChildrenResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	children, allocated, err := contractAccessor.ListChildren(ctx, contractID)
+	if err != nil {
+		return &api.ListChildrenResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.ListChildrenResponse{
+		Children:  []*api.ContractSummary{},
+		Allocated: reflectToApiQuota(allocated),
+	}
+	for _, c := range children {
+		res.Children = append(res.Children, reflectToApiSummary(c))
+	}
+	return res, nil
+}
+
+// SetParent makes a contract a sub-contract of another, or detaches it from its parent.
+// The caller must be an owner of the contract and of the parent which it is attached to or detached from.
+func (s *server) SetParent(ctx context.Context, in *api.SetParentRequest) (*api.SetParentResponse, error) {
+	log.Info("Request 'SetParent' for contract id ", in.ContractId, " to parent ", in.ParentId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.SetParentResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if in.ParentId != "" {
+		if _, err := checkContractId(in.ParentId); err != nil {
+			return &api.SetParentResponse{
+				Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+			}, err
+		}
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner); err != nil {
+		return &api.SetParentResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if code, err := checkActive(ctx, contractID); err != nil {
+		return &api.SetParentResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	ancestors, err := contractAccessor.GetAncestry(ctx, contractID)
+	if err != nil {
+		return &api.SetParentResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	parentID := in.ParentId
+	if parentID == "" && len(ancestors) > 0 {
+		parentID = ancestors[0].Contract.ID
+	}
+	if parentID != "" {
+		if code, err := checkMembership(ctx, parentID, contract.RoleOwner); err != nil {
+			return &api.SetParentResponse{
+				Status: api.NewStatus(code, err),
+			}, err
+		}
+	}
+	// the quota of the new parent is taken by the contract, so that it must be active as well
+	if in.ParentId != "" {
+		if code, err := checkActive(ctx, in.ParentId); err != nil {
+			return &api.SetParentResponse{
+				Status: api.NewStatus(code, err),
+			}, err
+		}
+	}
+
+	prev, err := contractAccessor.SetParent(ctx, contractID, in.ParentId)
+	if err != nil {
+		code := pb.Code_INTERNAL
+		switch {
+		case errors.Is(err, contract.ErrInvalidParent):
+			code = pb.Code_INVALID_ARGUMENT
+		case errors.Is(err, contract.ErrQuotaExceeded):
+			code = pb.Code_FAILED_PRECONDITION
+		}
+		return &api.SetParentResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.SetParentResponse{ParentId: in.ParentId}
+	if prev != nil {
+		res.PrevParentId = *prev
+	}
+	return res, nil
+}
+
+func reflectToApiSummary(c contract.ContractWithQuota) *api.ContractSummary {
+	summary := &api.ContractSummary{
+		ContractId:        c.Contract.ID,
+		ContractorName:    c.Contract.ContractorName,
+		AvailableServices: c.Contract.AvailableServices,
+		Quota:             reflectToApiQuota(c.Quota),
+		State:             c.Contract.State,
+	}
+	if summary.AvailableServices == nil {
+		summary.AvailableServices = []string{}
+	}
+	if c.Contract.ParentID != nil {
+		summary.ParentId = *c.Contract.ParentID
+	}
+	return summary
+}
+
+func reflectToApiQuota(quota model.ResourceQuota) api.Quota {
+	return api.Quota{
+		Cpu:      quota.Cpu,
+		Memory:   quota.Memory,
+		Block:    quota.Block,
+		BlockSsd: quota.BlockSsd,
+		Fs:       quota.Fs,
+		FsSsd:    quota.FsSsd,
+	}
+}
+
+// RenderManifests renders the quota and services of a contract into Kubernetes manifests with
+// the mapping of the configuration.
+func (s *server) RenderManifests(ctx context.Context, in *api.RenderManifestsRequest) (*api.RenderManifestsResponse, error) {
+	log.Info("Request 'RenderManifests' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.RenderManifestsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.RenderManifestsResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	c, err := contractAccessor.GetContract(ctx, contractID)
+	if err != nil {
+		return &api.RenderManifestsResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	manifests, err := contract.RenderManifests(c, cfg.Manifests.mapping())
+	if err != nil {
+		return &api.RenderManifestsResponse{
+			Status: api.NewStatus(pb.Code_INTERNAL, err),
+		}, err
+	}
+	res := &api.RenderManifestsResponse{Manifests: []*api.Manifest{}}
+	for _, m := range manifests {
+		res.Manifests = append(res.Manifests, &api.Manifest{Path: m.Path, Kind: m.Kind, Content: string(m.Content)})
+	}
+	return res, nil
+}
+
+// ListRegionalQuotas returns the regional quotas of a contract with its quota and the part of it
+// which is not allocated to any region.
+func (s *server) ListRegionalQuotas(ctx context.Context, in *api.ListRegionalQuotasRequest) (*api.ListRegionalQuotasResponse, error) {
+	log.Info("Request 'ListRegionalQuotas' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.ListRegionalQuotasResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.ListRegionalQuotasResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	quotas, err := contractAccessor.ListRegionalQuotas(ctx, contractID)
+	if err != nil {
+		return &api.ListRegionalQuotasResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	total, err := contractAccessor.GetResourceQuota(ctx, contractID)
+	if err != nil {
+		return &api.ListRegionalQuotasResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	allocated := contract.SumRegionalQuotas(quotas)
+	return &api.ListRegionalQuotasResponse{
+		RegionalQuotas: reflectToApiRegionalQuotas(quotas),
+		Total: api.Quota{
+			Cpu: total.Cpu, Memory: total.Memory, Block: total.Block,
+			BlockSsd: total.BlockSsd, Fs: total.Fs, FsSsd: total.FsSsd,
+		},
+		Allocated: reflectToApiQuota(allocated),
+		Unallocated: api.Quota{
+			Cpu:      total.Cpu - allocated.Cpu,
+			Memory:   total.Memory - allocated.Memory,
+			Block:    total.Block - allocated.Block,
+			BlockSsd: total.BlockSsd - allocated.BlockSsd,
+			Fs:       total.Fs - allocated.Fs,
+			FsSsd:    total.FsSsd - allocated.FsSsd,
+		},
+	}, nil
+}
+
+// AllocateRegionalQuota allocates a slice of the quota of a contract to a region of a CSP.
+func (s *server) AllocateRegionalQuota(ctx context.Context, in *api.AllocateRegionalQuotaRequest) (*api.UpdateRegionalQuotasResponse, error) {
+	log.Info("Request 'AllocateRegionalQuota' for contract id ", in.ContractId, " in ", in.Csp, " ", in.Region)
+	quota := reflectToModelRegionalQuota(&api.RegionalQuota{Csp: in.Csp, Region: in.Region, Quota: in.Quota})
+	return updateRegionalQuotas(ctx, in.ContractId, []model.RegionalQuota{quota},
+		func(contractID string) ([]model.RegionalQuota, []model.RegionalQuota, error) {
+			return contractAccessor.AllocateRegionalQuota(ctx, contractID, quota)
+		})
+}
+
+// ReleaseRegionalQuota releases the regional quota of a CSP and region back to the contract.
+func (s *server) ReleaseRegionalQuota(ctx context.Context, in *api.ReleaseRegionalQuotaRequest) (*api.UpdateRegionalQuotasResponse, error) {
+	log.Info("Request 'ReleaseRegionalQuota' for contract id ", in.ContractId, " in ", in.Csp, " ", in.Region)
+	return updateRegionalQuotas(ctx, in.ContractId, []model.RegionalQuota{{Csp: in.Csp, Region: in.Region}},
+		func(contractID string) ([]model.RegionalQuota, []model.RegionalQuota, error) {
+			return contractAccessor.ReleaseRegionalQuota(ctx, contractID, in.Csp, in.Region)
+		})
+}
+
+// RebalanceRegionalQuotas replaces all the regional quotas of a contract at once.
+func (s *server) RebalanceRegionalQuotas(ctx context.Context, in *api.RebalanceRegionalQuotasRequest) (*api.UpdateRegionalQuotasResponse, error) {
+	log.Info("Request 'RebalanceRegionalQuotas' for contract id ", in.ContractId)
+	quotas := []model.RegionalQuota{}
+	for _, q := range in.RegionalQuotas {
+		if q == nil {
+			continue
+		}
+		quotas = append(quotas, reflectToModelRegionalQuota(q))
+	}
+	return updateRegionalQuotas(ctx, in.ContractId, quotas,
+		func(contractID string) ([]model.RegionalQuota, []model.RegionalQuota, error) {
+			return contractAccessor.RebalanceRegionalQuotas(ctx, contractID, quotas)
+		})
+}
+
+// updateRegionalQuotas checks a request to change the regional quotas of a contract, which is
+// allowed to owners and admins of an active contract, and applies it with update.
+func updateRegionalQuotas(ctx context.Context, contractId string, quotas []model.RegionalQuota,
+	update func(contractID string) ([]model.RegionalQuota, []model.RegionalQuota, error)) (*api.UpdateRegionalQuotasResponse, error) {
+	setContractIdAttribute(ctx, contractId)
+	contractID, err := checkContractId(contractId)
+	if err != nil {
+		return &api.UpdateRegionalQuotasResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if err := contract.ValidateRegionalQuotas(quotas); err != nil {
+		return &api.UpdateRegionalQuotasResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner, contract.RoleAdmin); err != nil {
+		return &api.UpdateRegionalQuotasResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if code, err := checkActive(ctx, contractID); err != nil {
+		return &api.UpdateRegionalQuotasResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	prev, curr, err := update(contractID)
+	if err != nil {
+		code := pb.Code_INTERNAL
+		switch {
+		case errors.Is(err, contract.ErrRegionalQuotaExceeded):
+			code = pb.Code_FAILED_PRECONDITION
+		case errors.Is(err, contract.ErrNoRegionalQuota):
+			code = pb.Code_NOT_FOUND
+		}
+		return &api.UpdateRegionalQuotasResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.UpdateRegionalQuotasResponse{
+		PrevRegionalQuotas: reflectToApiRegionalQuotas(prev),
+		RegionalQuotas:     reflectToApiRegionalQuotas(curr),
+	}, nil
+}
+
+func reflectToApiRegionalQuotas(quotas []model.RegionalQuota) []*api.RegionalQuota {
+	res := []*api.RegionalQuota{}
+	for _, q := range quotas {
+		res = append(res, &api.RegionalQuota{
+			Csp:    q.Csp,
+			Region: q.Region,
+			Quota: api.Quota{
+				Cpu: q.Cpu, Memory: q.Memory, Block: q.Block, BlockSsd: q.BlockSsd, Fs: q.Fs, FsSsd: q.FsSsd,
+			},
+		})
+	}
+	return res
+}
+
+func reflectToModelRegionalQuota(q *api.RegionalQuota) model.RegionalQuota {
+	return model.RegionalQuota{
+		Csp:      q.Csp,
+		Region:   q.Region,
+		Cpu:      q.Quota.Cpu,
+		Memory:   q.Quota.Memory,
+		Block:    q.Quota.Block,
+		BlockSsd: q.Quota.BlockSsd,
+		Fs:       q.Quota.Fs,
+		FsSsd:    q.Quota.FsSsd,
+	}
+}
+
+// ListCsps returns the CSP accounts of a contract.
+func (s *server) ListCsps(ctx context.Context, in *api.ListCspsRequest) (*api.ListCspsResponse, error) {
+	log.Info("Request 'ListCsps' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.ListCspsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.ListCspsResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	csps, err := contractAccessor.ListCsps(ctx, contractID)
+	if err != nil {
+		return &api.ListCspsResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.ListCspsResponse{Csps: []*api.ContractCsp{}}
+	for _, c := range csps {
+		res.Csps = append(res.Csps, reflectToApiCsp(c))
+	}
+	return res, nil
+}
+
+// AttachCsp attaches a CSP account to a contract. The account is either an existing CSP info of
+// the contract in tks-info, or a new one which is created with the name and the credential.
+func (s *server) AttachCsp(ctx context.Context, in *api.AttachCspRequest) (*api.AttachCspResponse, error) {
+	log.Info("Request 'AttachCsp' for contract id ", in.ContractId, " csp id ", in.CspId, " csp name ", in.CspName)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.AttachCspResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	role := contract.CspRole(in.Role)
+	if role == "" {
+		role = contract.CspRoleSecondary
+	}
+	if err := contract.ValidateCspRole(role); err != nil {
+		return &api.AttachCspResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	var cspID uuid.UUID
+	switch {
+	case in.CspId != "" && in.CspName != "":
+		err = fmt.Errorf("either cspId or cspName must be specified, not both")
+	case in.CspId != "":
+		if cspID, err = uuid.Parse(in.CspId); err != nil {
+			err = fmt.Errorf("invalid CSP ID %s", in.CspId)
+		}
+	case in.CspName == "":
+		err = fmt.Errorf("either cspId or cspName must be specified")
+	}
+	if err != nil {
+		return &api.AttachCspResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner, contract.RoleAdmin); err != nil {
+		return &api.AttachCspResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if code, err := checkActive(ctx, contractID); err != nil {
+		return &api.AttachCspResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	cspName := in.CspName
+	if cspID == uuid.Nil {
+		res, err := cspInfoClient.CreateCSPInfo(ctx, &pb.CreateCSPInfoRequest{
+			ContractId: contractID,
+			CspName:    in.CspName,
+			Auth:       in.CspAuth,
+		})
+		if err != nil {
+			return &api.AttachCspResponse{
+				Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+			}, rpcError(ctx, err)
+		}
+		if res.GetCode() != pb.Code_OK_UNSPECIFIED {
+			err := fmt.Errorf("failed to create CSP info : %s", res.GetError().GetMsg())
+			return &api.AttachCspResponse{
+				Status: api.NewStatus(res.GetCode(), err),
+			}, err
+		}
+		if cspID, err = uuid.Parse(res.GetId()); err != nil {
+			return &api.AttachCspResponse{
+				Status: api.NewStatus(pb.Code_INTERNAL, err),
+			}, err
+		}
+		log.Info("newly created CSP Id:", cspID)
+	} else {
+		res, err := cspInfoClient.GetCSPInfo(ctx, &pb.IDRequest{Id: cspID.String()})
+		if err != nil {
+			return &api.AttachCspResponse{
+				Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+			}, rpcError(ctx, err)
+		}
+		if res.GetCode() != pb.Code_OK_UNSPECIFIED {
+			err := fmt.Errorf("failed to get CSP info %s : %s", cspID, res.GetError().GetMsg())
+			return &api.AttachCspResponse{
+				Status: api.NewStatus(res.GetCode(), err),
+			}, err
+		}
+		if res.GetContractId() != contractID {
+			err := fmt.Errorf("CSP info %s belongs to contract %s", cspID, res.GetContractId())
+			return &api.AttachCspResponse{
+				Status: api.NewStatus(pb.Code_FAILED_PRECONDITION, err),
+			}, err
+		}
+		cspName = res.GetCspName()
+	}
+
+	csp, err := contractAccessor.AttachCsp(ctx, contractID, model.ContractCsp{
+		CspID:   cspID,
+		CspName: cspName,
+		Role:    string(role),
+	})
+	if err != nil {
+		return &api.AttachCspResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.AttachCspResponse{Csp: reflectToApiCsp(csp)}, nil
+}
+
+// DetachCsp detaches a CSP account from a contract. The CSP info remains in tks-info.
+func (s *server) DetachCsp(ctx context.Context, in *api.DetachCspRequest) (*api.DetachCspResponse, error) {
+	log.Info("Request 'DetachCsp' for contract id ", in.ContractId, " csp id ", in.CspId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.DetachCspResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	cspID, err := uuid.Parse(in.CspId)
+	if err != nil {
+		err = fmt.Errorf("invalid CSP ID %s", in.CspId)
+		return &api.DetachCspResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner, contract.RoleAdmin); err != nil {
+		return &api.DetachCspResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if code, err := checkActive(ctx, contractID); err != nil {
+		return &api.DetachCspResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	csp, err := contractAccessor.DetachCsp(ctx, contractID, cspID)
+	if err != nil {
+		code := pb.Code_INTERNAL
+		switch {
+		case errors.Is(err, contract.ErrCspNotAttached):
+			code = pb.Code_NOT_FOUND
+		case errors.Is(err, contract.ErrPrimaryCsp), errors.Is(err, contract.ErrLastCsp):
+			code = pb.Code_FAILED_PRECONDITION
+		}
+		return &api.DetachCspResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.DetachCspResponse{Csp: reflectToApiCsp(csp)}, nil
+}
+
+func reflectToApiCsp(csp model.ContractCsp) *api.ContractCsp {
+	return &api.ContractCsp{
+		CspId:      csp.CspID.String(),
+		CspName:    csp.CspName,
+		Role:       csp.Role,
+		AttachedAt: csp.CreatedAt,
+	}
+}
+
+// ListMembers returns the members of a contract. Every member can list them.
+func (s *server) ListMembers(ctx context.Context, in *api.ListMembersRequest) (*api.ListMembersResponse, error) {
+	log.Info("Request 'ListMembers' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.ListMembersResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID); err != nil {
+		return &api.ListMembersResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	members, err := contractAccessor.ListMembers(ctx, contractID)
+	if err != nil {
+		return &api.ListMembersResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.ListMembersResponse{Members: []*api.ContractMember{}}
+	for _, m := range members {
+		res.Members = append(res.Members, reflectToApiMember(m))
+	}
+	return res, nil
+}
+
+// AddMember adds a user to a contract, or changes the role of a member. Only owners can change
+// the members, and the only owner cannot be demoted.
+func (s *server) AddMember(ctx context.Context, in *api.AddMemberRequest) (*api.AddMemberResponse, error) {
+	log.Info("Request 'AddMember' for contract id ", in.ContractId, " user id ", in.UserId, " role ", in.Role)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.AddMemberResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	userID, err := uuid.Parse(in.UserId)
+	if err != nil {
+		err = fmt.Errorf("invalid user ID %s", in.UserId)
+		return &api.AddMemberResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if err := contract.ValidateRole(contract.Role(in.Role)); err != nil {
+		return &api.AddMemberResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner); err != nil {
+		return &api.AddMemberResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if _, err := contractAccessor.GetContract(ctx, contractID); err != nil {
+		return &api.AddMemberResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+
+	if err := contractAccessor.AddMember(ctx, contractID, userID, contract.Role(in.Role)); err != nil {
+		code := pb.Code_INTERNAL
+		if errors.Is(err, contract.ErrLastOwner) {
+			code = pb.Code_FAILED_PRECONDITION
+		}
+		return &api.AddMemberResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+	members, err := contractAccessor.ListMembers(ctx, contractID)
+	if err != nil {
+		return &api.AddMemberResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.AddMemberResponse{}
+	for _, m := range members {
+		if m.UserID == userID {
+			res.Member = reflectToApiMember(m)
+		}
+	}
+	return res, nil
+}
+
+// RemoveMember removes a user from a contract. Only owners can change the members, and the only
+// owner cannot be removed.
+func (s *server) RemoveMember(ctx context.Context, in *api.RemoveMemberRequest) (*api.RemoveMemberResponse, error) {
+	log.Info("Request 'RemoveMember' for contract id ", in.ContractId, " user id ", in.UserId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.RemoveMemberResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	userID, err := uuid.Parse(in.UserId)
+	if err != nil {
+		err = fmt.Errorf("invalid user ID %s", in.UserId)
+		return &api.RemoveMemberResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner); err != nil {
+		return &api.RemoveMemberResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+
+	if err := contractAccessor.RemoveMember(ctx, contractID, userID); err != nil {
+		code := pb.Code_NOT_FOUND
+		if errors.Is(err, contract.ErrLastOwner) {
+			code = pb.Code_FAILED_PRECONDITION
+		}
+		return &api.RemoveMemberResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.RemoveMemberResponse{}, nil
+}
+
+// ListUserContracts returns the contracts which a user belongs to. Users can list only their own
+// contracts, and operators can list the contracts of any user.
+func (s *server) ListUserContracts(ctx context.Context, in *api.ListUserContractsRequest) (*api.ListUserContractsResponse, error) {
+	log.Info("Request 'ListUserContracts' for user id ", in.UserId)
+	userID, err := uuid.Parse(in.UserId)
+	if err != nil {
+		err = fmt.Errorf("invalid user ID %s", in.UserId)
+		return &api.ListUserContractsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	caller, ok, err := callerFromContext(ctx)
+	if err != nil {
+		return &api.ListUserContractsResponse{
+			Status: api.NewStatus(callerErrorCode(err), err),
+		}, err
+	}
+	if ok && caller != userID {
+		err := fmt.Errorf("user %s cannot list the contracts of user %s", caller, userID)
+		return &api.ListUserContractsResponse{
+			Status: api.NewStatus(pb.Code_PERMISSION_DENIED, err),
+		}, err
+	}
+
+	contracts, err := contractAccessor.GetUserContracts(ctx, userID, nil)
+	if err != nil {
+		return &api.ListUserContractsResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+		}, rpcError(ctx, err)
+	}
+	roles, err := contractAccessor.GetUserRoles(ctx, userID)
+	if err != nil {
+		return &api.ListUserContractsResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.ListUserContractsResponse{Contracts: []*api.UserContract{}}
+	for _, c := range contracts {
+		res.Contracts = append(res.Contracts, &api.UserContract{
+			ContractId:     c.GetContractId(),
+			ContractorName: c.GetContractorName(),
+			Role:           string(roles[c.GetContractId()]),
+		})
+	}
+	return res, nil
+}
+
+func reflectToApiMember(m model.ContractMember) *api.ContractMember {
+	return &api.ContractMember{
+		UserId:  m.UserID.String(),
+		Role:    m.Role,
+		AddedAt: m.CreatedAt,
+	}
+}
+
+// GetContractCharges returns the charges of a contract for a billing period.
+func (s *server) GetContractCharges(ctx context.Context, in *api.GetContractChargesRequest) (*api.GetContractChargesResponse, error) {
+	log.Info("Request 'GetContractCharges' for contract id ", in.ContractId, " from ", in.From, " to ", in.To)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.GetContractChargesResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	from, to := in.From, in.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		now := time.Now().UTC()
+		from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	if !from.Before(to) {
+		err := fmt.Errorf("from %s must be before to %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+		return &api.GetContractChargesResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkMembership(ctx, contractID, contract.RoleOwner, contract.RoleAdmin); err != nil {
+		return &api.GetContractChargesResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if _, err := contractAccessor.GetContract(ctx, contractID); err != nil {
+		return &api.GetContractChargesResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+
+	charges, err := contractAccessor.GetCharges(ctx, contractID, from, to)
+	if err != nil {
+		code := pb.Code_INTERNAL
+		if errors.Is(err, contract.ErrNoPrice) {
+			code = pb.Code_FAILED_PRECONDITION
+		}
+		return &api.GetContractChargesResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.GetContractChargesResponse{
+		Currency: charges.Currency,
+		From:     charges.From,
+		To:       charges.To,
+		Lines:    []*api.ChargeLine{},
+		Total:    charges.Total,
+	}
+	for _, l := range charges.Lines {
+		res.Lines = append(res.Lines, &api.ChargeLine{
+			Kind:      l.Kind,
+			Item:      l.Item,
+			PricePlan: l.PricePlan,
+			Quantity:  l.Quantity,
+			UnitPrice: l.UnitPrice,
+			From:      l.From,
+			To:        l.To,
+			Amount:    l.Amount,
+		})
+	}
+	return res, nil
+}
+
+// SetPricePlan assigns a contract to a price plan. Only operators can change price plans.
+func (s *server) SetPricePlan(ctx context.Context, in *api.SetPricePlanRequest) (*api.SetPricePlanResponse, error) {
+	log.Info("Request 'SetPricePlan' for contract id ", in.ContractId, " to plan ", in.PricePlan)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.SetPricePlanResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if in.PricePlan == "" {
+		err := fmt.Errorf("pricePlan must be specified")
+		return &api.SetPricePlanResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.SetPricePlanResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if _, err := contractAccessor.GetContract(ctx, contractID); err != nil {
+		return &api.SetPricePlanResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+
+	prev, err := contractAccessor.SetPricePlan(ctx, contractID, in.PricePlan)
+	if err != nil {
+		return &api.SetPricePlanResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_FAILED_PRECONDITION), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.SetPricePlanResponse{PrevPricePlan: prev, PricePlan: in.PricePlan}, nil
+}
+
+// ListPricePlans returns the versions of price plans.
+func (s *server) ListPricePlans(ctx context.Context, in *api.ListPricePlansRequest) (*api.ListPricePlansResponse, error) {
+	log.Info("Request 'ListPricePlans' for plans ", in.Names)
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.ListPricePlansResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	plans, err := contractAccessor.ListPricePlans(ctx, in.Names...)
+	if err != nil {
+		return &api.ListPricePlansResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.ListPricePlansResponse{PricePlans: []*api.PricePlan{}}
+	for _, p := range plans {
+		res.PricePlans = append(res.PricePlans, reflectToApiPricePlan(p))
+	}
+	return res, nil
+}
+
+// CreatePricePlan adds a version of a price plan. Only operators can create price plans.
+func (s *server) CreatePricePlan(ctx context.Context, in *api.CreatePricePlanRequest) (*api.CreatePricePlanResponse, error) {
+	log.Info("Request 'CreatePricePlan' for plan ", in.Name, " effective from ", in.EffectiveFrom)
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.CreatePricePlanResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	plan := model.PricePlan{
+		Name:           in.Name,
+		EffectiveFrom:  in.EffectiveFrom,
+		Currency:       in.Currency,
+		ResourcePrices: in.ResourcePrices,
+		ServicePrices:  in.ServicePrices,
+	}
+	if plan.ResourcePrices == nil {
+		plan.ResourcePrices = model.StringMap{}
+	}
+	if plan.ServicePrices == nil {
+		plan.ServicePrices = model.StringMap{}
+	}
+	if err := contract.ValidatePricePlan(plan); err != nil {
+		return &api.CreatePricePlanResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+
+	created, err := contractAccessor.CreatePricePlan(ctx, plan)
+	if err != nil {
+		return &api.CreatePricePlanResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_FAILED_PRECONDITION), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.CreatePricePlanResponse{PricePlan: reflectToApiPricePlan(created)}, nil
+}
+
+// ListInvoices returns invoices and credit notes. Invoices of every contract are only for operators.
+func (s *server) ListInvoices(ctx context.Context, in *api.ListInvoicesRequest) (*api.ListInvoicesResponse, error) {
+	log.Info("Request 'ListInvoices' for contract id ", in.ContractId, " in period ", in.Period)
+	setContractIdAttribute(ctx, in.ContractId)
+	if err := checkInvoiceFormat(in.Format); err != nil {
+		return &api.ListInvoicesResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	var periodStart time.Time
+	if in.Period != "" {
+		var err error
+		if periodStart, err = contract.ParsePeriod(in.Period); err != nil {
+			return &api.ListInvoicesResponse{
+				Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+			}, err
+		}
+	}
+	if in.ContractId == "" {
+		if code, err := checkInternalCall(ctx); err != nil {
+			return &api.ListInvoicesResponse{
+				Status: api.NewStatus(code, err),
+			}, err
+		}
+	} else {
+		if _, err := checkContractId(in.ContractId); err != nil {
+			return &api.ListInvoicesResponse{
+				Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+			}, err
+		}
+		if code, err := checkMembership(ctx, in.ContractId, contract.RoleOwner, contract.RoleAdmin); err != nil {
+			return &api.ListInvoicesResponse{
+				Status: api.NewStatus(code, err),
+			}, err
+		}
+	}
+
+	invoices, err := contractAccessor.ListInvoices(ctx, in.ContractId, periodStart)
+	if err != nil {
+		return &api.ListInvoicesResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.ListInvoicesResponse{Invoices: []*api.Invoice{}}
+	for _, invoice := range invoices {
+		res.Invoices = append(res.Invoices, reflectToApiInvoice(invoice))
+	}
+	if in.Format == api.InvoiceFormatCSV {
+		if res.Csv, err = renderInvoicesCSV(res.Invoices...); err != nil {
+			return &api.ListInvoicesResponse{
+				Status: api.NewStatus(pb.Code_INTERNAL, err),
+			}, err
+		}
+	}
+	return res, nil
+}
+
+// GetInvoice returns an invoice or a credit note.
+func (s *server) GetInvoice(ctx context.Context, in *api.GetInvoiceRequest) (*api.GetInvoiceResponse, error) {
+	log.Info("Request 'GetInvoice' for invoice id ", in.InvoiceId)
+	if err := checkInvoiceFormat(in.Format); err != nil {
+		return &api.GetInvoiceResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	invoiceID, err := uuid.Parse(in.InvoiceId)
+	if err != nil {
+		err := fmt.Errorf("invalid invoice ID %s", in.InvoiceId)
+		return &api.GetInvoiceResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+
+	invoice, err := contractAccessor.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return &api.GetInvoiceResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	setContractIdAttribute(ctx, invoice.ContractID)
+	if code, err := checkMembership(ctx, invoice.ContractID, contract.RoleOwner, contract.RoleAdmin); err != nil {
+		return &api.GetInvoiceResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	res := &api.GetInvoiceResponse{Invoice: reflectToApiInvoice(invoice)}
+	if in.Format == api.InvoiceFormatCSV {
+		if res.Csv, err = renderInvoicesCSV(res.Invoice); err != nil {
+			return &api.GetInvoiceResponse{
+				Status: api.NewStatus(pb.Code_INTERNAL, err),
+			}, err
+		}
+	}
+	return res, nil
+}
+
+// ClosePeriod issues the invoices of a billing period which is over. Only operators can close periods.
+func (s *server) ClosePeriod(ctx context.Context, in *api.ClosePeriodRequest) (*api.ClosePeriodResponse, error) {
+	log.Info("Request 'ClosePeriod' for period ", in.Period, " of contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.ClosePeriodResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	periodStart, err := contract.ParsePeriod(in.Period)
+	if err != nil {
+		return &api.ClosePeriodResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	now := time.Now()
+	if periodStart.AddDate(0, 1, 0).After(now) {
+		err := fmt.Errorf("%w : %s", contract.ErrPeriodNotOver, in.Period)
+		return &api.ClosePeriodResponse{
+			Status: api.NewStatus(pb.Code_FAILED_PRECONDITION, err),
+		}, err
+	}
+
+	if in.ContractId == "" {
+		report, err := contractAccessor.CloseAllPeriods(ctx, periodStart, now)
+		publishInvoices(ctx, report.Issued...)
+		if err != nil {
+			return &api.ClosePeriodResponse{
+				Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+			}, rpcError(ctx, err)
+		}
+		res := &api.ClosePeriodResponse{Issued: []*api.Invoice{}}
+		for _, invoice := range report.Issued {
+			res.Issued = append(res.Issued, reflectToApiInvoice(invoice))
+		}
+		if len(report.Failed) > 0 {
+			res.Failed = map[string]string{}
+			for id, err := range report.Failed {
+				res.Failed[id] = err.Error()
+			}
+		}
+		return res, nil
+	}
+
+	if _, err := checkContractId(in.ContractId); err != nil {
+		return &api.ClosePeriodResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	// a deleted contract is still invoiced for the periods in which it is used
+	if _, err := contractAccessor.GetContract(ctx, in.ContractId); err != nil {
+		if history, herr := contractAccessor.GetHistory(ctx, in.ContractId, 0, 1); herr != nil || len(history) == 0 {
+			return &api.ClosePeriodResponse{
+				Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+			}, rpcError(ctx, err)
+		}
+	}
+	invoice, created, err := contractAccessor.ClosePeriod(ctx, in.ContractId, periodStart, now)
+	if err != nil {
+		return &api.ClosePeriodResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_FAILED_PRECONDITION), err),
+		}, rpcError(ctx, err)
+	}
+	res := &api.ClosePeriodResponse{Issued: []*api.Invoice{}}
+	if created {
+		publishInvoices(ctx, invoice)
+		res.Issued = append(res.Issued, reflectToApiInvoice(invoice))
+	}
+	return res, nil
+}
+
+// IssueCreditNote corrects an invoice with a credit note. Only operators can issue credit notes.
+func (s *server) IssueCreditNote(ctx context.Context, in *api.IssueCreditNoteRequest) (*api.IssueCreditNoteResponse, error) {
+	log.Info("Request 'IssueCreditNote' for invoice id ", in.InvoiceId, " of amount ", in.Amount)
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.IssueCreditNoteResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	invoiceID, err := uuid.Parse(in.InvoiceId)
+	if err != nil {
+		err := fmt.Errorf("invalid invoice ID %s", in.InvoiceId)
+		return &api.IssueCreditNoteResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if in.Reason == "" {
+		err := fmt.Errorf("reason must be specified")
+		return &api.IssueCreditNoteResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if _, err := contractAccessor.GetInvoice(ctx, invoiceID); err != nil {
+		return &api.IssueCreditNoteResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+
+	note, err := contractAccessor.IssueCreditNote(ctx, invoiceID, in.Amount, in.Reason)
+	if err != nil {
+		code := pb.Code_INVALID_ARGUMENT
+		if errors.Is(err, contract.ErrCreditExceeded) {
+			code = pb.Code_FAILED_PRECONDITION
+		}
+		return &api.IssueCreditNoteResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+	publishInvoices(ctx, note)
+	return &api.IssueCreditNoteResponse{CreditNote: reflectToApiInvoice(note)}, nil
+}
+
+func checkInvoiceFormat(format string) error {
+	switch format {
+	case "", api.InvoiceFormatJSON, api.InvoiceFormatCSV:
+		return nil
+	}
+	return fmt.Errorf("invalid format %s, must be %s or %s", format, api.InvoiceFormatJSON, api.InvoiceFormatCSV)
+}
+
+func renderInvoicesCSV(invoices ...*api.Invoice) (string, error) {
+	var b strings.Builder
+	if err := api.WriteInvoicesCSV(&b, invoices...); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func reflectToApiInvoice(invoice model.Invoice) *api.Invoice {
+	res := &api.Invoice{
+		InvoiceId:   invoice.ID.String(),
+		Number:      invoice.Number,
+		ContractId:  invoice.ContractID,
+		Kind:        invoice.Kind,
+		Period:      contract.FormatPeriod(invoice.PeriodStart),
+		PeriodStart: invoice.PeriodStart,
+		PeriodEnd:   invoice.PeriodEnd,
+		Currency:    invoice.Currency,
+		Lines:       []*api.InvoiceLine{},
+		Total:       invoice.Total,
+		Reason:      invoice.Reason,
+		IssuedAt:    invoice.CreatedAt,
+	}
+	if invoice.InvoiceID != nil {
+		res.CorrectedInvoiceId = invoice.InvoiceID.String()
+	}
+	for _, l := range invoice.Lines {
+		res.Lines = append(res.Lines, &api.InvoiceLine{
+			Kind:      l.Kind,
+			Item:      l.Item,
+			PricePlan: l.PricePlan,
+			Quantity:  l.Quantity,
+			UnitPrice: l.UnitPrice,
+			From:      l.From,
+			To:        l.To,
+			Amount:    l.Amount,
+		})
+	}
+	return res
+}
+
+func reflectToApiPricePlan(plan model.PricePlan) *api.PricePlan {
+	return &api.PricePlan{
+		Name:           plan.Name,
+		EffectiveFrom:  plan.EffectiveFrom,
+		Currency:       plan.Currency,
+		ResourcePrices: plan.ResourcePrices,
+		ServicePrices:  plan.ServicePrices,
+		CreatedAt:      plan.CreatedAt,
+	}
+}
+
+// checkInternalCall returns an error if the request is not authenticated or is made on behalf
+// of a user. Bulk operations across contracts are only for operators, who call with the internal
+// token and without the user id.
+func checkInternalCall(ctx context.Context) (pb.Code, error) {
+	_, ok, err := callerFromContext(ctx)
+	if err != nil {
+		return callerErrorCode(err), err
+	}
+	if ok {
+		return pb.Code_PERMISSION_DENIED, fmt.Errorf("only internal calls are allowed")
+	}
+	return pb.Code_OK_UNSPECIFIED, nil
+}
+
+// GetReconcileReport returns the report of the last reconciliation of contracts with tks-info.
+// Only operators can get the report.
+func (s *server) GetReconcileReport(ctx context.Context, in *api.GetReconcileReportRequest) (*api.GetReconcileReportResponse, error) {
+	log.Info("Request 'GetReconcileReport'")
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.GetReconcileReportResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	report := reconciler.lastReport()
+	if report == nil {
+		err := fmt.Errorf("contracts have not been reconciled yet")
+		return &api.GetReconcileReportResponse{
+			Status: api.NewStatus(pb.Code_NOT_FOUND, err),
+		}, err
+	}
+	return &api.GetReconcileReportResponse{Report: report}, nil
+}
+
+// Reconcile reconciles contracts with tks-info now and returns the report. Drifts are repaired
+// if the policy of the reconciler is repair, unless it is a dry run. Only operators can reconcile.
+func (s *server) Reconcile(ctx context.Context, in *api.ReconcileRequest) (*api.ReconcileResponse, error) {
+	log.Info("Request 'Reconcile' dry run ", in.DryRun)
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.ReconcileResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	report := reconciler.reconcileOnce(ctx, time.Now(), in.DryRun)
+	if report.Error != "" {
+		err := errors.New(report.Error)
+		return &api.ReconcileResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_UNAVAILABLE), err),
+			Report: report,
+		}, rpcError(ctx, err)
+	}
+	return &api.ReconcileResponse{Report: report}, nil
+}
+
+// DiagnoseDatabase scans the database for inconsistencies without repairing them. Only operators
+// can scan the database.
+func (s *server) DiagnoseDatabase(ctx context.Context, in *api.DiagnoseDatabaseRequest) (*api.DiagnoseDatabaseResponse, error) {
+	log.Info("Request 'DiagnoseDatabase'")
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.DiagnoseDatabaseResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	report, err := contractAccessor.Doctor(ctx, false, nil)
+	if err != nil {
+		return &api.DiagnoseDatabaseResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.DiagnoseDatabaseResponse{Report: reflectToApiDoctorReport(report)}, nil
+}
+
+// RepairDatabase repairs inconsistencies in the database in a transaction. Only operators can
+// repair the database.
+func (s *server) RepairDatabase(ctx context.Context, in *api.RepairDatabaseRequest) (*api.RepairDatabaseResponse, error) {
+	log.Info("Request 'RepairDatabase' for kinds ", in.Kinds)
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.RepairDatabaseResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	for _, kind := range in.Kinds {
+		if err := contract.ValidateDoctorKind(kind); err != nil {
+			return &api.RepairDatabaseResponse{
+				Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+			}, err
+		}
+	}
+	report, err := contractAccessor.Doctor(ctx, true, in.Kinds)
+	if err != nil {
+		return &api.RepairDatabaseResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_ABORTED), err),
+			Report: reflectToApiDoctorReport(report),
+		}, rpcError(ctx, err)
+	}
+	return &api.RepairDatabaseResponse{Report: reflectToApiDoctorReport(report)}, nil
+}
+
+func reflectToApiDoctorReport(report contract.DoctorReport) *api.DoctorReport {
+	res := &api.DoctorReport{
+		Contracts: report.Contracts,
+		Committed: report.Committed,
+		Issues:    []*api.DoctorIssue{},
+	}
+	for _, issue := range report.Issues {
+		rowIds := make([]string, 0, len(issue.RowIDs))
+		for _, id := range issue.RowIDs {
+			rowIds = append(rowIds, id.String())
+		}
+		res.Issues = append(res.Issues, &api.DoctorIssue{
+			Kind:       issue.Kind,
+			ContractId: issue.ContractID,
+			Table:      issue.Table,
+			RowIds:     rowIds,
+			Repairable: issue.Repairable,
+			Repaired:   issue.Repaired,
+		})
+	}
+	return res
+}
+
+// SetDefaultContract makes a contract the default one. Only operators can change the default contract.
+func (s *server) SetDefaultContract(ctx context.Context, in *api.SetDefaultContractRequest) (*api.SetDefaultContractResponse, error) {
+	log.Info("Request 'SetDefaultContract' for contract id ", in.ContractId)
+	setContractIdAttribute(ctx, in.ContractId)
+	contractID, err := checkContractId(in.ContractId)
+	if err != nil {
+		return &api.SetDefaultContractResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.SetDefaultContractResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	prev, err := contractAccessor.SetDefaultContract(ctx, contractID)
+	if err != nil {
+		return &api.SetDefaultContractResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_NOT_FOUND), err),
+		}, rpcError(ctx, err)
+	}
+	return &api.SetDefaultContractResponse{PrevContractId: prev, ContractId: contractID}, nil
+}
+
+// EnsureDefaultContract makes sure that there is a default contract, creating it if there is none.
+// Only operators can ensure the default contract.
+func (s *server) EnsureDefaultContract(ctx context.Context, in *api.EnsureDefaultContractRequest) (*api.EnsureDefaultContractResponse, error) {
+	log.Info("Request 'EnsureDefaultContract' for contract name ", in.ContractorName)
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.EnsureDefaultContractResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if in.ContractorName == "" {
+		err := fmt.Errorf("contractor name must be specified")
+		return &api.EnsureDefaultContractResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	quota := &pb.ContractQuota{
+		Cpu: in.Quota.Cpu, Memory: in.Quota.Memory, Block: in.Quota.Block,
+		BlockSsd: in.Quota.BlockSsd, Fs: in.Quota.Fs, FsSsd: in.Quota.FsSsd,
+	}
+	contractID, created, err := contractAccessor.EnsureDefaultContract(ctx, in.ContractorName, in.AvailableServices,
+		quota, in.Description)
+	if err != nil {
+		return &api.EnsureDefaultContractResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_ABORTED), err),
+		}, rpcError(ctx, err)
+	}
+	setContractIdAttribute(ctx, contractID)
+	return &api.EnsureDefaultContractResponse{ContractId: contractID, Created: created}, nil
+}
+
+// ExportContracts returns contracts with their quota and members as a contract document.
+func (s *server) ExportContracts(ctx context.Context, in *api.ExportContractsRequest) (*api.ExportContractsResponse, error) {
+	log.Info("Request 'ExportContracts' for contract ids ", in.ContractIds)
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.ExportContractsResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	for _, id := range in.ContractIds {
+		if _, err := checkContractId(id); err != nil {
+			return &api.ExportContractsResponse{
+				Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+			}, err
+		}
+	}
+
+	records, err := contractAccessor.Export(ctx, in.ContractIds)
+	if err != nil {
+		code := pb.Code_INTERNAL
+		if len(in.ContractIds) > 0 {
+			code = pb.Code_NOT_FOUND
+		}
+		return &api.ExportContractsResponse{
+			Status: api.NewStatus(errorCode(ctx, code), err),
+		}, rpcError(ctx, err)
+	}
+
+	doc := &api.ContractDocument{
+		Version:    api.DocumentVersion,
+		ExportedAt: time.Now().UTC(),
+		Contracts:  []*api.ContractRecord{},
+	}
+	for _, r := range records {
+		doc.Contracts = append(doc.Contracts, reflectToApiRecord(r))
+	}
+	return &api.ExportContractsResponse{Document: doc}, nil
+}
+
+// ImportContracts creates or overwrites contracts from a contract document and reports the
+// result of each record. CSP info and repositories of imported contracts are not created.
+func (s *server) ImportContracts(ctx context.Context, in *api.ImportContractsRequest) (*api.ImportContractsResponse, error) {
+	const MX_RECORDS = 1000
+
+	if code, err := checkInternalCall(ctx); err != nil {
+		return &api.ImportContractsResponse{
+			Status: api.NewStatus(code, err),
+		}, err
+	}
+	if in.Document == nil || in.Document.Version != api.DocumentVersion {
+		err := fmt.Errorf("document of version %s must be specified", api.DocumentVersion)
+		return &api.ImportContractsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	log.Info("Request 'ImportContracts' for ", len(in.Document.Contracts), " contracts, on conflict ",
+		in.OnConflict, ", dry run ", in.DryRun)
+	if len(in.Document.Contracts) > MX_RECORDS {
+		err := fmt.Errorf("too many contracts %d, at most %d are allowed", len(in.Document.Contracts), MX_RECORDS)
+		return &api.ImportContractsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	policy, err := contract.ParseConflictPolicy(in.OnConflict)
+	if err != nil {
+		return &api.ImportContractsResponse{
+			Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+		}, err
+	}
+	records := make([]contract.ContractRecord, 0, len(in.Document.Contracts))
+	for i, r := range in.Document.Contracts {
+		record, err := reflectToContractRecord(r)
+		if err != nil {
+			err = fmt.Errorf("contracts[%d] : %s", i, err)
+			return &api.ImportContractsResponse{
+				Status: api.NewStatus(pb.Code_INVALID_ARGUMENT, err),
+			}, err
+		}
+		records = append(records, record)
+	}
+
+	report, err := contractAccessor.Import(ctx, records, policy, in.DryRun)
+	if err != nil {
+		return &api.ImportContractsResponse{
+			Status: api.NewStatus(errorCode(ctx, pb.Code_INTERNAL), err),
+		}, rpcError(ctx, err)
+	}
+
+	res := &api.ImportContractsResponse{
+		Report: &api.ImportReport{
+			DryRun:      report.DryRun,
+			Committed:   report.Committed,
+			Created:     report.Count(contract.ImportCreated),
+			Overwritten: report.Count(contract.ImportOverwritten),
+			Skipped:     report.Count(contract.ImportSkipped),
+			Failed:      report.Count(contract.ImportFailed),
+			Results:     []*api.ImportResult{},
+		},
+	}
+	for _, r := range report.Results {
+		res.Report.Results = append(res.Report.Results, &api.ImportResult{
+			ContractId:     r.ContractID,
+			ContractorName: r.ContractorName,
+			Action:         r.Action,
+			Error:          r.Error,
+		})
+	}
+	if !report.DryRun && !report.Committed {
+		err := fmt.Errorf("import is rolled back because %d contracts failed", res.Report.Failed)
+		res.Status = api.NewStatus(pb.Code_ABORTED, err)
+		return res, err
+	}
+	return res, nil
+}
+
+func reflectToApiRecord(r contract.ContractRecord) *api.ContractRecord {
+	createdAt := r.Contract.CreatedAt
+	record := &api.ContractRecord{
+		ContractId:        r.Contract.ID,
+		ContractorName:    r.Contract.ContractorName,
+		AvailableServices: r.Contract.AvailableServices,
+		Description:       r.Contract.Description,
+		Labels:            r.Contract.Labels,
+		Annotations:       r.Contract.Annotations,
+		Quota:             reflectToApiQuota(r.Quota),
+		EffectiveFrom:     r.Contract.EffectiveFrom,
+		ExpiresAt:         r.Contract.ExpiresAt,
+		State:             r.Contract.State,
+		PricePlan:         r.Contract.PricePlan,
+		CreatedAt:         &createdAt,
+
+		BusinessRegistrationNumber: r.Contract.BusinessRegistrationNumber,
+		BillingAddress:             r.Contract.BillingAddress,
+		ContactEmail:               r.Contract.ContactEmail,
+		ContactPhone:               r.Contract.ContactPhone,
+		IsDefault:                  r.Contract.IsDefault,
+	}
+	if record.AvailableServices == nil {
+		record.AvailableServices = []string{}
+	}
+	if r.Contract.ParentID != nil {
+		record.ParentId = *r.Contract.ParentID
+	}
+	if r.Contract.Creator != uuid.Nil {
+		record.Creator = r.Contract.Creator.String()
+	}
+	for _, m := range r.Members {
+		record.Members = append(record.Members, &api.Member{UserId: m.UserID.String(), Role: m.Role})
+	}
+	return record
+}
+
+func reflectToContractRecord(r *api.ContractRecord) (contract.ContractRecord, error) {
+	record := contract.ContractRecord{
+		Contract: model.Contract{
+			ID:                r.ContractId,
+			ContractorName:    r.ContractorName,
+			AvailableServices: r.AvailableServices,
+			Description:       r.Description,
+			Labels:            r.Labels,
+			Annotations:       r.Annotations,
+			EffectiveFrom:     r.EffectiveFrom,
+			ExpiresAt:         r.ExpiresAt,
+			State:             r.State,
+			PricePlan:         r.PricePlan,
+
+			BusinessRegistrationNumber: r.BusinessRegistrationNumber,
+			BillingAddress:             r.BillingAddress,
+			ContactEmail:               r.ContactEmail,
+			ContactPhone:               r.ContactPhone,
+			IsDefault:                  r.IsDefault,
+		},
+		Quota: model.ResourceQuota{
+			Cpu:      r.Quota.Cpu,
+			Memory:   r.Quota.Memory,
+			Block:    r.Quota.Block,
+			BlockSsd: r.Quota.BlockSsd,
+			Fs:       r.Quota.Fs,
+			FsSsd:    r.Quota.FsSsd,
+		},
+	}
+	if r.Creator != "" {
+		creator, err := uuid.Parse(r.Creator)
+		if err != nil {
+			return record, fmt.Errorf("invalid creator %s", r.Creator)
+		}
+		record.Contract.Creator = creator
+	}
+	if r.ParentId != "" {
+		parentId := r.ParentId
+		record.Contract.ParentID = &parentId
+	}
+	if r.CreatedAt != nil {
+		record.Contract.CreatedAt = *r.CreatedAt
+	}
+	if r.Members != nil {
+		record.Members = []model.ContractMember{}
+	}
+	for _, m := range r.Members {
+		userId, err := uuid.Parse(m.UserId)
+		if err != nil {
+			return record, fmt.Errorf("invalid user ID %s", m.UserId)
+		}
+		record.Members = append(record.Members, model.ContractMember{UserID: userId, Role: m.Role})
+	}
+	return record, nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,9 +16,14 @@ import (
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/openinfradev/tks-common/pkg/argowf"
 	mockargo "github.com/openinfradev/tks-common/pkg/argowf/mock"
 	"github.com/openinfradev/tks-common/pkg/helper"
 
@@ -25,6 +32,7 @@ import (
 	pb "github.com/openinfradev/tks-proto/tks_pb"
 	mocktks "github.com/openinfradev/tks-proto/tks_pb/mock"
 
+	"github.com/openinfradev/tks-contract/pkg/api"
 	"github.com/openinfradev/tks-contract/pkg/contract"
 	model "github.com/openinfradev/tks-contract/pkg/contract/model"
 )
@@ -63,11 +71,45 @@ func getAccessor() (*contract.Accessor, error) {
 	if err := db.AutoMigrate(&model.ResourceQuota{}); err != nil {
 		return nil, err
 	}
+	if err := db.AutoMigrate(&model.ContractMember{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.ContractHistory{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.PricePlan{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.Invoice{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.RegionalQuota{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.ContractCsp{}); err != nil {
+		return nil, err
+	}
 
 	return contract.New(db), nil
 }
 
+// testInternalToken is the internal token of the server under test.
+const testInternalToken = "test-internal-token"
+
+// internalContext returns ctx with the internal token, as operators call.
+func internalContext(ctx context.Context) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(authorizationMetadataKey, "Bearer "+testInternalToken))
+}
+
+// userContext returns ctx with the internal token and the user id, as TKS services call on behalf of users.
+func userContext(ctx context.Context, userId string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(authorizationMetadataKey, "Bearer "+testInternalToken,
+		userIdMetadataKey, userId))
+}
+
 func TestMain(m *testing.M) {
+	internalToken = func() string { return testInternalToken }
+
 	pool, resource, err := helper.CreatePostgres()
 	if err != nil {
 		fmt.Printf("Could not create postgres: %s", err)
@@ -86,6 +128,94 @@ func TestMain(m *testing.M) {
 
 // TestCases
 
+func TestAuthentication(t *testing.T) {
+	userId := uuid.New().String()
+	testCases := []struct {
+		name         string
+		ctx          context.Context
+		internalCode pb.Code
+	}{
+		{
+			name:         "NO_METADATA",
+			ctx:          context.Background(),
+			internalCode: pb.Code_UNAUTHENTICATED,
+		},
+		{
+			name:         "USER_ID_WITHOUT_TOKEN",
+			ctx:          metadata.NewIncomingContext(context.Background(), metadata.Pairs(userIdMetadataKey, userId)),
+			internalCode: pb.Code_UNAUTHENTICATED,
+		},
+		{
+			name: "WRONG_TOKEN",
+			ctx: metadata.NewIncomingContext(context.Background(),
+				metadata.Pairs(authorizationMetadataKey, "Bearer "+testInternalToken+"-wrong")),
+			internalCode: pb.Code_UNAUTHENTICATED,
+		},
+		{
+			name:         "INTERNAL",
+			ctx:          internalContext(context.Background()),
+			internalCode: pb.Code_OK_UNSPECIFIED,
+		},
+		{
+			name:         "USER",
+			ctx:          userContext(context.Background(), userId),
+			internalCode: pb.Code_PERMISSION_DENIED,
+		},
+		{
+			name:         "INVALID_USER_ID",
+			ctx:          userContext(context.Background(), "invalid"),
+			internalCode: pb.Code_INVALID_ARGUMENT,
+		},
+	}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &empty.Empty{}, nil
+	}
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			code, _ := checkInternalCall(tc.ctx)
+			require.Equal(t, tc.internalCode, code)
+			if tc.internalCode == pb.Code_UNAUTHENTICATED {
+				code, _ := checkMembership(tc.ctx, "P0123abcd")
+				require.Equal(t, pb.Code_UNAUTHENTICATED, code)
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: "/tks_pb.ContractService/GetContract"}
+			_, err := authServerInterceptor(tc.ctx, &pb.GetContractRequest{}, info, handler)
+			switch tc.internalCode {
+			case pb.Code_UNAUTHENTICATED, pb.Code_INVALID_ARGUMENT:
+				require.Equal(t, codes.Code(tc.internalCode), status.Code(err))
+			default:
+				require.NoError(t, err)
+			}
+
+			info = &grpc.UnaryServerInfo{FullMethod: healthServicePrefix + "Check"}
+			_, err = authServerInterceptor(tc.ctx, &empty.Empty{}, info, handler)
+			require.NoError(t, err)
+		})
+	}
+
+	// authentication is disabled until a token is configured, so that existing callers keep working
+	t.Run("NO_TOKEN_CONFIGURED", func(t *testing.T) {
+		defer func(token func() string) { internalToken = token }(internalToken)
+		internalToken = func() string { return "" }
+
+		code, _ := checkInternalCall(context.Background())
+		require.Equal(t, pb.Code_OK_UNSPECIFIED, code)
+		userCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(userIdMetadataKey, userId))
+		code, _ = checkInternalCall(userCtx)
+		require.Equal(t, pb.Code_PERMISSION_DENIED, code)
+
+		info := &grpc.UnaryServerInfo{FullMethod: "/tks_pb.ContractService/GetContract"}
+		_, err := authServerInterceptor(context.Background(), &pb.GetContractRequest{}, info, handler)
+		require.NoError(t, err)
+		_, err = authServerInterceptor(userCtx, &pb.GetContractRequest{}, info, handler)
+		require.NoError(t, err)
+	})
+}
+
 func TestCreateContract(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -100,7 +230,7 @@ func TestCreateContract(t *testing.T) {
 				mockInfoClient.EXPECT().CreateCSPInfo(gomock.Any(), gomock.Any()).Return(&pb.IDResponse{
 					Code:  pb.Code_OK_UNSPECIFIED,
 					Error: nil,
-					Id:    helper.GenerateContractId(),
+					Id:    uuid.New().String(),
 				}, nil)
 
 				mockArgoClient.EXPECT().
@@ -144,6 +274,23 @@ func TestCreateContract(t *testing.T) {
 			checkResponse: func(req *pb.CreateContractRequest, res *pb.CreateContractResponse, err error) {
 				require.NoError(t, err)
 				require.Equal(t, res.Code, pb.Code_INVALID_ARGUMENT)
+				requireRolledBack(t, req)
+			},
+		},
+		{
+			name: "CSP_INVALID_ID",
+			in:   randomRequest(),
+			buildStubs: func(mockInfoClient *mocktks.MockCspInfoServiceClient, mockArgoClient *mockargo.MockClient) {
+				mockInfoClient.EXPECT().CreateCSPInfo(gomock.Any(), gomock.Any()).Return(&pb.IDResponse{
+					Code:  pb.Code_OK_UNSPECIFIED,
+					Error: nil,
+					Id:    "invalid",
+				}, nil)
+			},
+			checkResponse: func(req *pb.CreateContractRequest, res *pb.CreateContractResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, res.Code, pb.Code_INTERNAL)
+				requireRolledBack(t, req)
 			},
 		},
 		{
@@ -165,6 +312,7 @@ func TestCreateContract(t *testing.T) {
 			checkResponse: func(req *pb.CreateContractRequest, res *pb.CreateContractResponse, err error) {
 				require.NoError(t, err)
 				require.Equal(t, res.Code, pb.Code_INTERNAL)
+				requireRolledBack(t, req)
 			},
 		},
 	}
@@ -173,7 +321,7 @@ func TestCreateContract(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(internalContext(context.Background()))
 			defer cancel()
 
 			ctrl := gomock.NewController(t)
@@ -197,6 +345,14 @@ func TestCreateContract(t *testing.T) {
 
 }
 
+// requireRolledBack requires that the contract of a failed CreateContract is deleted, so that its
+// name can be taken again.
+func requireRolledBack(t *testing.T, req *pb.CreateContractRequest) {
+	contractId, err := contractAccessor.Create(context.Background(), req.GetContractorName(), nil, &pb.ContractQuota{}, uuid.Nil, "")
+	require.NoError(t, err)
+	require.NoError(t, contractAccessor.Delete(context.Background(), contractId))
+}
+
 func TestUpdateQuota(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -259,7 +415,7 @@ func TestUpdateQuota(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(internalContext(context.Background()))
 			defer cancel()
 
 			ctrl := gomock.NewController(t)
@@ -338,7 +494,7 @@ func TestUpdateServices(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(internalContext(context.Background()))
 			defer cancel()
 
 			ctrl := gomock.NewController(t)
@@ -355,6 +511,153 @@ func TestUpdateServices(t *testing.T) {
 
 }
 
+func TestUpdateRoles(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	admin := uuid.New()
+	viewer := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "update-roles", []string{}, &pb.ContractQuota{}, owner, "")
+	require.NoError(t, err)
+	require.NoError(t, contractAccessor.AddMember(context.Background(), contractId, admin, contract.RoleAdmin))
+	require.NoError(t, contractAccessor.AddMember(context.Background(), contractId, viewer, contract.RoleViewer))
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	quotaRes, err := s.UpdateQuota(userCtx(viewer), &pb.UpdateQuotaRequest{ContractId: contractId, Quota: &pb.ContractQuota{Cpu: 1000}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, quotaRes.GetCode())
+	servicesRes, err := s.UpdateServices(userCtx(viewer), &pb.UpdateServicesRequest{ContractId: contractId, AvailableServices: []string{"lma"}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, servicesRes.GetCode())
+	quotaRes, err = s.UpdateQuota(userCtx(uuid.New()), &pb.UpdateQuotaRequest{ContractId: contractId, Quota: &pb.ContractQuota{Cpu: 1000}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, quotaRes.GetCode())
+
+	quotaRes, err = s.UpdateQuota(userCtx(owner), &pb.UpdateQuotaRequest{ContractId: contractId, Quota: &pb.ContractQuota{Cpu: 1000}})
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), quotaRes.GetCurrentQuota().GetCpu())
+	servicesRes, err = s.UpdateServices(userCtx(admin), &pb.UpdateServicesRequest{ContractId: contractId, AvailableServices: []string{"lma"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"lma"}, servicesRes.GetCurrentServices())
+
+	require.NoError(t, contractAccessor.Delete(context.Background(), contractId))
+}
+
+func TestMembers(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	admin := uuid.New()
+	viewer := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "members", []string{}, &pb.ContractQuota{}, owner, "")
+	require.NoError(t, err)
+	require.NoError(t, contractAccessor.AddMember(context.Background(), contractId, admin, contract.RoleAdmin))
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	addRes, err := s.AddMember(userCtx(admin), &api.AddMemberRequest{ContractId: contractId, UserId: viewer.String(), Role: "viewer"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, addRes.GetCode())
+	addRes, err = s.AddMember(userCtx(uuid.New()), &api.AddMemberRequest{ContractId: contractId, UserId: viewer.String(), Role: "viewer"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, addRes.GetCode())
+	addRes, err = s.AddMember(userCtx(owner), &api.AddMemberRequest{ContractId: contractId, UserId: viewer.String(), Role: "guest"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, addRes.GetCode())
+	addRes, err = s.AddMember(userCtx(owner), &api.AddMemberRequest{ContractId: contractId, UserId: "invalid", Role: "viewer"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, addRes.GetCode())
+
+	addRes, err = s.AddMember(userCtx(owner), &api.AddMemberRequest{ContractId: contractId, UserId: viewer.String(), Role: "viewer"})
+	require.NoError(t, err)
+	require.Equal(t, viewer.String(), addRes.Member.UserId)
+	require.Equal(t, "viewer", addRes.Member.Role)
+	require.False(t, addRes.Member.AddedAt.IsZero())
+
+	listRes, err := s.ListMembers(userCtx(viewer), &api.ListMembersRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Len(t, listRes.Members, 3)
+	listRes, err = s.ListMembers(userCtx(uuid.New()), &api.ListMembersRequest{ContractId: contractId})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, listRes.GetCode())
+
+	// the only owner can be neither demoted nor removed
+	addRes, err = s.AddMember(userCtx(owner), &api.AddMemberRequest{ContractId: contractId, UserId: owner.String(), Role: "admin"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, addRes.GetCode())
+	removeRes, err := s.RemoveMember(userCtx(owner), &api.RemoveMemberRequest{ContractId: contractId, UserId: owner.String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, removeRes.GetCode())
+
+	removeRes, err = s.RemoveMember(userCtx(viewer), &api.RemoveMemberRequest{ContractId: contractId, UserId: admin.String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, removeRes.GetCode())
+	removeRes, err = s.RemoveMember(userCtx(owner), &api.RemoveMemberRequest{ContractId: contractId, UserId: admin.String()})
+	require.NoError(t, err)
+	removeRes, err = s.RemoveMember(userCtx(owner), &api.RemoveMemberRequest{ContractId: contractId, UserId: admin.String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_NOT_FOUND, removeRes.GetCode())
+
+	contractsRes, err := s.ListUserContracts(userCtx(viewer), &api.ListUserContractsRequest{UserId: viewer.String()})
+	require.NoError(t, err)
+	require.Len(t, contractsRes.Contracts, 1)
+	require.Equal(t, contractId, contractsRes.Contracts[0].ContractId)
+	require.Equal(t, "viewer", contractsRes.Contracts[0].Role)
+	contractsRes, err = s.ListUserContracts(internalContext(context.Background()), &api.ListUserContractsRequest{UserId: owner.String()})
+	require.NoError(t, err)
+	require.Equal(t, "owner", contractsRes.Contracts[0].Role)
+	contractsRes, err = s.ListUserContracts(userCtx(viewer), &api.ListUserContractsRequest{UserId: owner.String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, contractsRes.GetCode())
+
+	require.NoError(t, contractAccessor.Delete(context.Background(), contractId))
+}
+
+func TestGrpcCpuCores(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	ctx := internalContext(context.Background())
+	contractId, err := contractAccessor.Create(context.Background(), "grpc-cores", []string{}, &pb.ContractQuota{}, uuid.New(), "")
+	require.NoError(t, err)
+
+	s := &grpcServer{&server{}}
+	quotaRes, err := s.UpdateQuota(ctx, &pb.UpdateQuotaRequest{ContractId: contractId, Quota: &pb.ContractQuota{Cpu: 4, Memory: 16}})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), quotaRes.GetCurrentQuota().GetCpu())
+	require.Equal(t, int64(16), quotaRes.GetCurrentQuota().GetMemory())
+
+	// the gateway and the database use millicores
+	stored, err := s.server.GetQuota(ctx, &pb.GetQuotaRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Equal(t, int64(4000), stored.GetQuota().GetCpu())
+
+	_, err = s.server.UpdateQuota(ctx, &pb.UpdateQuotaRequest{ContractId: contractId, Quota: &pb.ContractQuota{Cpu: 2500}})
+	require.NoError(t, err)
+	getRes, err := s.GetContract(ctx, &pb.GetContractRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), getRes.GetContract().GetQuota().GetCpu())
+	quota, err := s.GetQuota(ctx, &pb.GetQuotaRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), quota.GetQuota().GetCpu())
+
+	quotaRes, err = s.UpdateQuota(ctx, &pb.UpdateQuotaRequest{ContractId: contractId, Quota: &pb.ContractQuota{Cpu: math.MaxInt64}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, quotaRes.GetCode())
+
+	require.NoError(t, contractAccessor.Delete(context.Background(), contractId))
+}
+
 func TestGetContract(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -402,7 +705,7 @@ func TestGetContract(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(internalContext(context.Background()))
 			defer cancel()
 
 			ctrl := gomock.NewController(t)
@@ -419,6 +722,71 @@ func TestGetContract(t *testing.T) {
 
 }
 
+func TestGetContractMembership(t *testing.T) {
+	memberId := uuid.New()
+
+	testCases := []struct {
+		name          string
+		userId        string
+		buildStubs    func()
+		checkResponse func(res *pb.GetContractResponse, err error)
+	}{
+		{
+			name:   "PERMISSION_DENIED",
+			userId: memberId.String(),
+			buildStubs: func() {
+			},
+			checkResponse: func(res *pb.GetContractResponse, err error) {
+				require.Error(t, err)
+				require.Equal(t, res.Code, pb.Code_PERMISSION_DENIED)
+			},
+		},
+		{
+			name:   "OK",
+			userId: memberId.String(),
+			buildStubs: func() {
+				err := contractAccessor.AddMember(context.Background(), createdContractId, memberId, contract.RoleViewer)
+				require.NoError(t, err)
+			},
+			checkResponse: func(res *pb.GetContractResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, res.Code, pb.Code_OK_UNSPECIFIED)
+				require.Equal(t, createdContractId, res.GetContract().GetContractId())
+			},
+		},
+		{
+			name:   "INVALID_USER_ID",
+			userId: "invalid_user_id",
+			buildStubs: func() {
+			},
+			checkResponse: func(res *pb.GetContractResponse, err error) {
+				require.Error(t, err)
+				require.Equal(t, res.Code, pb.Code_INVALID_ARGUMENT)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(internalContext(context.Background()))
+			defer cancel()
+			ctx = userContext(ctx, tc.userId)
+
+			contractAccessor, err = getAccessor()
+
+			tc.buildStubs()
+
+			s := server{}
+			res, err := s.GetContract(ctx, &pb.GetContractRequest{ContractId: createdContractId})
+
+			tc.checkResponse(res, err)
+		})
+	}
+
+}
+
 func TestGetContracts(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -443,7 +811,7 @@ func TestGetContracts(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(internalContext(context.Background()))
 			defer cancel()
 
 			ctrl := gomock.NewController(t)
@@ -460,6 +828,38 @@ func TestGetContracts(t *testing.T) {
 
 }
 
+func TestGetContractsCanceled(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	canceled, cancel := context.WithCancel(internalContext(context.Background()))
+	cancel()
+	expired, cancel := context.WithDeadline(internalContext(context.Background()), time.Now().Add(-time.Second))
+	defer cancel()
+
+	testCases := []struct {
+		name string
+		ctx  context.Context
+		code pb.Code
+	}{
+		{name: "Canceled", ctx: canceled, code: pb.Code_CANCELLED},
+		{name: "DeadlineExceeded", ctx: expired, code: pb.Code_DEADLINE_EXCEEDED},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			s := server{}
+			res, err := s.GetContracts(tc.ctx, &pb.GetContractsRequest{})
+
+			require.Error(t, err)
+			require.Equal(t, tc.code, res.GetCode())
+		})
+	}
+}
+
 func TestGetDefaultContract(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -471,7 +871,7 @@ func TestGetDefaultContract(t *testing.T) {
 			name: "NOT_FOUND",
 			in:   &empty.Empty{},
 			buildStubs: func() {
-				_, _ = contractAccessor.Create("NO_DEFAULT_NAME", []string{}, &pb.ContractQuota{}, uuid.New(), "")
+				_, _ = contractAccessor.Create(context.Background(), "NO_DEFAULT_NAME", []string{}, &pb.ContractQuota{}, uuid.New(), "")
 			},
 			checkResponse: func(req *empty.Empty, res *pb.GetContractResponse, err error) {
 				require.Error(t, err)
@@ -482,7 +882,7 @@ func TestGetDefaultContract(t *testing.T) {
 			name: "OK",
 			in:   &empty.Empty{},
 			buildStubs: func() {
-				_, _ = contractAccessor.Create("default", []string{}, &pb.ContractQuota{}, uuid.New(), "")
+				_, _ = contractAccessor.Create(context.Background(), "default", []string{}, &pb.ContractQuota{}, uuid.New(), "")
 			},
 			checkResponse: func(req *empty.Empty, res *pb.GetContractResponse, err error) {
 				require.NoError(t, err)
@@ -499,7 +899,7 @@ func TestGetDefaultContract(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(internalContext(context.Background()))
 			defer cancel()
 
 			ctrl := gomock.NewController(t)
@@ -574,7 +974,7 @@ func TestGetQuota(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(internalContext(context.Background()))
 			defer cancel()
 
 			ctrl := gomock.NewController(t)
@@ -641,7 +1041,7 @@ func TestGetAvailableServices(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(internalContext(context.Background()))
 			defer cancel()
 
 			ctrl := gomock.NewController(t)
@@ -713,3 +1113,811 @@ func randomRequest() *pb.CreateContractRequest {
 	testResourceQuota := randomResourceQuota()
 	return reflectToRequest(testContract, testResourceQuota)
 }
+
+func TestDeleteContract(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	viewer := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "delete", []string{}, &pb.ContractQuota{}, owner, "")
+	require.NoError(t, err)
+	require.NoError(t, contractAccessor.AddMember(context.Background(), contractId, viewer, contract.RoleViewer))
+	cspId := uuid.New()
+	_, err = contractAccessor.AttachCsp(context.Background(), contractId, model.ContractCsp{
+		CspID: cspId, CspName: "aws", Role: string(contract.CspRolePrimary),
+	})
+	require.NoError(t, err)
+
+	cleanup := argowf.SubmitOptions{Parameters: []string{
+		"contract_id=" + contractId,
+		"csp_ids=" + cspId.String(),
+		"revision=" + cfg.Argo.Revision,
+	}}
+
+	testCases := []struct {
+		name       string
+		userId     uuid.UUID
+		in         *api.DeleteContractRequest
+		buildStubs func(mockArgoClient *mockargo.MockClient)
+		code       pb.Code
+	}{
+		{name: "INVALID_ARGUMENT", userId: owner, in: &api.DeleteContractRequest{ContractId: "invalid_contract_id"}, code: pb.Code_INVALID_ARGUMENT},
+		{name: "PERMISSION_DENIED", userId: viewer, in: &api.DeleteContractRequest{ContractId: contractId}, code: pb.Code_PERMISSION_DENIED},
+		{
+			name:   "FailedToCallWorkflow",
+			userId: owner,
+			in:     &api.DeleteContractRequest{ContractId: contractId},
+			buildStubs: func(mockArgoClient *mockargo.MockClient) {
+				mockArgoClient.EXPECT().
+					SumbitWorkflowFromWftpl("tks-delete-contract-repo", "argo", cleanup).
+					Return("", fmt.Errorf("argo gone")).
+					Times(1)
+			},
+			code: pb.Code_INTERNAL,
+		},
+		{
+			name:   "OK",
+			userId: owner,
+			in:     &api.DeleteContractRequest{ContractId: contractId},
+			buildStubs: func(mockArgoClient *mockargo.MockClient) {
+				mockArgoClient.EXPECT().
+					SumbitWorkflowFromWftpl("tks-delete-contract-repo", "argo", cleanup).
+					Return("delete-contract-repo-abcd", nil).
+					Times(1)
+			},
+			code: pb.Code_OK_UNSPECIFIED,
+		},
+		{name: "NOT_FOUND", in: &api.DeleteContractRequest{ContractId: contractId}, code: pb.Code_NOT_FOUND},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := internalContext(context.Background())
+			if tc.userId != uuid.Nil {
+				ctx = userContext(ctx, tc.userId.String())
+			}
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockArgoClient := mockargo.NewMockClient(ctrl)
+			argowfClient = mockArgoClient
+			if tc.buildStubs != nil {
+				tc.buildStubs(mockArgoClient)
+			}
+
+			s := server{}
+			res, err := s.DeleteContract(ctx, tc.in)
+
+			require.Equal(t, tc.code, res.GetCode())
+			if tc.code == pb.Code_OK_UNSPECIFIED {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+			if tc.code == pb.Code_INTERNAL {
+				// the contract is kept so that the deletion can be retried
+				_, err := contractAccessor.GetContract(context.Background(), contractId)
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetContractHistory(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	contractId, err := contractAccessor.Create(context.Background(), "history", []string{"lma"}, &pb.ContractQuota{Cpu: 4}, uuid.New(), "")
+	require.NoError(t, err)
+	_, _, err = contractAccessor.UpdateResourceQuota(context.Background(), contractId, &pb.ContractQuota{Cpu: 8})
+	require.NoError(t, err)
+
+	s := server{}
+	res, err := s.GetContractHistory(internalContext(context.Background()), &api.GetContractHistoryRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Equal(t, pb.Code_OK_UNSPECIFIED, res.GetCode())
+	require.Len(t, res.Records, 2)
+	require.Equal(t, contract.HistoryQuotaUpdated, res.Records[0].Action)
+	require.JSONEq(t, `{"cpu": 8, "memory": 0, "block": 0, "blockSsd": 0, "fs": 0, "fsSsd": 0}`, string(res.Records[0].Current))
+
+	res, err = s.GetContractHistory(internalContext(context.Background()), &api.GetContractHistoryRequest{ContractId: contractId, Limit: -1})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+}
+
+func TestExportImportContracts(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	contractId, err := contractAccessor.Create(context.Background(), "export", []string{"lma"}, &pb.ContractQuota{Cpu: 4}, uuid.New(), "")
+	require.NoError(t, err)
+
+	s := server{}
+	userCtx := userContext(context.Background(), uuid.New().String())
+	exported, err := s.ExportContracts(userCtx, &api.ExportContractsRequest{})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, exported.GetCode())
+
+	exported, err = s.ExportContracts(internalContext(context.Background()), &api.ExportContractsRequest{ContractIds: []string{contractId}})
+	require.NoError(t, err)
+	require.Equal(t, api.DocumentVersion, exported.Document.Version)
+	require.Len(t, exported.Document.Contracts, 1)
+	require.Equal(t, "export", exported.Document.Contracts[0].ContractorName)
+
+	doc := exported.Document
+	doc.Contracts = append(doc.Contracts, &api.ContractRecord{ContractorName: "import", Quota: api.Quota{Cpu: 2}})
+
+	imported, err := s.ImportContracts(internalContext(context.Background()), &api.ImportContractsRequest{Document: doc, OnConflict: "unknown"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, imported.GetCode())
+
+	imported, err = s.ImportContracts(internalContext(context.Background()), &api.ImportContractsRequest{Document: doc, DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, imported.Report.Skipped)
+	require.Equal(t, 1, imported.Report.Created)
+	require.False(t, imported.Report.Committed)
+
+	imported, err = s.ImportContracts(internalContext(context.Background()), &api.ImportContractsRequest{Document: doc, OnConflict: "fail"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_ABORTED, imported.GetCode())
+	require.Equal(t, 1, imported.Report.Failed)
+
+	imported, err = s.ImportContracts(internalContext(context.Background()), &api.ImportContractsRequest{Document: doc, OnConflict: "overwrite"})
+	require.NoError(t, err)
+	require.Equal(t, 1, imported.Report.Overwritten)
+	require.Equal(t, 1, imported.Report.Created)
+	require.True(t, imported.Report.Committed)
+}
+
+func TestSetLabels(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	viewer := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "set-labels", []string{}, &pb.ContractQuota{}, owner, "")
+	require.NoError(t, err)
+	require.NoError(t, contractAccessor.AddMember(context.Background(), contractId, viewer, contract.RoleViewer))
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	res, err := s.SetLabels(userCtx(viewer), &api.SetLabelsRequest{ContractId: contractId, Labels: map[string]string{"tier": "gold"}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, res.GetCode())
+
+	res, err = s.SetLabels(userCtx(owner), &api.SetLabelsRequest{ContractId: contractId, Labels: map[string]string{"tier": "gold gold"}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+
+	res, err = s.SetLabels(userCtx(owner), &api.SetLabelsRequest{ContractId: contractId,
+		Labels: map[string]string{"tier": "gold"}, Annotations: map[string]string{"salesOwner": "kim"}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"tier": "gold"}, res.Labels)
+
+	labels, err := s.GetLabels(userCtx(viewer), &api.GetLabelsRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Equal(t, "kim", labels.Annotations["salesOwner"])
+
+	selectorCtx := func(ctx context.Context, selector string) context.Context {
+		md, _ := metadata.FromIncomingContext(ctx)
+		md = metadata.Join(md, metadata.Pairs(labelSelectorMetadataKey, selector))
+		return metadata.NewIncomingContext(context.Background(), md)
+	}
+	contracts, err := s.GetContracts(selectorCtx(userCtx(viewer), "tier=gold"), &pb.GetContractsRequest{})
+	require.NoError(t, err)
+	require.Len(t, contracts.GetContracts(), 1)
+	contracts, err = s.GetContracts(selectorCtx(internalContext(context.Background()), "tier=silver"), &pb.GetContractsRequest{})
+	require.NoError(t, err)
+	for _, c := range contracts.GetContracts() {
+		require.NotEqual(t, contractId, c.GetContractId())
+	}
+	contracts, err = s.GetContracts(selectorCtx(internalContext(context.Background()), "tier in gold"), &pb.GetContractsRequest{})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, contracts.GetCode())
+}
+
+func TestRenewContract(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	admin := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "renew", []string{}, &pb.ContractQuota{}, owner, "")
+	require.NoError(t, err)
+	require.NoError(t, contractAccessor.AddMember(context.Background(), contractId, admin, contract.RoleAdmin))
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	res, err := s.RenewContract(userCtx(admin), &api.RenewContractRequest{ContractId: contractId, ExpiresAt: expiresAt})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, res.GetCode())
+
+	res, err = s.RenewContract(userCtx(owner), &api.RenewContractRequest{ContractId: contractId})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+
+	res, err = s.RenewContract(userCtx(owner), &api.RenewContractRequest{ContractId: contractId, ExpiresAt: expiresAt})
+	require.NoError(t, err)
+	require.Nil(t, res.PrevTerm.ExpiresAt)
+	require.True(t, expiresAt.Equal(*res.CurrentTerm.ExpiresAt))
+
+	// the contract is expired by the job and cannot be changed until it is renewed
+	newExpiryChecker(time.Hour, 24*time.Hour, contract.StateReadOnly).checkOnce(context.Background(), expiresAt.Add(time.Second))
+	term, err := s.GetTerm(userCtx(admin), &api.GetTermRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Equal(t, string(contract.StateReadOnly), term.Term.State)
+
+	quota, err := s.UpdateQuota(internalContext(context.Background()), &pb.UpdateQuotaRequest{ContractId: contractId, Quota: &pb.ContractQuota{Cpu: 8}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, quota.GetCode())
+	labels, err := s.SetLabels(userCtx(owner), &api.SetLabelsRequest{ContractId: contractId, Labels: map[string]string{"tier": "gold"}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, labels.GetCode())
+	parent, err := s.SetParent(internalContext(context.Background()), &api.SetParentRequest{ContractId: contractId})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, parent.GetCode())
+	detached, err := s.DetachCsp(userCtx(owner), &api.DetachCspRequest{ContractId: contractId, CspId: uuid.New().String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, detached.GetCode())
+
+	res, err = s.RenewContract(userCtx(owner), &api.RenewContractRequest{ContractId: contractId,
+		ExpiresAt: expiresAt.Add(365 * 24 * time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, string(contract.StateReadOnly), res.PrevTerm.State)
+	require.Equal(t, string(contract.StateActive), res.CurrentTerm.State)
+	_, err = s.UpdateQuota(internalContext(context.Background()), &pb.UpdateQuotaRequest{ContractId: contractId, Quota: &pb.ContractQuota{Cpu: 8}})
+	require.NoError(t, err)
+}
+
+func TestSetParent(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	other := uuid.New()
+	master, err := contractAccessor.Create(context.Background(), "set-parent-master", []string{}, &pb.ContractQuota{Cpu: 10}, owner, "")
+	require.NoError(t, err)
+	child, err := contractAccessor.Create(context.Background(), "set-parent-child", []string{}, &pb.ContractQuota{Cpu: 8}, owner, "")
+	require.NoError(t, err)
+	foreign, err := contractAccessor.Create(context.Background(), "set-parent-foreign", []string{}, &pb.ContractQuota{Cpu: 8}, other, "")
+	require.NoError(t, err)
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	res, err := s.SetParent(userCtx(other), &api.SetParentRequest{ContractId: foreign, ParentId: master})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, res.GetCode())
+
+	res, err = s.SetParent(userCtx(owner), &api.SetParentRequest{ContractId: child, ParentId: master})
+	require.NoError(t, err)
+	require.Equal(t, master, res.ParentId)
+
+	res, err = s.SetParent(userCtx(owner), &api.SetParentRequest{ContractId: master, ParentId: child})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+
+	res, err = s.SetParent(internalContext(context.Background()), &api.SetParentRequest{ContractId: foreign, ParentId: master})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, res.GetCode())
+
+	quota, err := s.UpdateQuota(internalContext(context.Background()), &pb.UpdateQuotaRequest{ContractId: child, Quota: &pb.ContractQuota{Cpu: 11}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, quota.GetCode())
+
+	children, err := s.ListChildren(userCtx(owner), &api.ListChildrenRequest{ContractId: master})
+	require.NoError(t, err)
+	require.Len(t, children.Children, 1)
+	require.Equal(t, int64(8), children.Allocated.Cpu)
+
+	ancestry, err := s.GetAncestry(userCtx(owner), &api.GetAncestryRequest{ContractId: child})
+	require.NoError(t, err)
+	require.Len(t, ancestry.Ancestors, 1)
+	require.Equal(t, master, ancestry.Ancestors[0].ContractId)
+
+	deleted, err := s.DeleteContract(userCtx(owner), &api.DeleteContractRequest{ContractId: master})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, deleted.GetCode())
+
+	res, err = s.SetParent(userCtx(owner), &api.SetParentRequest{ContractId: child})
+	require.NoError(t, err)
+	require.Equal(t, master, res.PrevParentId)
+}
+
+func TestGetContractCharges(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	viewer := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "charges", []string{"lma"}, &pb.ContractQuota{Cpu: 4}, owner, "")
+	require.NoError(t, err)
+	require.NoError(t, contractAccessor.AddMember(context.Background(), contractId, viewer, contract.RoleViewer))
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	planName := "test-" + uuid.New().String()[:8]
+	in := &api.CreatePricePlanRequest{
+		Name: planName, EffectiveFrom: time.Now().Add(-time.Hour), Currency: "KRW",
+		ResourcePrices: map[string]string{"cpu": "3000"}, ServicePrices: map[string]string{"lma": "300"},
+	}
+	plan, err := s.CreatePricePlan(userCtx(owner), in)
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, plan.GetCode())
+	plan, err = s.CreatePricePlan(internalContext(context.Background()), &api.CreatePricePlanRequest{Name: planName, Currency: "KRW"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, plan.GetCode())
+	_, err = s.CreatePricePlan(internalContext(context.Background()), in)
+	require.NoError(t, err)
+
+	set, err := s.SetPricePlan(userCtx(owner), &api.SetPricePlanRequest{ContractId: contractId, PricePlan: planName})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, set.GetCode())
+	set, err = s.SetPricePlan(internalContext(context.Background()), &api.SetPricePlanRequest{ContractId: contractId, PricePlan: planName})
+	require.NoError(t, err)
+	require.Equal(t, contract.DefaultPricePlan, set.PrevPricePlan)
+
+	from := time.Now()
+	req := &api.GetContractChargesRequest{ContractId: contractId, From: from, To: from.Add(time.Minute)}
+	res, err := s.GetContractCharges(userCtx(viewer), req)
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, res.GetCode())
+	res, err = s.GetContractCharges(userCtx(owner), req)
+	require.NoError(t, err)
+	require.Equal(t, "KRW", res.Currency)
+	require.NotEmpty(t, res.Lines)
+
+	res, err = s.GetContractCharges(userCtx(owner), &api.GetContractChargesRequest{ContractId: contractId, From: from, To: from})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+}
+
+func TestInvoices(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	viewer := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "invoices", []string{}, &pb.ContractQuota{Cpu: 4}, owner, "")
+	require.NoError(t, err)
+	require.NoError(t, contractAccessor.AddMember(context.Background(), contractId, viewer, contract.RoleViewer))
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	period := contract.FormatPeriod(time.Now())
+	closed, err := s.ClosePeriod(userCtx(owner), &api.ClosePeriodRequest{Period: period, ContractId: contractId})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, closed.GetCode())
+	closed, err = s.ClosePeriod(internalContext(context.Background()), &api.ClosePeriodRequest{Period: "June", ContractId: contractId})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, closed.GetCode())
+	closed, err = s.ClosePeriod(internalContext(context.Background()), &api.ClosePeriodRequest{Period: period, ContractId: contractId})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, closed.GetCode())
+
+	// the current period cannot be closed through the API, so issue its invoice directly
+	periodStart, _ := contract.ParsePeriod(period)
+	invoice, _, err := contractAccessor.ClosePeriod(context.Background(), contractId, periodStart, periodStart.AddDate(0, 2, 0))
+	require.NoError(t, err)
+
+	list, err := s.ListInvoices(userCtx(owner), &api.ListInvoicesRequest{})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, list.GetCode())
+	list, err = s.ListInvoices(userCtx(viewer), &api.ListInvoicesRequest{ContractId: contractId})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, list.GetCode())
+	list, err = s.ListInvoices(userCtx(owner), &api.ListInvoicesRequest{ContractId: contractId, Period: period, Format: api.InvoiceFormatCSV})
+	require.NoError(t, err)
+	require.Len(t, list.Invoices, 1)
+	require.True(t, strings.HasPrefix(list.Csv, "number,kind,contract_id"))
+
+	got, err := s.GetInvoice(userCtx(viewer), &api.GetInvoiceRequest{InvoiceId: invoice.ID.String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, got.GetCode())
+	got, err = s.GetInvoice(userCtx(owner), &api.GetInvoiceRequest{InvoiceId: invoice.ID.String()})
+	require.NoError(t, err)
+	require.Equal(t, invoice.Number, got.Invoice.Number)
+	got, err = s.GetInvoice(userCtx(owner), &api.GetInvoiceRequest{InvoiceId: uuid.New().String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_NOT_FOUND, got.GetCode())
+
+	note, err := s.IssueCreditNote(userCtx(owner), &api.IssueCreditNoteRequest{InvoiceId: invoice.ID.String(), Reason: "refund"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, note.GetCode())
+	note, err = s.IssueCreditNote(internalContext(context.Background()), &api.IssueCreditNoteRequest{InvoiceId: invoice.ID.String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, note.GetCode())
+	note, err = s.IssueCreditNote(internalContext(context.Background()), &api.IssueCreditNoteRequest{InvoiceId: invoice.ID.String(), Reason: "refund"})
+	require.NoError(t, err)
+	require.Equal(t, "-"+invoice.Total, note.CreditNote.Total)
+	note, err = s.IssueCreditNote(internalContext(context.Background()), &api.IssueCreditNoteRequest{InvoiceId: invoice.ID.String(), Amount: "1", Reason: "refund"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, note.GetCode())
+}
+
+func TestRenderManifests(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "manifests", []string{"lma"},
+		&pb.ContractQuota{Cpu: 32, Memory: 128, Block: 512, BlockSsd: 512}, owner, "")
+	require.NoError(t, err)
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	res, err := s.RenderManifests(userCtx(uuid.New()), &api.RenderManifestsRequest{ContractId: contractId})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, res.GetCode())
+	res, err = s.RenderManifests(internalContext(context.Background()), &api.RenderManifestsRequest{ContractId: "invalid"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+
+	res, err = s.RenderManifests(userCtx(owner), &api.RenderManifestsRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Len(t, res.Manifests, 3)
+	require.Equal(t, "quota/resource-quota.yaml", res.Manifests[0].Path)
+	require.Contains(t, res.Manifests[0].Content, "requests.memory: 128Gi")
+	require.Contains(t, res.Manifests[0].Content, "requests.storage: 1Ti")
+	require.Contains(t, res.Manifests[0].Content, contractId)
+}
+
+func TestRegionalQuotas(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "regional", []string{"lma"},
+		&pb.ContractQuota{Cpu: 32, Memory: 128}, owner, "")
+	require.NoError(t, err)
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	res, err := s.AllocateRegionalQuota(userCtx(uuid.New()), &api.AllocateRegionalQuotaRequest{
+		ContractId: contractId, Csp: "aws", Region: "ap-northeast-2", Quota: api.Quota{Cpu: 8},
+	})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, res.GetCode())
+	res, err = s.AllocateRegionalQuota(userCtx(owner), &api.AllocateRegionalQuotaRequest{
+		ContractId: contractId, Csp: "", Quota: api.Quota{Cpu: 8},
+	})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+	res, err = s.AllocateRegionalQuota(userCtx(owner), &api.AllocateRegionalQuotaRequest{
+		ContractId: contractId, Csp: "aws", Region: "ap-northeast-2", Quota: api.Quota{Cpu: 64},
+	})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, res.GetCode())
+
+	res, err = s.AllocateRegionalQuota(userCtx(owner), &api.AllocateRegionalQuotaRequest{
+		ContractId: contractId, Csp: "aws", Region: "ap-northeast-2", Quota: api.Quota{Cpu: 24, Memory: 64},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.PrevRegionalQuotas, 0)
+	require.Len(t, res.RegionalQuotas, 1)
+
+	// the quota of the contract cannot be lowered below its regional quotas
+	quotaRes, err := s.UpdateQuota(internalContext(context.Background()), &pb.UpdateQuotaRequest{
+		ContractId: contractId, Quota: &pb.ContractQuota{Cpu: 16, Memory: 128},
+	})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, quotaRes.GetCode())
+
+	res, err = s.RebalanceRegionalQuotas(userCtx(owner), &api.RebalanceRegionalQuotasRequest{
+		ContractId: contractId,
+		RegionalQuotas: []*api.RegionalQuota{
+			{Csp: "aws", Region: "ap-northeast-2", Quota: api.Quota{Cpu: 16, Memory: 64}},
+			{Csp: "gcp", Region: "asia-northeast3", Quota: api.Quota{Cpu: 16, Memory: 32}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.PrevRegionalQuotas, 1)
+	require.Len(t, res.RegionalQuotas, 2)
+
+	listRes, err := s.ListRegionalQuotas(userCtx(owner), &api.ListRegionalQuotasRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Len(t, listRes.RegionalQuotas, 2)
+	require.Equal(t, int64(32), listRes.Total.Cpu)
+	require.Equal(t, int64(32), listRes.Allocated.Cpu)
+	require.Equal(t, int64(0), listRes.Unallocated.Cpu)
+	require.Equal(t, int64(32), listRes.Unallocated.Memory)
+
+	res, err = s.ReleaseRegionalQuota(userCtx(owner), &api.ReleaseRegionalQuotaRequest{
+		ContractId: contractId, Csp: "azure", Region: "koreacentral",
+	})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_NOT_FOUND, res.GetCode())
+	res, err = s.ReleaseRegionalQuota(userCtx(owner), &api.ReleaseRegionalQuotaRequest{
+		ContractId: contractId, Csp: "gcp", Region: "asia-northeast3",
+	})
+	require.NoError(t, err)
+	require.Len(t, res.RegionalQuotas, 1)
+	require.Equal(t, "aws", res.RegionalQuotas[0].Csp)
+}
+
+func TestCsps(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockInfoClient := mocktks.NewMockCspInfoServiceClient(ctrl)
+	cspInfoClient = mockInfoClient
+
+	owner := uuid.New()
+	contractId, err := contractAccessor.Create(context.Background(), "csps", []string{"lma"},
+		&pb.ContractQuota{}, owner, "")
+	require.NoError(t, err)
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+
+	s := server{}
+	res, err := s.AttachCsp(userCtx(owner), &api.AttachCspRequest{ContractId: contractId, Role: "primary"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+	res, err = s.AttachCsp(userCtx(owner), &api.AttachCspRequest{ContractId: contractId, CspName: "aws", Role: "backup"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+	res, err = s.AttachCsp(userCtx(uuid.New()), &api.AttachCspRequest{ContractId: contractId, CspName: "aws"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, res.GetCode())
+
+	primaryId := uuid.New()
+	mockInfoClient.EXPECT().CreateCSPInfo(gomock.Any(), gomock.Any()).
+		Return(&pb.IDResponse{Code: pb.Code_OK_UNSPECIFIED, Id: primaryId.String()}, nil)
+	res, err = s.AttachCsp(userCtx(owner), &api.AttachCspRequest{
+		ContractId: contractId, CspName: "aws", CspAuth: "auth", Role: "primary",
+	})
+	require.NoError(t, err)
+	require.Equal(t, primaryId.String(), res.Csp.CspId)
+
+	// an existing CSP info must belong to the contract
+	drId := uuid.New()
+	mockInfoClient.EXPECT().GetCSPInfo(gomock.Any(), gomock.Any()).
+		Return(&pb.GetCSPInfoResponse{Code: pb.Code_OK_UNSPECIFIED, ContractId: "P0123abcd", CspName: "gcp"}, nil)
+	res, err = s.AttachCsp(userCtx(owner), &api.AttachCspRequest{ContractId: contractId, CspId: drId.String(), Role: "dr"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, res.GetCode())
+	mockInfoClient.EXPECT().GetCSPInfo(gomock.Any(), gomock.Any()).
+		Return(&pb.GetCSPInfoResponse{Code: pb.Code_OK_UNSPECIFIED, ContractId: contractId, CspName: "gcp"}, nil)
+	res, err = s.AttachCsp(userCtx(owner), &api.AttachCspRequest{ContractId: contractId, CspId: drId.String(), Role: "dr"})
+	require.NoError(t, err)
+	require.Equal(t, "gcp", res.Csp.CspName)
+
+	listRes, err := s.ListCsps(userCtx(owner), &api.ListCspsRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Len(t, listRes.Csps, 2)
+	require.Equal(t, primaryId.String(), listRes.Csps[0].CspId)
+
+	detachRes, err := s.DetachCsp(userCtx(owner), &api.DetachCspRequest{ContractId: contractId, CspId: primaryId.String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, detachRes.GetCode())
+	detachRes, err = s.DetachCsp(userCtx(owner), &api.DetachCspRequest{ContractId: contractId, CspId: uuid.New().String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_NOT_FOUND, detachRes.GetCode())
+	detachRes, err = s.DetachCsp(userCtx(owner), &api.DetachCspRequest{ContractId: contractId, CspId: drId.String()})
+	require.NoError(t, err)
+	require.Equal(t, "dr", detachRes.Csp.Role)
+	// the last account cannot be detached
+	detachRes, err = s.DetachCsp(userCtx(owner), &api.DetachCspRequest{ContractId: contractId, CspId: primaryId.String()})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_FAILED_PRECONDITION, detachRes.GetCode())
+}
+
+func TestReconcile(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockInfoClient := mocktks.NewMockCspInfoServiceClient(ctrl)
+	cspInfoClient = mockInfoClient
+	// leftovers of other tests may be deleted as well
+	mockArgoClient := mockargo.NewMockClient(ctrl)
+	argowfClient = mockArgoClient
+	mockArgoClient.EXPECT().SumbitWorkflowFromWftpl("tks-delete-contract-repo", "argo", gomock.Any()).
+		Return("delete-contract-repo-abcd", nil).AnyTimes()
+
+	contractId, err := contractAccessor.Create(context.Background(), "reconcile", []string{"lma"},
+		&pb.ContractQuota{}, uuid.New(), "")
+	require.NoError(t, err)
+	staleId, unlinkedId := uuid.New(), uuid.New()
+	_, err = contractAccessor.AttachCsp(context.Background(), contractId, model.ContractCsp{
+		CspID: staleId, CspName: "aws", Role: "primary",
+	})
+	require.NoError(t, err)
+	infos := &pb.GetCSPInfosResponse{Code: pb.Code_OK_UNSPECIFIED, CspInfos: []*pb.CSPInfo{
+		{ContractId: contractId, CspId: unlinkedId.String(), Name: "gcp"},
+	}}
+	// leftoverId was left behind by a failed creation, keptId has lost the CSP info of its account,
+	// and importedId has been imported without CSP accounts.
+	leftoverId, err := contractAccessor.Create(context.Background(), "reconcile-leftover", []string{},
+		&pb.ContractQuota{}, uuid.New(), "")
+	require.NoError(t, err)
+	keptId, err := contractAccessor.Create(context.Background(), "reconcile-kept", []string{},
+		&pb.ContractQuota{}, uuid.New(), "")
+	require.NoError(t, err)
+	_, err = contractAccessor.AttachCsp(context.Background(), keptId, model.ContractCsp{
+		CspID: uuid.New(), CspName: "aws", Role: "primary",
+	})
+	require.NoError(t, err)
+	imported, err := contractAccessor.Import(context.Background(), []contract.ContractRecord{{
+		Contract: model.Contract{ContractorName: "reconcile-imported"},
+	}}, contract.ConflictFail, false)
+	require.NoError(t, err)
+	require.True(t, imported.Committed)
+	importedId := imported.Results[0].ContractID
+
+	s := server{}
+	userCtx := userContext(context.Background(), uuid.New().String())
+	_, err = s.Reconcile(userCtx, &api.ReconcileRequest{})
+	require.Error(t, err)
+
+	reconciler = newCspReconciler(0, reconcilePolicyRepair, 0)
+	reportRes, err := s.GetReconcileReport(internalContext(context.Background()), &api.GetReconcileReportRequest{})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_NOT_FOUND, reportRes.GetCode())
+
+	driftsOf := func(report *api.ReconcileReport, contractId string) []*api.CspDrift {
+		var drifts []*api.CspDrift
+		for _, drift := range report.Drifts {
+			if drift.ContractId == contractId {
+				drifts = append(drifts, drift)
+			}
+		}
+		return drifts
+	}
+
+	// a dry run only reports drifts
+	mockInfoClient.EXPECT().GetCSPInfos(gomock.Any(), gomock.Any()).Return(infos, nil)
+	res, err := s.Reconcile(internalContext(context.Background()), &api.ReconcileRequest{DryRun: true})
+	require.NoError(t, err)
+	drifts := driftsOf(res.Report, contractId)
+	require.Len(t, drifts, 2)
+	require.Equal(t, driftStaleCspLink, drifts[0].Kind)
+	require.False(t, drifts[0].Repaired)
+	require.Equal(t, driftUnlinkedCsp, drifts[1].Kind)
+	drifts = driftsOf(res.Report, leftoverId)
+	require.Len(t, drifts, 1)
+	require.Equal(t, driftContractWithoutCsp, drifts[0].Kind)
+	_, err = contractAccessor.GetContract(context.Background(), leftoverId)
+	require.NoError(t, err)
+
+	// the unlinked account replaces the stale primary account
+	mockInfoClient.EXPECT().GetCSPInfos(gomock.Any(), gomock.Any()).Return(infos, nil)
+	res, err = s.Reconcile(internalContext(context.Background()), &api.ReconcileRequest{})
+	require.NoError(t, err)
+	for _, drift := range append(driftsOf(res.Report, contractId), driftsOf(res.Report, leftoverId)...) {
+		require.True(t, drift.Repaired, drift.Error)
+	}
+	_, err = contractAccessor.GetContract(context.Background(), leftoverId)
+	require.Error(t, err)
+	// contracts which have had a CSP account or have been imported are only reported
+	for _, id := range []string{keptId, importedId} {
+		drifts = driftsOf(res.Report, id)
+		require.Len(t, drifts, 1)
+		require.Equal(t, driftContractWithoutCsp, drifts[0].Kind)
+		require.False(t, drifts[0].Repaired)
+		require.Empty(t, drifts[0].Error)
+		_, err = contractAccessor.GetContract(context.Background(), id)
+		require.NoError(t, err)
+	}
+	csps, err := contractAccessor.ListCsps(context.Background(), contractId)
+	require.NoError(t, err)
+	require.Len(t, csps, 1)
+	require.Equal(t, unlinkedId, csps[0].CspID)
+	require.Equal(t, "primary", csps[0].Role)
+
+	reportRes, err = s.GetReconcileReport(internalContext(context.Background()), &api.GetReconcileReportRequest{})
+	require.NoError(t, err)
+	require.Equal(t, res.Report, reportRes.Report)
+
+	mockInfoClient.EXPECT().GetCSPInfos(gomock.Any(), gomock.Any()).Return(nil, errors.New("unavailable"))
+	res, err = s.Reconcile(internalContext(context.Background()), &api.ReconcileRequest{})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_UNAVAILABLE, res.GetCode())
+	require.NotEmpty(t, res.Report.Error)
+}
+
+func TestRepairDatabaseValidation(t *testing.T) {
+	s := server{}
+	userCtx := userContext(context.Background(), uuid.New().String())
+	diagnoseRes, err := s.DiagnoseDatabase(userCtx, &api.DiagnoseDatabaseRequest{})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, diagnoseRes.GetCode())
+
+	res, err := s.RepairDatabase(internalContext(context.Background()), &api.RepairDatabaseRequest{Kinds: []string{"everything"}})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+}
+
+func TestDefaultContractValidation(t *testing.T) {
+	s := server{}
+	userCtx := userContext(context.Background(), uuid.New().String())
+	setRes, err := s.SetDefaultContract(userCtx, &api.SetDefaultContractRequest{ContractId: "Pedcaa975"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, setRes.GetCode())
+
+	setRes, err = s.SetDefaultContract(internalContext(context.Background()), &api.SetDefaultContractRequest{ContractId: "invalid_contract_id"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, setRes.GetCode())
+
+	ensureRes, err := s.EnsureDefaultContract(userCtx, &api.EnsureDefaultContractRequest{ContractorName: "default"})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, ensureRes.GetCode())
+
+	ensureRes, err = s.EnsureDefaultContract(internalContext(context.Background()), &api.EnsureDefaultContractRequest{})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, ensureRes.GetCode())
+}
+
+func TestUpdateContract(t *testing.T) {
+	var err error
+	contractAccessor, err = getAccessor()
+	require.NoError(t, err)
+
+	owner := uuid.New()
+	viewer := uuid.New()
+	name := "update-contract-" + uuid.New().String()[:8]
+	contractId, err := contractAccessor.Create(context.Background(), name, []string{}, &pb.ContractQuota{}, owner, "")
+	require.NoError(t, err)
+	_, err = contractAccessor.Create(context.Background(), name+"-taken", []string{}, &pb.ContractQuota{}, owner, "")
+	require.NoError(t, err)
+	require.NoError(t, contractAccessor.AddMember(context.Background(), contractId, viewer, contract.RoleViewer))
+	userCtx := func(userId uuid.UUID) context.Context {
+		return userContext(context.Background(), userId.String())
+	}
+	str := func(s string) *string { return &s }
+
+	s := server{}
+	res, err := s.UpdateContract(userCtx(viewer), &api.UpdateContractRequest{ContractId: contractId, Description: str("viewer")})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_PERMISSION_DENIED, res.GetCode())
+
+	res, err = s.UpdateContract(userCtx(owner), &api.UpdateContractRequest{ContractId: contractId, ContactEmail: str("billing")})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_INVALID_ARGUMENT, res.GetCode())
+
+	res, err = s.UpdateContract(userCtx(owner), &api.UpdateContractRequest{ContractId: contractId, ContractorName: str(name + "-taken")})
+	require.Error(t, err)
+	require.Equal(t, pb.Code_ALREADY_EXISTS, res.GetCode())
+
+	res, err = s.UpdateContract(userCtx(owner), &api.UpdateContractRequest{ContractId: contractId,
+		ContractorName: str(name + "-renamed"), BillingAddress: str("Seoul"), ContactPhone: str("02-1234-5678")})
+	require.NoError(t, err)
+	require.Equal(t, name, res.PrevProfile.ContractorName)
+	require.Equal(t, name+"-renamed", res.Profile.ContractorName)
+
+	profile, err := s.GetProfile(userCtx(viewer), &api.GetProfileRequest{ContractId: contractId})
+	require.NoError(t, err)
+	require.Equal(t, "Seoul", profile.Profile.BillingAddress)
+	require.Equal(t, "02-1234-5678", profile.Profile.ContactPhone)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gorm.io/gorm"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+)
+
+const (
+	// livenessService reports whether the process is alive and its health checks keep running.
+	livenessService = "liveness"
+	// readinessService reports whether all dependencies are reachable.
+	// The overall server status ("") follows readiness.
+	readinessService = "readiness"
+)
+
+// healthCheck is a named check of a dependency.
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// healthChecker periodically runs dependency checks and reflects them to a grpc health server.
+type healthChecker struct {
+	server   *health.Server
+	checks   []healthCheck
+	interval time.Duration
+	timeout  time.Duration
+	ready    bool
+}
+
+func newHealthChecker(interval time.Duration, timeout time.Duration) *healthChecker {
+	h := &healthChecker{
+		server:   health.NewServer(),
+		interval: interval,
+		timeout:  timeout,
+	}
+	h.server.SetServingStatus(livenessService, healthpb.HealthCheckResponse_SERVING)
+	h.setReadiness(healthpb.HealthCheckResponse_NOT_SERVING)
+	return h
+}
+
+// addCheck adds a dependency check which must pass for the server to be ready.
+func (h *healthChecker) addCheck(name string, check func(ctx context.Context) error) {
+	h.checks = append(h.checks, healthCheck{name: name, check: check})
+}
+
+// run checks dependencies every interval until ctx is done.
+func (h *healthChecker) run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.checkOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkOnce runs all checks and updates readiness.
+func (h *healthChecker) checkOnce(ctx context.Context) {
+	ready := true
+	for _, c := range h.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		err := c.check(checkCtx)
+		cancel()
+		if err != nil {
+			log.Error("health check failed for ", c.name, " : ", err)
+			ready = false
+		}
+	}
+
+	if ready != h.ready {
+		log.Info("readiness is changed to ", ready)
+	}
+	h.ready = ready
+	if ready {
+		h.setReadiness(healthpb.HealthCheckResponse_SERVING)
+	} else {
+		h.setReadiness(healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+// shutdown marks every service as NOT_SERVING and ignores later updates.
+func (h *healthChecker) shutdown() {
+	h.server.Shutdown()
+}
+
+func (h *healthChecker) setReadiness(status healthpb.HealthCheckResponse_ServingStatus) {
+	h.server.SetServingStatus("", status)
+	h.server.SetServingStatus(readinessService, status)
+}
+
+// databaseCheck pings the database behind db.
+func databaseCheck(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// tcpCheck checks that a tcp connection can be established to address:port.
+func tcpCheck(address string, port int) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", address, port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealthChecker(t *testing.T) {
+	ctx := context.Background()
+	var dbErr error
+
+	h := newHealthChecker(time.Second, time.Second)
+	h.addCheck("database", func(ctx context.Context) error { return dbErr })
+
+	status := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		res, err := h.server.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return res.GetStatus()
+	}
+
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(readinessService))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, status(livenessService))
+
+	h.checkOnce(ctx)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, status(""))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, status(readinessService))
+
+	dbErr = errors.New("database is down")
+	h.checkOnce(ctx)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(""))
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(readinessService))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, status(livenessService))
+
+	h.shutdown()
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(livenessService))
+}
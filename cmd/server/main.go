@@ -1,18 +1,22 @@
 package main
 
 import (
-	"flag"
+	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/openinfradev/tks-common/pkg/argowf"
-	"github.com/openinfradev/tks-common/pkg/grpc_client"
-	"github.com/openinfradev/tks-common/pkg/grpc_server"
 	"github.com/openinfradev/tks-common/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/openinfradev/tks-contract/pkg/contract"
 	pb "github.com/openinfradev/tks-proto/tks_pb"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 )
 
 type server struct {
@@ -25,95 +29,228 @@ var (
 	cspInfoClient    pb.CspInfoServiceClient
 )
 
-var (
-	port              int
-	tlsEnabled        bool
-	tlsClientCertPath string
-	tlsCertPath       string
-	tlsKeyPath        string
-
-	infoServiceAddress string
-	infoServicePort    int
-	argoAddress        string
-	argoPort           int
-	dbhost             string
-	dbport             string
-	dbuser             string
-	dbpassword         string
-	revision           string
-)
-
-func init() {
-	flag.IntVar(&port, "port", 9110, "service port")
-	flag.BoolVar(&tlsEnabled, "tlsEnabled", false, "enabled tls")
-	flag.StringVar(&tlsClientCertPath, "tls-client-cert-path", "../../cert/tks-ca.crt", "path of ca cert file for tls")
-	flag.StringVar(&tlsCertPath, "tls-cert-path", "../../cert/tks-server.crt", "path of cert file for tls")
-	flag.StringVar(&tlsKeyPath, "tls-key-path", "../../cert/tks-server.key", "path of key file for tls")
-	flag.StringVar(&infoServiceAddress, "info-address", "localhost", "service address for tks-info")
-	flag.IntVar(&infoServicePort, "info-port", 9111, "service port for tks-info")
-	flag.StringVar(&argoAddress, "argo-address", "localhost", "service address for argo-workflow")
-	flag.IntVar(&argoPort, "argo-port", 2746, "service port for argo-workflow")
-	flag.StringVar(&dbhost, "dbhost", "localhost", "host of postgreSQL")
-	flag.StringVar(&dbport, "dbport", "5432", "port of postgreSQL")
-	flag.StringVar(&dbuser, "dbuser", "postgres", "postgreSQL user")
-	flag.StringVar(&dbpassword, "dbpassword", "password", "password for postgreSQL user")
-	flag.StringVar(&revision, "revision", "main", "revision for workflow parameter")
-}
+var cfg = defaultConfig()
 
 func main() {
-	flag.Parse()
+	var err error
+	cfg, err = loadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatal("failed to load configuration : ", err)
+	}
 
 	log.Info("*** Arguments *** ")
-	log.Info("port : ", port)
-	log.Info("tlsEnabled : ", tlsEnabled)
-	log.Info("tlsClientCertPath : ", tlsClientCertPath)
-	log.Info("tlsCertPath : ", tlsCertPath)
-	log.Info("tlsKeyPath : ", tlsKeyPath)
-	log.Info("infoServiceAddress : ", infoServiceAddress)
-	log.Info("infoServicePort : ", infoServicePort)
-	log.Info("argoAddress : ", argoAddress)
-	log.Info("argoPort : ", argoPort)
-	log.Info("dbhost : ", dbhost)
-	log.Info("dbport : ", dbport)
-	log.Info("dbuser : ", dbuser)
-	log.Info("dbpassword : ", dbpassword)
-	log.Info("revision : ", revision)
+	redacted := cfg.Redacted()
+	for _, opt := range redacted.options() {
+		log.Info(opt.flag, " : ", valueString(opt.value))
+	}
 	log.Info("****************** ")
 
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+	log.Info("server is stopped")
+}
+
+// run starts the server and blocks until it is stopped by a signal.
+func run() error {
+	// initialize tracing
+	shutdownTracer, err := initTracer(cfg.Tracing.Exporter, cfg.Tracing.Endpoint, cfg.Tracing.File, cfg.Tracing.SampleRatio)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing : %s", err)
+	}
+	defer func() {
+		if err := shutdownTracer(context.Background()); err != nil {
+			log.Error("failed to shutdown tracing : ", err)
+		}
+	}()
+
+	// connections are closed in the order they are opened when run returns
+	var closers []func()
+	defer func() {
+		for _, closeFn := range closers {
+			closeFn()
+		}
+	}()
+
+	// background workers are stopped before connections are closed
+	workers := newWorkerGroup()
+	defer workers.Stop()
+
 	// initialize database
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=tks port=%s sslmode=disable TimeZone=Asia/Seoul",
-		dbhost, dbuser, dbpassword, dbport)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	dbPassword := func() string { return cfg.Database.Password }
+	if cfg.Database.PasswordFile != "" {
+		passwordFile, err := newSecretFile(cfg.Database.PasswordFile)
+		if err != nil {
+			return err
+		}
+		workers.Go("db-password-reloader", func(ctx context.Context) {
+			passwordFile.watch(ctx, cfg.Server.SecretReloadInterval)
+		})
+		dbPassword = passwordFile.Get
+	}
+
+	// initialize the internal token, which every caller must pass if it is configured
+	if cfg.Server.InternalTokenFile != "" {
+		tokenFile, err := newSecretFile(cfg.Server.InternalTokenFile)
+		if err != nil {
+			return err
+		}
+		workers.Go("internal-token-reloader", func(ctx context.Context) {
+			tokenFile.watch(ctx, cfg.Server.SecretReloadInterval)
+		})
+		internalToken = tokenFile.Get
+	} else {
+		internalToken = func() string { return cfg.Server.InternalToken }
+	}
+	if internalToken() == "" {
+		log.Error("no internal token is configured, so requests are not authenticated and tks-user-id is trusted as given")
+	}
+
+	startupCtx, stopStartup := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	db, err := openDatabase(startupCtx, cfg.Database, dbPassword)
+	stopStartup()
 	if err != nil {
-		log.Fatal("failed to open database ", err)
+		return fmt.Errorf("failed to open database : %s", err)
+	}
+	closers = append(closers, func() {
+		if sqlDB, err := db.DB(); err == nil {
+			log.Info("closing database connections")
+			_ = sqlDB.Close()
+		}
+	})
+	if err := registerGormMetrics(db); err != nil {
+		return fmt.Errorf("failed to register database metrics : %s", err)
+	}
+	if err := registerGormTracing(db); err != nil {
+		return fmt.Errorf("failed to register database tracing : %s", err)
 	}
 	contractAccessor = contract.New(db)
+	if cfg.Cache.Size > 0 {
+		contractAccessor = contractAccessor.WithCache(metricsCache{contract.NewLRUCache(cfg.Cache.Size, cfg.Cache.TTL)})
+	}
+	if cfg.Default.Create {
+		if err := ensureDefaultContract(cfg.Default); err != nil {
+			return fmt.Errorf("failed to ensure default contract : %s", err)
+		}
+	}
 
 	// initialize argo client
-	_argowfClient, err := argowf.New(argoAddress, argoPort, false, "")
+	_argowfClient, err := argowf.New(cfg.Argo.Address, cfg.Argo.Port, false, "")
 	if err != nil {
-		log.Fatal("failed to create argowf client : ", err)
+		return fmt.Errorf("failed to create argowf client : %s", err)
 	}
 	argowfClient = _argowfClient
 
 	// initialize csp_info client
-	cc, sc, err := grpc_client.CreateCspInfoClient(infoServiceAddress, infoServicePort, tlsEnabled, tlsClientCertPath)
+	cc, sc, err := createCspInfoClient(cfg.Info.Address, cfg.Info.Port, cfg.Server.TLSEnabled, cfg.Info.TLSClientCertPath,
+		otelgrpc.UnaryClientInterceptor(), metricsClientInterceptor)
 	if err != nil {
-		log.Fatal("failed to create cspinfo client : ", err)
+		return fmt.Errorf("failed to create cspinfo client : %s", err)
 	}
-	defer cc.Close()
+	closers = append(closers, func() {
+		log.Info("closing tks-info connection")
+		_ = cc.Close()
+	})
 	cspInfoClient = sc
 
 	// start server
-	s, conn, err := grpc_server.CreateServer(port, tlsEnabled, tlsCertPath, tlsKeyPath)
+	interceptors := []grpc.UnaryServerInterceptor{
+		otelgrpc.UnaryServerInterceptor(), metricsServerInterceptor, loggingServerInterceptor,
+		authServerInterceptor, actorServerInterceptor, timeoutServerInterceptor(cfg.Server.RequestTimeout),
+	}
+	contractServer := &server{}
+	var gw *gateway
+	if cfg.Server.GatewayPort != 0 {
+		if gw, err = newGateway(contractRoutes(contractServer), interceptors...); err != nil {
+			return err
+		}
+	}
+	s, conn, err := createServer(cfg.Server.Port, cfg.Server.TLSEnabled, cfg.Server.TLSCertPath, cfg.Server.TLSKeyPath,
+		interceptors...)
 	if err != nil {
-		log.Fatal("failed to crate grpc_server : ", err)
+		return fmt.Errorf("failed to crate grpc_server : %s", err)
+	}
+	// the server is stopped forcibly on every return, also when run fails after serving is started.
+	// Stop does nothing after the graceful shutdown below.
+	defer s.Stop()
+
+	// start background workers
+	healthChecker := newHealthChecker(cfg.Health.Interval, cfg.Health.Timeout)
+	healthChecker.addCheck("database", databaseCheck(db))
+	healthChecker.addCheck("tks-info", tcpCheck(cfg.Info.Address, cfg.Info.Port))
+	healthChecker.addCheck("argo", tcpCheck(cfg.Argo.Address, cfg.Argo.Port))
+	workers.Go("health-checker", healthChecker.run)
+	events = newEventPublisher(cfg.Events.WebhookURL, cfg.Events.Timeout)
+	if cfg.Expiry.Interval > 0 {
+		expiryChecker := newExpiryChecker(cfg.Expiry.Interval, cfg.Expiry.NoticePeriod, contract.State(cfg.Expiry.ExpiredState))
+		workers.Go("expiry-checker", expiryChecker.run)
+	}
+	if cfg.Billing.Interval > 0 {
+		workers.Go("billing-closer", newBillingCloser(cfg.Billing.Interval).run)
 	}
+	reconciler = newCspReconciler(cfg.Reconciler.Interval, cfg.Reconciler.Policy, cfg.Reconciler.GracePeriod)
+	if cfg.Reconciler.Interval > 0 {
+		workers.Go("csp-reconciler", reconciler.run)
+	}
+
+	// initialize metrics
+	prometheus.MustRegister(newStatisticsCollector(contractAccessor))
+	metricsServer := startMetricsServer(cfg.Server.MetricsAddress, cfg.Server.MetricsPort)
 
 	// register & serve
-	pb.RegisterContractServiceServer(s, &server{})
-	if err := s.Serve(conn); err != nil {
-		log.Fatal("failed to serve:", err)
+	pb.RegisterContractServiceServer(s, &grpcServer{contractServer})
+	healthpb.RegisterHealthServer(s, healthChecker.server)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(conn)
+	}()
+
+	var gatewayServer *http.Server
+	if gw != nil {
+		gatewayServer = startGatewayServer(cfg.Server.GatewayAddress, cfg.Server.GatewayPort, cfg.Server.TLSEnabled,
+			cfg.Server.TLSCertPath, cfg.Server.TLSKeyPath, gw)
+	}
+
+	err = waitForSignal(serveErr)
+	if err != nil {
+		log.Error("failed to serve : ", err)
 	}
 
+	// shutdown, in which the gateway and the grpc server share a single deadline
+	log.Info("shutting down server")
+	healthChecker.shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if gatewayServer != nil {
+		if err := gatewayServer.Shutdown(ctx); err != nil {
+			log.Error("failed to shutdown gateway server : ", err)
+		}
+	}
+	gracefulStop(ctx, s)
+	workers.Stop()
+
+	if err := metricsServer.Shutdown(ctx); err != nil {
+		log.Error("failed to shutdown metrics server : ", err)
+	}
+
+	return err
+}
+
+// ensureDefaultContract creates the default contract of the configuration if there is none.
+func ensureDefaultContract(cfg DefaultConfig) error {
+	quota, err := cfg.quota()
+	if err != nil {
+		return err
+	}
+	contractID, created, err := contractAccessor.EnsureDefaultContract(context.Background(), cfg.ContractorName,
+		cfg.AvailableServices, quota, cfg.Description)
+	if err != nil {
+		return err
+	}
+	if created {
+		log.Info("created default contract ", cfg.ContractorName, " with id ", contractID)
+	} else {
+		log.Info("default contract id is ", contractID)
+	}
+	return nil
 }
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+const metricsNamespace = "tks_contract"
+
+var (
+	rpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Number of handled RPCs by method and result code.",
+	}, []string{"method", "code"})
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of handled RPCs by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of database queries by operation and table.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "table"})
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Number of failed database queries by operation and table.",
+	}, []string{"operation", "table"})
+
+	argoSubmissions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "argo",
+		Name:      "workflow_submissions_total",
+		Help:      "Number of argo workflow submissions by workflow template and result.",
+	}, []string{"template", "result"})
+
+	cspInfoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cspinfo",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of calls to tks-info by method and gRPC status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	contractEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "events",
+		Name:      "published_total",
+		Help:      "Number of published contract events by type and result.",
+	}, []string{"type", "result"})
+	expiringContracts = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "expiry",
+		Name:      "expiring_contracts",
+		Help:      "Number of active contracts which expire within the notice period, as of the last check.",
+	})
+	invoicesIssued = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "billing",
+		Name:      "invoices_issued_total",
+		Help:      "Number of issued invoices and credit notes by kind.",
+	}, []string{"kind"})
+	billingFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "billing",
+		Name:      "failures_total",
+		Help:      "Number of contracts whose invoices could not be issued by the billing job.",
+	})
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Number of contract cache lookups by result, hit or miss.",
+	}, []string{"result"})
+	reconcilerRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "reconciler",
+		Name:      "runs_total",
+		Help:      "Number of reconciliations with tks-info by result.",
+	}, []string{"result"})
+	reconcilerDrifts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "reconciler",
+		Name:      "drifts",
+		Help:      "Number of drifts between contracts and tks-info by kind, as of the last reconciliation.",
+	}, []string{"kind"})
+	reconcilerRepairs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "reconciler",
+		Name:      "repairs_total",
+		Help:      "Number of repaired drifts between contracts and tks-info by kind and result.",
+	}, []string{"kind", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(rpcRequests, rpcDuration, dbQueryDuration, dbQueryErrors,
+		argoSubmissions, cspInfoDuration, contractEvents, expiringContracts,
+		invoicesIssued, billingFailures, cacheRequests, reconcilerRuns, reconcilerDrifts, reconcilerRepairs)
+}
+
+// startMetricsServer serves prometheus metrics on the given address and port in background.
+func startMetricsServer(address string, port int) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{
+		Addr:    net.JoinHostPort(address, strconv.Itoa(port)),
+		Handler: mux,
+	}
+	go func() {
+		log.Info("Starting metrics server on ", srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error("failed to serve metrics : ", err)
+		}
+	}()
+	return srv
+}
+
+// codeResponse is implemented by every ContractService response.
+type codeResponse interface {
+	GetCode() pb.Code
+}
+
+// metricsServerInterceptor records the count, latency and result code of each RPC.
+func metricsServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	rpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+
+	code := status.Code(err).String()
+	if res, ok := resp.(codeResponse); ok && res != nil {
+		code = res.GetCode().String()
+	}
+	rpcRequests.WithLabelValues(info.FullMethod, code).Inc()
+	return resp, err
+}
+
+// metricsClientInterceptor records the latency of each outbound RPC.
+func metricsClientInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	cspInfoDuration.WithLabelValues(method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// observeArgoSubmission records the result of an argo workflow submission.
+func observeArgoSubmission(workflowTemplate string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	argoSubmissions.WithLabelValues(workflowTemplate, result).Inc()
+}
+
+const gormStartTimeKey = "metrics:start_time"
+
+// registerGormMetrics registers gorm callbacks which record duration and errors of each query.
+func registerGormMetrics(db *gorm.DB) error {
+	return registerGormCallbacks(db, "metrics", startGormTimer, observeGormQuery)
+}
+
+func startGormTimer(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(gormStartTimeKey, time.Now())
+	}
+}
+
+func observeGormQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		table := db.Statement.Table
+		if v, ok := db.InstanceGet(gormStartTimeKey); ok {
+			if start, ok := v.(time.Time); ok {
+				dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+			}
+		}
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			dbQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
+
+// statisticsTimeout bounds the database queries run on each scrape.
+const statisticsTimeout = 10 * time.Second
+
+// statisticsCollector exposes business gauges which are read from the database on each scrape.
+type statisticsCollector struct {
+	accessor  *contract.Accessor
+	contracts *prometheus.Desc
+	quota     *prometheus.Desc
+}
+
+func newStatisticsCollector(accessor *contract.Accessor) *statisticsCollector {
+	return &statisticsCollector{
+		accessor: accessor,
+		contracts: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "contracts"),
+			"Number of contracts.", nil, nil),
+		quota: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "allocated_quota"),
+			"Total quota allocated across all contracts by resource.", []string{"resource"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *statisticsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.contracts
+	ch <- c.quota
+}
+
+// Collect implements prometheus.Collector.
+func (c *statisticsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statisticsTimeout)
+	defer cancel()
+	stats, err := c.accessor.GetStatistics(ctx)
+	if err != nil {
+		log.Error("failed to collect contract statistics : ", err)
+		ch <- prometheus.NewInvalidMetric(c.contracts, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.contracts, prometheus.GaugeValue, float64(stats.Contracts))
+	for resource, value := range map[string]int64{
+		"cpu":       stats.Cpu,
+		"memory":    stats.Memory,
+		"block":     stats.Block,
+		"block_ssd": stats.BlockSsd,
+		"fs":        stats.Fs,
+		"fs_ssd":    stats.FsSsd,
+	} {
+		ch <- prometheus.MustNewConstMetric(c.quota, prometheus.GaugeValue, float64(value), resource)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+func TestMetricsServerInterceptor(t *testing.T) {
+	const method = "/contract.ContractService/GetQuota"
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &pb.GetQuotaResponse{Code: pb.Code_NOT_FOUND}, nil
+	}
+
+	before := testutil.ToFloat64(rpcRequests.WithLabelValues(method, pb.Code_NOT_FOUND.String()))
+	_, err := metricsServerInterceptor(context.Background(), &pb.GetQuotaRequest{}, info, handler)
+	require.NoError(t, err)
+
+	after := testutil.ToFloat64(rpcRequests.WithLabelValues(method, pb.Code_NOT_FOUND.String()))
+	require.Equal(t, before+1, after)
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+// openAPIPath is the path of the OpenAPI document served by the gateway.
+const openAPIPath = "/v1/openapi.json"
+
+// openAPIDocument returns an OpenAPI 3 document describing routes. Schemas of protocol buffer
+// messages are generated from their descriptors following the protojson mapping, and those of
+// the api package from their Go types following encoding/json.
+func openAPIDocument(routes []route) map[string]interface{} {
+	schemas := map[string]interface{}{
+		"Error": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"code":  map[string]interface{}{"type": "string"},
+				"error": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"msg": map[string]interface{}{"type": "string"}}},
+			},
+		},
+	}
+	paths := map[string]interface{}{}
+
+	for _, rt := range routes {
+		op := map[string]interface{}{
+			"operationId": rt.rpc,
+			"summary":     rt.summary,
+			"tags":        []string{"ContractService"},
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "OK",
+					"content":     jsonContent(messageSchemaRef(rt.response, schemas)),
+				},
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Error"}),
+				},
+			},
+		}
+
+		params := []interface{}{
+			map[string]interface{}{
+				"name":        userIdMetadataKey,
+				"in":          "header",
+				"description": "user id of the caller. Requests are limited to the contracts which the user belongs to.",
+				"schema":      map[string]interface{}{"type": "string", "format": "uuid"},
+			},
+		}
+		params = append(params, queryParameters(rt.request())...)
+		for param, key := range rt.metadata {
+			params = append(params, map[string]interface{}{
+				"name":        param,
+				"in":          "query",
+				"description": fmt.Sprintf("passed to the RPC as gRPC metadata %s", key),
+				"schema":      map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, seg := range strings.Split(rt.path, "/") {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				params = append(params, map[string]interface{}{
+					"name":     strings.Trim(seg, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "string"},
+				})
+			}
+		}
+		op["parameters"] = params
+
+		if rt.body {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(messageSchemaRef(rt.request(), schemas)),
+			}
+		}
+
+		item, ok := paths[rt.path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[rt.path] = item
+		}
+		item[strings.ToLower(rt.method)] = op
+	}
+
+	paths[openAPIPath] = map[string]interface{}{
+		strings.ToLower(http.MethodGet): map[string]interface{}{
+			"operationId": "GetOpenAPI",
+			"summary":     "Get this document",
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{"description": "OK"},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "tks-contract",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// messageSchemaRef adds the schema of m and the messages it refers to into schemas,
+// and returns a reference to it. m is either a protocol buffer message or a Go struct.
+func messageSchemaRef(m interface{}, schemas map[string]interface{}) map[string]interface{} {
+	if pm, ok := m.(proto.Message); ok {
+		return messageSchema(pm.ProtoReflect().Descriptor(), schemas)
+	}
+	return typeSchema(reflect.TypeOf(m), schemas)
+}
+
+// queryParameters returns the parameters of the fields of req which have a query tag.
+func queryParameters(req interface{}) []interface{} {
+	t := reflect.TypeOf(req)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var params []interface{}
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("query")
+		if name == "" {
+			continue
+		}
+		params = append(params, map[string]interface{}{
+			"name":   name,
+			"in":     "query",
+			"schema": typeSchema(t.Field(i).Type, nil),
+		})
+	}
+	return params
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	codeType       = reflect.TypeOf(api.Code(0))
+)
+
+// typeSchema returns the schema of a Go type following encoding/json.
+func typeSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	case codeType:
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem(), schemas)
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		name := t.Name()
+		ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+		if _, ok := schemas[name]; ok {
+			return ref
+		}
+		properties := map[string]interface{}{}
+		schemas[name] = map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+		addStructProperties(t, properties, schemas)
+		return ref
+	}
+	return map[string]interface{}{}
+}
+
+func addStructProperties(t reflect.Type, properties map[string]interface{}, schemas map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			addStructProperties(f.Type, properties, schemas)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = typeSchema(f.Type, schemas)
+	}
+}
+
+func messageSchema(md protoreflect.MessageDescriptor, schemas map[string]interface{}) map[string]interface{} {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "google.protobuf.Duration":
+		return map[string]interface{}{"type": "string"}
+	case "google.protobuf.Empty":
+		return map[string]interface{}{"type": "object"}
+	}
+
+	name := string(md.Name())
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+	properties := map[string]interface{}{}
+	schemas[name] = map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		properties[fd.JSONName()] = fieldSchema(fd, schemas)
+	}
+	return ref
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor, schemas map[string]interface{}) map[string]interface{} {
+	if fd.IsMap() {
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": singularSchema(fd.MapValue(), schemas),
+		}
+	}
+	if fd.IsList() {
+		return map[string]interface{}{
+			"type":  "array",
+			"items": singularSchema(fd, schemas),
+		}
+	}
+	return singularSchema(fd, schemas)
+}
+
+func singularSchema(fd protoreflect.FieldDescriptor, schemas map[string]interface{}) map[string]interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson encodes 64-bit integers as strings
+		return map[string]interface{}{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		var names []string
+		values := fd.Enum().Values()
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return map[string]interface{}{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchema(fd.Message(), schemas)
+	}
+	return map[string]interface{}{"type": "string"}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/google/uuid"
+	"github.com/openinfradev/tks-common/pkg/log"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// Kinds of drifts between contracts and the CSP info of tks-info.
+const (
+	// driftContractWithoutCsp is a contract which has no CSP info in tks-info. It is repaired by
+	// deleting the contract only if it was left behind by a failed creation, which is a contract
+	// whose only history is its creation. Other contracts, such as imported ones, the default
+	// contract and contracts which have had a CSP account, are only reported.
+	driftContractWithoutCsp = "contract_without_csp"
+	// driftCspWithoutContract is a CSP info whose contract does not exist. It is only reported,
+	// because tks-info has no API to delete CSP info.
+	driftCspWithoutContract = "csp_without_contract"
+	// driftUnlinkedCsp is a CSP info of a contract which is not attached to the contract.
+	// It is repaired by attaching the account.
+	driftUnlinkedCsp = "unlinked_csp"
+	// driftStaleCspLink is an attached CSP account which has no CSP info of the contract.
+	// It is repaired by detaching the account.
+	driftStaleCspLink = "stale_csp_link"
+)
+
+var driftKinds = []string{driftContractWithoutCsp, driftCspWithoutContract, driftUnlinkedCsp, driftStaleCspLink}
+
+// Policies of the reconciler.
+const (
+	reconcilePolicyReport = "report"
+	reconcilePolicyRepair = "repair"
+)
+
+func validateReconcilePolicy(policy string) error {
+	switch policy {
+	case reconcilePolicyReport, reconcilePolicyRepair:
+		return nil
+	}
+	return fmt.Errorf("invalid reconcile policy %s", policy)
+}
+
+// cspReconciler periodically compares the CSP accounts of contracts with the CSP info of
+// tks-info, and reports or repairs the drifts according to its policy.
+type cspReconciler struct {
+	interval time.Duration
+	policy   string
+	// gracePeriod skips contracts and accounts which are newer than it, because they may be
+	// in the middle of being created.
+	gracePeriod time.Duration
+
+	// running serializes reconciliations of the job and of the Reconcile API.
+	running sync.Mutex
+	mu      sync.RWMutex
+	report  *api.ReconcileReport
+}
+
+func newCspReconciler(interval time.Duration, policy string, gracePeriod time.Duration) *cspReconciler {
+	return &cspReconciler{
+		interval:    interval,
+		policy:      policy,
+		gracePeriod: gracePeriod,
+	}
+}
+
+// reconciler reconciles contracts with tks-info. It is replaced by run with the configured one.
+var reconciler = newCspReconciler(0, reconcilePolicyReport, 0)
+
+// run reconciles contracts with tks-info every interval until ctx is done.
+func (r *cspReconciler) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.reconcileOnce(ctx, time.Now(), false)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lastReport returns the report of the last reconciliation, or nil if none has run yet.
+func (r *cspReconciler) lastReport() *api.ReconcileReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.report
+}
+
+// reconcileOnce reconciles contracts with tks-info as of now and returns the report. Drifts are
+// repaired only if the policy is repair and dryRun is false.
+func (r *cspReconciler) reconcileOnce(ctx context.Context, now time.Time, dryRun bool) *api.ReconcileReport {
+	r.running.Lock()
+	defer r.running.Unlock()
+
+	report := &api.ReconcileReport{
+		Policy:    r.policy,
+		DryRun:    dryRun,
+		StartedAt: now,
+		Drifts:    []*api.CspDrift{},
+	}
+	if err := r.reconcile(ctx, now, report); err != nil {
+		log.Error("could not reconcile contracts with tks-info : ", err)
+		report.Error = err.Error()
+		reconcilerRuns.WithLabelValues("failure").Inc()
+	} else {
+		reconcilerRuns.WithLabelValues("success").Inc()
+		counts := map[string]int{}
+		for _, drift := range report.Drifts {
+			counts[drift.Kind]++
+		}
+		for _, kind := range driftKinds {
+			reconcilerDrifts.WithLabelValues(kind).Set(float64(counts[kind]))
+		}
+	}
+	report.FinishedAt = time.Now()
+
+	r.mu.Lock()
+	r.report = report
+	r.mu.Unlock()
+	return report
+}
+
+func (r *cspReconciler) reconcile(ctx context.Context, now time.Time, report *api.ReconcileReport) error {
+	// CSP info is fetched before contracts, so that the contract of a CSP info created in
+	// between always exists.
+	res, err := cspInfoClient.GetCSPInfos(ctx, &empty.Empty{})
+	if err != nil {
+		return fmt.Errorf("failed to get CSP info : %s", err)
+	}
+	if res.GetCode() != pb.Code_OK_UNSPECIFIED {
+		return fmt.Errorf("failed to get CSP info : %s", res.GetError().GetMsg())
+	}
+	contracts, links, err := contractAccessor.ListAllCsps(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list CSP accounts of contracts : %s", err)
+	}
+	report.Contracts = len(contracts)
+	report.CspInfos = len(res.GetCspInfos())
+	report.Drifts = findCspDrifts(contracts, links, res.GetCspInfos(), now.Add(-r.gracePeriod))
+	for _, drift := range report.Drifts {
+		log.Info("CSP drift ", drift.Kind, " of contract ", drift.ContractId, " csp id ", drift.CspId)
+	}
+
+	if r.policy == reconcilePolicyRepair && !report.DryRun {
+		repairCspDrifts(ctx, report.Drifts, contracts, links)
+	}
+	return nil
+}
+
+// findCspDrifts compares the CSP accounts attached to contracts with the CSP info of tks-info.
+// Contracts and accounts created after cutoff are skipped.
+func findCspDrifts(contracts []model.Contract, links []model.ContractCsp, infos []*pb.CSPInfo, cutoff time.Time) []*api.CspDrift {
+	exists := map[string]bool{}
+	for _, c := range contracts {
+		exists[c.ID] = !c.CreatedAt.After(cutoff)
+	}
+	linked := map[string]map[uuid.UUID]bool{}
+	for _, link := range links {
+		if linked[link.ContractID] == nil {
+			linked[link.ContractID] = map[uuid.UUID]bool{}
+		}
+		linked[link.ContractID][link.CspID] = true
+	}
+
+	drifts := []*api.CspDrift{}
+	known := map[string]map[uuid.UUID]bool{}
+	for _, info := range infos {
+		checked, ok := exists[info.GetContractId()]
+		if !ok {
+			drifts = append(drifts, &api.CspDrift{
+				Kind:       driftCspWithoutContract,
+				ContractId: info.GetContractId(),
+				CspId:      info.GetCspId(),
+				CspName:    info.GetName(),
+			})
+			continue
+		}
+		if !checked {
+			continue
+		}
+		cspID, err := uuid.Parse(info.GetCspId())
+		if err != nil {
+			log.Error("invalid CSP id ", info.GetCspId(), " of contract ", info.GetContractId(), " in tks-info")
+			continue
+		}
+		if known[info.GetContractId()] == nil {
+			known[info.GetContractId()] = map[uuid.UUID]bool{}
+		}
+		known[info.GetContractId()][cspID] = true
+		if !linked[info.GetContractId()][cspID] {
+			drifts = append(drifts, &api.CspDrift{
+				Kind:       driftUnlinkedCsp,
+				ContractId: info.GetContractId(),
+				CspId:      cspID.String(),
+				CspName:    info.GetName(),
+			})
+		}
+	}
+
+	for _, c := range contracts {
+		if !exists[c.ID] {
+			continue
+		}
+		// Attached accounts of a contract without any CSP info are not reported as stale
+		// links, so that they are not detached all at once.
+		if len(known[c.ID]) == 0 {
+			drifts = append(drifts, &api.CspDrift{
+				Kind:       driftContractWithoutCsp,
+				ContractId: c.ID,
+			})
+		}
+	}
+	for _, link := range links {
+		if !exists[link.ContractID] || len(known[link.ContractID]) == 0 || link.CreatedAt.After(cutoff) {
+			continue
+		}
+		if !known[link.ContractID][link.CspID] {
+			drifts = append(drifts, &api.CspDrift{
+				Kind:       driftStaleCspLink,
+				ContractId: link.ContractID,
+				CspId:      link.CspID.String(),
+				CspName:    link.CspName,
+				Role:       link.Role,
+			})
+		}
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].ContractId != drifts[j].ContractId {
+			return drifts[i].ContractId < drifts[j].ContractId
+		}
+		return drifts[i].Kind < drifts[j].Kind
+	})
+	return drifts
+}
+
+// repairCspDrifts attaches unlinked CSP accounts and detaches stale ones. A stale primary account
+// is replaced by another attached account first. Contracts without CSP info are deleted if they
+// were left behind by a failed creation. The result is set to each repaired drift.
+func repairCspDrifts(ctx context.Context, drifts []*api.CspDrift, contracts []model.Contract, links []model.ContractCsp) {
+	stale := map[string]bool{}
+	for _, drift := range drifts {
+		if drift.Kind == driftStaleCspLink {
+			stale[drift.ContractId+"/"+drift.CspId] = true
+		}
+	}
+	// valid holds the attached accounts of each contract which have CSP info, in attached order.
+	valid := map[string][]model.ContractCsp{}
+	for _, link := range links {
+		if !stale[link.ContractID+"/"+link.CspID.String()] {
+			valid[link.ContractID] = append(valid[link.ContractID], link)
+		}
+	}
+
+	isDefault := map[string]bool{}
+	for _, c := range contracts {
+		isDefault[c.ID] = c.IsDefault
+	}
+	attached := map[string]bool{}
+	for _, link := range links {
+		attached[link.ContractID] = true
+	}
+	for _, drift := range drifts {
+		if drift.Kind != driftContractWithoutCsp || isDefault[drift.ContractId] || attached[drift.ContractId] {
+			continue
+		}
+		deleted, err := contractAccessor.DeleteUnused(ctx, drift.ContractId, cleanupContract(ctx, drift.ContractId))
+		if deleted || err != nil {
+			setRepairResult(drift, err)
+		}
+	}
+
+	for _, drift := range drifts {
+		if drift.Kind != driftUnlinkedCsp {
+			continue
+		}
+		role := contract.CspRoleSecondary
+		if len(valid[drift.ContractId]) == 0 {
+			role = contract.CspRolePrimary
+		}
+		csp, err := contractAccessor.AttachCsp(ctx, drift.ContractId, model.ContractCsp{
+			CspID:   uuid.MustParse(drift.CspId),
+			CspName: drift.CspName,
+			Role:    string(role),
+		})
+		if err == nil {
+			valid[drift.ContractId] = append(valid[drift.ContractId], csp)
+		}
+		setRepairResult(drift, err)
+	}
+
+	// Stale primary accounts are detached last, because they cannot be detached while other
+	// accounts are attached.
+	var staleLinks []*api.CspDrift
+	for _, drift := range drifts {
+		if drift.Kind == driftStaleCspLink {
+			staleLinks = append(staleLinks, drift)
+		}
+	}
+	sort.SliceStable(staleLinks, func(i, j int) bool {
+		return staleLinks[i].Role != string(contract.CspRolePrimary) && staleLinks[j].Role == string(contract.CspRolePrimary)
+	})
+	for _, drift := range staleLinks {
+		cspID := uuid.MustParse(drift.CspId)
+		var err error
+		if contract.CspRole(drift.Role) == contract.CspRolePrimary && len(valid[drift.ContractId]) > 0 {
+			next := valid[drift.ContractId][0]
+			next.Role = string(contract.CspRolePrimary)
+			_, err = contractAccessor.AttachCsp(ctx, drift.ContractId, next)
+		}
+		if err == nil {
+			_, err = contractAccessor.DetachCsp(ctx, drift.ContractId, cspID)
+		}
+		setRepairResult(drift, err)
+	}
+}
+
+func setRepairResult(drift *api.CspDrift, err error) {
+	if err != nil {
+		log.Error("could not repair CSP drift ", drift.Kind, " of contract ", drift.ContractId, " csp id ", drift.CspId, " : ", err)
+		drift.Error = err.Error()
+		reconcilerRepairs.WithLabelValues(drift.Kind, "failure").Inc()
+		return
+	}
+	log.Info("CSP drift ", drift.Kind, " of contract ", drift.ContractId, " csp id ", drift.CspId, " is repaired")
+	drift.Repaired = true
+	reconcilerRepairs.WithLabelValues(drift.Kind, "success").Inc()
+}
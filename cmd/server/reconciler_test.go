@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestFindCspDrifts(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	cutoff := now.Add(-10 * time.Minute)
+	primary, stale, unlinked, orphan, fresh := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	contracts := []model.Contract{
+		{ID: "P0000000a", CreatedAt: old},
+		{ID: "P0000000b", CreatedAt: old},
+		{ID: "P0000000c", CreatedAt: now},
+	}
+	links := []model.ContractCsp{
+		{ContractID: "P0000000a", CspID: primary, CspName: "aws", Role: "primary", CreatedAt: old},
+		{ContractID: "P0000000a", CspID: stale, CspName: "gcp", Role: "dr", CreatedAt: old},
+		{ContractID: "P0000000a", CspID: fresh, CspName: "azure", Role: "secondary", CreatedAt: now},
+		{ContractID: "P0000000b", CspID: uuid.New(), CspName: "aws", Role: "primary", CreatedAt: old},
+	}
+	infos := []*pb.CSPInfo{
+		{ContractId: "P0000000a", CspId: primary.String(), Name: "aws"},
+		{ContractId: "P0000000a", CspId: unlinked.String(), Name: "openstack"},
+		{ContractId: "P0000000c", CspId: uuid.New().String(), Name: "aws"},
+		{ContractId: "P0000000z", CspId: orphan.String(), Name: "aws"},
+	}
+
+	drifts := findCspDrifts(contracts, links, infos, cutoff)
+	require.Equal(t, []*api.CspDrift{
+		{Kind: driftStaleCspLink, ContractId: "P0000000a", CspId: stale.String(), CspName: "gcp", Role: "dr"},
+		{Kind: driftUnlinkedCsp, ContractId: "P0000000a", CspId: unlinked.String(), CspName: "openstack"},
+		{Kind: driftContractWithoutCsp, ContractId: "P0000000b"},
+		{Kind: driftCspWithoutContract, ContractId: "P0000000z", CspId: orphan.String(), CspName: "aws"},
+	}, drifts)
+
+	require.Empty(t, findCspDrifts(nil, nil, nil, cutoff))
+}
+
+func TestValidateReconcilePolicy(t *testing.T) {
+	require.NoError(t, validateReconcilePolicy(reconcilePolicyReport))
+	require.NoError(t, validateReconcilePolicy(reconcilePolicyRepair))
+	require.Error(t, validateReconcilePolicy("delete"))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+)
+
+// secretFile holds a secret read from a file, such as a mounted kubernetes secret,
+// and reloads it when the content of the file changes.
+type secretFile struct {
+	path string
+
+	mu    sync.RWMutex
+	value string
+}
+
+func newSecretFile(path string) (*secretFile, error) {
+	s := &secretFile{path: path}
+	if _, err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the current secret.
+func (s *secretFile) Get() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
+
+// watch reloads the secret every interval until ctx is done.
+func (s *secretFile) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.reload()
+			if err != nil {
+				log.Error("failed to reload secret file ", s.path, " : ", err)
+				continue
+			}
+			if changed {
+				log.Info("secret is reloaded from ", s.path)
+			}
+		}
+	}
+}
+
+// reload reads the file and returns true if the secret is changed.
+func (s *secretFile) reload() (bool, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read secret file %s : %s", s.path, err)
+	}
+	value := strings.TrimRight(string(b), "\r\n")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == s.value {
+		return false, nil
+	}
+	s.value = value
+	return true, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinfradev/tks-contract/pkg/redact"
+)
+
+func TestSecretFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, ioutil.WriteFile(path, []byte("first\n"), 0600))
+
+	s, err := newSecretFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "first", s.Get())
+
+	changed, err := s.reload()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("second"), 0600))
+	changed, err = s.reload()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, "second", s.Get())
+}
+
+func TestConfigRedacted(t *testing.T) {
+	c := defaultConfig()
+	c.Database.Password = "secret"
+	c.Server.InternalToken = "token"
+	c.Server.InternalTokenFile = "/etc/tks-contract/token"
+
+	redacted := c.Redacted()
+	require.Equal(t, redact.Mask, redacted.Database.Password)
+	require.Equal(t, redact.Mask, redacted.Server.InternalToken)
+	require.Equal(t, "/etc/tks-contract/token", redacted.Server.InternalTokenFile)
+	require.Equal(t, "secret", c.Database.Password)
+	require.Equal(t, "token", c.Server.InternalToken)
+	for _, opt := range redacted.options() {
+		require.NotContains(t, valueString(opt.value), "secret", opt.flag)
+		require.NotEqual(t, "token", valueString(opt.value), opt.flag)
+	}
+}
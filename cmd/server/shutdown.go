@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"google.golang.org/grpc"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+)
+
+// workerGroup runs background workers until they are stopped.
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkerGroup() *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workerGroup{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Go runs fn in background. fn must return when ctx is done.
+func (g *workerGroup) Go(name string, fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		log.Info("starting worker ", name)
+		fn(g.ctx)
+		log.Info("worker ", name, " is stopped")
+	}()
+}
+
+// Stop cancels every worker and waits for them to return.
+func (g *workerGroup) Stop() {
+	g.cancel()
+	g.wg.Wait()
+}
+
+// waitForSignal blocks until SIGTERM or SIGINT is received or serving fails.
+func waitForSignal(serveErr <-chan error) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+
+	select {
+	case sig := <-signals:
+		log.Info("received signal ", sig)
+		return nil
+	case err := <-serveErr:
+		return err
+	}
+}
+
+// gracefulStop stops accepting new RPCs and waits for in-flight RPCs until ctx is done.
+// Remaining RPCs are cancelled after that.
+func gracefulStop(ctx context.Context, s *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		log.Info("all in-flight requests are completed")
+	case <-ctx.Done():
+		log.Error("in-flight requests are not completed before the shutdown deadline. stopping forcibly")
+		s.Stop()
+	}
+}
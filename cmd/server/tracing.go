@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+)
+
+const (
+	serviceName = "tks-contract"
+
+	traceExporterNone   = "none"
+	traceExporterOtlp   = "otlp"
+	traceExporterStdout = "stdout"
+)
+
+var tracer = otel.Tracer("github.com/openinfradev/tks-contract/cmd/server")
+
+// initTracer installs a global tracer provider exporting spans to the given exporter.
+// The returned function flushes and stops the exporter.
+func initTracer(exporter string, endpoint string, file string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		spanExporter sdktrace.SpanExporter
+		closer       io.Closer
+		err          error
+	)
+	switch exporter {
+	case traceExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case traceExporterOtlp:
+		spanExporter, err = otlptracegrpc.New(context.Background(),
+			otlptracegrpc.WithEndpoint(endpoint),
+			otlptracegrpc.WithInsecure())
+	case traceExporterStdout:
+		var w io.Writer = os.Stdout
+		if file != "" {
+			f, ferr := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if ferr != nil {
+				return nil, fmt.Errorf("failed to open trace file %s : %s", file, ferr)
+			}
+			w, closer = f, f
+		}
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %s", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter : %s", exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	log.Info("tracing is enabled with exporter ", exporter)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
+
+// setContractIdAttribute attaches the contract ID to the current span.
+func setContractIdAttribute(ctx context.Context, contractId string) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("contract.id", contractId))
+}
+
+// endSpan records err on span if any and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+const gormSpanKey = "tracing:span"
+
+// registerGormTracing registers gorm callbacks which create a span for each query.
+func registerGormTracing(db *gorm.DB) error {
+	return registerGormCallbacks(db, "tracing", startGormSpan, endGormSpan)
+}
+
+func startGormSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		_, span := tracer.Start(ctx, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient))
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endGormSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(gormSpanKey)
+		if !ok {
+			return
+		}
+		span, ok := v.(trace.Span)
+		if !ok {
+			return
+		}
+		span.SetAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBSQLTableKey.String(db.Statement.Table),
+			semconv.DBStatementKey.String(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+		err := db.Error
+		if err == gorm.ErrRecordNotFound {
+			err = nil
+		}
+		endSpan(span, err)
+	}
+}
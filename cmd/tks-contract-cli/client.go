@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// userIdHeader is the header for the user id of the caller, same as the gRPC metadata key.
+const userIdHeader = "tks-user-id"
+
+// client calls the HTTP/JSON gateway of tks-contract.
+type client struct {
+	address string
+	userId  string
+	token   string
+	http    *http.Client
+}
+
+func newClient(p profile) (*client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: p.InsecureSkipVerify,
+	}
+	if p.CACert != "" {
+		b, err := ioutil.ReadFile(p.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate %s : %s", p.CACert, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificate found in %s", p.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if p.Cert != "" || p.Key != "" {
+		cert, err := tls.LoadX509KeyPair(p.Cert, p.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate : %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	var token string
+	if p.TokenFile != "" {
+		b, err := ioutil.ReadFile(p.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token file %s : %s", p.TokenFile, err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &client{
+		address: strings.TrimRight(p.Address, "/"),
+		userId:  p.UserId,
+		token:   token,
+		http: &http.Client{
+			Transport: transport,
+			Timeout:   p.Timeout,
+		},
+	}, nil
+}
+
+// clientFor returns a client for the profile selected by the flags of cmd.
+func (c *cli) clientFor(cmd *cobra.Command) (*client, error) {
+	p, err := c.resolveProfile(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(p)
+}
+
+// call sends in to the gateway and decodes the response into out. If the response code is not OK,
+// it returns a *codeError.
+func (cl *client) call(ctx context.Context, method string, path string, query url.Values, in interface{}, out interface{}) error {
+	var body []byte
+	if in != nil {
+		b, err := api.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+	u := cl.address + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if cl.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cl.token)
+	}
+	if cl.userId != "" {
+		req.Header.Set(userIdHeader, cl.userId)
+	}
+
+	resp, err := cl.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := api.Unmarshal(b, out, true); err != nil {
+		return fmt.Errorf("unexpected response with status %s : %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	code, msg := responseStatus(out)
+	if code != pb.Code_OK_UNSPECIFIED {
+		return &codeError{code: code, msg: msg}
+	}
+	return nil
+}
+
+// responseStatus returns the code and error message of a response of ContractService or the api package.
+func responseStatus(res interface{}) (pb.Code, string) {
+	var code pb.Code
+	if r, ok := res.(interface{ GetCode() pb.Code }); ok {
+		code = r.GetCode()
+	}
+	switch r := res.(type) {
+	case interface{ GetError() *pb.Error }:
+		return code, r.GetError().GetMsg()
+	case interface{ GetMsg() string }:
+		return code, r.GetMsg()
+	}
+	return code, ""
+}
+
+// contractPath returns the gateway path of a contract.
+func contractPath(contractId string, elems ...string) string {
+	path := "/v1/contracts/" + url.PathEscape(contractId)
+	for _, e := range elems {
+		path += "/" + e
+	}
+	return path
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	"github.com/openinfradev/tks-contract/pkg/contract/quantity"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// quotaFlags binds flags for the six dimensions of a resource quota.
+func quotaFlags(cmd *cobra.Command, quota *pb.ContractQuota) {
+	cmd.Flags().Var(&quantityValue{quantity.Cpu, &quota.Cpu}, "cpu", "cpu quota in cores, e.g. 4 or 500m")
+	cmd.Flags().Var(&quantityValue{quantity.Memory, &quota.Memory}, "memory", "memory quota in GiB, e.g. 128 or 1Ti")
+	cmd.Flags().Var(&quantityValue{quantity.Block, &quota.Block}, "block", "block storage quota in GiB")
+	cmd.Flags().Var(&quantityValue{quantity.BlockSsd, &quota.BlockSsd}, "block-ssd", "ssd block storage quota in GiB")
+	cmd.Flags().Var(&quantityValue{quantity.Fs, &quota.Fs}, "fs", "file storage quota in GiB")
+	cmd.Flags().Var(&quantityValue{quantity.FsSsd, &quota.FsSsd}, "fs-ssd", "ssd file storage quota in GiB")
+}
+
+// quantityValue is a flag of a quota dimension, which is either an integer in the unit of the
+// dimension or a quantity such as 16Gi.
+type quantityValue struct {
+	dimension string
+	value     *int64
+}
+
+func (v *quantityValue) String() string {
+	if v.value == nil {
+		return "0"
+	}
+	return quantity.Format(v.dimension, *v.value)
+}
+
+func (v *quantityValue) Set(s string) error {
+	n, err := quantity.Parse(v.dimension, s)
+	if err != nil {
+		return err
+	}
+	*v.value = n
+	return nil
+}
+
+func (v *quantityValue) Type() string {
+	return "quantity"
+}
+
+func (c *cli) newCreateCommand() *cobra.Command {
+	var (
+		in       = &pb.CreateContractRequest{Quota: &pb.ContractQuota{}}
+		authFile string
+	)
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a contract",
+		Example: `  tks-contract-cli create --name acme --services lma,servicemesh --cpu 32 --memory 128 \
+    --csp-name aws --csp-auth-file aws-auth.json`,
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if authFile != "" {
+				b, err := ioutil.ReadFile(authFile)
+				if err != nil {
+					return fmt.Errorf("failed to read %s : %s", authFile, err)
+				}
+				in.CspAuth = strings.TrimSpace(string(b))
+			}
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &pb.CreateContractResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPost, "/v1/contracts", nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "CONTRACT ID\tCSP ID")
+				fmt.Fprintf(w, "%s\t%s\n", res.GetContractId(), res.GetCspId())
+			})
+		},
+	}
+	cmd.Flags().StringVar(&in.ContractorName, "name", "", "name of the contractor")
+	cmd.Flags().StringSliceVar(&in.AvailableServices, "services", nil, "available services, comma separated")
+	cmd.Flags().StringVar(&in.CspName, "csp-name", "", "name of the CSP")
+	cmd.Flags().StringVar(&in.CspAuth, "csp-auth", "", "credential of the CSP")
+	cmd.Flags().StringVar(&authFile, "csp-auth-file", "", "path of file holding credential of the CSP")
+	cmd.Flags().StringVar(&in.Creator, "creator", "", "user id of the creator, who becomes the owner")
+	cmd.Flags().StringVar(&in.Description, "description", "", "description of the contract")
+	quotaFlags(cmd, in.Quota)
+	_ = cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func (c *cli) newGetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "get CONTRACT_ID",
+		Short:   "Get a contract",
+		Long:    "Get a contract. The default contract is returned for CONTRACT_ID 'default'.",
+		Example: "  tks-contract-cli get default -o yaml",
+		Args:    exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &pb.GetContractResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0]), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printContracts(w, res.GetContract())
+			})
+		},
+	}
+}
+
+func (c *cli) newListCommand() *cobra.Command {
+	var selector string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List contracts",
+		Long:  "List contracts. With --user-id, only the contracts which the user belongs to are listed.",
+		Example: `  tks-contract-cli list -l region=kr-central
+  tks-contract-cli list -l 'tier in (gold,silver),!deprecated'`,
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			query := url.Values{}
+			if selector != "" {
+				query.Set("labelSelector", selector)
+			}
+			res := &pb.GetContractsResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, "/v1/contracts", query, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printContracts(w, res.GetContracts()...)
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "label selector, e.g. key=value, key!=value, key in (v1,v2), key notin (v1,v2), key, !key")
+	return cmd
+}
+
+func (c *cli) newDeleteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete CONTRACT_ID",
+		Short: "Delete a contract with its quota and members",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.DeleteContractResponse{}
+			if err := cl.call(cmd.Context(), http.MethodDelete, contractPath(args[0]), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintf(w, "contract %s is deleted\n", args[0])
+			})
+		},
+	}
+}
+
+func (c *cli) newHistoryCommand() *cobra.Command {
+	var offset, limit int
+	cmd := &cobra.Command{
+		Use:   "history CONTRACT_ID",
+		Short: "List the changes of a contract, newest first",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			query := url.Values{}
+			if offset > 0 {
+				query.Set("offset", strconv.Itoa(offset))
+			}
+			if limit > 0 {
+				query.Set("limit", strconv.Itoa(limit))
+			}
+			res := &api.GetContractHistoryResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "history"), query, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "TIME\tACTION\tACTOR\tPREVIOUS\tCURRENT")
+				for _, r := range res.Records {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+						r.Action, r.Actor, string(r.Previous), string(r.Current))
+				}
+			})
+		},
+	}
+	cmd.Flags().IntVar(&offset, "offset", 0, "number of records to skip")
+	cmd.Flags().IntVar(&limit, "limit", 0, "maximum number of records (default and maximum 100)")
+	return cmd
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newContractorCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "contractor",
+		Short: "Get or set the name, description and contractor profile of a contract",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get CONTRACT_ID",
+		Short: "Get the contractor profile of a contract",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.GetProfileResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "profile"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printProfile(w, res.Profile)
+			})
+		},
+	})
+
+	var fields struct {
+		name, description, businessNumber, billingAddress, email, phone string
+	}
+	set := &cobra.Command{
+		Use:   "set CONTRACT_ID",
+		Short: "Change the name, description and contractor profile of a contract",
+		Long: `Change the name, description and contractor profile of a contract. Fields which are not given
+are kept, and a field except the name is cleared with an empty value.`,
+		Example: `  tks-contract-cli contractor set P0123abcd --name acme-korea --business-number 123-45-67890 \
+    --contact-email billing@acme.example --contact-phone 02-1234-5678`,
+		Args: exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := &api.UpdateContractRequest{ContractId: args[0]}
+			for _, f := range []struct {
+				flag  string
+				value string
+				field **string
+			}{
+				{"name", fields.name, &in.ContractorName},
+				{"description", fields.description, &in.Description},
+				{"business-number", fields.businessNumber, &in.BusinessRegistrationNumber},
+				{"billing-address", fields.billingAddress, &in.BillingAddress},
+				{"contact-email", fields.email, &in.ContactEmail},
+				{"contact-phone", fields.phone, &in.ContactPhone},
+			} {
+				if cmd.Flags().Changed(f.flag) {
+					value := f.value
+					*f.field = &value
+				}
+			}
+			if in.ContractorName == nil && in.Description == nil && in.BusinessRegistrationNumber == nil &&
+				in.BillingAddress == nil && in.ContactEmail == nil && in.ContactPhone == nil {
+				return usageError{fmt.Errorf("no field to change is given")}
+			}
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.UpdateContractResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPatch, contractPath(args[0]), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printProfile(w, res.Profile)
+			})
+		},
+	}
+	set.Flags().StringVar(&fields.name, "name", "", "name of the contractor")
+	set.Flags().StringVar(&fields.description, "description", "", "description of the contract")
+	set.Flags().StringVar(&fields.businessNumber, "business-number", "", "business registration number, e.g. 123-45-67890")
+	set.Flags().StringVar(&fields.billingAddress, "billing-address", "", "billing address")
+	set.Flags().StringVar(&fields.email, "contact-email", "", "email of the primary contact")
+	set.Flags().StringVar(&fields.phone, "contact-phone", "", "phone number of the primary contact")
+	cmd.AddCommand(set)
+
+	return cmd
+}
+
+func printProfile(w io.Writer, profile *api.ContractProfile) {
+	if profile == nil {
+		return
+	}
+	fmt.Fprintf(w, "CONTRACTOR NAME\t%s\n", profile.ContractorName)
+	fmt.Fprintf(w, "DESCRIPTION\t%s\n", profile.Description)
+	fmt.Fprintf(w, "BUSINESS REGISTRATION NUMBER\t%s\n", profile.BusinessRegistrationNumber)
+	fmt.Fprintf(w, "BILLING ADDRESS\t%s\n", profile.BillingAddress)
+	fmt.Fprintf(w, "CONTACT EMAIL\t%s\n", profile.ContactEmail)
+	fmt.Fprintf(w, "CONTACT PHONE\t%s\n", profile.ContactPhone)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newCspCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "csp",
+		Short: "List, attach and detach the CSP accounts of a contract",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list CONTRACT_ID",
+		Short: "List the CSP accounts of a contract",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.ListCspsResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "csps"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printCsps(w, res.Csps...)
+			})
+		},
+	})
+
+	var (
+		in       = &api.AttachCspRequest{}
+		authFile string
+	)
+	attach := &cobra.Command{
+		Use:   "attach CONTRACT_ID (--csp-id ID | --csp-name NAME --csp-auth-file FILE)",
+		Short: "Attach a CSP account to a contract, or change the role of an attached account",
+		Long: `Attach an existing CSP info of the contract by --csp-id, or create a new CSP info in tks-info
+with --csp-name and the credential. If the account is attached as primary, the previous primary
+account becomes secondary.`,
+		Example: `  tks-contract-cli csp attach P0123abcd --csp-name aws --csp-auth-file aws-dr.json --role dr
+  tks-contract-cli csp attach P0123abcd --csp-id 1f0c3f6e-8b0e-4a8e-9d6b-2f5c1c0e7a10 --role primary`,
+		Args: exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if authFile != "" {
+				b, err := ioutil.ReadFile(authFile)
+				if err != nil {
+					return fmt.Errorf("failed to read %s : %s", authFile, err)
+				}
+				in.CspAuth = strings.TrimSpace(string(b))
+			}
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			in.ContractId = args[0]
+			res := &api.AttachCspResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPost, contractPath(args[0], "csps"), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printCsps(w, res.Csp)
+			})
+		},
+	}
+	attach.Flags().StringVar(&in.CspId, "csp-id", "", "id of an existing CSP info of the contract")
+	attach.Flags().StringVar(&in.CspName, "csp-name", "", "name of the CSP of a new CSP info")
+	attach.Flags().StringVar(&in.CspAuth, "csp-auth", "", "credential of the CSP of a new CSP info")
+	attach.Flags().StringVar(&authFile, "csp-auth-file", "", "path of file holding credential of the CSP")
+	attach.Flags().StringVar(&in.Role, "role", "secondary", "role of the account (primary, secondary, dr)")
+	cmd.AddCommand(attach)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "detach CONTRACT_ID CSP_ID",
+		Short:   "Detach a CSP account from a contract. The CSP info remains in tks-info.",
+		Example: "  tks-contract-cli csp detach P0123abcd 1f0c3f6e-8b0e-4a8e-9d6b-2f5c1c0e7a10",
+		Args:    exactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.DetachCspResponse{}
+			if err := cl.call(cmd.Context(), http.MethodDelete, contractPath(args[0], "csps", url.PathEscape(args[1])), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printCsps(w, res.Csp)
+			})
+		},
+	})
+
+	return cmd
+}
+
+func printCsps(w io.Writer, csps ...*api.ContractCsp) {
+	fmt.Fprintln(w, "CSP ID\tNAME\tROLE\tATTACHED")
+	for _, csp := range csps {
+		if csp == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", csp.CspId, csp.CspName, csp.Role, csp.AttachedAt.Format("2006-01-02 15:04:05"))
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+func (c *cli) newDefaultCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "default",
+		Short: "Manage the default contract",
+		Long: `Manage the default contract, which is returned by 'get default'. At most one contract is the
+default. It is for operators.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "set CONTRACT_ID",
+		Short:   "Make a contract the default one",
+		Example: `  tks-contract-cli default set P0123abcd --user-id ""`,
+		Args:    exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.SetDefaultContractResponse{}
+			in := &api.SetDefaultContractRequest{ContractId: args[0]}
+			if err := cl.call(cmd.Context(), http.MethodPut, "/v1/contracts/default", nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "PREV CONTRACT ID\tCONTRACT ID")
+				fmt.Fprintf(w, "%s\t%s\n", res.PrevContractId, res.ContractId)
+			})
+		},
+	})
+
+	var (
+		in    = &api.EnsureDefaultContractRequest{}
+		quota = &pb.ContractQuota{}
+	)
+	ensure := &cobra.Command{
+		Use:   "ensure --name NAME",
+		Short: "Create the default contract if there is none",
+		Long: `Create the default contract if there is none. If a contract of the name exists, it is made the
+default instead, and the services, quota and description are ignored.`,
+		Example: `  tks-contract-cli default ensure --name default --services lma --cpu 8 --memory 32Gi --user-id ""`,
+		Args:    exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			in.Quota = api.Quota{
+				Cpu: quota.Cpu, Memory: quota.Memory, Block: quota.Block,
+				BlockSsd: quota.BlockSsd, Fs: quota.Fs, FsSsd: quota.FsSsd,
+			}
+			res := &api.EnsureDefaultContractResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPost, "/v1/contracts/default", nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "CONTRACT ID\tCREATED")
+				fmt.Fprintf(w, "%s\t%t\n", res.ContractId, res.Created)
+			})
+		},
+	}
+	ensure.Flags().StringVar(&in.ContractorName, "name", "", "name of the contractor of the default contract")
+	ensure.Flags().StringSliceVar(&in.AvailableServices, "services", nil, "available services, comma separated")
+	ensure.Flags().StringVar(&in.Description, "description", "", "description of the contract")
+	quotaFlags(ensure, quota)
+	_ = ensure.MarkFlagRequired("name")
+	cmd.AddCommand(ensure)
+
+	return cmd
+}
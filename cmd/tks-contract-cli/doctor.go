@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newDoctorCommand() *cobra.Command {
+	var (
+		repair bool
+		in     = &api.RepairDatabaseRequest{}
+	)
+	cmd := &cobra.Command{
+		Use:   "doctor [--repair [--kind KIND]...]",
+		Short: "Scan the database for inconsistencies and repair them",
+		Long: `Scan the database for rows of contracts which do not exist (orphan_rows), contracts without
+a quota (missing_quota) or with more than one (duplicate_quota), and contracts with an invalid id
+(invalid_contract_id). With --repair, orphan rows and duplicate quotas are deleted, keeping the most
+recently updated quota, and a zero quota is created for contracts without one, all in a transaction.
+Invalid contract ids are only reported. It is for operators.`,
+		Example: `  tks-contract-cli doctor --user-id ""
+  tks-contract-cli doctor --repair --kind missing_quota --user-id ""`,
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(in.Kinds) > 0 && !repair {
+				return usageError{fmt.Errorf("--kind is only for --repair")}
+			}
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			if !repair {
+				res := &api.DiagnoseDatabaseResponse{}
+				if err := cl.call(cmd.Context(), http.MethodGet, "/v1/doctor", nil, nil, res); err != nil {
+					return err
+				}
+				return c.print(res, func(w io.Writer) {
+					printDoctorReport(w, res.Report)
+				})
+			}
+			res := &api.RepairDatabaseResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPost, "/v1/doctor/repair", nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printDoctorReport(w, res.Report)
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&repair, "repair", false, "repair the repairable inconsistencies in a transaction")
+	cmd.Flags().StringSliceVar(&in.Kinds, "kind", nil, "kind of inconsistencies to repair (default is every kind)")
+	return cmd
+}
+
+func printDoctorReport(w io.Writer, report *api.DoctorReport) {
+	if report == nil {
+		return
+	}
+	fmt.Fprintln(w, "KIND\tCONTRACT ID\tTABLE\tROWS\tRESULT")
+	for _, issue := range report.Issues {
+		result := "reported"
+		switch {
+		case issue.Repaired:
+			result = "repaired"
+		case issue.Repairable:
+			result = "repairable"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", issue.Kind, issue.ContractId, issue.Table, strings.Join(issue.RowIds, ","), result)
+	}
+	fmt.Fprintf(w, "\n%d contracts, %d issues\n", report.Contracts, len(report.Issues))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newChildrenCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "children CONTRACT_ID",
+		Short: "List the sub-contracts of a contract and the quota allocated to them",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.ListChildrenResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "children"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printSummaries(w, res.Children...)
+				q := res.Allocated
+				fmt.Fprintf(w, "\t(allocated)\t\t\t\t%s\n", formatQuota(q.Cpu, q.Memory, q.Block, q.BlockSsd, q.Fs, q.FsSsd))
+			})
+		},
+	}
+}
+
+func (c *cli) newAncestryCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ancestry CONTRACT_ID",
+		Short: "Get the ancestors of a contract from its parent to the root",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.GetAncestryResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "ancestry"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printSummaries(w, res.Ancestors...)
+			})
+		},
+	}
+}
+
+func (c *cli) newParentCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "parent",
+		Short: "Attach a contract to a parent contract or detach it",
+	}
+	setParent := func(cmd *cobra.Command, contractId string, parentId string) error {
+		cl, err := c.clientFor(cmd)
+		if err != nil {
+			return err
+		}
+		in := &api.SetParentRequest{ContractId: contractId, ParentId: parentId}
+		res := &api.SetParentResponse{}
+		if err := cl.call(cmd.Context(), http.MethodPut, contractPath(contractId, "parent"), nil, in, res); err != nil {
+			return err
+		}
+		return c.print(res, func(w io.Writer) {
+			fmt.Fprintln(w, "ID\tPREVIOUS PARENT\tPARENT")
+			fmt.Fprintf(w, "%s\t%s\t%s\n", contractId, orNone(res.PrevParentId), orNone(res.ParentId))
+		})
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set CONTRACT_ID PARENT_ID",
+		Short: "Make a contract a sub-contract of PARENT_ID",
+		Long: `Make a contract a sub-contract of PARENT_ID. The quotas of the sub-contracts of PARENT_ID,
+including the contract, must fit in the quota of PARENT_ID.`,
+		Args: exactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setParent(cmd, args[0], args[1])
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "unset CONTRACT_ID",
+		Short: "Detach a contract from its parent",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setParent(cmd, args[0], "")
+		},
+	})
+	return cmd
+}
+
+func printSummaries(w io.Writer, contracts ...*api.ContractSummary) {
+	fmt.Fprintln(w, "ID\tNAME\tPARENT\tSERVICES\tSTATE\tCPU\tMEMORY\tBLOCK\tBLOCK_SSD\tFS\tFS_SSD")
+	for _, ct := range contracts {
+		q := ct.Quota
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", ct.ContractId, ct.ContractorName,
+			orNone(ct.ParentId), strings.Join(ct.AvailableServices, ","), ct.State,
+			formatQuota(q.Cpu, q.Memory, q.Block, q.BlockSsd, q.Fs, q.FsSsd))
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+// outputCSV writes invoices as CSV, which only invoice commands support.
+const outputCSV = "csv"
+
+func (c *cli) newInvoiceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "invoice",
+		Short: "Fetch invoices and close billing periods",
+		Long: `Fetch invoices and credit notes, close billing periods and issue credit notes.
+Invoices can be written as CSV with -o csv. Closing periods and issuing credit notes are for operators.`,
+	}
+
+	var contractId, period string
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List invoices and credit notes",
+		Example: `  tks-contract-cli invoice list --contract P0123abcd
+  tks-contract-cli invoice list --period 2026-06 -o csv --user-id "" > invoices-2026-06.csv`,
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			query := url.Values{}
+			if contractId != "" {
+				query.Set("contractId", contractId)
+			}
+			if period != "" {
+				query.Set("period", period)
+			}
+			res := &api.ListInvoicesResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, "/v1/invoices", query, nil, res); err != nil {
+				return err
+			}
+			return c.printInvoices(res, res.Invoices...)
+		},
+	}
+	list.Flags().StringVar(&contractId, "contract", "", "contract of invoices (default is every contract)")
+	list.Flags().StringVar(&period, "period", "", "billing period in YYYY-MM (default is every period)")
+	cmd.AddCommand(list)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get INVOICE_ID",
+		Short: "Get an invoice or a credit note with its lines",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.GetInvoiceResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, "/v1/invoices/"+url.PathEscape(args[0]), nil, nil, res); err != nil {
+				return err
+			}
+			if c.output == outputCSV {
+				return api.WriteInvoicesCSV(c.out, res.Invoice)
+			}
+			return c.print(res, func(w io.Writer) {
+				printInvoiceLines(w, res.Invoice)
+			})
+		},
+	})
+
+	var closeContractId string
+	closeCmd := &cobra.Command{
+		Use:   "close PERIOD",
+		Short: "Issue the invoices of a billing period which is over",
+		Long: `Issue the invoices of PERIOD in YYYY-MM for every contract, or for --contract.
+Contracts which already have the invoices of PERIOD are skipped, so that it can be run again.`,
+		Example: `  tks-contract-cli invoice close 2026-06 --user-id ""`,
+		Args:    exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			in := &api.ClosePeriodRequest{Period: args[0], ContractId: closeContractId}
+			res := &api.ClosePeriodResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPost, "/v1/invoices/close", nil, in, res); err != nil {
+				return err
+			}
+			if c.output == outputCSV {
+				return api.WriteInvoicesCSV(c.out, res.Issued...)
+			}
+			return c.print(res, func(w io.Writer) {
+				printInvoices(w, res.Issued...)
+				ids := make([]string, 0, len(res.Failed))
+				for id := range res.Failed {
+					ids = append(ids, id)
+				}
+				sort.Strings(ids)
+				for _, id := range ids {
+					fmt.Fprintf(w, "-\tfailed\t%s\t%s\t\t%s\n", id, args[0], res.Failed[id])
+				}
+			})
+		},
+	}
+	closeCmd.Flags().StringVar(&closeContractId, "contract", "", "contract to close the period for (default is every contract)")
+	cmd.AddCommand(closeCmd)
+
+	var amount, reason string
+	credit := &cobra.Command{
+		Use:   "credit INVOICE_ID --reason REASON",
+		Short: "Correct an invoice with a credit note",
+		Long: `Correct an invoice with a credit note of --amount, or of the remaining balance of the
+invoice if --amount is omitted. The credit notes of an invoice cannot exceed its total.`,
+		Example: `  tks-contract-cli invoice credit 5cd1f0e6-9c5e-4c2b-a8a4-2b4c39b0c7f1 --amount 15000 --reason "outage on 6/12" --user-id ""`,
+		Args:    exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if reason == "" {
+				return usageError{fmt.Errorf("--reason must be specified")}
+			}
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			in := &api.IssueCreditNoteRequest{InvoiceId: args[0], Amount: amount, Reason: reason}
+			res := &api.IssueCreditNoteResponse{}
+			path := "/v1/invoices/" + url.PathEscape(args[0]) + "/credit-notes"
+			if err := cl.call(cmd.Context(), http.MethodPost, path, nil, in, res); err != nil {
+				return err
+			}
+			return c.printInvoices(res, res.CreditNote)
+		},
+	}
+	credit.Flags().StringVar(&amount, "amount", "", "amount to credit (default is the remaining balance)")
+	credit.Flags().StringVar(&reason, "reason", "", "reason of the correction (required)")
+	cmd.AddCommand(credit)
+	return cmd
+}
+
+// printInvoices writes invoices as CSV if it is selected, or v in the selected output format.
+func (c *cli) printInvoices(v interface{}, invoices ...*api.Invoice) error {
+	if c.output == outputCSV {
+		return api.WriteInvoicesCSV(c.out, invoices...)
+	}
+	return c.print(v, func(w io.Writer) {
+		printInvoices(w, invoices...)
+	})
+}
+
+func printInvoices(w io.Writer, invoices ...*api.Invoice) {
+	fmt.Fprintln(w, "ID\tNUMBER\tCONTRACT\tPERIOD\tTOTAL\tISSUED")
+	for _, inv := range invoices {
+		if inv == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s %s\t%s\n", inv.InvoiceId, inv.Number, inv.ContractId, inv.Period,
+			inv.Total, inv.Currency, inv.IssuedAt.Format("2006-01-02 15:04:05"))
+	}
+}
+
+func printInvoiceLines(w io.Writer, inv *api.Invoice) {
+	if inv == nil {
+		return
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\n", inv.Number, inv.ContractId, inv.Period)
+	if inv.Reason != "" {
+		fmt.Fprintf(w, "reason\t%s\n", inv.Reason)
+	}
+	fmt.Fprintln(w, "KIND\tITEM\tQUANTITY\tUNIT PRICE\tFROM\tTO\tAMOUNT")
+	for _, l := range inv.Lines {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", l.Kind, l.Item, formatLineQuantity(l.Kind, l.Item, l.Quantity), l.UnitPrice,
+			l.From.Format("2006-01-02"), l.To.Format("2006-01-02"), l.Amount)
+	}
+	fmt.Fprintf(w, "total\t\t\t\t\t\t%s %s\n", inv.Total, inv.Currency)
+}
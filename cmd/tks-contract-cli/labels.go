@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newLabelsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "labels",
+		Short: "Get or set the labels and annotations of a contract",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get CONTRACT_ID",
+		Short: "Get the labels and annotations of a contract",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.GetLabelsResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "labels"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printLabels(w, res.Labels, res.Annotations)
+			})
+		},
+	})
+
+	var annotations []string
+	set := &cobra.Command{
+		Use:   "set CONTRACT_ID [KEY=VALUE | KEY-]...",
+		Short: "Set and remove labels and annotations of a contract",
+		Long: `Set and remove labels and annotations of a contract. KEY=VALUE sets a label and KEY- removes it.
+Annotations are given in the same form with --annotation. Keys which are not given are kept.`,
+		Example: `  tks-contract-cli labels set P0123abcd region=kr-central tier=gold deprecated-
+  tks-contract-cli labels set P0123abcd --annotation salesOwner=kim`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return usageError{fmt.Errorf("requires CONTRACT_ID")}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := &api.SetLabelsRequest{ContractId: args[0]}
+			var err error
+			if in.Labels, in.RemoveLabels, err = parseKeyValues(args[1:]); err != nil {
+				return err
+			}
+			if in.Annotations, in.RemoveAnnotations, err = parseKeyValues(annotations); err != nil {
+				return err
+			}
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.SetLabelsResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPatch, contractPath(args[0], "labels"), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printLabels(w, res.Labels, res.Annotations)
+			})
+		},
+	}
+	set.Flags().StringArrayVar(&annotations, "annotation", nil, "annotation to set as KEY=VALUE or remove as KEY-, repeatable")
+	cmd.AddCommand(set)
+
+	return cmd
+}
+
+// parseKeyValues parses KEY=VALUE arguments to set and KEY- arguments to remove.
+func parseKeyValues(args []string) (set map[string]string, remove []string, err error) {
+	for _, arg := range args {
+		if i := strings.Index(arg, "="); i > 0 {
+			if set == nil {
+				set = map[string]string{}
+			}
+			set[arg[:i]] = arg[i+1:]
+			continue
+		}
+		if strings.HasSuffix(arg, "-") && len(arg) > 1 {
+			remove = append(remove, strings.TrimSuffix(arg, "-"))
+			continue
+		}
+		return nil, nil, usageError{fmt.Errorf("invalid argument %q, expected KEY=VALUE or KEY-", arg)}
+	}
+	return set, remove, nil
+}
+
+func printLabels(w io.Writer, labels map[string]string, annotations map[string]string) {
+	fmt.Fprintln(w, "KIND\tKEY\tVALUE")
+	for _, kind := range []struct {
+		name string
+		m    map[string]string
+	}{{"label", labels}, {"annotation", annotations}} {
+		keys := make([]string, 0, len(kind.m))
+		for k := range kind.m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", kind.name, k, kind.m[k])
+		}
+	}
+}
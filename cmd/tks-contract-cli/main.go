@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// cli holds the global options of tks-contract-cli.
+type cli struct {
+	configPath  string
+	profileName string
+	output      string
+	// conn holds connection options given by flags, which override the selected profile.
+	conn profile
+
+	out io.Writer
+}
+
+func main() {
+	c := &cli{out: os.Stdout}
+	cmd := c.newRootCommand()
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+func (c *cli) newRootCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tks-contract-cli",
+		Short: "Manage contracts of tks-contract",
+		Long: `Manage contracts of tks-contract through its HTTP/JSON gateway.
+
+Errors are reported with the response code of the server as the exit code,
+for example 5 for NOT_FOUND and 7 for PERMISSION_DENIED.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			switch c.output {
+			case outputTable, outputJSON, outputYAML:
+				return nil
+			case outputCSV:
+				if cmd.Parent() != nil && cmd.Parent().Name() == "invoice" {
+					return nil
+				}
+				return usageError{fmt.Errorf("output format %s is only for invoice commands", c.output)}
+			}
+			return usageError{fmt.Errorf("unknown output format %s", c.output)}
+		},
+	}
+	cmd.SetOut(c.out)
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError{err}
+	})
+
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&c.configPath, "config", defaultConfigPath(), "path of the profile configuration file")
+	flags.StringVar(&c.profileName, "profile", "", "connection profile to use (default is the current profile)")
+	flags.StringVarP(&c.output, "output", "o", outputTable, "output format (table, json, yaml, or csv for invoices)")
+	flags.StringVar(&c.conn.Address, "address", "", "address of the gateway, e.g. https://tks-contract:9180")
+	flags.StringVar(&c.conn.UserId, "user-id", "", "user id of the caller")
+	flags.StringVar(&c.conn.TokenFile, "token-file", "", "path of file holding the internal token of tks-contract")
+	flags.StringVar(&c.conn.CACert, "ca-cert", "", "path of CA certificate to verify the gateway")
+	flags.StringVar(&c.conn.Cert, "cert", "", "path of client certificate")
+	flags.StringVar(&c.conn.Key, "key", "", "path of client key")
+	flags.BoolVar(&c.conn.InsecureSkipVerify, "insecure-skip-verify", false, "skip verification of the gateway certificate")
+	flags.DurationVar(&c.conn.Timeout, "timeout", 0, "timeout of each request (default 30s)")
+
+	cmd.AddCommand(
+		c.newCreateCommand(),
+		c.newGetCommand(),
+		c.newListCommand(),
+		c.newDeleteCommand(),
+		c.newHistoryCommand(),
+		c.newExportCommand(),
+		c.newImportCommand(),
+		c.newQuotaCommand(),
+		c.newServicesCommand(),
+		c.newCspCommand(),
+		c.newMemberCommand(),
+		c.newRegionalQuotaCommand(),
+		c.newManifestsCommand(),
+		c.newLabelsCommand(),
+		c.newTermCommand(),
+		c.newRenewCommand(),
+		c.newChildrenCommand(),
+		c.newAncestryCommand(),
+		c.newParentCommand(),
+		c.newChargesCommand(),
+		c.newPricePlanCommand(),
+		c.newInvoiceCommand(),
+		c.newReconcileCommand(),
+		c.newDoctorCommand(),
+		c.newContractorCommand(),
+		c.newDefaultCommand(),
+		c.newProfileCommand(),
+	)
+	return cmd
+}
+
+// usageError is an error in command line arguments.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+// exactArgs is cobra.ExactArgs which reports a usage error.
+func exactArgs(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(n)(cmd, args); err != nil {
+			return usageError{err}
+		}
+		return nil
+	}
+}
+
+// codeError is an error response of the server.
+type codeError struct {
+	code pb.Code
+	msg  string
+}
+
+func (e *codeError) Error() string {
+	if e.msg == "" {
+		return e.code.String()
+	}
+	return fmt.Sprintf("%s : %s", e.code.String(), e.msg)
+}
+
+// exitCode returns the exit code for err. Server errors exit with their response code,
+// and connection failures exit as UNAVAILABLE.
+func exitCode(err error) int {
+	var (
+		ce *codeError
+		ue usageError
+		ne net.Error
+		le *url.Error
+	)
+	switch {
+	case errors.As(err, &ce):
+		return int(ce.code)
+	case errors.As(err, &ue):
+		return int(pb.Code_INVALID_ARGUMENT)
+	case errors.As(err, &ne), errors.As(err, &le):
+		return int(pb.Code_UNAVAILABLE)
+	}
+	return int(pb.Code_UNKNOWN)
+}
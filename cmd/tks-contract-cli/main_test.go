@@ -0,0 +1,754 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// run executes tks-contract-cli with args and returns its output.
+func run(t *testing.T, configPath string, args ...string) (string, error) {
+	var out bytes.Buffer
+	c := &cli{out: &out}
+	cmd := c.newRootCommand()
+	cmd.SetArgs(append([]string{"--config", configPath}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestProfile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	_, err := run(t, configPath, "profile", "set", "dev", "--address", "http://dev:9180", "--user-id", "u1")
+	require.NoError(t, err)
+	_, err = run(t, configPath, "profile", "set", "prod", "--address", "https://prod:9180", "--timeout", "5s")
+	require.NoError(t, err)
+
+	cfg, err := loadConfig(configPath)
+	require.NoError(t, err)
+	require.Equal(t, "dev", cfg.CurrentProfile)
+	require.Equal(t, "http://dev:9180", cfg.Profiles["dev"].Address)
+	require.Equal(t, 5*time.Second, cfg.Profiles["prod"].Timeout)
+
+	_, err = run(t, configPath, "profile", "use", "prod")
+	require.NoError(t, err)
+	out, err := run(t, configPath, "profile", "list")
+	require.NoError(t, err)
+	require.Regexp(t, `\*\s+prod`, out)
+
+	_, err = run(t, configPath, "profile", "use", "unknown")
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestResolveProfile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+	_, err := run(t, configPath, "profile", "set", "dev", "--address", "http://dev:9180", "--user-id", "u1")
+	require.NoError(t, err)
+
+	c := &cli{out: &bytes.Buffer{}}
+	cmd := c.newRootCommand()
+	require.NoError(t, cmd.ParseFlags([]string{"--config", configPath, "--user-id", "u2"}))
+	p, err := c.resolveProfile(cmd)
+	require.NoError(t, err)
+	require.Equal(t, "http://dev:9180", p.Address)
+	require.Equal(t, "u2", p.UserId)
+	require.Equal(t, defaultTimeout, p.Timeout)
+
+	c = &cli{out: &bytes.Buffer{}}
+	cmd = c.newRootCommand()
+	require.NoError(t, cmd.ParseFlags([]string{"--config", configPath, "--profile", "unknown"}))
+	_, err = c.resolveProfile(cmd)
+	require.Error(t, err)
+}
+
+func TestExitCode(t *testing.T) {
+	require.Equal(t, int(pb.Code_NOT_FOUND), exitCode(&codeError{code: pb.Code_NOT_FOUND}))
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(usageError{errors.New("bad flag")}))
+	require.Equal(t, int(pb.Code_UNKNOWN), exitCode(errors.New("unknown")))
+}
+
+func TestHistoryCommand(t *testing.T) {
+	var userId, authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId = r.Header.Get(userIdHeader)
+		authorization = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/v1/contracts/P0123abcd/history":
+			require.Equal(t, "10", r.URL.Query().Get("limit"))
+			_, _ = w.Write([]byte(`{"records": [{"id": "6c0f8b4c-3b9f-4f57-9d55-3b5b0f0a8d01", "contractId": "P0123abcd",
+				"action": "quota_updated", "actor": "u1", "previous": {"cpu": 4}, "current": {"cpu": 8},
+				"createdAt": "2022-06-01T00:00:00Z"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code": "NOT_FOUND", "error": {"msg": "contract not found"}}`))
+		}
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenPath, []byte("secret\n"), 0600))
+
+	out, err := run(t, configPath, "history", "P0123abcd", "--limit", "10", "--address", srv.URL, "--user-id", "u1",
+		"--token-file", tokenPath)
+	require.NoError(t, err)
+	require.Equal(t, "u1", userId)
+	require.Equal(t, "Bearer secret", authorization)
+	require.Contains(t, out, "quota_updated")
+	require.Contains(t, out, `{"cpu": 4}`)
+
+	out, err = run(t, configPath, "history", "P0123abcd", "--limit", "10", "--address", srv.URL, "-o", "json")
+	require.NoError(t, err)
+	require.Contains(t, out, `"action": "quota_updated"`)
+
+	out, err = run(t, configPath, "history", "P0123abcd", "--limit", "10", "--address", srv.URL, "-o", "yaml")
+	require.NoError(t, err)
+	require.Contains(t, out, "action: quota_updated")
+
+	_, err = run(t, configPath, "delete", "P0000none", "--address", srv.URL)
+	require.EqualError(t, err, "NOT_FOUND : contract not found")
+	require.Equal(t, int(pb.Code_NOT_FOUND), exitCode(err))
+
+	_, err = run(t, configPath, "history", "P0123abcd", "--address", srv.URL, "-o", "xml")
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+// commandCase is a command which is run against a stub gateway. The stub checks that the command
+// sends the expected request, and replies with response.
+type commandCase struct {
+	name string
+	args []string
+	// files are written in a temporary directory, which replaces {dir} in args.
+	files map[string]string
+	// method is empty if the command must fail without sending a request.
+	method string
+	path   string
+	query  map[string]string
+	header map[string]string
+	// request is the expected body, which is compared with the body as JSON.
+	request  interface{}
+	status   int
+	response interface{}
+	// code is the exit code of the command, and err is its error message if not empty.
+	code pb.Code
+	err  string
+	// output are regular expressions which the output must match.
+	output []string
+	// written are the files which the command must write in the temporary directory.
+	written map[string]string
+}
+
+func TestImportExportCommand(t *testing.T) {
+	importDoc := "version: tks-contract/v1\ncontracts:\n  - contractorName: acme\n"
+	importResponse := func(dryRun bool) *api.ImportContractsResponse {
+		return &api.ImportContractsResponse{Report: &api.ImportReport{DryRun: dryRun, Committed: !dryRun, Skipped: 1,
+			Results: []*api.ImportResult{{ContractorName: "acme", Action: "skipped"}}}}
+	}
+	rolledBack := importResponse(false)
+	rolledBack.Status = api.NewStatus(pb.Code_ABORTED, errors.New("import is rolled back"))
+	rolledBack.Report.Committed = false
+
+	runCommandCases(t, []commandCase{
+		{
+			name:   "export",
+			args:   []string{"export", "P0123abcd", "-o", "yaml"},
+			method: http.MethodGet, path: "/v1/contracts/export",
+			query: map[string]string{"contractId": "P0123abcd"},
+			response: &api.ExportContractsResponse{Document: &api.ContractDocument{
+				Version: "tks-contract/v1", Contracts: []*api.ContractRecord{{ContractorName: "acme"}},
+			}},
+			output: []string{"version: tks-contract/v1", "contractorName: acme"},
+		},
+		{
+			name:   "import dry run",
+			args:   []string{"import", "-f", "{dir}/contracts.yaml", "--dry-run"},
+			files:  map[string]string{"contracts.yaml": importDoc},
+			method: http.MethodPost, path: "/v1/contracts/import",
+			request: &api.ImportContractsRequest{DryRun: true, OnConflict: "skip", Document: &api.ContractDocument{
+				Version: "tks-contract/v1", Contracts: []*api.ContractRecord{{ContractorName: "acme"}},
+			}},
+			response: importResponse(true),
+			output:   []string{"acme", `1 skipped, 0 failed \(dry run, nothing is changed\)`},
+		},
+		{
+			name:   "import rolled back",
+			args:   []string{"import", "-f", "{dir}/contracts.yaml", "--on-conflict", "fail"},
+			files:  map[string]string{"contracts.yaml": importDoc},
+			method: http.MethodPost, path: "/v1/contracts/import",
+			request: &api.ImportContractsRequest{OnConflict: "fail", Document: &api.ContractDocument{
+				Version: "tks-contract/v1", Contracts: []*api.ContractRecord{{ContractorName: "acme"}},
+			}},
+			status:   http.StatusConflict,
+			response: rolledBack,
+			code:     pb.Code_ABORTED,
+			output:   []string{`\(rolled back\)`},
+		},
+		{
+			name:  "import of unknown version",
+			args:  []string{"import", "-f", "{dir}/contracts.yaml"},
+			files: map[string]string{"contracts.yaml": "version: tks-contract/v0\n"},
+			code:  pb.Code_INVALID_ARGUMENT,
+		},
+	})
+}
+
+// runCommandCases runs each command against a stub gateway which checks the request of the command.
+func runCommandCases(t *testing.T, testCases []commandCase) {
+	var (
+		tc       commandCase
+		requests int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, tc.method, r.Method, tc.name)
+		assert.Equal(t, tc.path, r.URL.Path, tc.name)
+		for k, v := range tc.query {
+			assert.Equal(t, v, r.URL.Query().Get(k), "%s: query %s", tc.name, k)
+		}
+		for k, v := range tc.header {
+			assert.Equal(t, v, r.Header.Get(k), "%s: header %s", tc.name, k)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if tc.request != nil {
+			expected, err := api.Marshal(tc.request)
+			assert.NoError(t, err, tc.name)
+			assert.JSONEq(t, string(expected), string(body), tc.name)
+		}
+		if tc.status != 0 {
+			w.WriteHeader(tc.status)
+		}
+		b, _ := api.Marshal(tc.response)
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+
+	for _, tc = range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+			}
+			args := []string{"--address", srv.URL}
+			for _, arg := range tc.args {
+				args = append(args, strings.ReplaceAll(arg, "{dir}", dir))
+			}
+			requests = 0
+
+			out, err := run(t, filepath.Join(dir, "cli.yaml"), args...)
+			if tc.method == "" {
+				require.Zero(t, requests, "no request is expected")
+			} else {
+				require.Equal(t, 1, requests)
+			}
+			if tc.code == pb.Code_OK_UNSPECIFIED {
+				require.NoError(t, err)
+			} else {
+				require.Equal(t, int(tc.code), exitCode(err), "unexpected error %v", err)
+			}
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+			}
+			for _, o := range tc.output {
+				require.Regexp(t, o, out)
+			}
+			for name, content := range tc.written {
+				b, err := ioutil.ReadFile(filepath.Join(dir, name))
+				require.NoError(t, err)
+				require.Equal(t, content, string(b))
+			}
+		})
+	}
+}
+
+func mustReadAll(r *http.Request) []byte {
+	b, _ := ioutil.ReadAll(r.Body)
+	return b
+}
+
+func TestParseKeyValues(t *testing.T) {
+	set, remove, err := parseKeyValues([]string{"region=kr-central", "tier=", "deprecated-"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"region": "kr-central", "tier": ""}, set)
+	require.Equal(t, []string{"deprecated"}, remove)
+
+	_, _, err = parseKeyValues([]string{"region"})
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestRenewCommand(t *testing.T) {
+	var in api.RenewContractRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := api.Unmarshal(mustReadAll(r), &in, false); err != nil || r.URL.Path != "/v1/contracts/P0123abcd/renew" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := api.Marshal(&api.RenewContractResponse{
+			PrevTerm:    &api.Term{State: "read_only"},
+			CurrentTerm: &api.Term{EffectiveFrom: time.Now(), ExpiresAt: &in.ExpiresAt, State: "active"},
+		})
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "renew", "P0123abcd", "--until", "2027-12-31", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2027, 12, 31, 23, 59, 59, 0, time.UTC), in.ExpiresAt.UTC())
+	require.Nil(t, in.EffectiveFrom)
+	require.Contains(t, out, "2027-12-31T23:59:59Z")
+	require.Contains(t, out, "read_only")
+
+	_, err = run(t, configPath, "renew", "P0123abcd", "--until", "2027-12-31T15:00:00+09:00", "--from", "2027-01-01",
+		"--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), *in.EffectiveFrom)
+
+	_, err = run(t, configPath, "renew", "P0123abcd", "--until", "next year", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+	_, err = run(t, configPath, "renew", "P0123abcd", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestParentCommand(t *testing.T) {
+	var in api.SetParentRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := api.Unmarshal(mustReadAll(r), &in, false); err != nil || r.Method != http.MethodPut ||
+			r.URL.Path != "/v1/contracts/P0123abcd/parent" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := api.Marshal(&api.SetParentResponse{PrevParentId: "P0000prev", ParentId: in.ParentId})
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "parent", "set", "P0123abcd", "P0000root", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "P0000root", in.ParentId)
+	require.Contains(t, out, "P0000prev")
+
+	out, err = run(t, configPath, "parent", "unset", "P0123abcd", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "", in.ParentId)
+	require.Regexp(t, `P0123abcd\s+P0000prev\s+-`, out)
+}
+
+func TestChargesCommand(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/contracts/P0123abcd/charges" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		query = r.URL.Query()
+		b, _ := api.Marshal(&api.GetContractChargesResponse{
+			Currency: "KRW",
+			Lines: []*api.ChargeLine{
+				{Kind: "resource", Item: "cpu", PricePlan: "standard", Quantity: 8000, UnitPrice: "1000", Amount: "4000.00"},
+			},
+			Total: "4000.00",
+		})
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "charges", "P0123abcd", "--from", "2026-06-01", "--to", "2026-07-01", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "2026-06-01T00:00:00Z", query.Get("from"))
+	require.Equal(t, "2026-07-01T00:00:00Z", query.Get("to"))
+	require.Regexp(t, `cpu\s+standard\s+8\s+1000`, out)
+	require.Contains(t, out, "4000.00 KRW")
+
+	_, err = run(t, configPath, "charges", "P0123abcd", "--from", "June", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestPricePlanCommand(t *testing.T) {
+	var in api.CreatePricePlanRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := api.Unmarshal(mustReadAll(r), &in, false); err != nil || r.Method != http.MethodPost ||
+			r.URL.Path != "/v1/price-plans/standard/versions" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := api.Marshal(&api.CreatePricePlanResponse{PricePlan: &api.PricePlan{
+			Name: in.Name, EffectiveFrom: in.EffectiveFrom, Currency: in.Currency,
+			ResourcePrices: in.ResourcePrices, ServicePrices: in.ServicePrices,
+		}})
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "price-plan", "create", "standard", "--from", "2026-07-01", "--currency", "KRW",
+		"--resource-price", "cpu=30000,memory=4000", "--service-price", "lma=100000", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"cpu": "30000", "memory": "4000"}, in.ResourcePrices)
+	require.Equal(t, map[string]string{"lma": "100000"}, in.ServicePrices)
+	require.Equal(t, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), in.EffectiveFrom)
+	require.Contains(t, out, "cpu=30000,memory=4000")
+
+	_, err = run(t, configPath, "price-plan", "create", "standard", "--currency", "KRW", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestInvoiceCommand(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/invoices" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		query = r.URL.Query()
+		b, _ := api.Marshal(&api.ListInvoicesResponse{Invoices: []*api.Invoice{{
+			InvoiceId: "5cd1f0e6-9c5e-4c2b-a8a4-2b4c39b0c7f1", Number: "INV-202606-P0123abcd", ContractId: "P0123abcd",
+			Kind: "invoice", Period: "2026-06", Currency: "KRW", Total: "4000.00",
+			Lines: []*api.InvoiceLine{{Kind: "resource", Item: "cpu", PricePlan: "standard", Quantity: 8000, UnitPrice: "1000", Amount: "4000.00"}},
+		}}})
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "invoice", "list", "--period", "2026-06", "-o", "csv", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "2026-06", query.Get("period"))
+	require.Contains(t, out, "number,kind,contract_id,period")
+	require.Contains(t, out, "INV-202606-P0123abcd,invoice,P0123abcd,2026-06,KRW,resource,cpu")
+
+	out, err = run(t, configPath, "invoice", "list", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Regexp(t, `INV-202606-P0123abcd\s+P0123abcd\s+2026-06\s+4000.00 KRW`, out)
+
+	_, err = run(t, configPath, "charges", "P0123abcd", "-o", "csv", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+	_, err = run(t, configPath, "invoice", "credit", "5cd1f0e6-9c5e-4c2b-a8a4-2b4c39b0c7f1", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestQuotaFlags(t *testing.T) {
+	quota := &pb.ContractQuota{}
+	cmd := &cobra.Command{}
+	quotaFlags(cmd, quota)
+	require.NoError(t, cmd.ParseFlags([]string{"--cpu", "1500m", "--memory", "1Ti", "--fs-ssd", "512"}))
+	require.Equal(t, int64(1500), quota.Cpu)
+	require.Equal(t, int64(1024), quota.Memory)
+	require.Equal(t, int64(512), quota.FsSsd)
+	require.Error(t, cmd.ParseFlags([]string{"--memory", "512Mi"}))
+
+	require.Equal(t, "1500m\t1Ti\t0\t0\t0\t512Gi", formatQuota(quota.Cpu, quota.Memory, quota.Block, quota.BlockSsd, quota.Fs, quota.FsSsd))
+}
+
+func TestManifestsCommand(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/contracts/P0123abcd/manifests" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := api.Marshal(&api.RenderManifestsResponse{Manifests: []*api.Manifest{
+			{Path: "quota/resource-quota.yaml", Kind: "ResourceQuota", Content: "kind: ResourceQuota\n"},
+			{Path: "quota/kustomization.yaml", Kind: "Kustomization", Content: "kind: Kustomization\n"},
+		}})
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "manifests", "P0123abcd", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "# quota/resource-quota.yaml\nkind: ResourceQuota\n---\n# quota/kustomization.yaml\nkind: Kustomization\n", out)
+
+	dir := t.TempDir()
+	_, err = run(t, configPath, "manifests", "P0123abcd", "--dir", dir, "--address", srv.URL)
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(filepath.Join(dir, "P0123abcd", "quota", "resource-quota.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, "kind: ResourceQuota\n", string(b))
+
+	_, err = run(t, configPath, "manifests", "P4567efgh", "--address", srv.URL)
+	require.Error(t, err)
+	_, err = run(t, configPath, "manifests", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestRegionalQuotaCommand(t *testing.T) {
+	var got api.RebalanceRegionalQuotasRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/contracts/P0123abcd/regional-quotas" || r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ = api.Marshal(&api.UpdateRegionalQuotasResponse{RegionalQuotas: got.RegionalQuotas})
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "cli.yaml")
+	path := filepath.Join(dir, "regions.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`regionalQuotas:
+- csp: aws
+  region: ap-northeast-2
+  quota: {cpu: 16, memory: 1Ti}
+- csp: openstack
+  quota: {cpu: 8}
+`), 0644))
+
+	out, err := run(t, configPath, "regional-quota", "rebalance", "P0123abcd", "-f", path, "--address", srv.URL)
+	require.NoError(t, err)
+	require.Len(t, got.RegionalQuotas, 2)
+	require.Equal(t, int64(1024), got.RegionalQuotas[0].Quota.Memory)
+	require.Equal(t, "openstack", got.RegionalQuotas[1].Csp)
+	require.Contains(t, out, "ap-northeast-2")
+	require.Contains(t, out, "1Ti")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("regionalQuotas:\n- csp: aws\n  quota: {gpu: 1}\n"), 0644))
+	_, err = run(t, configPath, "regional-quota", "rebalance", "P0123abcd", "-f", path, "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestCspCommand(t *testing.T) {
+	var got api.AttachCspRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b []byte
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/contracts/P0123abcd/csps":
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &got)
+			b, _ = api.Marshal(&api.AttachCspResponse{Csp: &api.ContractCsp{CspId: "csp-2", CspName: got.CspName, Role: got.Role}})
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/contracts/P0123abcd/csps/csp-2":
+			b, _ = api.Marshal(&api.DetachCspResponse{Csp: &api.ContractCsp{CspId: "csp-2", Role: "dr"}})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "cli.yaml")
+	authPath := filepath.Join(dir, "auth.json")
+	require.NoError(t, ioutil.WriteFile(authPath, []byte("secret\n"), 0600))
+
+	out, err := run(t, configPath, "csp", "attach", "P0123abcd", "--csp-name", "aws", "--csp-auth-file", authPath,
+		"--role", "dr", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, api.AttachCspRequest{ContractId: "P0123abcd", CspName: "aws", CspAuth: "secret", Role: "dr"}, got)
+	require.Contains(t, out, "csp-2")
+
+	out, err = run(t, configPath, "csp", "detach", "P0123abcd", "csp-2", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Contains(t, out, "dr")
+	_, err = run(t, configPath, "csp", "detach", "P0123abcd", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestReconcileCommand(t *testing.T) {
+	var got api.ReconcileRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/reconciliation" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		report := &api.ReconcileReport{Policy: "repair", Contracts: 2, CspInfos: 1, Drifts: []*api.CspDrift{
+			{Kind: "unlinked_csp", ContractId: "P0123abcd", CspId: "csp-1", CspName: "aws", Repaired: true},
+		}}
+		var b []byte
+		if r.Method == http.MethodPost {
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &got)
+			report.DryRun = got.DryRun
+			b, _ = api.Marshal(&api.ReconcileResponse{Report: report})
+		} else {
+			b, _ = api.Marshal(&api.GetReconcileReportResponse{Report: report})
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "reconcile", "run", "--dry-run", "--address", srv.URL)
+	require.NoError(t, err)
+	require.True(t, got.DryRun)
+	require.Contains(t, out, "(dry run)")
+
+	out, err = run(t, configPath, "reconcile", "report", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Contains(t, out, "unlinked_csp")
+	require.Contains(t, out, "repaired")
+}
+
+func TestDoctorCommand(t *testing.T) {
+	var got api.RepairDatabaseRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issue := &api.DoctorIssue{Kind: "missing_quota", ContractId: "P0123abcd", Table: "resource_quota", Repairable: true}
+		var b []byte
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/doctor":
+			b, _ = api.Marshal(&api.DiagnoseDatabaseResponse{Report: &api.DoctorReport{Contracts: 1, Issues: []*api.DoctorIssue{issue}}})
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/doctor/repair":
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &got)
+			issue.Repaired = true
+			b, _ = api.Marshal(&api.RepairDatabaseResponse{Report: &api.DoctorReport{Contracts: 1, Issues: []*api.DoctorIssue{issue}, Committed: true}})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "doctor", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Contains(t, out, "repairable")
+
+	out, err = run(t, configPath, "doctor", "--repair", "--kind", "missing_quota", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, []string{"missing_quota"}, got.Kinds)
+	require.Contains(t, out, "repaired")
+
+	_, err = run(t, configPath, "doctor", "--kind", "missing_quota", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestDefaultCommand(t *testing.T) {
+	var (
+		set    api.SetDefaultContractRequest
+		ensure api.EnsureDefaultContractRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/contracts/default" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var b []byte
+		switch r.Method {
+		case http.MethodPut:
+			_ = json.Unmarshal(body, &set)
+			b, _ = api.Marshal(&api.SetDefaultContractResponse{PrevContractId: "P0123abcd", ContractId: set.ContractId})
+		case http.MethodPost:
+			_ = json.Unmarshal(body, &ensure)
+			b, _ = api.Marshal(&api.EnsureDefaultContractResponse{ContractId: "P0123abcd", Created: true})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "default", "set", "P4567efgh", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "P4567efgh", set.ContractId)
+	require.Contains(t, out, "P0123abcd")
+
+	out, err = run(t, configPath, "default", "ensure", "--name", "default", "--services", "lma", "--memory", "32Gi",
+		"--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "default", ensure.ContractorName)
+	require.Equal(t, []string{"lma"}, ensure.AvailableServices)
+	require.Equal(t, int64(32), ensure.Quota.Memory)
+	require.Contains(t, out, "true")
+
+	_, err = run(t, configPath, "default", "set", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+	_, err = run(t, configPath, "default", "ensure", "--address", srv.URL)
+	require.Error(t, err)
+}
+
+func TestContractorCommand(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		profile := &api.ContractProfile{ContractorName: "acme", ContactEmail: "billing@acme.example"}
+		var b []byte
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/contracts/P0123abcd/profile":
+			b, _ = api.Marshal(&api.GetProfileResponse{Profile: profile})
+		case r.Method == http.MethodPatch && r.URL.Path == "/v1/contracts/P0123abcd":
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &got)
+			profile.ContractorName = "acme-korea"
+			b, _ = api.Marshal(&api.UpdateContractResponse{Profile: profile})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+	configPath := filepath.Join(t.TempDir(), "cli.yaml")
+
+	out, err := run(t, configPath, "contractor", "get", "P0123abcd", "--address", srv.URL)
+	require.NoError(t, err)
+	require.Contains(t, out, "billing@acme.example")
+
+	out, err = run(t, configPath, "contractor", "set", "P0123abcd", "--name", "acme-korea", "--billing-address", "",
+		"--address", srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "acme-korea", got["contractorName"])
+	require.Equal(t, "", got["billingAddress"])
+	require.NotContains(t, got, "contactEmail")
+	require.Contains(t, out, "acme-korea")
+
+	_, err = run(t, configPath, "contractor", "set", "P0123abcd", "--address", srv.URL)
+	require.Equal(t, int(pb.Code_INVALID_ARGUMENT), exitCode(err))
+}
+
+func TestMemberCommand(t *testing.T) {
+	runCommandCases(t, []commandCase{
+		{
+			name:   "list members",
+			args:   []string{"member", "list", "P0123abcd"},
+			method: http.MethodGet, path: "/v1/contracts/P0123abcd/members",
+			response: &api.ListMembersResponse{Members: []*api.ContractMember{{UserId: "user-1", Role: "owner"}}},
+			output:   []string{`user-1\s+owner`},
+		},
+		{
+			name:   "add member",
+			args:   []string{"member", "add", "P0123abcd", "user-2", "--role", "admin"},
+			method: http.MethodPut, path: "/v1/contracts/P0123abcd/members/user-2",
+			request:  &api.AddMemberRequest{ContractId: "P0123abcd", UserId: "user-2", Role: "admin"},
+			response: &api.AddMemberResponse{Member: &api.ContractMember{UserId: "user-2", Role: "admin"}},
+			output:   []string{`user-2\s+admin`},
+		},
+		{
+			name:   "remove last owner",
+			args:   []string{"member", "remove", "P0123abcd", "user-1"},
+			method: http.MethodDelete, path: "/v1/contracts/P0123abcd/members/user-1",
+			status:   http.StatusPreconditionFailed,
+			response: &api.RemoveMemberResponse{Status: api.NewStatus(pb.Code_FAILED_PRECONDITION, errors.New("the only owner of a contract cannot be removed or demoted"))},
+			code:     pb.Code_FAILED_PRECONDITION,
+		},
+		{
+			name:   "list user contracts",
+			args:   []string{"member", "contracts", "user-1"},
+			method: http.MethodGet, path: "/v1/users/user-1/contracts",
+			response: &api.ListUserContractsResponse{Contracts: []*api.UserContract{{ContractId: "P0123abcd", ContractorName: "acme", Role: "owner"}}},
+			output:   []string{`P0123abcd\s+acme\s+owner`},
+		},
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newManifestsCommand() *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "manifests CONTRACT_ID...",
+		Short: "Render the quota of contracts into Kubernetes ResourceQuota and LimitRange",
+		Long: `Render the quota and services of contracts into Kubernetes ResourceQuota and LimitRange
+manifests with a kustomization of them, as mapped by the manifests configuration of the server.
+
+The manifests are written to stdout, or with --dir into DIR/CONTRACT_ID in the layout of the
+GitOps repository of each contract, such as DIR/P0123abcd/quota/resource-quota.yaml.`,
+		Example: `  tks-contract-cli manifests P0123abcd
+  tks-contract-cli manifests P0123abcd P4567efgh --dir ./contracts`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return usageError{fmt.Errorf("requires CONTRACT_ID")}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			responses := make([]*api.RenderManifestsResponse, 0, len(args))
+			for _, id := range args {
+				res := &api.RenderManifestsResponse{}
+				if err := cl.call(cmd.Context(), http.MethodGet, contractPath(id, "manifests"), nil, nil, res); err != nil {
+					return err
+				}
+				responses = append(responses, res)
+			}
+
+			if dir != "" {
+				for i, res := range responses {
+					for _, m := range res.Manifests {
+						path, err := writeManifest(dir, args[i], m)
+						if err != nil {
+							return err
+						}
+						fmt.Fprintln(c.out, path)
+					}
+				}
+				return nil
+			}
+			if c.output != outputTable {
+				if len(responses) == 1 {
+					return c.print(responses[0], nil)
+				}
+				return c.print(responses, nil)
+			}
+			docs := []string{}
+			for _, res := range responses {
+				for _, m := range res.Manifests {
+					docs = append(docs, fmt.Sprintf("# %s\n%s", m.Path, m.Content))
+				}
+			}
+			_, err = io.WriteString(c.out, strings.Join(docs, "---\n"))
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", "", "directory to write the manifests of each contract into DIR/CONTRACT_ID")
+	return cmd
+}
+
+// writeManifest writes a manifest of a contract into dir, and returns its path.
+func writeManifest(dir string, contractId string, m *api.Manifest) (string, error) {
+	rel := filepath.FromSlash(m.Path)
+	if filepath.IsAbs(rel) || strings.HasPrefix(filepath.Clean(rel), "..") {
+		return "", fmt.Errorf("invalid path %s of manifest", m.Path)
+	}
+	path := filepath.Join(dir, contractId, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory of %s : %s", path, err)
+	}
+	if err := ioutil.WriteFile(path, []byte(m.Content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s : %s", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newMemberCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "member",
+		Short: "List, add and remove the members of a contract",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list CONTRACT_ID",
+		Short: "List the members of a contract",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.ListMembersResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "members"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printMembers(w, res.Members...)
+			})
+		},
+	})
+
+	in := &api.AddMemberRequest{}
+	add := &cobra.Command{
+		Use:   "add CONTRACT_ID USER_ID",
+		Short: "Add a user to a contract, or change the role of a member",
+		Long: `Add a user to a contract with the role, or change the role of a member. Only owners can change
+the members, and the only owner of a contract cannot be demoted.`,
+		Example: "  tks-contract-cli member add P0123abcd 8d3c5a52-7f1e-4a43-9b8e-1c2d3e4f5a6b --role admin",
+		Args:    exactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			in.ContractId, in.UserId = args[0], args[1]
+			res := &api.AddMemberResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPut, contractPath(args[0], "members", url.PathEscape(args[1])), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printMembers(w, res.Member)
+			})
+		},
+	}
+	add.Flags().StringVar(&in.Role, "role", "viewer", "role of the member (owner, admin, viewer)")
+	cmd.AddCommand(add)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "remove CONTRACT_ID USER_ID",
+		Short:   "Remove a user from a contract. The only owner of a contract cannot be removed.",
+		Example: "  tks-contract-cli member remove P0123abcd 8d3c5a52-7f1e-4a43-9b8e-1c2d3e4f5a6b",
+		Args:    exactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.RemoveMemberResponse{}
+			if err := cl.call(cmd.Context(), http.MethodDelete, contractPath(args[0], "members", url.PathEscape(args[1])), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintf(w, "user %s is removed from contract %s\n", args[1], args[0])
+			})
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "contracts USER_ID",
+		Short: "List the contracts which a user belongs to",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.ListUserContractsResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, "/v1/users/"+url.PathEscape(args[0])+"/contracts", nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "CONTRACT ID\tCONTRACTOR\tROLE")
+				for _, uc := range res.Contracts {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", uc.ContractId, uc.ContractorName, uc.Role)
+				}
+			})
+		},
+	})
+
+	return cmd
+}
+
+func printMembers(w io.Writer, members ...*api.ContractMember) {
+	fmt.Fprintln(w, "USER ID\tROLE\tADDED")
+	for _, m := range members {
+		if m == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", m.UserId, m.Role, m.AddedAt.Format("2006-01-02 15:04:05"))
+	}
+}
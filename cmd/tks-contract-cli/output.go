@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	"github.com/openinfradev/tks-contract/pkg/contract/quantity"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// print writes v in the selected output format. table writes v as a table.
+func (c *cli) print(v interface{}, table func(w io.Writer)) error {
+	switch c.output {
+	case outputTable:
+		w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	case outputJSON:
+		b, err := marshalIndent(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(c.out, string(b))
+		return err
+	case outputYAML:
+		b, err := marshalYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = c.out.Write(b)
+		return err
+	}
+	return usageError{fmt.Errorf("unknown output format %s", c.output)}
+}
+
+func marshalIndent(v interface{}) ([]byte, error) {
+	b, err := api.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "  "); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// marshalYAML encodes v as YAML with the same field names and order as JSON.
+func marshalYAML(v interface{}) ([]byte, error) {
+	b, err := api.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, err
+	}
+	clearStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// clearStyle resets the flow style which YAML nodes decoded from JSON have.
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		clearStyle(n)
+	}
+}
+
+func printContracts(w io.Writer, contracts ...*pb.Contract) {
+	fmt.Fprintln(w, "ID\tNAME\tSERVICES\tCPU\tMEMORY\tBLOCK\tBLOCK_SSD\tFS\tFS_SSD\tCREATED")
+	for _, ct := range contracts {
+		q := ct.GetQuota()
+		created := ""
+		if ct.GetCreatedAt() != nil {
+			created = ct.GetCreatedAt().AsTime().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ct.GetContractId(), ct.GetContractorName(),
+			strings.Join(ct.GetAvailableServices(), ","), formatQuota(q.GetCpu(), q.GetMemory(), q.GetBlock(),
+				q.GetBlockSsd(), q.GetFs(), q.GetFsSsd()), created)
+	}
+}
+
+func printQuota(w io.Writer, quotas ...*pb.ContractQuota) {
+	fmt.Fprintln(w, "CPU\tMEMORY\tBLOCK\tBLOCK_SSD\tFS\tFS_SSD")
+	for _, q := range quotas {
+		fmt.Fprintln(w, formatQuota(q.GetCpu(), q.GetMemory(), q.GetBlock(), q.GetBlockSsd(), q.GetFs(), q.GetFsSsd()))
+	}
+}
+
+// formatQuota returns the six dimensions of a quota as tab separated quantities, such as 16Gi.
+func formatQuota(cpu, memory, block, blockSsd, fs, fsSsd int64) string {
+	values := []int64{cpu, memory, block, blockSsd, fs, fsSsd}
+	columns := make([]string, len(values))
+	for i, dimension := range quantity.Dimensions() {
+		columns[i] = quantity.Format(dimension, values[i])
+	}
+	return strings.Join(columns, "\t")
+}
+
+// formatLineQuantity returns the quantity of a charge line, which is a quantity such as 500m for resources.
+func formatLineQuantity(kind string, item string, v int64) string {
+	if kind != "resource" {
+		return strconv.FormatInt(v, 10)
+	}
+	return quantity.Format(item, v)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newChargesCommand() *cobra.Command {
+	var from, to string
+	cmd := &cobra.Command{
+		Use:   "charges CONTRACT_ID",
+		Short: "Get the charges of a contract for a billing period",
+		Long: `Get the charges of a contract from --from until --to, prorated by the changes of its quota
+and services. The default period is from the start of the current month in UTC until now.
+DATE is either YYYY-MM-DD, which is the start of the day in UTC, or RFC 3339.`,
+		Example: `  tks-contract-cli charges P0123abcd --from 2026-06-01 --to 2026-07-01`,
+		Args:    exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := url.Values{}
+			for _, p := range []struct {
+				name  string
+				value string
+			}{{"from", from}, {"to", to}} {
+				if p.value == "" {
+					continue
+				}
+				t, err := parseDate(p.value, false)
+				if err != nil {
+					return err
+				}
+				query.Set(p.name, t.Format(time.RFC3339))
+			}
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.GetContractChargesResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "charges"), query, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "KIND\tITEM\tPLAN\tQUANTITY\tUNIT PRICE\tFROM\tTO\tAMOUNT")
+				for _, l := range res.Lines {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", l.Kind, l.Item, l.PricePlan,
+						formatLineQuantity(l.Kind, l.Item, l.Quantity),
+						l.UnitPrice, l.From.Format(time.RFC3339), l.To.Format(time.RFC3339), l.Amount)
+				}
+				fmt.Fprintf(w, "total\t\t\t\t\t%s\t%s\t%s %s\n", res.From.Format(time.RFC3339),
+					res.To.Format(time.RFC3339), res.Total, res.Currency)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&from, "from", "", "start of the billing period")
+	cmd.Flags().StringVar(&to, "to", "", "end of the billing period, exclusive")
+	return cmd
+}
+
+func (c *cli) newPricePlanCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "price-plan",
+		Short: "Manage price plans and the price plans of contracts",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list [NAME...]",
+		Short: "List the versions of price plans",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			query := url.Values{}
+			for _, name := range args {
+				query.Add("name", name)
+			}
+			res := &api.ListPricePlansResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, "/v1/price-plans", query, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printPricePlans(w, res.PricePlans...)
+			})
+		},
+	})
+
+	var from, currency string
+	var resourcePrices, servicePrices map[string]string
+	create := &cobra.Command{
+		Use:   "create NAME --from DATE --currency CODE",
+		Short: "Add a version of a price plan",
+		Long: `Add a version of a price plan which is effective from DATE until the next version.
+Prices are per unit and month. Resource prices are keyed by cpu, memory, block, block_ssd, fs and fs_ssd.`,
+		Example: `  tks-contract-cli price-plan create standard --from 2026-07-01 --currency KRW \
+    --resource-price cpu=30000,memory=4000 --service-price lma=100000`,
+		Args: exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if from == "" || currency == "" {
+				return usageError{fmt.Errorf("--from and --currency must be specified")}
+			}
+			in := &api.CreatePricePlanRequest{
+				Name:           args[0],
+				Currency:       currency,
+				ResourcePrices: resourcePrices,
+				ServicePrices:  servicePrices,
+			}
+			var err error
+			if in.EffectiveFrom, err = parseDate(from, false); err != nil {
+				return err
+			}
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.CreatePricePlanResponse{}
+			path := "/v1/price-plans/" + url.PathEscape(args[0]) + "/versions"
+			if err := cl.call(cmd.Context(), http.MethodPost, path, nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printPricePlans(w, res.PricePlan)
+			})
+		},
+	}
+	create.Flags().StringVar(&from, "from", "", "start of the version (required)")
+	create.Flags().StringVar(&currency, "currency", "", "ISO 4217 currency code (required)")
+	create.Flags().StringToStringVar(&resourcePrices, "resource-price", nil, "price of a quota dimension, as DIMENSION=PRICE")
+	create.Flags().StringToStringVar(&servicePrices, "service-price", nil, "price of a service, as SERVICE=PRICE")
+	cmd.AddCommand(create)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set CONTRACT_ID NAME",
+		Short: "Assign a contract to a price plan",
+		Args:  exactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			in := &api.SetPricePlanRequest{ContractId: args[0], PricePlan: args[1]}
+			res := &api.SetPricePlanResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPut, contractPath(args[0], "price-plan"), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "PREVIOUS PLAN\tPLAN")
+				fmt.Fprintf(w, "%s\t%s\n", orNone(res.PrevPricePlan), res.PricePlan)
+			})
+		},
+	})
+	return cmd
+}
+
+func printPricePlans(w io.Writer, plans ...*api.PricePlan) {
+	fmt.Fprintln(w, "NAME\tEFFECTIVE FROM\tCURRENCY\tRESOURCE PRICES\tSERVICE PRICES")
+	for _, p := range plans {
+		if p == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.EffectiveFrom.Format(time.RFC3339), p.Currency,
+			formatPrices(p.ResourcePrices), formatPrices(p.ServicePrices))
+	}
+}
+
+func formatPrices(prices map[string]string) string {
+	keys := make([]string, 0, len(prices))
+	for k := range prices {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+prices[k])
+	}
+	return orNone(strings.Join(pairs, ","))
+}
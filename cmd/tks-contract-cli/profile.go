@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultProfile = "default"
+	defaultAddress = "http://localhost:9180"
+	defaultTimeout = 30 * time.Second
+)
+
+// profile holds the options to connect to a tks-contract gateway.
+type profile struct {
+	Address            string        `yaml:"address"`
+	UserId             string        `yaml:"userId,omitempty"`
+	TokenFile          string        `yaml:"tokenFile,omitempty"`
+	CACert             string        `yaml:"caCert,omitempty"`
+	Cert               string        `yaml:"cert,omitempty"`
+	Key                string        `yaml:"key,omitempty"`
+	InsecureSkipVerify bool          `yaml:"insecureSkipVerify,omitempty"`
+	Timeout            time.Duration `yaml:"timeout,omitempty"`
+}
+
+// cliConfig is the content of the profile configuration file.
+type cliConfig struct {
+	CurrentProfile string              `yaml:"currentProfile"`
+	Profiles       map[string]*profile `yaml:"profiles"`
+}
+
+func defaultConfigPath() string {
+	if path := os.Getenv("TKS_CONTRACT_CLI_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tks-contract", "cli.yaml")
+}
+
+// loadConfig reads the profile configuration file. A missing file is an empty configuration.
+func loadConfig(path string) (*cliConfig, error) {
+	cfg := &cliConfig{Profiles: map[string]*profile{}}
+	if path == "" {
+		return cfg, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s : %s", path, err)
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s : %s", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*profile{}
+	}
+	return cfg, nil
+}
+
+func (cfg *cliConfig) save(path string) error {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory of config file %s : %s", path, err)
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// currentProfileName returns the name of the profile selected by the --profile flag
+// or the configuration file.
+func (c *cli) currentProfileName(cfg *cliConfig) string {
+	if c.profileName != "" {
+		return c.profileName
+	}
+	if cfg.CurrentProfile != "" {
+		return cfg.CurrentProfile
+	}
+	return defaultProfile
+}
+
+// mergeFlags overrides p with the connection flags which are set in cmd.
+func (c *cli) mergeFlags(cmd *cobra.Command, p *profile) {
+	flags := cmd.Flags()
+	if flags.Changed("address") {
+		p.Address = c.conn.Address
+	}
+	if flags.Changed("user-id") {
+		p.UserId = c.conn.UserId
+	}
+	if flags.Changed("token-file") {
+		p.TokenFile = c.conn.TokenFile
+	}
+	if flags.Changed("ca-cert") {
+		p.CACert = c.conn.CACert
+	}
+	if flags.Changed("cert") {
+		p.Cert = c.conn.Cert
+	}
+	if flags.Changed("key") {
+		p.Key = c.conn.Key
+	}
+	if flags.Changed("insecure-skip-verify") {
+		p.InsecureSkipVerify = c.conn.InsecureSkipVerify
+	}
+	if flags.Changed("timeout") {
+		p.Timeout = c.conn.Timeout
+	}
+}
+
+// resolveProfile returns the connection options from the selected profile and flags.
+func (c *cli) resolveProfile(cmd *cobra.Command) (profile, error) {
+	cfg, err := loadConfig(c.configPath)
+	if err != nil {
+		return profile{}, err
+	}
+	name := c.currentProfileName(cfg)
+	var p profile
+	if stored, ok := cfg.Profiles[name]; ok {
+		p = *stored
+	} else if c.profileName != "" {
+		return profile{}, usageError{fmt.Errorf("unknown profile %s", name)}
+	}
+	c.mergeFlags(cmd, &p)
+
+	if p.Address == "" {
+		p.Address = defaultAddress
+	}
+	if p.Timeout == 0 {
+		p.Timeout = defaultTimeout
+	}
+	return p, nil
+}
+
+func (c *cli) newProfileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage connection profiles",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List connection profiles",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(c.configPath)
+			if err != nil {
+				return err
+			}
+			current := c.currentProfileName(cfg)
+			var names []string
+			for name := range cfg.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "CURRENT\tNAME\tADDRESS\tUSER ID")
+			for _, name := range names {
+				mark := ""
+				if name == current {
+					mark = "*"
+				}
+				p := cfg.Profiles[name]
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", mark, name, p.Address, p.UserId)
+			}
+			return w.Flush()
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set NAME",
+		Short: "Create or update a connection profile from the connection flags",
+		Example: `  tks-contract-cli profile set prod --address https://tks-contract:9180 --ca-cert ca.crt
+  tks-contract-cli profile set prod --token-file /etc/tks-contract/token --user-id 5cd1f0e6-9c5e-4c2b-a8a4-2b4c39b0c7f1`,
+		Args: exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(c.configPath)
+			if err != nil {
+				return err
+			}
+			p, ok := cfg.Profiles[args[0]]
+			if !ok {
+				p = &profile{}
+				cfg.Profiles[args[0]] = p
+			}
+			c.mergeFlags(cmd, p)
+			if cfg.CurrentProfile == "" {
+				cfg.CurrentProfile = args[0]
+			}
+			return cfg.save(c.configPath)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "use NAME",
+		Short: "Set the current connection profile",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(c.configPath)
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return usageError{fmt.Errorf("unknown profile %s", args[0])}
+			}
+			cfg.CurrentProfile = args[0]
+			return cfg.save(c.configPath)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a connection profile",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(c.configPath)
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Profiles[args[0]]; !ok {
+				return usageError{fmt.Errorf("unknown profile %s", args[0])}
+			}
+			delete(cfg.Profiles, args[0])
+			if cfg.CurrentProfile == args[0] {
+				cfg.CurrentProfile = ""
+			}
+			return cfg.save(c.configPath)
+		},
+	})
+
+	return cmd
+}
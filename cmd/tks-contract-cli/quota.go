@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+func (c *cli) newQuotaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "quota",
+		Short: "Get or set the resource quota of a contract",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get CONTRACT_ID",
+		Short: "Get the resource quota of a contract",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &pb.GetQuotaResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "quota"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printQuota(w, res.GetQuota())
+			})
+		},
+	})
+
+	quota := &pb.ContractQuota{}
+	set := &cobra.Command{
+		Use:     "set CONTRACT_ID",
+		Short:   "Set the resource quota of a contract. Omitted dimensions are not changed.",
+		Example: "  tks-contract-cli quota set P0123abcd --cpu 64 --memory 256",
+		Args:    exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			in := &pb.UpdateQuotaRequest{ContractId: args[0], Quota: quota}
+			res := &pb.UpdateQuotaResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPatch, contractPath(args[0], "quota"), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "\tCPU\tMEMORY\tBLOCK\tBLOCK_SSD\tFS\tFS_SSD")
+				for _, row := range []struct {
+					name  string
+					quota *pb.ContractQuota
+				}{{"PREVIOUS", res.GetPrevQuota()}, {"CURRENT", res.GetCurrentQuota()}} {
+					q := row.quota
+					fmt.Fprintf(w, "%s\t%s\n", row.name, formatQuota(q.GetCpu(), q.GetMemory(), q.GetBlock(),
+						q.GetBlockSsd(), q.GetFs(), q.GetFsSsd()))
+				}
+			})
+		},
+	}
+	quotaFlags(set, quota)
+	cmd.AddCommand(set)
+
+	return cmd
+}
+
+func (c *cli) newServicesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "services",
+		Short: "Get or set the available services of a contract",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get CONTRACT_ID",
+		Short: "Get the available services of a contract",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &pb.GetAvailableServicesResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "services"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "SERVICES")
+				fmt.Fprintln(w, strings.Join(res.GetAvaiableServiceApps(), ","))
+			})
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "set CONTRACT_ID SERVICE...",
+		Short:   "Replace the available services of a contract",
+		Example: "  tks-contract-cli services set P0123abcd lma servicemesh",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return usageError{fmt.Errorf("requires CONTRACT_ID")}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			in := &pb.UpdateServicesRequest{ContractId: args[0], AvailableServices: args[1:]}
+			res := &pb.UpdateServicesResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPatch, contractPath(args[0], "services"), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				fmt.Fprintln(w, "PREVIOUS\tCURRENT")
+				fmt.Fprintf(w, "%s\t%s\n", strings.Join(res.GetPrevServices(), ","),
+					strings.Join(res.GetCurrentServices(), ","))
+			})
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newReconcileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Reconcile the CSP accounts of contracts with tks-info",
+		Long: `Compare the CSP accounts attached to contracts with the CSP info of tks-info. Drifts are
+repaired if the policy of the server is repair, and only reported otherwise. It is for operators.`,
+	}
+
+	var dryRun bool
+	run := &cobra.Command{
+		Use:     "run",
+		Short:   "Reconcile contracts with tks-info now",
+		Example: `  tks-contract-cli reconcile run --dry-run --user-id ""`,
+		Args:    exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.ReconcileResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPost, "/v1/reconciliation", nil, &api.ReconcileRequest{DryRun: dryRun}, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printReconcileReport(w, res.Report)
+			})
+		},
+	}
+	run.Flags().BoolVar(&dryRun, "dry-run", false, "only report drifts, whatever the policy is")
+	cmd.AddCommand(run)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "report",
+		Short: "Show the report of the last reconciliation",
+		Args:  exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.GetReconcileReportResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, "/v1/reconciliation", nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printReconcileReport(w, res.Report)
+			})
+		},
+	})
+
+	return cmd
+}
+
+func printReconcileReport(w io.Writer, report *api.ReconcileReport) {
+	if report == nil {
+		return
+	}
+	fmt.Fprintln(w, "KIND\tCONTRACT ID\tCSP ID\tCSP NAME\tROLE\tRESULT")
+	for _, drift := range report.Drifts {
+		result := "reported"
+		switch {
+		case drift.Error != "":
+			result = "failed: " + drift.Error
+		case drift.Repaired:
+			result = "repaired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", drift.Kind, drift.ContractId, drift.CspId, drift.CspName, drift.Role, result)
+	}
+	policy := report.Policy
+	if report.DryRun {
+		policy += " (dry run)"
+	}
+	fmt.Fprintf(w, "\npolicy %s, %d contracts, %d CSP info, %d drifts, finished at %s\n", policy,
+		report.Contracts, report.CspInfos, len(report.Drifts), report.FinishedAt.Format("2006-01-02 15:04:05"))
+	if report.Error != "" {
+		fmt.Fprintf(w, "error: %s\n", report.Error)
+	}
+}
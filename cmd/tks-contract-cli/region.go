@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+func (c *cli) newRegionalQuotaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "regional-quota",
+		Short: "List, allocate and rebalance the regional quotas of a contract",
+		Long: `A contract quota can be split into slices for regions or accounts of CSPs. The quota of
+the contract is the total, and the slices never exceed it in any dimension.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list CONTRACT_ID",
+		Short: "List the regional quotas of a contract with its total and unallocated quota",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.ListRegionalQuotasResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "regional-quotas"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printRegionalQuotas(w, res.RegionalQuotas)
+				for _, row := range []struct {
+					name  string
+					quota api.Quota
+				}{{"ALLOCATED", res.Allocated}, {"UNALLOCATED", res.Unallocated}, {"TOTAL", res.Total}} {
+					q := row.quota
+					fmt.Fprintf(w, "%s\t\t%s\n", row.name, formatQuota(q.Cpu, q.Memory, q.Block, q.BlockSsd, q.Fs, q.FsSsd))
+				}
+			})
+		},
+	})
+
+	var (
+		in    = &api.AllocateRegionalQuotaRequest{}
+		quota = &pb.ContractQuota{}
+	)
+	set := &cobra.Command{
+		Use:   "set CONTRACT_ID --csp CSP [--region REGION]",
+		Short: "Allocate a slice of the quota of a contract to a region, replacing the slice of the region if any",
+		Example: `  tks-contract-cli regional-quota set P0123abcd --csp aws --region ap-northeast-2 --cpu 16 --memory 64Gi
+  tks-contract-cli regional-quota set P0123abcd --csp openstack-prod --cpu 8`,
+		Args: exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			in.ContractId = args[0]
+			in.Quota = api.Quota{
+				Cpu: quota.Cpu, Memory: quota.Memory, Block: quota.Block,
+				BlockSsd: quota.BlockSsd, Fs: quota.Fs, FsSsd: quota.FsSsd,
+			}
+			res := &api.UpdateRegionalQuotasResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPost, contractPath(args[0], "regional-quotas"), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printRegionalQuotas(w, res.RegionalQuotas)
+			})
+		},
+	}
+	set.Flags().StringVar(&in.Csp, "csp", "", "CSP or CSP account of the slice")
+	set.Flags().StringVar(&in.Region, "region", "", "region of the slice, or empty for the whole CSP account")
+	quotaFlags(set, quota)
+	_ = set.MarkFlagRequired("csp")
+	cmd.AddCommand(set)
+
+	var csp, region string
+	remove := &cobra.Command{
+		Use:     "remove CONTRACT_ID --csp CSP [--region REGION]",
+		Short:   "Release the slice of a region back to the contract",
+		Example: "  tks-contract-cli regional-quota remove P0123abcd --csp aws --region ap-northeast-2",
+		Args:    exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			query := url.Values{"csp": {csp}}
+			if region != "" {
+				query.Set("region", region)
+			}
+			res := &api.UpdateRegionalQuotasResponse{}
+			if err := cl.call(cmd.Context(), http.MethodDelete, contractPath(args[0], "regional-quotas"), query, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printRegionalQuotas(w, res.RegionalQuotas)
+			})
+		},
+	}
+	remove.Flags().StringVar(&csp, "csp", "", "CSP or CSP account of the slice")
+	remove.Flags().StringVar(&region, "region", "", "region of the slice")
+	_ = remove.MarkFlagRequired("csp")
+	cmd.AddCommand(remove)
+
+	var file string
+	rebalance := &cobra.Command{
+		Use:   "rebalance CONTRACT_ID -f FILE",
+		Short: "Replace all the regional quotas of a contract at once. Regions which are not in the file are released.",
+		Long: `Replace all the regional quotas of a contract at once, so that quota can be moved between
+regions without exceeding the contract quota in between. The file is YAML or JSON such as
+
+  regionalQuotas:
+  - csp: aws
+    region: ap-northeast-2
+    quota: {cpu: 16, memory: 64Gi}
+  - csp: gcp
+    region: asia-northeast3
+    quota: {cpu: 16, memory: 32Gi}`,
+		Example: "  tks-contract-cli regional-quota rebalance P0123abcd -f regions.yaml",
+		Args:    exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				b   []byte
+				err error
+			)
+			if file == "-" {
+				b, err = ioutil.ReadAll(os.Stdin)
+			} else {
+				b, err = ioutil.ReadFile(file)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s : %s", file, err)
+			}
+			in, err := decodeRegionalQuotas(b)
+			if err != nil {
+				return usageError{err}
+			}
+			in.ContractId = args[0]
+
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.UpdateRegionalQuotasResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPut, contractPath(args[0], "regional-quotas"), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printRegionalQuotas(w, res.RegionalQuotas)
+			})
+		},
+	}
+	rebalance.Flags().StringVarP(&file, "file", "f", "", "path of the regional quotas, or - for standard input")
+	_ = rebalance.MarkFlagRequired("file")
+	cmd.AddCommand(rebalance)
+
+	return cmd
+}
+
+// decodeRegionalQuotas decodes regional quotas in YAML or JSON into a rebalance request.
+func decodeRegionalQuotas(b []byte) (*api.RebalanceRegionalQuotasRequest, error) {
+	b = bytes.TrimSpace(b)
+	if !bytes.HasPrefix(b, []byte("{")) {
+		var v interface{}
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, fmt.Errorf("invalid regional quotas : %s", err)
+		}
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("invalid regional quotas : %s", err)
+		}
+	}
+	in := &api.RebalanceRegionalQuotasRequest{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(in); err != nil {
+		return nil, fmt.Errorf("invalid regional quotas : %s", err)
+	}
+	return in, nil
+}
+
+func printRegionalQuotas(w io.Writer, quotas []*api.RegionalQuota) {
+	fmt.Fprintln(w, "CSP\tREGION\tCPU\tMEMORY\tBLOCK\tBLOCK_SSD\tFS\tFS_SSD")
+	for _, r := range quotas {
+		q := r.Quota
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Csp, r.Region, formatQuota(q.Cpu, q.Memory, q.Block, q.BlockSsd, q.Fs, q.FsSsd))
+	}
+}
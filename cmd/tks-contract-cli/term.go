@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newTermCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "term CONTRACT_ID",
+		Short: "Get the term and the state of a contract",
+		Args:  exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.GetTermResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, contractPath(args[0], "term"), nil, nil, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printTerms(w, []string{"current"}, res.Term)
+			})
+		},
+	}
+}
+
+func (c *cli) newRenewCommand() *cobra.Command {
+	var until, from string
+	cmd := &cobra.Command{
+		Use:   "renew CONTRACT_ID --until DATE",
+		Short: "Extend the term of a contract",
+		Long: `Extend the term of a contract until DATE, and make it active again if it is expired.
+DATE is either YYYY-MM-DD, which is the end of the day in UTC, or RFC 3339.`,
+		Example: `  tks-contract-cli renew P0123abcd --until 2027-12-31
+  tks-contract-cli renew P0123abcd --until 2027-12-31T15:00:00+09:00 --from 2027-01-01`,
+		Args: exactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if until == "" {
+				return usageError{fmt.Errorf("--until must be specified")}
+			}
+			in := &api.RenewContractRequest{ContractId: args[0]}
+			var err error
+			if in.ExpiresAt, err = parseDate(until, true); err != nil {
+				return err
+			}
+			if from != "" {
+				effectiveFrom, err := parseDate(from, false)
+				if err != nil {
+					return err
+				}
+				in.EffectiveFrom = &effectiveFrom
+			}
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.RenewContractResponse{}
+			if err := cl.call(cmd.Context(), http.MethodPost, contractPath(args[0], "renew"), nil, in, res); err != nil {
+				return err
+			}
+			return c.print(res, func(w io.Writer) {
+				printTerms(w, []string{"previous", "current"}, res.PrevTerm, res.CurrentTerm)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&until, "until", "", "end of the renewed term (required)")
+	cmd.Flags().StringVar(&from, "from", "", "start of the renewed term (default is the current start)")
+	return cmd
+}
+
+// parseDate parses YYYY-MM-DD or RFC 3339. A date is the start of the day in UTC,
+// or the end of the day if endOfDay is true.
+func parseDate(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, usageError{fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", s)}
+	}
+	return t, nil
+}
+
+func printTerms(w io.Writer, names []string, terms ...*api.Term) {
+	fmt.Fprintln(w, "TERM\tEFFECTIVE FROM\tEXPIRES AT\tSTATE\tEXPIRY WARNED AT")
+	for i, t := range terms {
+		if t == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", names[i], t.EffectiveFrom.Format(time.RFC3339),
+			formatTime(t.ExpiresAt), t.State, formatTime(t.ExpiryWarnedAt))
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
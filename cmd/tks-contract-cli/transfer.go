@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+)
+
+func (c *cli) newExportCommand() *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "export [CONTRACT_ID...]",
+		Short: "Export contracts with their quota and members as a contract document",
+		Long: `Export contracts with their quota and members as a contract document, which can be
+imported with the import command. All contracts are exported if no CONTRACT_ID is given.
+The document is written in YAML unless -o json is given.`,
+		Example: "  tks-contract-cli export -f contracts.yaml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			query := url.Values{}
+			for _, id := range args {
+				query.Add("contractId", id)
+			}
+			res := &api.ExportContractsResponse{}
+			if err := cl.call(cmd.Context(), http.MethodGet, "/v1/contracts/export", query, nil, res); err != nil {
+				return err
+			}
+
+			var b []byte
+			if c.output == outputJSON {
+				b, err = marshalIndent(res.Document)
+				b = append(b, '\n')
+			} else {
+				b, err = marshalYAML(res.Document)
+			}
+			if err != nil {
+				return err
+			}
+			if file == "" {
+				_, err = c.out.Write(b)
+				return err
+			}
+			if err := ioutil.WriteFile(file, b, 0600); err != nil {
+				return fmt.Errorf("failed to write %s : %s", file, err)
+			}
+			fmt.Fprintf(c.out, "%d contracts are exported to %s\n", len(res.Document.Contracts), file)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "path of file to write the document to (default is standard output)")
+	return cmd
+}
+
+func (c *cli) newImportCommand() *cobra.Command {
+	var (
+		file string
+		in   = &api.ImportContractsRequest{}
+	)
+	cmd := &cobra.Command{
+		Use:   "import -f FILE",
+		Short: "Import contracts from a contract document in JSON or YAML",
+		Long: `Import contracts from a contract document in JSON or YAML, as written by the export command.
+A contract whose id or contractor name already exists is a conflict, which is handled by --on-conflict:
+  skip       keep the existing contract
+  overwrite  replace the existing contract with the record
+  fail       roll back the whole import
+Imported contracts get no CSP info or repositories.`,
+		Example: `  tks-contract-cli import -f contracts.yaml --dry-run
+  tks-contract-cli import -f contracts.yaml --on-conflict overwrite`,
+		Args: exactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				b   []byte
+				err error
+			)
+			if file == "-" {
+				b, err = ioutil.ReadAll(os.Stdin)
+			} else {
+				b, err = ioutil.ReadFile(file)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s : %s", file, err)
+			}
+			if in.Document, err = api.DecodeDocument(b); err != nil {
+				return usageError{err}
+			}
+
+			cl, err := c.clientFor(cmd)
+			if err != nil {
+				return err
+			}
+			res := &api.ImportContractsResponse{}
+			err = cl.call(cmd.Context(), http.MethodPost, "/v1/contracts/import", nil, in, res)
+			var ce *codeError
+			if err != nil && (res.Report == nil || !errors.As(err, &ce)) {
+				return err
+			}
+			if perr := c.print(res, func(w io.Writer) {
+				printImportReport(w, res.Report)
+			}); perr != nil {
+				return perr
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "path of the document, or - for standard input")
+	cmd.Flags().StringVar(&in.OnConflict, "on-conflict", "skip", "how to handle existing contracts (skip, overwrite, fail)")
+	cmd.Flags().BoolVar(&in.DryRun, "dry-run", false, "report what would be imported without changing anything")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func printImportReport(w io.Writer, report *api.ImportReport) {
+	fmt.Fprintln(w, "CONTRACT ID\tNAME\tACTION\tERROR")
+	for _, r := range report.Results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.ContractId, r.ContractorName, r.Action, r.Error)
+	}
+	state := "committed"
+	switch {
+	case report.DryRun:
+		state = "dry run, nothing is changed"
+	case !report.Committed:
+		state = "rolled back"
+	}
+	fmt.Fprintf(w, "\n%d created, %d overwritten, %d skipped, %d failed (%s)\n",
+		report.Created, report.Overwritten, report.Skipped, report.Failed, state)
+}
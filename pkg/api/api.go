@@ -0,0 +1,727 @@
+// Package api defines the messages of the tks-contract HTTP/JSON API which are not part
+// of ContractService in tks-proto, and encodes both kinds of messages as JSON.
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// Code is a response code which is encoded as its name, as protojson encodes pb.Code.
+type Code pb.Code
+
+// MarshalJSON implements json.Marshaler.
+func (c Code) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pb.Code(c).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Code) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		n, err := strconv.ParseInt(string(b), 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid code %s", b)
+		}
+		*c = Code(n)
+		return nil
+	}
+	v, ok := pb.Code_value[name]
+	if !ok {
+		return fmt.Errorf("unknown code %s", name)
+	}
+	*c = Code(v)
+	return nil
+}
+
+// Error is an error of a failed request.
+type Error struct {
+	Msg string `json:"msg,omitempty"`
+}
+
+// Status is embedded in every response to hold its code and error,
+// in the same shape as ContractService responses.
+type Status struct {
+	Code  Code   `json:"code,omitempty"`
+	Error *Error `json:"error,omitempty"`
+}
+
+// GetCode returns the response code.
+func (s Status) GetCode() pb.Code {
+	return pb.Code(s.Code)
+}
+
+// GetMsg returns the error message, if any.
+func (s Status) GetMsg() string {
+	if s.Error == nil {
+		return ""
+	}
+	return s.Error.Msg
+}
+
+// NewStatus returns a status with code and the message of err.
+func NewStatus(code pb.Code, err error) Status {
+	s := Status{Code: Code(code)}
+	if err != nil {
+		s.Error = &Error{Msg: err.Error()}
+	}
+	return s
+}
+
+// DeleteContractRequest is a request to delete a contract.
+type DeleteContractRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// DeleteContractResponse is a response of DeleteContract.
+type DeleteContractResponse struct {
+	Status
+}
+
+// GetContractHistoryRequest is a request for the changes of a contract.
+type GetContractHistoryRequest struct {
+	ContractId string `json:"contractId"`
+	Offset     int    `json:"offset" query:"offset"`
+	Limit      int    `json:"limit" query:"limit"`
+}
+
+// HistoryRecord is a change of a contract.
+type HistoryRecord struct {
+	Id         string          `json:"id"`
+	ContractId string          `json:"contractId"`
+	Action     string          `json:"action"`
+	Actor      string          `json:"actor,omitempty"`
+	Previous   json.RawMessage `json:"previous,omitempty"`
+	Current    json.RawMessage `json:"current,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// GetContractHistoryResponse is a response of GetContractHistory.
+type GetContractHistoryResponse struct {
+	Status
+	Records []*HistoryRecord `json:"records"`
+}
+
+// GetLabelsRequest is a request for the labels and annotations of a contract.
+type GetLabelsRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// GetLabelsResponse is a response of GetLabels.
+type GetLabelsResponse struct {
+	Status
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// SetLabelsRequest is a request to set and remove labels and annotations of a contract.
+// Keys which are not given are kept.
+type SetLabelsRequest struct {
+	ContractId        string            `json:"contractId"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	RemoveLabels      []string          `json:"removeLabels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+	RemoveAnnotations []string          `json:"removeAnnotations,omitempty"`
+}
+
+// SetLabelsResponse is a response of SetLabels with the resulting labels and annotations.
+type SetLabelsResponse struct {
+	Status
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// Term is the term of a contract.
+type Term struct {
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	// ExpiresAt is omitted if the contract does not expire.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// State is one of active, read_only and suspended. Expired contracts are not active.
+	State string `json:"state"`
+	// ExpiryWarnedAt is when the contract was flagged as nearing expiry in the current term.
+	ExpiryWarnedAt *time.Time `json:"expiryWarnedAt,omitempty"`
+}
+
+// GetTermRequest is a request for the term of a contract.
+type GetTermRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// GetTermResponse is a response of GetTerm.
+type GetTermResponse struct {
+	Status
+	Term *Term `json:"term,omitempty"`
+}
+
+// RenewContractRequest is a request to extend the term of a contract. A renewed contract is active.
+type RenewContractRequest struct {
+	ContractId string `json:"contractId"`
+	// ExpiresAt must be later than now and the current expiry.
+	ExpiresAt time.Time `json:"expiresAt"`
+	// EffectiveFrom is the start of the renewed term. If omitted, the start is kept.
+	EffectiveFrom *time.Time `json:"effectiveFrom,omitempty"`
+}
+
+// RenewContractResponse is a response of RenewContract with the previous and renewed terms.
+type RenewContractResponse struct {
+	Status
+	PrevTerm    *Term `json:"prevTerm,omitempty"`
+	CurrentTerm *Term `json:"currentTerm,omitempty"`
+}
+
+// ContractSummary is a contract with its quota in a hierarchy of contracts.
+type ContractSummary struct {
+	ContractId        string   `json:"contractId"`
+	ContractorName    string   `json:"contractorName"`
+	ParentId          string   `json:"parentId,omitempty"`
+	AvailableServices []string `json:"availableServices"`
+	Quota             Quota    `json:"quota"`
+	State             string   `json:"state"`
+}
+
+// GetAncestryRequest is a request for the ancestors of a contract.
+type GetAncestryRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// GetAncestryResponse is a response of GetAncestry. Ancestors are ordered from the parent to the root.
+type GetAncestryResponse struct {
+	Status
+	Ancestors []*ContractSummary `json:"ancestors"`
+}
+
+// ListChildrenRequest is a request for the sub-contracts of a contract.
+type ListChildrenRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// ListChildrenResponse is a response of ListChildren. Allocated is the sum of the quotas of the
+// children, which never exceeds the quota of the contract.
+type ListChildrenResponse struct {
+	Status
+	Children  []*ContractSummary `json:"children"`
+	Allocated Quota              `json:"allocated"`
+}
+
+// SetParentRequest is a request to make a contract a sub-contract of another.
+type SetParentRequest struct {
+	ContractId string `json:"contractId"`
+	// ParentId is the new parent. If empty, the contract is detached from its parent.
+	ParentId string `json:"parentId"`
+}
+
+// SetParentResponse is a response of SetParent with the previous and current parents.
+type SetParentResponse struct {
+	Status
+	PrevParentId string `json:"prevParentId,omitempty"`
+	ParentId     string `json:"parentId,omitempty"`
+}
+
+// PricePlan is a version of the unit prices of a plan, effective until the next version of the plan.
+// Prices are decimal strings per unit and month.
+type PricePlan struct {
+	Name          string    `json:"name"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	// Currency is an ISO 4217 code, which is the same in every version of a plan.
+	Currency string `json:"currency"`
+	// ResourcePrices are keyed by quota dimension: cpu, memory, block, block_ssd, fs and fs_ssd.
+	ResourcePrices map[string]string `json:"resourcePrices"`
+	// ServicePrices are keyed by service name.
+	ServicePrices map[string]string `json:"servicePrices"`
+	CreatedAt     time.Time         `json:"createdAt"`
+}
+
+// CreatePricePlanRequest is a request to add a version of a price plan.
+type CreatePricePlanRequest struct {
+	Name           string            `json:"name"`
+	EffectiveFrom  time.Time         `json:"effectiveFrom"`
+	Currency       string            `json:"currency"`
+	ResourcePrices map[string]string `json:"resourcePrices"`
+	ServicePrices  map[string]string `json:"servicePrices"`
+}
+
+// CreatePricePlanResponse is a response of CreatePricePlan.
+type CreatePricePlanResponse struct {
+	Status
+	PricePlan *PricePlan `json:"pricePlan,omitempty"`
+}
+
+// ListPricePlansRequest is a request for the versions of price plans.
+type ListPricePlansRequest struct {
+	// Names are the plans to list. If empty, all plans are listed.
+	Names []string `json:"names" query:"name"`
+}
+
+// ListPricePlansResponse is a response of ListPricePlans. Versions are ordered by name and effective time.
+type ListPricePlansResponse struct {
+	Status
+	PricePlans []*PricePlan `json:"pricePlans"`
+}
+
+// SetPricePlanRequest is a request to assign a contract to a price plan.
+type SetPricePlanRequest struct {
+	ContractId string `json:"contractId"`
+	PricePlan  string `json:"pricePlan"`
+}
+
+// SetPricePlanResponse is a response of SetPricePlan with the previous and current plans.
+type SetPricePlanResponse struct {
+	Status
+	PrevPricePlan string `json:"prevPricePlan,omitempty"`
+	PricePlan     string `json:"pricePlan,omitempty"`
+}
+
+// ChargeLine is the charge of an item during a period in which its quantity and unit price do not change.
+type ChargeLine struct {
+	// Kind is resource for a quota dimension, or service for an available service.
+	Kind      string    `json:"kind"`
+	Item      string    `json:"item"`
+	PricePlan string    `json:"pricePlan"`
+	Quantity  int64     `json:"quantity"`
+	UnitPrice string    `json:"unitPrice"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	// Amount is the unit price times the quantity, prorated by the part of the month the line covers.
+	Amount string `json:"amount"`
+}
+
+// GetContractChargesRequest is a request for the charges of a contract for [from, to).
+type GetContractChargesRequest struct {
+	ContractId string `json:"contractId"`
+	// From is the start of the billing period. If omitted, it is the start of the current month in UTC.
+	From time.Time `json:"from" query:"from"`
+	// To is the end of the billing period. If omitted, it is now.
+	To time.Time `json:"to" query:"to"`
+}
+
+// GetContractChargesResponse is a response of GetContractCharges. Total is the sum of the amounts of lines.
+type GetContractChargesResponse struct {
+	Status
+	Currency string        `json:"currency,omitempty"`
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Lines    []*ChargeLine `json:"lines"`
+	Total    string        `json:"total"`
+}
+
+// RenderManifestsRequest is a request for the Kubernetes manifests of the quota of a contract.
+type RenderManifestsRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// Manifest is a Kubernetes manifest in YAML. Path is relative to the GitOps repository of the contract.
+type Manifest struct {
+	Path    string `json:"path"`
+	Kind    string `json:"kind"`
+	Content string `json:"content"`
+}
+
+// RenderManifestsResponse is a response of RenderManifests with ResourceQuota, LimitRange and
+// a kustomization of them.
+type RenderManifestsResponse struct {
+	Status
+	Manifests []*Manifest `json:"manifests"`
+}
+
+// RegionalQuota is a slice of the quota of a contract allocated to a region of a CSP. Region is
+// empty for a slice of a whole CSP account.
+type RegionalQuota struct {
+	Csp    string `json:"csp"`
+	Region string `json:"region,omitempty"`
+	Quota  Quota  `json:"quota"`
+}
+
+// ListRegionalQuotasRequest is a request for the regional quotas of a contract.
+type ListRegionalQuotasRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// ListRegionalQuotasResponse is a response of ListRegionalQuotas. Total is the quota of the contract,
+// Allocated is the sum of the regional quotas, and Unallocated is the rest of Total.
+type ListRegionalQuotasResponse struct {
+	Status
+	RegionalQuotas []*RegionalQuota `json:"regionalQuotas"`
+	Total          Quota            `json:"total"`
+	Allocated      Quota            `json:"allocated"`
+	Unallocated    Quota            `json:"unallocated"`
+}
+
+// AllocateRegionalQuotaRequest is a request to allocate a slice of the quota of a contract to a region,
+// replacing the slice of the same CSP and region if any.
+type AllocateRegionalQuotaRequest struct {
+	ContractId string `json:"contractId"`
+	Csp        string `json:"csp"`
+	Region     string `json:"region"`
+	Quota      Quota  `json:"quota"`
+}
+
+// ReleaseRegionalQuotaRequest is a request to release the slice of a CSP and region.
+type ReleaseRegionalQuotaRequest struct {
+	ContractId string `json:"contractId"`
+	Csp        string `json:"csp" query:"csp"`
+	Region     string `json:"region" query:"region"`
+}
+
+// RebalanceRegionalQuotasRequest is a request to replace all the regional quotas of a contract at once.
+type RebalanceRegionalQuotasRequest struct {
+	ContractId     string           `json:"contractId"`
+	RegionalQuotas []*RegionalQuota `json:"regionalQuotas"`
+}
+
+// UpdateRegionalQuotasResponse is a response of AllocateRegionalQuota, ReleaseRegionalQuota and
+// RebalanceRegionalQuotas with the previous and current regional quotas.
+type UpdateRegionalQuotasResponse struct {
+	Status
+	PrevRegionalQuotas []*RegionalQuota `json:"prevRegionalQuotas"`
+	RegionalQuotas     []*RegionalQuota `json:"regionalQuotas"`
+}
+
+// ContractCsp is a CSP account of tks-info which is attached to a contract.
+type ContractCsp struct {
+	CspId   string `json:"cspId"`
+	CspName string `json:"cspName"`
+	// Role is primary, secondary or dr. A contract has at most one primary account.
+	Role       string    `json:"role"`
+	AttachedAt time.Time `json:"attachedAt"`
+}
+
+// ListCspsRequest is a request for the CSP accounts of a contract.
+type ListCspsRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// ListCspsResponse is a response of ListCsps. The primary account comes first.
+type ListCspsResponse struct {
+	Status
+	Csps []*ContractCsp `json:"csps"`
+}
+
+// AttachCspRequest is a request to attach a CSP account to a contract. An existing CSP info of
+// the contract is attached by CspId, or a new one is created in tks-info with CspName and CspAuth.
+// Attaching an attached account changes its role.
+type AttachCspRequest struct {
+	ContractId string `json:"contractId"`
+	CspId      string `json:"cspId"`
+	CspName    string `json:"cspName"`
+	CspAuth    string `json:"cspAuth"`
+	// Role is the role of the account, which is secondary if empty.
+	Role string `json:"role"`
+}
+
+// AttachCspResponse is a response of AttachCsp.
+type AttachCspResponse struct {
+	Status
+	Csp *ContractCsp `json:"csp,omitempty"`
+}
+
+// DetachCspRequest is a request to detach a CSP account from a contract.
+type DetachCspRequest struct {
+	ContractId string `json:"contractId"`
+	CspId      string `json:"cspId"`
+}
+
+// DetachCspResponse is a response of DetachCsp with the detached account.
+type DetachCspResponse struct {
+	Status
+	Csp *ContractCsp `json:"csp,omitempty"`
+}
+
+// ContractMember is a user who belongs to a contract.
+type ContractMember struct {
+	UserId string `json:"userId"`
+	// Role is owner, admin or viewer.
+	Role    string    `json:"role"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// ListMembersRequest is a request for the members of a contract.
+type ListMembersRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// ListMembersResponse is a response of ListMembers.
+type ListMembersResponse struct {
+	Status
+	Members []*ContractMember `json:"members"`
+}
+
+// AddMemberRequest is a request to add a user to a contract, or to change the role of a member.
+type AddMemberRequest struct {
+	ContractId string `json:"contractId"`
+	UserId     string `json:"userId"`
+	Role       string `json:"role"`
+}
+
+// AddMemberResponse is a response of AddMember.
+type AddMemberResponse struct {
+	Status
+	Member *ContractMember `json:"member,omitempty"`
+}
+
+// RemoveMemberRequest is a request to remove a user from a contract.
+type RemoveMemberRequest struct {
+	ContractId string `json:"contractId"`
+	UserId     string `json:"userId"`
+}
+
+// RemoveMemberResponse is a response of RemoveMember.
+type RemoveMemberResponse struct {
+	Status
+}
+
+// UserContract is a contract which a user belongs to, with the role of the user.
+type UserContract struct {
+	ContractId     string `json:"contractId"`
+	ContractorName string `json:"contractorName"`
+	Role           string `json:"role"`
+}
+
+// ListUserContractsRequest is a request for the contracts which a user belongs to.
+type ListUserContractsRequest struct {
+	UserId string `json:"userId"`
+}
+
+// ListUserContractsResponse is a response of ListUserContracts.
+type ListUserContractsResponse struct {
+	Status
+	Contracts []*UserContract `json:"contracts"`
+}
+
+// CspDrift is a drift between a contract and the CSP info of tks-info.
+type CspDrift struct {
+	// Kind is contract_without_csp, csp_without_contract, unlinked_csp or stale_csp_link.
+	Kind       string `json:"kind"`
+	ContractId string `json:"contractId"`
+	CspId      string `json:"cspId,omitempty"`
+	CspName    string `json:"cspName,omitempty"`
+	// Role is the role of an attached CSP account which has no CSP info.
+	Role     string `json:"role,omitempty"`
+	Repaired bool   `json:"repaired"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ReconcileReport is the result of reconciling contracts with the CSP info of tks-info.
+type ReconcileReport struct {
+	// Policy is report or repair. Drifts are only reported in a dry run.
+	Policy     string      `json:"policy"`
+	DryRun     bool        `json:"dryRun,omitempty"`
+	StartedAt  time.Time   `json:"startedAt"`
+	FinishedAt time.Time   `json:"finishedAt"`
+	Contracts  int         `json:"contracts"`
+	CspInfos   int         `json:"cspInfos"`
+	Drifts     []*CspDrift `json:"drifts"`
+	// Error is set if the reconciliation failed before comparing contracts with tks-info.
+	Error string `json:"error,omitempty"`
+}
+
+// GetReconcileReportRequest is a request for the report of the last reconciliation.
+type GetReconcileReportRequest struct{}
+
+// GetReconcileReportResponse is a response of GetReconcileReport.
+type GetReconcileReportResponse struct {
+	Status
+	Report *ReconcileReport `json:"report,omitempty"`
+}
+
+// ReconcileRequest is a request to reconcile contracts with the CSP info of tks-info now.
+type ReconcileRequest struct {
+	// DryRun reports drifts without repairing them, whatever the policy is.
+	DryRun bool `json:"dryRun"`
+}
+
+// ReconcileResponse is a response of Reconcile.
+type ReconcileResponse struct {
+	Status
+	Report *ReconcileReport `json:"report,omitempty"`
+}
+
+// DoctorIssue is an inconsistency in the database.
+type DoctorIssue struct {
+	// Kind is orphan_rows, missing_quota, duplicate_quota or invalid_contract_id.
+	Kind       string `json:"kind"`
+	ContractId string `json:"contractId"`
+	Table      string `json:"table,omitempty"`
+	// RowIds are the rows which are deleted on repair.
+	RowIds     []string `json:"rowIds,omitempty"`
+	Repairable bool     `json:"repairable"`
+	Repaired   bool     `json:"repaired"`
+}
+
+// DoctorReport is the result of scanning the database for inconsistencies.
+type DoctorReport struct {
+	Contracts int            `json:"contracts"`
+	Issues    []*DoctorIssue `json:"issues"`
+	// Committed is true if repairs are committed.
+	Committed bool `json:"committed"`
+}
+
+// DiagnoseDatabaseRequest is a request to scan the database for inconsistencies.
+type DiagnoseDatabaseRequest struct{}
+
+// DiagnoseDatabaseResponse is a response of DiagnoseDatabase.
+type DiagnoseDatabaseResponse struct {
+	Status
+	Report *DoctorReport `json:"report,omitempty"`
+}
+
+// RepairDatabaseRequest is a request to repair inconsistencies in the database.
+type RepairDatabaseRequest struct {
+	// Kinds of inconsistencies to repair. Every repairable kind is repaired if it is empty.
+	Kinds []string `json:"kinds,omitempty"`
+}
+
+// RepairDatabaseResponse is a response of RepairDatabase.
+type RepairDatabaseResponse struct {
+	Status
+	Report *DoctorReport `json:"report,omitempty"`
+}
+
+// SetDefaultContractRequest is a request to make a contract the default one.
+type SetDefaultContractRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// SetDefaultContractResponse is a response of SetDefaultContract with the previous and current
+// default contracts.
+type SetDefaultContractResponse struct {
+	Status
+	PrevContractId string `json:"prevContractId,omitempty"`
+	ContractId     string `json:"contractId,omitempty"`
+}
+
+// EnsureDefaultContractRequest is a request to make sure that there is a default contract. If there
+// is none, the contract of ContractorName is made the default one, or it is created with
+// AvailableServices, Quota and Description.
+type EnsureDefaultContractRequest struct {
+	ContractorName    string   `json:"contractorName"`
+	AvailableServices []string `json:"availableServices,omitempty"`
+	Quota             Quota    `json:"quota"`
+	Description       string   `json:"description,omitempty"`
+}
+
+// EnsureDefaultContractResponse is a response of EnsureDefaultContract. Created is true if the
+// default contract is created by the request.
+type EnsureDefaultContractResponse struct {
+	Status
+	ContractId string `json:"contractId,omitempty"`
+	Created    bool   `json:"created"`
+}
+
+// ContractProfile is the contractor profile of a contract.
+type ContractProfile struct {
+	ContractorName             string `json:"contractorName"`
+	Description                string `json:"description"`
+	BusinessRegistrationNumber string `json:"businessRegistrationNumber"`
+	BillingAddress             string `json:"billingAddress"`
+	ContactEmail               string `json:"contactEmail"`
+	ContactPhone               string `json:"contactPhone"`
+}
+
+// GetProfileRequest is a request for the contractor profile of a contract.
+type GetProfileRequest struct {
+	ContractId string `json:"contractId"`
+}
+
+// GetProfileResponse is a response of GetProfile.
+type GetProfileResponse struct {
+	Status
+	Profile *ContractProfile `json:"profile,omitempty"`
+}
+
+// UpdateContractRequest is a request to change the contractor profile of a contract. Omitted
+// fields are left unchanged, and the fields except ContractorName are cleared with an empty string.
+type UpdateContractRequest struct {
+	ContractId                 string  `json:"contractId"`
+	ContractorName             *string `json:"contractorName,omitempty"`
+	Description                *string `json:"description,omitempty"`
+	BusinessRegistrationNumber *string `json:"businessRegistrationNumber,omitempty"`
+	BillingAddress             *string `json:"billingAddress,omitempty"`
+	ContactEmail               *string `json:"contactEmail,omitempty"`
+	ContactPhone               *string `json:"contactPhone,omitempty"`
+}
+
+// UpdateContractResponse is a response of UpdateContract with the previous and current profiles.
+type UpdateContractResponse struct {
+	Status
+	PrevProfile *ContractProfile `json:"prevProfile,omitempty"`
+	Profile     *ContractProfile `json:"profile,omitempty"`
+}
+
+// ExportContractsRequest is a request to export contracts as a contract document.
+type ExportContractsRequest struct {
+	// ContractIds are the contracts to export. If empty, all contracts are exported.
+	ContractIds []string `json:"contractIds" query:"contractId"`
+}
+
+// ExportContractsResponse is a response of ExportContracts.
+type ExportContractsResponse struct {
+	Status
+	Document *ContractDocument `json:"document,omitempty"`
+}
+
+// ImportContractsRequest is a request to import a contract document.
+type ImportContractsRequest struct {
+	Document *ContractDocument `json:"document"`
+	// OnConflict is one of skip (default), overwrite and fail, for contracts whose id or
+	// contractor name already exists. fail rolls back the whole import.
+	OnConflict string `json:"onConflict,omitempty"`
+	// DryRun reports what would be imported without changing anything.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// ImportResult is the result of importing a contract record.
+type ImportResult struct {
+	ContractId     string `json:"contractId,omitempty"`
+	ContractorName string `json:"contractorName"`
+	// Action is one of created, overwritten, skipped and failed.
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport is the result of an import, per record.
+type ImportReport struct {
+	DryRun bool `json:"dryRun"`
+	// Committed is false for a dry run, or if the import is rolled back.
+	Committed   bool            `json:"committed"`
+	Created     int             `json:"created"`
+	Overwritten int             `json:"overwritten"`
+	Skipped     int             `json:"skipped"`
+	Failed      int             `json:"failed"`
+	Results     []*ImportResult `json:"results"`
+}
+
+// ImportContractsResponse is a response of ImportContracts. The report is returned
+// even if some records failed.
+type ImportContractsResponse struct {
+	Status
+	Report *ImportReport `json:"report,omitempty"`
+}
+
+// Marshal encodes v as JSON. Protocol buffer messages are encoded with protojson.
+func Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return protojson.Marshal(m)
+	}
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON into v. Protocol buffer messages are decoded with protojson.
+// Unknown fields are rejected unless discardUnknown is true.
+func Unmarshal(b []byte, v interface{}, discardUnknown bool) error {
+	if m, ok := v.(proto.Message); ok {
+		return protojson.UnmarshalOptions{DiscardUnknown: discardUnknown}.Unmarshal(b, m)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if !discardUnknown {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
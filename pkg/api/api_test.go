@@ -0,0 +1,112 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinfradev/tks-contract/pkg/api"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+func TestStatusJSON(t *testing.T) {
+	b, err := api.Marshal(&api.DeleteContractResponse{})
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(b))
+
+	b, err = api.Marshal(&api.DeleteContractResponse{
+		Status: api.NewStatus(pb.Code_NOT_FOUND, errors.New("not found")),
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"code": "NOT_FOUND", "error": {"msg": "not found"}}`, string(b))
+
+	var res api.DeleteContractResponse
+	require.NoError(t, api.Unmarshal(b, &res, false))
+	require.Equal(t, pb.Code_NOT_FOUND, res.GetCode())
+	require.Equal(t, "not found", res.GetMsg())
+}
+
+func TestUnmarshal(t *testing.T) {
+	var res api.DeleteContractResponse
+	require.NoError(t, api.Unmarshal([]byte(`{"code": 7}`), &res, false))
+	require.Equal(t, pb.Code_PERMISSION_DENIED, res.GetCode())
+
+	require.Error(t, api.Unmarshal([]byte(`{"code": "NO_SUCH_CODE"}`), &res, false))
+	require.Error(t, api.Unmarshal([]byte(`{"unknown": 1}`), &res, false))
+	require.NoError(t, api.Unmarshal([]byte(`{"unknown": 1}`), &res, true))
+}
+
+func TestDecodeDocument(t *testing.T) {
+	yamlDoc := `
+version: tks-contract/v1
+contracts:
+  - contractId: P0123abcd
+    contractorName: acme
+    availableServices: [lma]
+    quota:
+      cpu: 32
+      memory: 1Ti
+    members:
+      - userId: 5cd1f0e6-9c5e-4c2b-a8a4-2b4c39b0c7f1
+        role: owner
+    createdAt: 2022-06-01T00:00:00Z
+`
+	doc, err := api.DecodeDocument([]byte(yamlDoc))
+	require.NoError(t, err)
+	require.Len(t, doc.Contracts, 1)
+	require.Equal(t, "acme", doc.Contracts[0].ContractorName)
+	require.Equal(t, int64(32), doc.Contracts[0].Quota.Cpu)
+	require.Equal(t, int64(1024), doc.Contracts[0].Quota.Memory)
+	require.Equal(t, "owner", doc.Contracts[0].Members[0].Role)
+	require.Equal(t, 2022, doc.Contracts[0].CreatedAt.Year())
+
+	doc, err = api.DecodeDocument([]byte(`{"version": "tks-contract/v1", "contracts": [{"contractorName": "acme"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, "acme", doc.Contracts[0].ContractorName)
+
+	_, err = api.DecodeDocument([]byte(`{"version": "tks-contract/v0", "contracts": []}`))
+	require.Error(t, err)
+	_, err = api.DecodeDocument([]byte("version: tks-contract/v1\nunknown: 1\n"))
+	require.Error(t, err)
+}
+
+func TestQuotaQuantities(t *testing.T) {
+	var q api.Quota
+	require.NoError(t, json.Unmarshal([]byte(`{"cpu": "4000m", "memory": "16Gi", "block": 10, "fsSsd": "2Ti"}`), &q))
+	require.Equal(t, api.Quota{Cpu: 4000, Memory: 16, Block: 10, FsSsd: 2048}, q)
+	require.Error(t, json.Unmarshal([]byte(`{"memory": "512Mi"}`), &q))
+	require.Error(t, json.Unmarshal([]byte(`{"gpu": 1}`), &q))
+
+	b, err := api.ParseQuotaQuantities([]byte(`{"contractId": "P0123abcd", "quota": {"cpu": 4, "block_ssd": "1Ti"}}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"contractId": "P0123abcd", "quota": {"cpu": 4, "block_ssd": 1024}}`, string(b))
+	b, err = api.ParseQuotaQuantities([]byte(`{"quota": {"cpu": "500m"}}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"quota": {"cpu": 500}}`, string(b))
+	_, err = api.ParseQuotaQuantities([]byte(`{"quota": {"cpu": "0.5m"}}`))
+	require.Error(t, err)
+}
+
+func TestWriteInvoicesCSV(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	var buf bytes.Buffer
+	require.NoError(t, api.WriteInvoicesCSV(&buf,
+		&api.Invoice{
+			Number: "INV-202606-P0123abcd", Kind: "invoice", ContractId: "P0123abcd", Period: "2026-06", Currency: "KRW",
+			Lines: []*api.InvoiceLine{
+				{Kind: "resource", Item: "cpu", PricePlan: "standard", Quantity: 8, UnitPrice: "1000.5", From: start, To: end, Amount: "8004.00"},
+			},
+			Total: "8004.00",
+		},
+		&api.Invoice{Number: "INV-202606-P0456abcd", Kind: "invoice", ContractId: "P0456abcd", Period: "2026-06", Total: "0.00"},
+	))
+	require.Equal(t, `number,kind,contract_id,period,currency,line_kind,item,price_plan,quantity,unit_price,from,to,amount,total
+INV-202606-P0123abcd,invoice,P0123abcd,2026-06,KRW,resource,cpu,standard,8,1000.5,2026-06-01T00:00:00Z,2026-07-01T00:00:00Z,8004.00,8004.00
+INV-202606-P0456abcd,invoice,P0456abcd,2026-06,,,,,,,,,,0.00
+`, buf.String())
+}
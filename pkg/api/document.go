@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentVersion is the version of contract documents written by ExportContracts.
+const DocumentVersion = "tks-contract/v1"
+
+// ContractDocument is a versioned document of contracts for export and import.
+type ContractDocument struct {
+	Version    string            `json:"version"`
+	ExportedAt time.Time         `json:"exportedAt"`
+	Contracts  []*ContractRecord `json:"contracts"`
+}
+
+// ContractRecord is a contract with its quota and members in a contract document.
+type ContractRecord struct {
+	// ContractId is kept on import. If it is empty, a new id is generated.
+	ContractId        string   `json:"contractId,omitempty"`
+	ContractorName    string   `json:"contractorName"`
+	AvailableServices []string `json:"availableServices"`
+	Description       string   `json:"description,omitempty"`
+	Creator           string   `json:"creator,omitempty"`
+	// BusinessRegistrationNumber, BillingAddress, ContactEmail and ContactPhone are the contractor
+	// profile, which replaces that of an overwritten contract.
+	BusinessRegistrationNumber string `json:"businessRegistrationNumber,omitempty"`
+	BillingAddress             string `json:"billingAddress,omitempty"`
+	ContactEmail               string `json:"contactEmail,omitempty"`
+	ContactPhone               string `json:"contactPhone,omitempty"`
+	// IsDefault makes the contract the default one in place of the current default on import.
+	// An overwritten contract is kept as the default if it is omitted.
+	IsDefault bool `json:"isDefault,omitempty"`
+	// ParentId is the parent contract, which must exist or precede this record in the document.
+	// The quotas of the children of a contract must fit in its quota.
+	ParentId string `json:"parentId,omitempty"`
+	Quota    Quota  `json:"quota"`
+	// Labels and Annotations replace those of an overwritten contract.
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Members replace the members of an overwritten contract. If omitted, the members are kept.
+	Members []*Member `json:"members,omitempty"`
+	// EffectiveFrom and ExpiresAt are the term of the contract. It does not expire if ExpiresAt is omitted.
+	EffectiveFrom *time.Time `json:"effectiveFrom,omitempty"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	// State is one of active (default), read_only and suspended.
+	State string `json:"state,omitempty"`
+	// PricePlan is the price plan of the contract, which is standard if omitted.
+	PricePlan string     `json:"pricePlan,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// Quota is a resource quota in a contract document. Cpu is in millicores, and the other
+// dimensions are in GiB.
+type Quota struct {
+	Cpu      int64 `json:"cpu"`
+	Memory   int64 `json:"memory"`
+	Block    int64 `json:"block"`
+	BlockSsd int64 `json:"blockSsd"`
+	Fs       int64 `json:"fs"`
+	FsSsd    int64 `json:"fsSsd"`
+}
+
+// Member is a member of a contract in a contract document.
+type Member struct {
+	UserId string `json:"userId"`
+	Role   string `json:"role"`
+}
+
+// DecodeDocument decodes a contract document in JSON or YAML and checks its version.
+func DecodeDocument(b []byte) (*ContractDocument, error) {
+	b = bytes.TrimSpace(b)
+	if !bytes.HasPrefix(b, []byte("{")) {
+		var v interface{}
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, fmt.Errorf("invalid document : %s", err)
+		}
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("invalid document : %s", err)
+		}
+	}
+
+	var doc ContractDocument
+	if err := Unmarshal(b, &doc, false); err != nil {
+		return nil, fmt.Errorf("invalid document : %s", err)
+	}
+	if doc.Version != DocumentVersion {
+		return nil, fmt.Errorf("unsupported document version %q, expected %q", doc.Version, DocumentVersion)
+	}
+	return &doc, nil
+}
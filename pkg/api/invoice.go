@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formats of invoices.
+const (
+	InvoiceFormatJSON = "json"
+	InvoiceFormatCSV  = "csv"
+)
+
+// Invoice is an invoice of a contract for a billing period, or a credit note which corrects an invoice.
+type Invoice struct {
+	InvoiceId  string `json:"invoiceId"`
+	Number     string `json:"number"`
+	ContractId string `json:"contractId"`
+	// Kind is invoice or credit_note.
+	Kind string `json:"kind"`
+	// Period is the billing period in YYYY-MM, which is a calendar month in UTC.
+	Period      string         `json:"period"`
+	PeriodStart time.Time      `json:"periodStart"`
+	PeriodEnd   time.Time      `json:"periodEnd"`
+	Currency    string         `json:"currency"`
+	Lines       []*InvoiceLine `json:"lines"`
+	// Total is the sum of the amounts of lines, which is negative for credit notes.
+	Total string `json:"total"`
+	// CorrectedInvoiceId is the invoice which a credit note corrects.
+	CorrectedInvoiceId string    `json:"correctedInvoiceId,omitempty"`
+	Reason             string    `json:"reason,omitempty"`
+	IssuedAt           time.Time `json:"issuedAt"`
+}
+
+// InvoiceLine is a line item of an invoice.
+type InvoiceLine struct {
+	// Kind is resource for a quota dimension, service for an available service, or credit for a credit note.
+	Kind      string    `json:"kind"`
+	Item      string    `json:"item"`
+	PricePlan string    `json:"pricePlan,omitempty"`
+	Quantity  int64     `json:"quantity"`
+	UnitPrice string    `json:"unitPrice"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Amount    string    `json:"amount"`
+}
+
+// ListInvoicesRequest is a request for invoices and credit notes.
+type ListInvoicesRequest struct {
+	// ContractId is the contract of invoices. If empty, invoices of every contract are listed.
+	ContractId string `json:"contractId" query:"contractId"`
+	// Period is a billing period in YYYY-MM. If empty, invoices of every period are listed.
+	Period string `json:"period" query:"period"`
+	// Format is json (default) or csv. The invoices are also rendered in Csv if it is csv.
+	Format string `json:"format" query:"format"`
+}
+
+// ListInvoicesResponse is a response of ListInvoices. Invoices are ordered by period and issue time.
+type ListInvoicesResponse struct {
+	Status
+	Invoices []*Invoice `json:"invoices"`
+	Csv      string     `json:"csv,omitempty"`
+}
+
+// GetInvoiceRequest is a request for an invoice or a credit note.
+type GetInvoiceRequest struct {
+	InvoiceId string `json:"invoiceId"`
+	// Format is json (default) or csv. The invoice is also rendered in Csv if it is csv.
+	Format string `json:"format" query:"format"`
+}
+
+// GetInvoiceResponse is a response of GetInvoice.
+type GetInvoiceResponse struct {
+	Status
+	Invoice *Invoice `json:"invoice,omitempty"`
+	Csv     string   `json:"csv,omitempty"`
+}
+
+// ClosePeriodRequest is a request to issue the invoices of a billing period which is over.
+type ClosePeriodRequest struct {
+	// Period is a billing period in YYYY-MM.
+	Period string `json:"period"`
+	// ContractId is the contract to close the period for. If empty, the period is closed for every contract.
+	ContractId string `json:"contractId,omitempty"`
+}
+
+// ClosePeriodResponse is a response of ClosePeriod. Invoices which were already issued are not
+// issued again, nor included in Issued.
+type ClosePeriodResponse struct {
+	Status
+	Issued []*Invoice `json:"issued"`
+	// Failed are the errors of contracts whose invoices could not be issued, keyed by contract id.
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// IssueCreditNoteRequest is a request to correct an invoice with a credit note.
+type IssueCreditNoteRequest struct {
+	InvoiceId string `json:"invoiceId"`
+	// Amount is a positive decimal to credit. If empty, the remaining balance of the invoice is credited.
+	Amount string `json:"amount,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// IssueCreditNoteResponse is a response of IssueCreditNote.
+type IssueCreditNoteResponse struct {
+	Status
+	CreditNote *Invoice `json:"creditNote,omitempty"`
+}
+
+var invoiceCSVHeader = []string{
+	"number", "kind", "contract_id", "period", "currency", "line_kind", "item", "price_plan",
+	"quantity", "unit_price", "from", "to", "amount", "total",
+}
+
+// WriteInvoicesCSV writes invoices as CSV with a row for each line. An invoice without lines
+// is written in a row with empty line columns.
+func WriteInvoicesCSV(w io.Writer, invoices ...*Invoice) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(invoiceCSVHeader); err != nil {
+		return err
+	}
+	for _, inv := range invoices {
+		head := []string{inv.Number, inv.Kind, inv.ContractId, inv.Period, inv.Currency}
+		if len(inv.Lines) == 0 {
+			row := append(append([]string{}, head...), "", "", "", "", "", "", "", "", inv.Total)
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		for _, l := range inv.Lines {
+			row := append(append([]string{}, head...), l.Kind, l.Item, l.PricePlan, strconv.FormatInt(l.Quantity, 10),
+				l.UnitPrice, l.From.Format(time.RFC3339), l.To.Format(time.RFC3339), l.Amount, inv.Total)
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/openinfradev/tks-contract/pkg/contract/quantity"
+)
+
+// quotaFields maps the JSON fields of quotas, in both protojson and proto names, to quota dimensions.
+var quotaFields = map[string]string{
+	"cpu":       quantity.Cpu,
+	"memory":    quantity.Memory,
+	"block":     quantity.Block,
+	"blockSsd":  quantity.BlockSsd,
+	"block_ssd": quantity.BlockSsd,
+	"fs":        quantity.Fs,
+	"fsSsd":     quantity.FsSsd,
+	"fs_ssd":    quantity.FsSsd,
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Each dimension is either an integer in the unit of
+// the dimension, or a quantity string such as "16Gi".
+func (q *Quota) UnmarshalJSON(b []byte) error {
+	b, err := parseQuantities(b)
+	if err != nil {
+		return err
+	}
+	type quota Quota
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*quota)(q))
+}
+
+// ParseQuotaQuantities converts the quantity strings in the quota field of a JSON request, such as
+// CreateContractRequest or UpdateQuotaRequest, to integers in the units of their dimensions, so
+// that the request can be decoded with protojson.
+func ParseQuotaQuantities(b []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		// leave it to the decoder to report
+		return b, nil
+	}
+	quota, ok := fields["quota"]
+	if !ok {
+		return b, nil
+	}
+	quota, err := parseQuantities(quota)
+	if err != nil {
+		return nil, err
+	}
+	fields["quota"] = quota
+	return json.Marshal(fields)
+}
+
+// parseQuantities converts the quantity strings of a JSON quota object to integers.
+func parseQuantities(b []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil || fields == nil {
+		return b, nil
+	}
+	for name, value := range fields {
+		dimension, ok := quotaFields[name]
+		if !ok {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			continue
+		}
+		v, err := quantity.Parse(dimension, s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quota : %w", err)
+		}
+		fields[name] = json.RawMessage(strconv.FormatInt(v, 10))
+	}
+	return json.Marshal(fields)
+}
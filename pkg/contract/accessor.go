@@ -1,12 +1,15 @@
 package contract
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/openinfradev/tks-common/pkg/log"
 	model "github.com/openinfradev/tks-contract/pkg/contract/model"
@@ -16,6 +19,8 @@ import (
 // Accessor is an accessor to in-memory contracts.
 type Accessor struct {
 	db *gorm.DB
+	// cache is nil unless the accessor is returned by WithCache.
+	cache *contractCache
 }
 
 // New returns new accessor's ptr.
@@ -25,29 +30,24 @@ func New(db *gorm.DB) *Accessor {
 	}
 }
 
-// GetContract returns a contract from database.
-func (x *Accessor) GetContract(id string) (*pb.Contract, error) {
-	var contract model.Contract
-	res := x.db.First(&contract, "id = ?", id)
-	if res.RowsAffected == 0 || res.Error != nil {
-		return &pb.Contract{}, fmt.Errorf("Not found contract for %s", id)
-	}
-	quota, err := x.GetResourceQuota(contract.ID)
+// GetContract returns a contract from database, or from the cache if the accessor has one.
+func (x *Accessor) GetContract(ctx context.Context, id string) (*pb.Contract, error) {
+	contract, quota, err := x.findContractWithQuota(ctx, id)
 	if err != nil {
 		return &pb.Contract{}, err
 	}
-	resContract := reflectToPbContract(contract, &quota)
+	pbQuota := reflectToPbQuota(quota)
+	resContract := reflectToPbContract(contract, &pbQuota)
 	return &resContract, nil
 }
 
-// GetDefaultContract returns a contract from database.
-func (x *Accessor) GetDefaultContract() (*pb.Contract, error) {
-	var contract model.Contract
-	res := x.db.First(&contract, "contractor_name = 'default'")
-	if res.RowsAffected == 0 || res.Error != nil {
-		return &pb.Contract{}, fmt.Errorf("Not found default contract")
+// GetDefaultContract returns the default contract from database.
+func (x *Accessor) GetDefaultContract(ctx context.Context) (*pb.Contract, error) {
+	contract, err := findDefaultContract(x.db.WithContext(ctx))
+	if err != nil {
+		return &pb.Contract{}, err
 	}
-	quota, err := x.GetResourceQuota(contract.ID)
+	quota, err := x.GetResourceQuota(ctx, contract.ID)
 	if err != nil {
 		return &pb.Contract{}, err
 	}
@@ -55,31 +55,33 @@ func (x *Accessor) GetDefaultContract() (*pb.Contract, error) {
 	return &resContract, nil
 }
 
-// getContract returns a resource quota from database.
-func (x *Accessor) GetResourceQuota(contractID string) (pb.ContractQuota, error) {
-	var quota model.ResourceQuota
-	res := x.db.Limit(1).Find(&quota, "contract_id = ?", contractID)
-	if res.RowsAffected == 0 || res.Error != nil {
-		return pb.ContractQuota{}, fmt.Errorf("Not found quota for contract id %s", contractID)
+// GetResourceQuota returns a resource quota from database, or from the cache if the accessor has one.
+func (x *Accessor) GetResourceQuota(ctx context.Context, contractID string) (pb.ContractQuota, error) {
+	_, quota, err := x.findContractWithQuota(ctx, contractID)
+	if err != nil {
+		return pb.ContractQuota{}, err
 	}
-
 	return reflectToPbQuota(quota), nil
 }
 
-// List returns a list of contracts from database.
-func (x *Accessor) List(offset, limit int) ([]*pb.Contract, error) {
+// List returns a list of contracts from database. If selector is not empty, only the contracts
+// whose labels match it are returned.
+func (x *Accessor) List(ctx context.Context, offset, limit int, selector Selector) ([]*pb.Contract, error) {
 	var (
 		contracts       []model.Contract
 		quota           model.ResourceQuota
 		resultContracts []*pb.Contract
 	)
-	res := x.db.Offset(offset).Limit(limit).Find(&contracts)
+	res := selector.apply(x.db.WithContext(ctx)).Offset(offset).Limit(limit).Find(&contracts)
 	if res.Error != nil {
 		return nil, res.Error
 	}
 	for _, contract := range contracts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		quota = model.ResourceQuota{}
-		res = x.db.Limit(1).Find(&quota, "contract_id = ?", contract.ID)
+		res = x.db.WithContext(ctx).Limit(1).Find(&quota, "contract_id = ?", contract.ID)
 		if res.RowsAffected == 0 || res.Error != nil {
 			return nil, fmt.Errorf("Not found quota for contract id %s", contract.ID)
 		}
@@ -91,101 +93,234 @@ func (x *Accessor) List(offset, limit int) ([]*pb.Contract, error) {
 }
 
 // Create creates a new contract in database.
-func (x *Accessor) Create(name string, availableServices []string, quota *pb.ContractQuota, creator uuid.UUID, description string) (string, error) {
+func (x *Accessor) Create(ctx context.Context, name string, availableServices []string, quota *pb.ContractQuota, creator uuid.UUID, description string) (string, error) {
 	pqStrArr := pq.StringArray{}
 
 	for _, svc := range availableServices {
 		pqStrArr = append(pqStrArr, svc)
 	}
 
-	contract := model.Contract{ContractorName: name, AvailableServices: pqStrArr, Creator: creator, Description: description}
-	err := x.db.Transaction(func(tx *gorm.DB) error {
-		res := tx.Create(&contract)
-		if res.Error != nil {
-			return res.Error
-		}
-		res = tx.Create(&model.ResourceQuota{Cpu: quota.Cpu, Memory: quota.Memory,
-			Block: quota.Block, BlockSsd: quota.BlockSsd, Fs: quota.Fs, FsSsd: quota.FsSsd, ContractID: contract.ID})
-		if res.Error != nil {
-			return res.Error
-		}
-		log.Info("sucessfully created contract ID ", contract.ID)
-		return nil
+	if actorFromContext(ctx) == uuid.Nil {
+		ctx = WithActor(ctx, creator)
+	}
+	now := time.Now()
+	contract := model.Contract{ContractorName: name, AvailableServices: pqStrArr, Creator: creator, Description: description,
+		EffectiveFrom: &now}
+	err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createContract(tx, &contract, quota, creator)
 	})
 
 	return contract.ID, err
 }
 
-// Delete contract
-func (x *Accessor) Delete(contractId string) error {
-	err := x.db.Transaction(func(tx *gorm.DB) error {
-		res := tx.Delete(&model.ResourceQuota{}, "contract_id = ?", contractId)
-		log.Info("resource quota is deleted! contractId : ", contractId)
-		if res.Error != nil {
-			return fmt.Errorf("could not delete resource quota for contractId %s", contractId)
+// createContract creates a contract with its quota, and its creator as the owner if any.
+func createContract(tx *gorm.DB, contract *model.Contract, quota *pb.ContractQuota, creator uuid.UUID) error {
+	res := tx.Create(contract)
+	if res.Error != nil {
+		return res.Error
+	}
+	res = tx.Create(&model.ResourceQuota{Cpu: quota.Cpu, Memory: quota.Memory,
+		Block: quota.Block, BlockSsd: quota.BlockSsd, Fs: quota.Fs, FsSsd: quota.FsSsd, ContractID: contract.ID})
+	if res.Error != nil {
+		return res.Error
+	}
+	if creator != uuid.Nil {
+		if err := addMember(tx, contract.ID, creator, RoleOwner); err != nil {
+			return err
 		}
+	}
+	if err := recordHistory(tx, contract.ID, HistoryCreated, nil, newHistoryContract(*contract, quota)); err != nil {
+		return err
+	}
+	log.Info("sucessfully created contract ID ", contract.ID)
+	return nil
+}
 
-		res = tx.Delete(&model.Contract{}, "id = ?", contractId)
-		log.Info("contract is deleted! contractId : ", contractId)
-		if res.Error != nil {
-			return fmt.Errorf("could not delete contract for contractId %s", contractId)
-		}
-		return nil
+// Delete contract
+func (x *Accessor) Delete(ctx context.Context, contractId string) error {
+	return x.DeleteWith(ctx, contractId, nil)
+}
+
+// DeleteWith deletes a contract, and calls cleanup with the CSP accounts which were attached to
+// the contract before the deletion is committed. The deletion is rolled back if cleanup fails.
+func (x *Accessor) DeleteWith(ctx context.Context, contractId string, cleanup func(csps []model.ContractCsp) error) error {
+	err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteContract(tx, contractId, cleanup)
 	})
+	if err == nil {
+		x.invalidate(ctx, contractId)
+	}
 
 	return err
 }
 
-// UpdateResourceQuota updates resource quota.
-func (x *Accessor) UpdateResourceQuota(contractID string, quota *pb.ContractQuota) (
-	p *pb.ContractQuota, c *pb.ContractQuota, err error) {
-	prev, err := x.GetResourceQuota(contractID)
+// DeleteUnused deletes a contract which was left behind by a failed creation, which is a contract
+// whose only history record is its creation, so that it has never had a CSP account, and has not
+// been imported or changed. The default contract is never deleted. Deleted is false if the contract
+// is kept. Cleanup is called as in DeleteWith.
+func (x *Accessor) DeleteUnused(ctx context.Context, contractId string, cleanup func(csps []model.ContractCsp) error) (deleted bool, err error) {
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		contract, err := findContract(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractId)
+		if err != nil {
+			return err
+		}
+		if contract.IsDefault || contract.ContractorName == LegacyDefaultContractorName {
+			return nil
+		}
+		var actions []string
+		if res := tx.Model(&model.ContractHistory{}).Where("contract_id = ?", contractId).Pluck("action", &actions); res.Error != nil {
+			return res.Error
+		}
+		if len(actions) != 1 || actions[0] != HistoryCreated {
+			return nil
+		}
+		var links int64
+		if res := tx.Model(&model.ContractCsp{}).Where("contract_id = ?", contractId).Count(&links); res.Error != nil {
+			return res.Error
+		}
+		if links > 0 {
+			return nil
+		}
+		deleted = true
+		return deleteContract(tx, contractId, cleanup)
+	})
+	if err != nil {
+		return false, err
+	}
+	if deleted {
+		x.invalidate(ctx, contractId)
+	}
+	return deleted, nil
+}
+
+func deleteContract(tx *gorm.DB, contractId string, cleanup func(csps []model.ContractCsp) error) error {
+	var (
+		contract model.Contract
+		quota    model.ResourceQuota
+	)
+	n, err := countChildren(tx, contractId)
 	if err != nil {
-		return &pb.ContractQuota{}, &pb.ContractQuota{}, fmt.Errorf("not found resource quota for contract ID %s", contractID)
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%w : could not delete contract %s which has %d sub-contracts", ErrHasSubContracts, contractId, n)
+	}
+	if res := tx.Limit(1).Find(&contract, "id = ?", contractId); res.Error == nil && res.RowsAffected > 0 {
+		tx.Limit(1).Find(&quota, "contract_id = ?", contractId)
+		pbQuota := reflectToPbQuota(quota)
+		if err := recordHistory(tx, contractId, HistoryDeleted, newHistoryContract(contract, &pbQuota), nil); err != nil {
+			return err
+		}
 	}
 
-	values := map[string]interface{}{
-		"cpu":       prev.Cpu,
-		"memory":    prev.Memory,
-		"block":     prev.Block,
-		"block_ssd": prev.BlockSsd,
-		"fs":        prev.Fs,
-		"fs_ssd":    prev.FsSsd,
+	res := tx.Delete(&model.RegionalQuota{}, "contract_id = ?", contractId)
+	if res.Error != nil {
+		return fmt.Errorf("could not delete regional quotas for contractId %s", contractId)
 	}
 
-	if quota.Cpu != 0 {
-		values["cpu"] = quota.Cpu
+	csps, err := findCsps(tx, contractId)
+	if err != nil {
+		return fmt.Errorf("could not find CSP accounts for contractId %s", contractId)
 	}
-	if quota.Memory != 0 {
-		values["memory"] = quota.Memory
+	res = tx.Delete(&model.ContractCsp{}, "contract_id = ?", contractId)
+	if res.Error != nil {
+		return fmt.Errorf("could not delete CSP accounts for contractId %s", contractId)
 	}
-	if quota.Block != 0 {
-		values["block"] = quota.Block
+
+	res = tx.Delete(&model.ResourceQuota{}, "contract_id = ?", contractId)
+	log.Info("resource quota is deleted! contractId : ", contractId)
+	if res.Error != nil {
+		return fmt.Errorf("could not delete resource quota for contractId %s", contractId)
 	}
-	if quota.Block != 0 {
-		values["block_ssd"] = quota.BlockSsd
+
+	res = tx.Delete(&model.ContractMember{}, "contract_id = ?", contractId)
+	if res.Error != nil {
+		return fmt.Errorf("could not delete members for contractId %s", contractId)
 	}
-	if quota.Fs != 0 {
-		values["fs"] = quota.Fs
+
+	res = tx.Delete(&model.Contract{}, "id = ?", contractId)
+	log.Info("contract is deleted! contractId : ", contractId)
+	if res.Error != nil {
+		return fmt.Errorf("could not delete contract for contractId %s", contractId)
 	}
-	if quota.FsSsd != 0 {
-		values["fs_ssd"] = quota.FsSsd
+	if cleanup != nil {
+		return cleanup(csps)
 	}
+	return nil
+}
 
-	res := x.db.Model(&model.ResourceQuota{}).
-		Where("contract_id = ?", contractID).
-		Updates(values)
+// UpdateResourceQuota updates resource quota.
+func (x *Accessor) UpdateResourceQuota(ctx context.Context, contractID string, quota *pb.ContractQuota) (
+	p *pb.ContractQuota, c *pb.ContractQuota, err error) {
+	var prev pb.ContractQuota
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockQuotaEnvelope(tx, contractID); err != nil {
+			return err
+		}
+		// the previous quota is read under the locks, so that no concurrent update is lost
+		prevQuota, err := findQuota(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractID)
+		if err != nil {
+			return fmt.Errorf("not found resource quota for contract ID %s", contractID)
+		}
+		prev = reflectToPbQuota(prevQuota)
 
-	if res.Error != nil || res.RowsAffected == 0 {
-		return nil, nil, fmt.Errorf("nothing updated in resource_quota for contract id %s", contractID)
+		values := map[string]interface{}{
+			"cpu":       prev.Cpu,
+			"memory":    prev.Memory,
+			"block":     prev.Block,
+			"block_ssd": prev.BlockSsd,
+			"fs":        prev.Fs,
+			"fs_ssd":    prev.FsSsd,
+		}
+
+		if quota.Cpu != 0 {
+			values["cpu"] = quota.Cpu
+		}
+		if quota.Memory != 0 {
+			values["memory"] = quota.Memory
+		}
+		if quota.Block != 0 {
+			values["block"] = quota.Block
+		}
+		if quota.BlockSsd != 0 {
+			values["block_ssd"] = quota.BlockSsd
+		}
+		if quota.Fs != 0 {
+			values["fs"] = quota.Fs
+		}
+		if quota.FsSsd != 0 {
+			values["fs_ssd"] = quota.FsSsd
+		}
+
+		res := tx.Model(&model.ResourceQuota{}).
+			Where("contract_id = ?", contractID).
+			Updates(values)
+
+		if res.Error != nil || res.RowsAffected == 0 {
+			return fmt.Errorf("nothing updated in resource_quota for contract id %s", contractID)
+		}
+		if err := checkQuotaEnvelope(tx, contractID); err != nil {
+			return err
+		}
+
+		var updated model.ResourceQuota
+		if res := tx.Limit(1).Find(&updated, "contract_id = ?", contractID); res.Error != nil {
+			return res.Error
+		}
+		updatedQuota := reflectToPbQuota(updated)
+		return recordHistory(tx, contractID, HistoryQuotaUpdated, newHistoryQuota(&prev), newHistoryQuota(&updatedQuota))
+	})
+	if err != nil {
+		return nil, nil, err
 	}
+	x.invalidate(ctx, contractID)
 
-	curr, err := x.GetResourceQuota(contractID)
+	curr, err := x.GetResourceQuota(ctx, contractID)
 	return &prev, &curr, err
 }
 
 // UpdateAvailableServices updates available service list and resource quota.
-func (x *Accessor) UpdateAvailableServices(id string, availableServices []string) (
+func (x *Accessor) UpdateAvailableServices(ctx context.Context, id string, availableServices []string) (
 	prev []string, curr []string, err error) {
 	pqStrArr := pq.StringArray{}
 
@@ -195,15 +330,23 @@ func (x *Accessor) UpdateAvailableServices(id string, availableServices []string
 	var (
 		contract model.Contract
 	)
-	if res := x.db.First(&contract, "id = ?", id); res.RowsAffected == 0 || res.Error != nil {
+	if res := x.db.WithContext(ctx).First(&contract, "id = ?", id); res.RowsAffected == 0 || res.Error != nil {
 		return nil, nil, fmt.Errorf("could not find contract for contract id %s", id)
 	}
 	prev = contract.AvailableServices
-	if res := x.db.Model(&model.Contract{}).Where("id = ?", id).Update("available_services", pqStrArr); res.RowsAffected == 0 || res.Error != nil {
-		return prev, curr, fmt.Errorf("RowsAffected is 0 for contract id %s", id)
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if res := tx.Model(&model.Contract{}).Where("id = ?", id).Update("available_services", pqStrArr); res.RowsAffected == 0 || res.Error != nil {
+			return fmt.Errorf("RowsAffected is 0 for contract id %s", id)
+		}
+		return recordHistory(tx, id, HistoryServicesUpdated,
+			historyServices{AvailableServices: prev}, historyServices{AvailableServices: pqStrArr})
+	})
+	if err != nil {
+		return prev, curr, err
 	}
+	x.invalidate(ctx, id)
 
-	if res := x.db.First(&contract, "id = ?", id); res.RowsAffected == 0 || res.Error != nil {
+	if res := x.db.WithContext(ctx).First(&contract, "id = ?", id); res.RowsAffected == 0 || res.Error != nil {
 		return nil, nil, fmt.Errorf("could not find contract for contract id %s", id)
 	}
 	curr = contract.AvailableServices
@@ -1,6 +1,7 @@
 package contract_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -31,6 +32,15 @@ func init() {
 }
 
 func getAccessor() (*contract.Accessor, error) {
+	db, err := getDB()
+	if err != nil {
+		return nil, err
+	}
+	return contract.New(db), nil
+}
+
+// getDB returns a migrated database, for tests which need to write rows directly.
+func getDB() (*gorm.DB, error) {
 	dsn := fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Seoul",
 		testDBHost, "postgres", "password", "tks", testDBPort)
@@ -47,8 +57,26 @@ func getAccessor() (*contract.Accessor, error) {
 	if err := db.AutoMigrate(&model.ResourceQuota{}); err != nil {
 		return nil, err
 	}
+	if err := db.AutoMigrate(&model.ContractMember{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.ContractHistory{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.PricePlan{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.Invoice{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.RegionalQuota{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&model.ContractCsp{}); err != nil {
+		return nil, err
+	}
 
-	return contract.New(db), nil
+	return db, nil
 }
 
 func TestMain(m *testing.M) {
@@ -82,7 +110,7 @@ func TestCreateContract(t *testing.T) {
 		Fs:     12800000,
 	}
 	contractName := "default"
-	contractId, err = accessor.Create(contractName, []string{"lma"}, &quota, uuid.New(), "")
+	contractId, err = accessor.Create(context.Background(), contractName, []string{"lma"}, &quota, uuid.New(), "")
 	if err != nil {
 		t.Errorf("an error was unexpected while creating new contract: %s", err)
 	}
@@ -94,7 +122,7 @@ func TestUpdateAvailableServices(t *testing.T) {
 	if err != nil {
 		t.Errorf("an error was unexpected while initilizing database %s", err)
 	}
-	_, _, err = accessor.UpdateAvailableServices(contractId, []string{"lma", "sm"})
+	_, _, err = accessor.UpdateAvailableServices(context.Background(), contractId, []string{"lma", "sm"})
 	if err != nil {
 		t.Errorf("an error was unexpected while querying contract data %s", err)
 	}
@@ -109,18 +137,34 @@ func TestUpdateResourceQuota(t *testing.T) {
 		Cpu:    128,
 		Memory: 1280000,
 	}
-	_, _, err = accessor.UpdateResourceQuota(contractId, &quota)
+	_, _, err = accessor.UpdateResourceQuota(context.Background(), contractId, &quota)
 
 	if err != nil {
 		t.Errorf("an error was unexpected while querying contract data %s", err)
 	}
+
+	// each dimension is updated only when it is given
+	_, curr, err := accessor.UpdateResourceQuota(context.Background(), contractId, &pb.ContractQuota{BlockSsd: 512})
+	if err != nil {
+		t.Fatalf("an error was unexpected while updating block_ssd %s", err)
+	}
+	if curr.BlockSsd != 512 || curr.Cpu != 128 || curr.Memory != 1280000 {
+		t.Errorf("expected only block_ssd to be updated, got %+v", curr)
+	}
+	_, curr, err = accessor.UpdateResourceQuota(context.Background(), contractId, &pb.ContractQuota{Block: 64})
+	if err != nil {
+		t.Fatalf("an error was unexpected while updating block %s", err)
+	}
+	if curr.Block != 64 || curr.BlockSsd != 512 {
+		t.Errorf("expected block_ssd to be kept when block is updated, got %+v", curr)
+	}
 }
 func TestGetContract(t *testing.T) {
 	accessor, err := getAccessor()
 	if err != nil {
 		t.Errorf("an error was unexpected while initilizing database %s", err)
 	}
-	contract, err := accessor.GetContract(contractId)
+	contract, err := accessor.GetContract(context.Background(), contractId)
 
 	if err != nil {
 		t.Errorf("an error was unexpected while querying contract data %s", err)
@@ -135,7 +179,7 @@ func TestGetContracts(t *testing.T) {
 	if err != nil {
 		t.Errorf("an error was unexpected while initilizing database %s", err)
 	}
-	contracts, err := accessor.List(0, 10)
+	contracts, err := accessor.List(context.Background(), 0, 10, nil)
 
 	if err != nil {
 		t.Errorf("an error was unexpected while querying contract data %s", err)
@@ -149,7 +193,7 @@ func TestGetDefaultContract(t *testing.T) {
 	if err != nil {
 		t.Errorf("an error was unexpected while initilizing database %s", err)
 	}
-	contract, err := accessor.GetDefaultContract()
+	contract, err := accessor.GetDefaultContract(context.Background())
 
 	if err != nil {
 		t.Errorf("an error was unexpected while querying contract data %s", err)
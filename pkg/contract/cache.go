@@ -0,0 +1,205 @@
+package contract
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// Cache is a store of serialized contracts in front of the database, such as the in-process
+// LRUCache or an external cache shared by servers. Entries expire after a TTL of the cache.
+// Failures of an external cache should be logged and treated as misses.
+type Cache interface {
+	// Get returns the value of key, or false if it is missing or expired.
+	Get(ctx context.Context, key string) ([]byte, bool)
+	// Set stores value with key.
+	Set(ctx context.Context, key string, value []byte)
+	// Delete removes keys. An error means that stale values may be served until they expire.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// WithCache returns a copy of the accessor which reads contracts and their quotas through cache.
+// Entries are invalidated by writes of the returned accessor, so servers which share the
+// database but not the cache may read stale contracts until the entries expire.
+func (x *Accessor) WithCache(cache Cache) *Accessor {
+	return &Accessor{db: x.db, cache: &contractCache{cache: cache}}
+}
+
+// contractCache reads contracts through a Cache and invalidates them on writes.
+type contractCache struct {
+	cache Cache
+	// mu orders storing a loaded contract and invalidating it, so that a contract loaded
+	// before a write is not stored after the write is invalidated.
+	mu         sync.RWMutex
+	generation uint64
+}
+
+// cachedContract is a cache entry of a contract with its quota.
+type cachedContract struct {
+	Contract model.Contract      `json:"contract"`
+	Quota    model.ResourceQuota `json:"quota"`
+}
+
+func contractCacheKey(contractID string) string {
+	return "tks-contract:contract:" + contractID
+}
+
+// findContractWithQuota returns a contract and its quota from the cache, or from db on a miss.
+func (x *Accessor) findContractWithQuota(ctx context.Context, contractID string) (model.Contract, model.ResourceQuota, error) {
+	db := x.db.WithContext(ctx)
+	if x.cache == nil {
+		return loadContractWithQuota(db, contractID)
+	}
+
+	key := contractCacheKey(contractID)
+	if b, ok := x.cache.cache.Get(ctx, key); ok {
+		var entry cachedContract
+		if err := json.Unmarshal(b, &entry); err == nil {
+			return entry.Contract, entry.Quota, nil
+		}
+		log.Error("invalid cache entry of contract id ", contractID)
+	}
+
+	x.cache.mu.RLock()
+	generation := x.cache.generation
+	x.cache.mu.RUnlock()
+	contract, quota, err := loadContractWithQuota(db, contractID)
+	if err != nil {
+		return contract, quota, err
+	}
+	b, err := json.Marshal(cachedContract{Contract: contract, Quota: quota})
+	if err != nil {
+		return contract, quota, nil
+	}
+	x.cache.mu.RLock()
+	if x.cache.generation == generation {
+		x.cache.cache.Set(ctx, key, b)
+	}
+	x.cache.mu.RUnlock()
+	return contract, quota, nil
+}
+
+func loadContractWithQuota(db *gorm.DB, contractID string) (model.Contract, model.ResourceQuota, error) {
+	contract, err := findContract(db, contractID)
+	if err != nil {
+		return model.Contract{}, model.ResourceQuota{}, err
+	}
+	quota, err := findQuota(db, contractID)
+	if err != nil {
+		return model.Contract{}, model.ResourceQuota{}, err
+	}
+	return contract, quota, nil
+}
+
+// invalidate removes contracts from the cache. It is called after writes to contracts or quotas
+// are committed.
+func (x *Accessor) invalidate(ctx context.Context, contractIDs ...string) {
+	if x.cache == nil || len(contractIDs) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(contractIDs))
+	for _, id := range contractIDs {
+		if id != "" {
+			keys = append(keys, contractCacheKey(id))
+		}
+	}
+	x.cache.mu.Lock()
+	defer x.cache.mu.Unlock()
+	x.cache.generation++
+	if err := x.cache.cache.Delete(ctx, keys...); err != nil {
+		log.Error("could not invalidate cached contracts ", contractIDs, " : ", err)
+	}
+}
+
+// LRUCache is an in-process Cache which holds at most size entries for ttl each, evicting the
+// least recently used entries first.
+type LRUCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRUCache returns an LRUCache of size entries which expire after ttl.
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Get implements Cache.
+func (c *LRUCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set implements Cache.
+func (c *LRUCache) Set(ctx context.Context, key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Delete implements Cache.
+func (c *LRUCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones which are not evicted yet.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,87 @@
+package contract_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+)
+
+func TestLRUCache(t *testing.T) {
+	ctx := context.Background()
+	cache := contract.NewLRUCache(2, time.Hour)
+	cache.Set(ctx, "a", []byte("1"))
+	cache.Set(ctx, "b", []byte("2"))
+	if _, ok := cache.Get(ctx, "a"); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+	// b is the least recently used entry
+	cache.Set(ctx, "c", []byte("3"))
+	if _, ok := cache.Get(ctx, "b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	if v, ok := cache.Get(ctx, "c"); !ok || string(v) != "3" {
+		t.Errorf("unexpected value %s of c", v)
+	}
+	if err := cache.Delete(ctx, "a", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(ctx, "a"); ok || cache.Len() != 1 {
+		t.Errorf("expected a to be deleted")
+	}
+
+	cache = contract.NewLRUCache(2, 10*time.Millisecond)
+	cache.Set(ctx, "a", []byte("1"))
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := cache.Get(ctx, "a"); ok || cache.Len() != 0 {
+		t.Errorf("expected a to be expired")
+	}
+}
+
+func TestCachedAccessor(t *testing.T) {
+	base, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	cache := contract.NewLRUCache(10, time.Hour)
+	accessor := base.WithCache(cache)
+	ctx := context.Background()
+	contractID, err := accessor.Create(ctx, "cached", []string{"lma"}, &pb.ContractQuota{Cpu: 4}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+
+	if _, err := accessor.GetContract(ctx, contractID); err != nil || cache.Len() != 1 {
+		t.Fatalf("expected contract to be cached, err %v", err)
+	}
+	// writes of another accessor are not seen until the entry expires
+	if _, _, err := base.UpdateAvailableServices(ctx, contractID, []string{"lma", "servicemesh"}); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := accessor.GetContract(ctx, contractID); len(c.GetAvailableServices()) != 1 {
+		t.Errorf("expected cached services, got %v", c.GetAvailableServices())
+	}
+
+	if _, _, err := accessor.UpdateResourceQuota(ctx, contractID, &pb.ContractQuota{Cpu: 8}); err != nil {
+		t.Fatal(err)
+	}
+	quota, err := accessor.GetResourceQuota(ctx, contractID)
+	if err != nil || quota.Cpu != 8 {
+		t.Errorf("expected updated quota, got %+v, err %v", quota, err)
+	}
+	c, err := accessor.GetContract(ctx, contractID)
+	if err != nil || len(c.GetAvailableServices()) != 2 || c.GetQuota().GetCpu() != 8 {
+		t.Errorf("expected updated contract, got %+v, err %v", c, err)
+	}
+
+	if err := accessor.Delete(ctx, contractID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := accessor.GetContract(ctx, contractID); err == nil {
+		t.Errorf("expected deleted contract not to be served from cache")
+	}
+}
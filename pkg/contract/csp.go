@@ -0,0 +1,187 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// CspRole is a role of a CSP account in a contract.
+type CspRole string
+
+const (
+	// CspRolePrimary is the account which clusters of the contract are created in by default.
+	// A contract has at most one primary account.
+	CspRolePrimary   CspRole = "primary"
+	CspRoleSecondary CspRole = "secondary"
+	// CspRoleDR is an account for disaster recovery.
+	CspRoleDR CspRole = "dr"
+)
+
+var (
+	// ErrCspNotAttached is returned on detaching a CSP account which is not attached to a contract.
+	ErrCspNotAttached = errors.New("CSP account is not attached")
+	// ErrPrimaryCsp is returned on detaching the primary CSP account while other accounts are attached.
+	ErrPrimaryCsp = errors.New("primary CSP account cannot be detached while other accounts are attached")
+	// ErrLastCsp is returned on detaching the last CSP account of a contract.
+	ErrLastCsp = errors.New("the last CSP account of a contract cannot be detached")
+)
+
+// ValidateCspRole returns an error if role is not a known CSP role.
+func ValidateCspRole(role CspRole) error {
+	switch role {
+	case CspRolePrimary, CspRoleSecondary, CspRoleDR:
+		return nil
+	}
+	return fmt.Errorf("invalid CSP role %s", role)
+}
+
+// historyCsp is a snapshot of a CSP account of a contract in history records.
+type historyCsp struct {
+	CspID   string `json:"cspId"`
+	CspName string `json:"cspName"`
+	Role    string `json:"role"`
+}
+
+// ListCsps returns the CSP accounts of a contract, the primary account first and the others
+// in the order they were attached.
+func (x *Accessor) ListCsps(ctx context.Context, contractID string) ([]model.ContractCsp, error) {
+	if _, err := findContract(x.db.WithContext(ctx), contractID); err != nil {
+		return nil, err
+	}
+	return findCsps(x.db.WithContext(ctx), contractID)
+}
+
+// AttachCsp attaches a CSP account to a contract, or changes the role of an attached account.
+// If the account becomes the primary, the previous primary account becomes a secondary one.
+func (x *Accessor) AttachCsp(ctx context.Context, contractID string, csp model.ContractCsp) (model.ContractCsp, error) {
+	if err := ValidateCspRole(CspRole(csp.Role)); err != nil {
+		return model.ContractCsp{}, err
+	}
+	if csp.CspID == uuid.Nil {
+		return model.ContractCsp{}, fmt.Errorf("CSP id must be specified")
+	}
+	csp.ContractID = contractID
+
+	err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := findContract(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractID); err != nil {
+			return err
+		}
+		var (
+			prev     interface{}
+			attached model.ContractCsp
+		)
+		res := tx.Limit(1).Find(&attached, "contract_id = ? AND csp_id = ?", contractID, csp.CspID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			prev = newHistoryCsp(attached)
+			if csp.CspName == "" {
+				csp.CspName = attached.CspName
+			}
+		}
+
+		if CspRole(csp.Role) == CspRolePrimary {
+			res := tx.Model(&model.ContractCsp{}).
+				Where("contract_id = ? AND role = ? AND csp_id <> ?", contractID, CspRolePrimary, csp.CspID).
+				Update("role", CspRoleSecondary)
+			if res.Error != nil {
+				return fmt.Errorf("could not demote primary CSP account of contract id %s: %s", contractID, res.Error)
+			}
+		}
+		res = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "contract_id"}, {Name: "csp_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"csp_name", "role", "updated_at"}),
+		}).Create(&csp)
+		if res.Error != nil {
+			return fmt.Errorf("could not attach CSP account %s to contract id %s: %s", csp.CspID, contractID, res.Error)
+		}
+		return recordHistory(tx, contractID, HistoryCspAttached, prev, newHistoryCsp(csp))
+	})
+	if err != nil {
+		return model.ContractCsp{}, err
+	}
+	log.Info("CSP account is attached! contractId : ", contractID, ", cspId : ", csp.CspID, ", role : ", csp.Role)
+	return csp, nil
+}
+
+// DetachCsp detaches a CSP account from a contract and returns it. The CSP info in tks-info is not deleted.
+// The last account of a contract cannot be detached.
+func (x *Accessor) DetachCsp(ctx context.Context, contractID string, cspID uuid.UUID) (model.ContractCsp, error) {
+	var csp model.ContractCsp
+	err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := findContract(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractID); err != nil {
+			return err
+		}
+		csps, err := findCsps(tx, contractID)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, c := range csps {
+			if c.CspID == cspID {
+				csp, found = c, true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w : %s to contract %s", ErrCspNotAttached, cspID, contractID)
+		}
+		if len(csps) == 1 {
+			return fmt.Errorf("%w : %s of contract %s", ErrLastCsp, cspID, contractID)
+		}
+		if CspRole(csp.Role) == CspRolePrimary {
+			return fmt.Errorf("%w : %s of contract %s", ErrPrimaryCsp, cspID, contractID)
+		}
+
+		if res := tx.Delete(&model.ContractCsp{}, "id = ?", csp.ID); res.Error != nil {
+			return fmt.Errorf("could not detach CSP account %s from contract id %s: %s", cspID, contractID, res.Error)
+		}
+		return recordHistory(tx, contractID, HistoryCspDetached, newHistoryCsp(csp), nil)
+	})
+	if err != nil {
+		return model.ContractCsp{}, err
+	}
+	log.Info("CSP account is detached! contractId : ", contractID, ", cspId : ", cspID)
+	return csp, nil
+}
+
+// ListAllCsps returns all contracts, with only their ids, creation times and default flags, and
+// all the CSP accounts attached to them, to reconcile them with tks-info.
+func (x *Accessor) ListAllCsps(ctx context.Context) ([]model.Contract, []model.ContractCsp, error) {
+	var (
+		contracts []model.Contract
+		csps      []model.ContractCsp
+	)
+	if res := x.db.WithContext(ctx).Select("id", "created_at", "is_default").Order("id").Find(&contracts); res.Error != nil {
+		return nil, nil, res.Error
+	}
+	if res := x.db.WithContext(ctx).Order("contract_id, created_at").Find(&csps); res.Error != nil {
+		return nil, nil, res.Error
+	}
+	return contracts, csps, nil
+}
+
+func newHistoryCsp(csp model.ContractCsp) *historyCsp {
+	return &historyCsp{CspID: csp.CspID.String(), CspName: csp.CspName, Role: csp.Role}
+}
+
+func findCsps(db *gorm.DB, contractID string) ([]model.ContractCsp, error) {
+	var csps []model.ContractCsp
+	res := db.Order("created_at").Find(&csps, "contract_id = ?", contractID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	sort.SliceStable(csps, func(i, j int) bool {
+		return csps[i].Role == string(CspRolePrimary) && csps[j].Role != string(CspRolePrimary)
+	})
+	return csps, nil
+}
@@ -0,0 +1,137 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestCsps(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	contractID, err := accessor.Create(ctx, "multi-csp", []string{}, &pb.ContractQuota{}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+
+	aws := model.ContractCsp{CspID: uuid.New(), CspName: "aws", Role: string(contract.CspRolePrimary)}
+	if _, err := accessor.AttachCsp(ctx, contractID, aws); err != nil {
+		t.Fatalf("an error was unexpected while attaching CSP account %s", err)
+	}
+	dr := model.ContractCsp{CspID: uuid.New(), CspName: "aws-dr", Role: string(contract.CspRoleDR)}
+	if _, err := accessor.AttachCsp(ctx, contractID, dr); err != nil {
+		t.Fatalf("an error was unexpected while attaching CSP account %s", err)
+	}
+	if _, err := accessor.AttachCsp(ctx, contractID, model.ContractCsp{CspID: uuid.New(), Role: "backup"}); err == nil {
+		t.Errorf("expected an error for an unknown role")
+	}
+
+	csps, err := accessor.ListCsps(ctx, contractID)
+	if err != nil || len(csps) != 2 || csps[0].CspID != aws.CspID || csps[1].Role != string(contract.CspRoleDR) {
+		t.Fatalf("unexpected CSP accounts %+v, err %v", csps, err)
+	}
+	if _, err := accessor.DetachCsp(ctx, contractID, aws.CspID); !errors.Is(err, contract.ErrPrimaryCsp) {
+		t.Errorf("expected ErrPrimaryCsp, got %v", err)
+	}
+
+	// promoting the DR account demotes the previous primary
+	gcp := model.ContractCsp{CspID: uuid.New(), CspName: "gcp", Role: string(contract.CspRolePrimary)}
+	if _, err := accessor.AttachCsp(ctx, contractID, gcp); err != nil {
+		t.Fatalf("an error was unexpected while attaching CSP account %s", err)
+	}
+	csps, err = accessor.ListCsps(ctx, contractID)
+	if err != nil || len(csps) != 3 || csps[0].CspID != gcp.CspID || csps[1].Role != string(contract.CspRoleSecondary) {
+		t.Fatalf("unexpected CSP accounts %+v, err %v", csps, err)
+	}
+
+	if _, err := accessor.DetachCsp(ctx, contractID, uuid.New()); !errors.Is(err, contract.ErrCspNotAttached) {
+		t.Errorf("expected ErrCspNotAttached, got %v", err)
+	}
+	if _, err := accessor.DetachCsp(ctx, contractID, aws.CspID); err != nil {
+		t.Errorf("an error was unexpected while detaching CSP account %s", err)
+	}
+	if _, err := accessor.DetachCsp(ctx, contractID, dr.CspID); err != nil {
+		t.Errorf("an error was unexpected while detaching CSP account %s", err)
+	}
+	if _, err := accessor.DetachCsp(ctx, contractID, gcp.CspID); !errors.Is(err, contract.ErrLastCsp) {
+		t.Errorf("expected ErrLastCsp, got %v", err)
+	}
+
+	// a failed cleanup rolls the deletion back, and the cleanup is given the attached accounts
+	errCleanup := errors.New("cleanup failed")
+	var cleaned []model.ContractCsp
+	cleanup := func(csps []model.ContractCsp) error {
+		cleaned = csps
+		return errCleanup
+	}
+	if err := accessor.DeleteWith(ctx, contractID, cleanup); !errors.Is(err, errCleanup) {
+		t.Fatalf("expected the cleanup error, got %v", err)
+	}
+	if len(cleaned) != 1 || cleaned[0].CspID != gcp.CspID {
+		t.Errorf("unexpected CSP accounts to clean up %+v", cleaned)
+	}
+	if csps, err := accessor.ListCsps(ctx, contractID); err != nil || len(csps) != 1 {
+		t.Fatalf("expected the deletion to be rolled back, got %+v, err %v", csps, err)
+	}
+
+	if err := accessor.DeleteWith(ctx, contractID, func([]model.ContractCsp) error { return nil }); err != nil {
+		t.Fatalf("an error was unexpected while deleting contract %s", err)
+	}
+	if _, err := accessor.ListCsps(ctx, contractID); err == nil {
+		t.Errorf("expected an error for CSP accounts of a deleted contract")
+	}
+}
+
+func TestDeleteUnused(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	leftover, err := accessor.Create(ctx, "unused-leftover", []string{}, &pb.ContractQuota{}, uuid.New(), "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	updated, err := accessor.Create(ctx, "unused-updated", []string{}, &pb.ContractQuota{}, uuid.New(), "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	if _, _, err := accessor.UpdateResourceQuota(ctx, updated, &pb.ContractQuota{Cpu: 4000}); err != nil {
+		t.Fatalf("an error was unexpected while updating quota: %s", err)
+	}
+	report, err := accessor.Import(ctx, []contract.ContractRecord{{
+		Contract: model.Contract{ContractorName: "unused-imported"},
+	}}, contract.ConflictFail, false)
+	if err != nil || !report.Committed {
+		t.Fatalf("an error was unexpected while importing contract: %+v, err %v", report, err)
+	}
+	imported := report.Results[0].ContractID
+
+	testCases := []struct {
+		name       string
+		contractID string
+		deleted    bool
+	}{
+		{"leftover of a failed creation", leftover, true},
+		{"changed after creation", updated, false},
+		{"imported", imported, false},
+	}
+	for _, tc := range testCases {
+		deleted, err := accessor.DeleteUnused(ctx, tc.contractID, nil)
+		if err != nil || deleted != tc.deleted {
+			t.Errorf("%s: expected deleted %v but got %v, err %v", tc.name, tc.deleted, deleted, err)
+		}
+		if _, err := accessor.GetContract(ctx, tc.contractID); (err != nil) != tc.deleted {
+			t.Errorf("%s: unexpected contract after DeleteUnused, err %v", tc.name, err)
+		}
+	}
+}
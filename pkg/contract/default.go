@@ -0,0 +1,170 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// LegacyDefaultContractorName is the contractor name of the default contract in databases
+// created before contracts are flagged as the default.
+const LegacyDefaultContractorName = "default"
+
+// ErrNoDefaultContract is returned if there is no default contract.
+var ErrNoDefaultContract = errors.New("Not found default contract")
+
+// historyDefault is a snapshot of whether a contract is the default in history records.
+type historyDefault struct {
+	IsDefault bool `json:"isDefault"`
+}
+
+// findDefaultContract returns the contract flagged as the default, or the contract with the legacy
+// default contractor name if no contract is flagged.
+func findDefaultContract(db *gorm.DB) (model.Contract, error) {
+	var contract model.Contract
+	res := db.Limit(1).Find(&contract, "is_default")
+	if res.Error != nil {
+		return model.Contract{}, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return contract, nil
+	}
+	res = db.Limit(1).Find(&contract, "contractor_name = ?", LegacyDefaultContractorName)
+	if res.Error != nil {
+		return model.Contract{}, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return model.Contract{}, ErrNoDefaultContract
+	}
+	return contract, nil
+}
+
+// SetDefaultContract makes a contract the default one, and returns the id of the previous
+// default contract, which is empty if there was none.
+func (x *Accessor) SetDefaultContract(ctx context.Context, contractID string) (prev string, err error) {
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := findContract(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractID); err != nil {
+			return err
+		}
+		prev, err = replaceDefault(tx, contractID)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	x.invalidate(ctx, contractID, prev)
+	log.Info("default contract is changed from ", prev, " to ", contractID)
+	return prev, nil
+}
+
+// replaceDefault flags contractID as the default in place of the current default contract, and
+// returns the id of the current one, which is empty if there was none.
+func replaceDefault(tx *gorm.DB, contractID string) (prev string, err error) {
+	var curr model.Contract
+	res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&curr, "is_default")
+	if res.Error != nil {
+		return "", res.Error
+	}
+	if res.RowsAffected > 0 {
+		prev = curr.ID
+	}
+	if prev == contractID {
+		return prev, nil
+	}
+	return prev, setDefault(tx, contractID, prev)
+}
+
+// setDefault flags contractID as the default instead of prev, if prev is not empty.
+func setDefault(tx *gorm.DB, contractID string, prev string) error {
+	if prev != "" {
+		res := tx.Model(&model.Contract{}).Where("id = ?", prev).Update("is_default", false)
+		if res.Error != nil {
+			return fmt.Errorf("could not unset default contract %s: %w", prev, res.Error)
+		}
+		if err := recordHistory(tx, prev, HistoryDefaultChanged, historyDefault{IsDefault: true}, historyDefault{}); err != nil {
+			return err
+		}
+	}
+	res := tx.Model(&model.Contract{}).Where("id = ?", contractID).Update("is_default", true)
+	if res.Error != nil {
+		return fmt.Errorf("could not set default contract %s: %w", contractID, res.Error)
+	}
+	return recordHistory(tx, contractID, HistoryDefaultChanged, historyDefault{}, historyDefault{IsDefault: true})
+}
+
+// EnsureDefaultContract returns the id of the default contract. If there is none, the contract with
+// contractor name is flagged as the default, or it is created with availableServices, quota and
+// description. Created is true if a contract is created.
+func (x *Accessor) EnsureDefaultContract(ctx context.Context, name string, availableServices []string,
+	quota *pb.ContractQuota, description string) (id string, created bool, err error) {
+	if name == "" {
+		return "", false, fmt.Errorf("contractor name of the default contract must be specified")
+	}
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var contract model.Contract
+		res := tx.Limit(1).Find(&contract, "is_default")
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			id = contract.ID
+			return nil
+		}
+		res = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&contract, "contractor_name = ?", name)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			id = contract.ID
+			return setDefault(tx, id, "")
+		}
+
+		now := time.Now()
+		contract = model.Contract{
+			ContractorName:    name,
+			AvailableServices: pq.StringArray(availableServices),
+			Description:       description,
+			EffectiveFrom:     &now,
+			IsDefault:         true,
+		}
+		if err := createContract(tx, &contract, quota, uuid.Nil); err != nil {
+			return err
+		}
+		id, created = contract.ID, true
+		return nil
+	})
+	if err != nil {
+		if !isDefaultConflict(err) {
+			return "", false, err
+		}
+		// another server flagged or created the default contract at the same time
+		var contract model.Contract
+		if res := x.db.WithContext(ctx).Limit(1).Find(&contract, "is_default"); res.Error == nil && res.RowsAffected > 0 {
+			return contract.ID, false, nil
+		}
+		return "", false, err
+	}
+	x.invalidate(ctx, id)
+	return id, created, nil
+}
+
+// uniqueViolation is the SQLSTATE of unique violations in PostgreSQL.
+const uniqueViolation = "23505"
+
+// isDefaultConflict returns true if err is the unique violation of idx_default_contract, which allows
+// only one default contract.
+func isDefaultConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "idx_default_contract"
+}
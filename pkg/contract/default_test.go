@@ -0,0 +1,142 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+	"gorm.io/gorm"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestDefaultContract(t *testing.T) {
+	db, err := getDB()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	accessor := contract.New(db)
+	ctx := context.Background()
+	resetDefault := func() {
+		if res := db.Model(&model.Contract{}).Where("is_default").Update("is_default", false); res.Error != nil {
+			t.Fatal(res.Error)
+		}
+	}
+	resetDefault()
+	defer resetDefault()
+
+	name := "default-" + uuid.New().String()[:8]
+	id, created, err := accessor.EnsureDefaultContract(ctx, name, []string{"lma"}, &pb.ContractQuota{Cpu: 4}, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while ensuring default contract %s", err)
+	}
+	if !created {
+		t.Errorf("expected the default contract to be created")
+	}
+	again, created, err := accessor.EnsureDefaultContract(ctx, name, nil, &pb.ContractQuota{}, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while ensuring default contract %s", err)
+	}
+	if created || again != id {
+		t.Errorf("expected the default contract %s to be kept, but got %s (created %t)", id, again, created)
+	}
+	quota, err := accessor.GetResourceQuota(ctx, id)
+	if err != nil || quota.Cpu != 4 {
+		t.Errorf("expected the quota of the default contract to be created, but got %+v (%v)", quota, err)
+	}
+
+	otherID, err := accessor.Create(ctx, name+"-other", []string{}, &pb.ContractQuota{}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	prev, err := accessor.SetDefaultContract(ctx, otherID)
+	if err != nil {
+		t.Fatalf("an error was unexpected while setting default contract %s", err)
+	}
+	if prev != id {
+		t.Errorf("expected previous default contract %s, but got %s", id, prev)
+	}
+	defaultContract, err := accessor.GetDefaultContract(ctx)
+	if err != nil || defaultContract.ContractId != otherID {
+		t.Errorf("expected default contract %s, but got %+v (%v)", otherID, defaultContract, err)
+	}
+	if _, err := accessor.SetDefaultContract(ctx, "P0000none"); err == nil {
+		t.Errorf("expected an error for a contract which does not exist")
+	}
+
+	resetDefault()
+	again, created, err = accessor.EnsureDefaultContract(ctx, name, nil, &pb.ContractQuota{}, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while ensuring default contract %s", err)
+	}
+	if created || again != id {
+		t.Errorf("expected the contract %s of the name to be made the default, but got %s (created %t)", id, again, created)
+	}
+}
+
+func TestEnsureDefaultContractRace(t *testing.T) {
+	db, err := getDB()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	other, err := getDB()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	accessor := contract.New(db)
+	ctx := context.Background()
+	resetDefault := func() {
+		if res := db.Model(&model.Contract{}).Where("is_default").Update("is_default", false); res.Error != nil {
+			t.Fatal(res.Error)
+		}
+	}
+	resetDefault()
+	defer resetDefault()
+
+	// another server flags a contract as the default just before this one does
+	var inject func(tx *gorm.DB)
+	if err := db.Callback().Update().Before("gorm:update").Register("test:race", func(tx *gorm.DB) {
+		if inject != nil {
+			inject(tx)
+			inject = nil
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	name := "race-" + uuid.New().String()[:8]
+	if _, err := accessor.Create(ctx, name, []string{}, &pb.ContractQuota{}, uuid.Nil, ""); err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	otherID, err := accessor.Create(ctx, name+"-other", []string{}, &pb.ContractQuota{}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	flagOther := func() {
+		if res := other.Model(&model.Contract{}).Where("id = ?", otherID).Update("is_default", true); res.Error != nil {
+			t.Fatal(res.Error)
+		}
+	}
+
+	inject = func(tx *gorm.DB) { flagOther() }
+	got, created, err := accessor.EnsureDefaultContract(ctx, name, nil, &pb.ContractQuota{}, "")
+	if err != nil || created || got != otherID {
+		t.Errorf("expected the default contract %s of the other server, but got %s (created %t, %v)", otherID, got, created, err)
+	}
+
+	// other errors are returned even if a default contract exists afterwards
+	resetDefault()
+	failure := errors.New("connection is lost")
+	inject = func(tx *gorm.DB) {
+		flagOther()
+		_ = tx.AddError(failure)
+	}
+	if got, _, err := accessor.EnsureDefaultContract(ctx, name, nil, &pb.ContractQuota{}, ""); !errors.Is(err, failure) {
+		t.Errorf("expected the error to be returned, but got %s (%v)", got, err)
+	}
+	if got, _, err := accessor.EnsureDefaultContract(ctx, name, nil, &pb.ContractQuota{}, ""); err != nil || got != otherID {
+		t.Errorf("expected the default contract %s, but got %s (%v)", otherID, got, err)
+	}
+}
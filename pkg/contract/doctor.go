@@ -0,0 +1,244 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/openinfradev/tks-common/pkg/helper"
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// Kinds of database inconsistencies found by Doctor.
+const (
+	// DoctorOrphanRows is rows of a contract which does not exist. They are deleted on repair.
+	DoctorOrphanRows = "orphan_rows"
+	// DoctorMissingQuota is a contract without a resource quota, which fails listing contracts.
+	// A zero quota is created on repair.
+	DoctorMissingQuota = "missing_quota"
+	// DoctorDuplicateQuota is a contract with more than one resource quota. The most recently
+	// updated one is kept on repair.
+	DoctorDuplicateQuota = "duplicate_quota"
+	// DoctorInvalidContractID is a contract whose id is not a valid contract id. It is only
+	// reported, because contract ids are referenced by other services.
+	DoctorInvalidContractID = "invalid_contract_id"
+)
+
+// DoctorKinds are all the kinds of database inconsistencies in the order they are repaired.
+var DoctorKinds = []string{DoctorOrphanRows, DoctorDuplicateQuota, DoctorMissingQuota, DoctorInvalidContractID}
+
+// ValidateDoctorKind returns an error if kind is not a known kind of inconsistencies.
+func ValidateDoctorKind(kind string) error {
+	for _, k := range DoctorKinds {
+		if kind == k {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid kind of inconsistency %s", kind)
+}
+
+// DoctorIssue is a database inconsistency of a contract.
+type DoctorIssue struct {
+	Kind       string
+	ContractID string
+	// Table of the rows, for orphan rows and duplicate quotas.
+	Table string
+	// RowIDs are the rows which are deleted on repair.
+	RowIDs     []uuid.UUID
+	Repairable bool
+	Repaired   bool
+}
+
+// DoctorReport is the result of Doctor. Committed is true if repairs are committed.
+type DoctorReport struct {
+	Contracts int
+	Issues    []DoctorIssue
+	Committed bool
+}
+
+// Count returns the number of issues of kind.
+func (r DoctorReport) Count(kind string) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// doctorRow is a row of a table which belongs to a contract.
+type doctorRow struct {
+	ID         uuid.UUID
+	ContractID string
+	UpdatedAt  time.Time
+}
+
+// Doctor scans the database for inconsistencies which foreign keys would prevent. If repair is
+// true, the repairable issues of kinds, or of every kind if kinds is empty, are repaired in a
+// transaction, which is rolled back as a whole if any repair fails.
+func (x *Accessor) Doctor(ctx context.Context, repair bool, kinds []string) (DoctorReport, error) {
+	var report DoctorReport
+	selected := map[string]bool{}
+	for _, kind := range kinds {
+		if err := ValidateDoctorKind(kind); err != nil {
+			return report, err
+		}
+		selected[kind] = true
+	}
+
+	err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if repair {
+			// Contracts and quotas must not change between the scan and the repairs.
+			if res := tx.Exec("LOCK TABLE contracts, resource_quota IN SHARE ROW EXCLUSIVE MODE"); res.Error != nil {
+				return res.Error
+			}
+		}
+		var err error
+		if report, err = diagnose(tx); err != nil {
+			return err
+		}
+		if !repair {
+			return errRollback
+		}
+		for i := range report.Issues {
+			issue := &report.Issues[i]
+			if !issue.Repairable || (len(selected) > 0 && !selected[issue.Kind]) {
+				continue
+			}
+			if err := repairIssue(tx, issue); err != nil {
+				return fmt.Errorf("could not repair %s of contract id %s : %s", issue.Kind, issue.ContractID, err)
+			}
+			issue.Repaired = true
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		return report, nil
+	}
+	if err != nil {
+		for i := range report.Issues {
+			report.Issues[i].Repaired = false
+		}
+		return report, err
+	}
+	report.Committed = true
+	for _, issue := range report.Issues {
+		if issue.Repaired {
+			x.invalidate(ctx, issue.ContractID)
+			log.Info("repaired ", issue.Kind, " of contract id ", issue.ContractID, " ", issue.Table, " ", issue.RowIDs)
+		}
+	}
+	return report, nil
+}
+
+func diagnose(tx *gorm.DB) (DoctorReport, error) {
+	var (
+		report    DoctorReport
+		contracts []string
+		quotas    []doctorRow
+	)
+	if res := tx.Model(&model.Contract{}).Order("id").Pluck("id", &contracts); res.Error != nil {
+		return report, res.Error
+	}
+	report.Contracts = len(contracts)
+	exists := map[string]bool{}
+	for _, id := range contracts {
+		exists[id] = true
+	}
+
+	for _, table := range []struct {
+		name  string
+		model interface{}
+	}{
+		{"resource_quota", &model.ResourceQuota{}},
+		{"regional_quotas", &model.RegionalQuota{}},
+		{"contract_csps", &model.ContractCsp{}},
+		{"contract_members", &model.ContractMember{}},
+	} {
+		var rows []doctorRow
+		if res := tx.Model(table.model).Select("id", "contract_id").Order("contract_id, id").Scan(&rows); res.Error != nil {
+			return report, res.Error
+		}
+		orphans := map[string][]uuid.UUID{}
+		for _, row := range rows {
+			if !exists[row.ContractID] {
+				orphans[row.ContractID] = append(orphans[row.ContractID], row.ID)
+			}
+		}
+		for id, rowIDs := range orphans {
+			report.Issues = append(report.Issues, DoctorIssue{
+				Kind: DoctorOrphanRows, ContractID: id, Table: table.name, RowIDs: rowIDs, Repairable: true,
+			})
+		}
+	}
+
+	res := tx.Model(&model.ResourceQuota{}).Select("id", "contract_id", "updated_at").
+		Order("contract_id, updated_at DESC, id").Scan(&quotas)
+	if res.Error != nil {
+		return report, res.Error
+	}
+	quotasOf := map[string][]uuid.UUID{}
+	for _, quota := range quotas {
+		quotasOf[quota.ContractID] = append(quotasOf[quota.ContractID], quota.ID)
+	}
+	for _, id := range contracts {
+		switch n := len(quotasOf[id]); {
+		case n == 0:
+			report.Issues = append(report.Issues, DoctorIssue{
+				Kind: DoctorMissingQuota, ContractID: id, Table: "resource_quota", Repairable: true,
+			})
+		case n > 1:
+			report.Issues = append(report.Issues, DoctorIssue{
+				Kind: DoctorDuplicateQuota, ContractID: id, Table: "resource_quota", RowIDs: quotasOf[id][1:], Repairable: true,
+			})
+		}
+		if !helper.ValidateContractId(id) {
+			report.Issues = append(report.Issues, DoctorIssue{Kind: DoctorInvalidContractID, ContractID: id})
+		}
+	}
+
+	order := map[string]int{}
+	for i, kind := range DoctorKinds {
+		order[kind] = i
+	}
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Kind != b.Kind {
+			return order[a.Kind] < order[b.Kind]
+		}
+		if a.ContractID != b.ContractID {
+			return a.ContractID < b.ContractID
+		}
+		return a.Table < b.Table
+	})
+	return report, nil
+}
+
+func repairIssue(tx *gorm.DB, issue *DoctorIssue) error {
+	switch issue.Kind {
+	case DoctorOrphanRows:
+		models := map[string]interface{}{
+			"resource_quota":   &model.ResourceQuota{},
+			"regional_quotas":  &model.RegionalQuota{},
+			"contract_csps":    &model.ContractCsp{},
+			"contract_members": &model.ContractMember{},
+		}
+		return tx.Delete(models[issue.Table], "id IN ?", issue.RowIDs).Error
+	case DoctorDuplicateQuota:
+		return tx.Delete(&model.ResourceQuota{}, "id IN ?", issue.RowIDs).Error
+	case DoctorMissingQuota:
+		if res := tx.Create(&model.ResourceQuota{ContractID: issue.ContractID}); res.Error != nil {
+			return res.Error
+		}
+		return recordHistory(tx, issue.ContractID, HistoryQuotaUpdated, nil, newHistoryQuota(&pb.ContractQuota{}))
+	}
+	return fmt.Errorf("%s is not repairable", issue.Kind)
+}
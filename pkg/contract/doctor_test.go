@@ -0,0 +1,94 @@
+package contract_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestDoctor(t *testing.T) {
+	db, err := getDB()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	accessor := contract.New(db)
+	ctx := context.Background()
+
+	missingID, err := accessor.Create(ctx, "doctor-missing", []string{}, &pb.ContractQuota{Cpu: 4}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	duplicateID, err := accessor.Create(ctx, "doctor-duplicate", []string{}, &pb.ContractQuota{Cpu: 4}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	if res := db.Delete(&model.ResourceQuota{}, "contract_id = ?", missingID); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res := db.Create(&model.ResourceQuota{ContractID: duplicateID, Cpu: 8}); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res := db.Create(&model.ResourceQuota{ContractID: "P0000orphan", Cpu: 2}); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res := db.Create(&model.ContractMember{ContractID: "P0000orphan", UserID: uuid.New(), Role: string(contract.RoleOwner)}); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res := db.Create(&model.Contract{ID: "bad id", ContractorName: "doctor-invalid"}); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res := db.Create(&model.ResourceQuota{ContractID: "bad id"}); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	defer db.Delete(&model.ResourceQuota{}, "contract_id = ?", "bad id")
+	defer db.Delete(&model.Contract{}, "id = ?", "bad id")
+
+	if _, err := accessor.List(ctx, 0, 1000, nil); err == nil {
+		t.Errorf("expected an error for a contract without quota")
+	}
+
+	report, err := accessor.Doctor(ctx, false, nil)
+	if err != nil {
+		t.Fatalf("an error was unexpected while scanning database %s", err)
+	}
+	if report.Committed || report.Count(contract.DoctorOrphanRows) != 2 || report.Count(contract.DoctorMissingQuota) != 1 ||
+		report.Count(contract.DoctorDuplicateQuota) != 1 || report.Count(contract.DoctorInvalidContractID) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	if _, err := accessor.Doctor(ctx, true, []string{"everything"}); err == nil {
+		t.Errorf("expected an error for an unknown kind")
+	}
+	report, err = accessor.Doctor(ctx, true, []string{contract.DoctorMissingQuota})
+	if err != nil || !report.Committed {
+		t.Fatalf("an error was unexpected while repairing database %s", err)
+	}
+	if report.Count(contract.DoctorOrphanRows) != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	for _, issue := range report.Issues {
+		if issue.Repaired != (issue.Kind == contract.DoctorMissingQuota) {
+			t.Errorf("unexpected repair of issue %+v", issue)
+		}
+	}
+	if _, err := accessor.List(ctx, 0, 1000, nil); err != nil {
+		t.Errorf("an error was unexpected while listing contracts %s", err)
+	}
+
+	if _, err := accessor.Doctor(ctx, true, nil); err != nil {
+		t.Fatalf("an error was unexpected while repairing database %s", err)
+	}
+	report, err = accessor.Doctor(ctx, false, nil)
+	if err != nil || len(report.Issues) != 1 || report.Issues[0].Kind != contract.DoctorInvalidContractID {
+		t.Fatalf("unexpected report %+v, err %v", report, err)
+	}
+	quota, err := accessor.GetResourceQuota(ctx, duplicateID)
+	if err != nil || quota.Cpu != 8 {
+		t.Errorf("expected the most recently updated quota to be kept, got %+v, err %v", quota, err)
+	}
+}
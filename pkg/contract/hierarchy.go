@@ -0,0 +1,292 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// maxContractDepth is the maximum number of levels of a contract hierarchy.
+const maxContractDepth = 8
+
+var (
+	// ErrQuotaExceeded is returned if the quotas of sub-contracts would exceed the quota of their parent.
+	ErrQuotaExceeded = errors.New("quota of sub-contracts exceeds quota of parent")
+	// ErrInvalidParent is returned if a contract would be an ancestor of itself, or its ancestry would be too deep.
+	ErrInvalidParent = errors.New("invalid parent")
+	// ErrHasSubContracts is returned on deleting a contract which has sub-contracts.
+	ErrHasSubContracts = errors.New("contract has sub-contracts")
+)
+
+// ContractWithQuota is a contract and its resource quota.
+type ContractWithQuota struct {
+	Contract model.Contract
+	Quota    model.ResourceQuota
+}
+
+// historyParent is a snapshot of the parent of a contract in history records.
+type historyParent struct {
+	ParentID *string `json:"parentId"`
+}
+
+// GetAncestry returns the ancestors of a contract from its parent to the root.
+func (x *Accessor) GetAncestry(ctx context.Context, contractID string) ([]ContractWithQuota, error) {
+	contract, err := findContract(x.db.WithContext(ctx), contractID)
+	if err != nil {
+		return nil, err
+	}
+	ancestors := []ContractWithQuota{}
+	for contract.ParentID != nil {
+		if len(ancestors) >= maxContractDepth {
+			return nil, fmt.Errorf("ancestry of contract %s is deeper than %d", contractID, maxContractDepth)
+		}
+		if contract, err = findContract(x.db.WithContext(ctx), *contract.ParentID); err != nil {
+			return nil, err
+		}
+		quota, err := findQuota(x.db.WithContext(ctx), contract.ID)
+		if err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, ContractWithQuota{Contract: contract, Quota: quota})
+	}
+	return ancestors, nil
+}
+
+// ListChildren returns the children of a contract ordered by creation time, and the sum of their quotas.
+func (x *Accessor) ListChildren(ctx context.Context, contractID string) ([]ContractWithQuota, model.ResourceQuota, error) {
+	if _, err := findContract(x.db.WithContext(ctx), contractID); err != nil {
+		return nil, model.ResourceQuota{}, err
+	}
+	var contracts []model.Contract
+	res := x.db.WithContext(ctx).Where("parent_id = ?", contractID).Order("created_at, id").Find(&contracts)
+	if res.Error != nil {
+		return nil, model.ResourceQuota{}, res.Error
+	}
+	children := make([]ContractWithQuota, 0, len(contracts))
+	for _, contract := range contracts {
+		quota, err := findQuota(x.db.WithContext(ctx), contract.ID)
+		if err != nil {
+			return nil, model.ResourceQuota{}, err
+		}
+		children = append(children, ContractWithQuota{Contract: contract, Quota: quota})
+	}
+	allocated, err := sumChildQuotas(x.db.WithContext(ctx), contractID)
+	if err != nil {
+		return nil, model.ResourceQuota{}, err
+	}
+	return children, allocated, nil
+}
+
+// SetParent makes parentID the parent of a contract, or detaches it from its parent if parentID is empty.
+// The quotas of the children of the parent, including the contract, must fit in the quota of the parent.
+func (x *Accessor) SetParent(ctx context.Context, contractID string, parentID string) (prev *string, err error) {
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		contract, err := findContract(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractID)
+		if err != nil {
+			return err
+		}
+		prev = contract.ParentID
+		if parentID != "" {
+			if err := lockContract(tx, parentID); err != nil {
+				return err
+			}
+			if err := checkCycle(tx, contractID, parentID); err != nil {
+				return err
+			}
+		}
+
+		var parent interface{}
+		if parentID != "" {
+			parent = parentID
+		}
+		res := tx.Model(&model.Contract{}).Where("id = ?", contractID).Update("parent_id", parent)
+		if res.Error != nil {
+			return fmt.Errorf("could not update parent of contract id %s: %s", contractID, res.Error)
+		}
+		if parentID != "" {
+			if err := checkQuotaEnvelope(tx, parentID); err != nil {
+				return err
+			}
+		}
+
+		curr := historyParent{}
+		if parentID != "" {
+			curr.ParentID = &parentID
+		}
+		return recordHistory(tx, contractID, HistoryParentUpdated, historyParent{ParentID: prev}, curr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	x.invalidate(ctx, contractID)
+	log.Info("parent of contract ID ", contractID, " is changed to ", parentID)
+	return prev, nil
+}
+
+// checkCycle returns an error if parentID is contractID or one of its descendants, or if the
+// hierarchy would be deeper than maxContractDepth with the subtree of contractID under parentID.
+func checkCycle(tx *gorm.DB, contractID string, parentID string) error {
+	height, err := subtreeHeight(tx, contractID)
+	if err != nil {
+		return err
+	}
+	id := parentID
+	for depth := 0; ; depth++ {
+		if id == contractID {
+			return fmt.Errorf("%w : contract %s cannot be a descendant of itself", ErrInvalidParent, contractID)
+		}
+		if depth+height >= maxContractDepth-1 {
+			return fmt.Errorf("%w : ancestry of contract %s would be deeper than %d", ErrInvalidParent, contractID, maxContractDepth)
+		}
+		ancestor, err := findContract(tx, id)
+		if err != nil {
+			return err
+		}
+		if ancestor.ParentID == nil {
+			return nil
+		}
+		id = *ancestor.ParentID
+	}
+}
+
+// subtreeHeight returns the number of levels of the descendants of a contract, up to maxContractDepth.
+func subtreeHeight(tx *gorm.DB, contractID string) (int, error) {
+	ids := []string{contractID}
+	for height := 0; height < maxContractDepth; height++ {
+		var children []string
+		if res := tx.Model(&model.Contract{}).Where("parent_id IN ?", ids).Pluck("id", &children); res.Error != nil {
+			return 0, res.Error
+		}
+		if len(children) == 0 {
+			return height, nil
+		}
+		ids = children
+	}
+	return maxContractDepth, nil
+}
+
+// lockQuotaEnvelope locks a contract and its parent, so that the quotas of a contract and its children
+// are changed one at a time while checking them against each other. A contract is locked before its
+// parent, as SetParent does, so that the locks are always taken from descendants to ancestors.
+func lockQuotaEnvelope(tx *gorm.DB, contractID string) error {
+	contract, err := findContract(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractID)
+	if err != nil {
+		return err
+	}
+	if contract.ParentID == nil {
+		return nil
+	}
+	return lockContract(tx, *contract.ParentID)
+}
+
+// checkQuotaEnvelope returns an error if the quotas of the children or the regional quotas of a contract
+// exceed its quota, or if the contract has a parent and the quotas of the children of the parent exceed
+// the quota of the parent.
+func checkQuotaEnvelope(tx *gorm.DB, contractID string) error {
+	contract, err := findContract(tx, contractID)
+	if err != nil {
+		return err
+	}
+	if err := checkChildQuotas(tx, contractID); err != nil {
+		return err
+	}
+	if err := checkRegionalQuotas(tx, contractID); err != nil {
+		return err
+	}
+	if contract.ParentID != nil {
+		return checkChildQuotas(tx, *contract.ParentID)
+	}
+	return nil
+}
+
+// checkChildQuotas returns an error if the sum of the quotas of the children of a contract
+// exceeds its quota in any resource.
+func checkChildQuotas(tx *gorm.DB, contractID string) error {
+	quota, err := findQuota(tx, contractID)
+	if err != nil {
+		return err
+	}
+	allocated, err := sumChildQuotas(tx, contractID)
+	if err != nil {
+		return err
+	}
+	if name, q, a, ok := exceeded(quota, allocated); ok {
+		return fmt.Errorf("%w : %s of sub-contracts %d exceeds %d of contract %s",
+			ErrQuotaExceeded, name, a, q, contractID)
+	}
+	return nil
+}
+
+// exceeded returns the first resource in which allocated exceeds quota.
+func exceeded(quota model.ResourceQuota, allocated model.ResourceQuota) (name string, q int64, a int64, ok bool) {
+	for _, r := range []struct {
+		name      string
+		quota     int64
+		allocated int64
+	}{
+		{"cpu", quota.Cpu, allocated.Cpu},
+		{"memory", quota.Memory, allocated.Memory},
+		{"block", quota.Block, allocated.Block},
+		{"blockSsd", quota.BlockSsd, allocated.BlockSsd},
+		{"fs", quota.Fs, allocated.Fs},
+		{"fsSsd", quota.FsSsd, allocated.FsSsd},
+	} {
+		if r.allocated > r.quota {
+			return r.name, r.quota, r.allocated, true
+		}
+	}
+	return "", 0, 0, false
+}
+
+// sumChildQuotas returns the sum of the quotas of the children of a contract.
+func sumChildQuotas(db *gorm.DB, contractID string) (model.ResourceQuota, error) {
+	var sum model.ResourceQuota
+	res := db.Model(&model.ResourceQuota{}).
+		Select("COALESCE(SUM(cpu), 0) AS cpu, COALESCE(SUM(memory), 0) AS memory, "+
+			"COALESCE(SUM(block), 0) AS block, COALESCE(SUM(block_ssd), 0) AS block_ssd, "+
+			"COALESCE(SUM(fs), 0) AS fs, COALESCE(SUM(fs_ssd), 0) AS fs_ssd").
+		Joins("JOIN contracts ON contracts.id = contract_id").
+		Where("contracts.parent_id = ?", contractID).
+		Scan(&sum)
+	return sum, res.Error
+}
+
+// countChildren returns the number of the children of a contract.
+func countChildren(db *gorm.DB, contractID string) (int64, error) {
+	var n int64
+	res := db.Model(&model.Contract{}).Where("parent_id = ?", contractID).Count(&n)
+	return n, res.Error
+}
+
+func findContract(db *gorm.DB, contractID string) (model.Contract, error) {
+	var contract model.Contract
+	res := db.Limit(1).Find(&contract, "id = ?", contractID)
+	if res.RowsAffected == 0 || res.Error != nil {
+		return model.Contract{}, fmt.Errorf("Not found contract for %s", contractID)
+	}
+	return contract, nil
+}
+
+func findQuota(db *gorm.DB, contractID string) (model.ResourceQuota, error) {
+	var quota model.ResourceQuota
+	res := db.Limit(1).Find(&quota, "contract_id = ?", contractID)
+	if res.RowsAffected == 0 || res.Error != nil {
+		return model.ResourceQuota{}, fmt.Errorf("Not found quota for contract id %s", contractID)
+	}
+	return quota, nil
+}
+
+func lockContract(tx *gorm.DB, contractID string) error {
+	var contract model.Contract
+	res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&contract, "id = ?", contractID)
+	if res.RowsAffected == 0 || res.Error != nil {
+		return fmt.Errorf("Not found contract for %s", contractID)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+)
+
+func TestHierarchy(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	create := func(name string, quota *pb.ContractQuota) string {
+		id, err := accessor.Create(ctx, name, []string{}, quota, uuid.Nil, "")
+		if err != nil {
+			t.Fatalf("an error was unexpected while creating new contract: %s", err)
+		}
+		return id
+	}
+	master := create("hierarchy-master", &pb.ContractQuota{Cpu: 100, Memory: 400, Block: 10, BlockSsd: 10, Fs: 10, FsSsd: 10})
+	sales := create("hierarchy-sales", &pb.ContractQuota{Cpu: 60, Memory: 200})
+	dev := create("hierarchy-dev", &pb.ContractQuota{Cpu: 50, Memory: 100})
+	team := create("hierarchy-team", &pb.ContractQuota{Cpu: 10})
+
+	if _, err := accessor.SetParent(ctx, sales, master); err != nil {
+		t.Fatalf("an error was unexpected while setting parent %s", err)
+	}
+	if _, err := accessor.SetParent(ctx, dev, master); !errors.Is(err, contract.ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded for cpu 110 of 100, got %v", err)
+	}
+	if _, _, err := accessor.UpdateResourceQuota(ctx, dev, &pb.ContractQuota{Cpu: 40}); err != nil {
+		t.Fatalf("an error was unexpected while updating quota %s", err)
+	}
+	if _, err := accessor.SetParent(ctx, dev, master); err != nil {
+		t.Fatalf("an error was unexpected while setting parent %s", err)
+	}
+	if _, err := accessor.SetParent(ctx, team, dev); err != nil {
+		t.Fatalf("an error was unexpected while setting parent %s", err)
+	}
+
+	if _, _, err := accessor.UpdateResourceQuota(ctx, sales, &pb.ContractQuota{Cpu: 61}); !errors.Is(err, contract.ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded for a child exceeding the envelope, got %v", err)
+	}
+	if _, _, err := accessor.UpdateResourceQuota(ctx, master, &pb.ContractQuota{Cpu: 99}); !errors.Is(err, contract.ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded for a parent smaller than its children, got %v", err)
+	}
+	if _, err := accessor.SetParent(ctx, master, team); !errors.Is(err, contract.ErrInvalidParent) {
+		t.Errorf("expected ErrInvalidParent for a cycle, got %v", err)
+	}
+
+	children, allocated, err := accessor.ListChildren(ctx, master)
+	if err != nil || len(children) != 2 || allocated.Cpu != 100 || allocated.Memory != 300 {
+		t.Errorf("unexpected children %v and allocated %+v, err %v", children, allocated, err)
+	}
+	ancestors, err := accessor.GetAncestry(ctx, team)
+	if err != nil || len(ancestors) != 2 || ancestors[0].Contract.ID != dev || ancestors[1].Contract.ID != master {
+		t.Errorf("unexpected ancestry %v, err %v", ancestors, err)
+	}
+
+	if err := accessor.Delete(ctx, dev); !errors.Is(err, contract.ErrHasSubContracts) {
+		t.Errorf("expected ErrHasSubContracts, got %v", err)
+	}
+	prev, err := accessor.SetParent(ctx, team, "")
+	if err != nil || prev == nil || *prev != dev {
+		t.Errorf("unexpected previous parent %v, err %v", prev, err)
+	}
+	if err := accessor.Delete(ctx, dev); err != nil {
+		t.Errorf("an error was unexpected while deleting contract %s", err)
+	}
+
+	before, err := accessor.GetStatistics(ctx)
+	if err != nil {
+		t.Fatalf("an error was unexpected while getting statistics %s", err)
+	}
+	if _, err := accessor.SetParent(ctx, team, master); !errors.Is(err, contract.ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded for cpu 110 of 100, got %v", err)
+	}
+	if _, _, err := accessor.UpdateResourceQuota(ctx, sales, &pb.ContractQuota{Cpu: 50}); err != nil {
+		t.Fatalf("an error was unexpected while updating quota %s", err)
+	}
+	if _, err := accessor.SetParent(ctx, team, master); err != nil {
+		t.Fatalf("an error was unexpected while setting parent %s", err)
+	}
+	after, err := accessor.GetStatistics(ctx)
+	if err != nil {
+		t.Fatalf("an error was unexpected while getting statistics %s", err)
+	}
+	if after.Contracts != before.Contracts || after.Cpu != before.Cpu-10 {
+		t.Errorf("expected cpu of sub-contract excluded from statistics, got %+v before and %+v after", before, after)
+	}
+}
+
+func TestHierarchyDepth(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	// chain creates a chain of contracts, in which each contract is the parent of the next one
+	chain := func(name string, levels int) []string {
+		ids := []string{}
+		for i := 0; i < levels; i++ {
+			id, err := accessor.Create(ctx, name+"-"+uuid.New().String()[:8], []string{}, &pb.ContractQuota{}, uuid.Nil, "")
+			if err != nil {
+				t.Fatalf("an error was unexpected while creating new contract: %s", err)
+			}
+			if i > 0 {
+				if _, err := accessor.SetParent(ctx, id, ids[i-1]); err != nil {
+					t.Fatalf("an error was unexpected while setting parent %s", err)
+				}
+			}
+			ids = append(ids, id)
+		}
+		return ids
+	}
+	upper := chain("depth-upper", 4)
+	lower := chain("depth-lower", 5)
+
+	// the 5 levels of the moved subtree under the 4 levels would make 9 levels
+	if _, err := accessor.SetParent(ctx, lower[0], upper[3]); !errors.Is(err, contract.ErrInvalidParent) {
+		t.Errorf("expected ErrInvalidParent for a hierarchy deeper than 8, got %v", err)
+	}
+	if _, err := accessor.SetParent(ctx, lower[1], upper[3]); err != nil {
+		t.Errorf("an error was unexpected while moving a subtree of 4 levels under 4 levels %s", err)
+	}
+}
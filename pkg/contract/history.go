@@ -0,0 +1,140 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// Actions of contract history records.
+const (
+	HistoryCreated               = "created"
+	HistoryDeleted               = "deleted"
+	HistoryQuotaUpdated          = "quota_updated"
+	HistoryServicesUpdated       = "services_updated"
+	HistoryImported              = "imported"
+	HistoryLabelsUpdated         = "labels_updated"
+	HistoryRenewed               = "renewed"
+	HistoryExpired               = "expired"
+	HistoryParentUpdated         = "parent_updated"
+	HistoryPricePlanUpdated      = "price_plan_updated"
+	HistoryRegionalQuotasUpdated = "regional_quotas_updated"
+	HistoryCspAttached           = "csp_attached"
+	HistoryCspDetached           = "csp_detached"
+	HistoryDefaultChanged        = "default_changed"
+	HistoryProfileUpdated        = "profile_updated"
+)
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx which carries the user who makes changes.
+// The user is recorded as the actor of history records written with the context.
+func WithActor(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+func actorFromContext(ctx context.Context) uuid.UUID {
+	if ctx == nil {
+		return uuid.Nil
+	}
+	if userID, ok := ctx.Value(actorKey{}).(uuid.UUID); ok {
+		return userID
+	}
+	return uuid.Nil
+}
+
+// historyQuota is a snapshot of a resource quota in history records.
+type historyQuota struct {
+	Cpu      int64 `json:"cpu"`
+	Memory   int64 `json:"memory"`
+	Block    int64 `json:"block"`
+	BlockSsd int64 `json:"blockSsd"`
+	Fs       int64 `json:"fs"`
+	FsSsd    int64 `json:"fsSsd"`
+}
+
+// historyContract is a snapshot of a contract in history records.
+type historyContract struct {
+	ContractorName    string        `json:"contractorName"`
+	AvailableServices []string      `json:"availableServices"`
+	Description       string        `json:"description"`
+	Quota             *historyQuota `json:"quota,omitempty"`
+	PricePlan         string        `json:"pricePlan,omitempty"`
+}
+
+// historyServices is a snapshot of available services in history records.
+type historyServices struct {
+	AvailableServices []string `json:"availableServices"`
+}
+
+func newHistoryQuota(quota *pb.ContractQuota) *historyQuota {
+	return &historyQuota{
+		Cpu:      quota.Cpu,
+		Memory:   quota.Memory,
+		Block:    quota.Block,
+		BlockSsd: quota.BlockSsd,
+		Fs:       quota.Fs,
+		FsSsd:    quota.FsSsd,
+	}
+}
+
+func newHistoryContract(contract model.Contract, quota *pb.ContractQuota) *historyContract {
+	h := &historyContract{
+		ContractorName:    contract.ContractorName,
+		AvailableServices: contract.AvailableServices,
+		Description:       contract.Description,
+		PricePlan:         contract.PricePlan,
+	}
+	if quota != nil {
+		h.Quota = newHistoryQuota(quota)
+	}
+	return h
+}
+
+// recordHistory writes a history record in tx. The actor is taken from the context of tx.
+func recordHistory(tx *gorm.DB, contractID string, action string, prev interface{}, curr interface{}) error {
+	marshal := func(v interface{}) (string, error) {
+		if v == nil {
+			return "", nil
+		}
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+	p, err := marshal(prev)
+	if err != nil {
+		return err
+	}
+	c, err := marshal(curr)
+	if err != nil {
+		return err
+	}
+
+	res := tx.Create(&model.ContractHistory{
+		ContractID: contractID,
+		Action:     action,
+		Actor:      actorFromContext(tx.Statement.Context),
+		Previous:   p,
+		Current:    c,
+	})
+	if res.Error != nil {
+		return fmt.Errorf("could not record history for contract id %s: %s", contractID, res.Error)
+	}
+	return nil
+}
+
+// GetHistory returns changes of a contract, newest first.
+func (x *Accessor) GetHistory(ctx context.Context, contractID string, offset, limit int) ([]model.ContractHistory, error) {
+	var records []model.ContractHistory
+	res := x.db.WithContext(ctx).Order("created_at DESC").Offset(offset).Limit(limit).
+		Find(&records, "contract_id = ?", contractID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return records, nil
+}
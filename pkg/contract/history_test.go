@@ -0,0 +1,57 @@
+package contract_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+)
+
+func TestContractHistory(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Errorf("an error was unexpected while initilizing database %s", err)
+	}
+	creator := uuid.New()
+	actor := uuid.New()
+
+	id, err := accessor.Create(context.Background(), "history", []string{"lma"}, &pb.ContractQuota{Cpu: 8}, creator, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	ctx := contract.WithActor(context.Background(), actor)
+	if _, _, err := accessor.UpdateResourceQuota(ctx, id, &pb.ContractQuota{Cpu: 16}); err != nil {
+		t.Errorf("an error was unexpected while updating quota %s", err)
+	}
+	if _, _, err := accessor.UpdateAvailableServices(ctx, id, []string{"lma", "servicemesh"}); err != nil {
+		t.Errorf("an error was unexpected while updating services %s", err)
+	}
+
+	records, err := accessor.GetHistory(context.Background(), id, 0, 10)
+	if err != nil {
+		t.Fatalf("an error was unexpected while querying history %s", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 history records but got %d", len(records))
+	}
+	if records[0].Action != contract.HistoryServicesUpdated || records[0].Actor != actor {
+		t.Errorf("expected services update by %s but got %s by %s", actor, records[0].Action, records[0].Actor)
+	}
+	if records[1].Action != contract.HistoryQuotaUpdated {
+		t.Errorf("expected quota update but got %s", records[1].Action)
+	}
+	if records[2].Action != contract.HistoryCreated || records[2].Actor != creator {
+		t.Errorf("expected creation by %s but got %s by %s", creator, records[2].Action, records[2].Actor)
+	}
+
+	if err := accessor.Delete(context.Background(), id); err != nil {
+		t.Errorf("an error was unexpected while deleting contract %s", err)
+	}
+	records, err = accessor.GetHistory(context.Background(), id, 0, 1)
+	if err != nil || len(records) != 1 || records[0].Action != contract.HistoryDeleted {
+		t.Errorf("expected history of deletion to be kept (err: %v)", err)
+	}
+}
@@ -0,0 +1,314 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// Kinds of invoices.
+const (
+	InvoiceKindInvoice    = "invoice"
+	InvoiceKindCreditNote = "credit_note"
+)
+
+// ChargeCredit is the kind of the line of a credit note.
+const ChargeCredit = "credit"
+
+// periodFormat is the format of billing periods, which are calendar months in UTC.
+const periodFormat = "2006-01"
+
+var (
+	// ErrPeriodNotOver is returned on closing a billing period which is not over.
+	ErrPeriodNotOver = errors.New("billing period is not over")
+	// ErrCreditExceeded is returned if the credit notes of an invoice would exceed its total.
+	ErrCreditExceeded = errors.New("credit exceeds the balance of invoice")
+	// ErrNotInPeriod is returned on closing a billing period in which a contract did not exist.
+	ErrNotInPeriod = errors.New("contract does not exist in billing period")
+)
+
+// ParsePeriod parses a billing period in YYYY-MM, and returns its start in UTC.
+func ParsePeriod(s string) (time.Time, error) {
+	t, err := time.Parse(periodFormat, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid billing period %q, expected YYYY-MM", s)
+	}
+	return t, nil
+}
+
+// FormatPeriod returns the billing period which contains t in YYYY-MM.
+func FormatPeriod(t time.Time) string {
+	return monthStart(t).Format(periodFormat)
+}
+
+// PreviousPeriod returns the start of the billing period before the one which contains t.
+func PreviousPeriod(t time.Time) time.Time {
+	return monthStart(t).AddDate(0, -1, 0)
+}
+
+func invoiceNumber(contractID string, periodStart time.Time) string {
+	return fmt.Sprintf("INV-%s-%s", periodStart.Format("200601"), contractID)
+}
+
+// ClosePeriod issues the invoice of a contract for the billing period which starts at periodStart and
+// is over by now. If the invoice is already issued, it is returned with created false, so that
+// closing a period again, on any server, issues no other invoice. A contract which is deleted is
+// invoiced for its usage until the deletion.
+func (x *Accessor) ClosePeriod(ctx context.Context, contractID string, periodStart time.Time, now time.Time) (
+	invoice model.Invoice, created bool, err error) {
+	if !periodStart.Equal(monthStart(periodStart)) {
+		return model.Invoice{}, false, fmt.Errorf("billing period must start at the start of a month in UTC, not %s",
+			periodStart.Format(time.RFC3339))
+	}
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	if periodEnd.After(now) {
+		return model.Invoice{}, false, fmt.Errorf("%w : %s ends at %s", ErrPeriodNotOver,
+			FormatPeriod(periodStart), periodEnd.Format(time.RFC3339))
+	}
+	number := invoiceNumber(contractID, periodStart)
+	if existing, ok, err := findInvoice(x.db.WithContext(ctx), number); err != nil || ok {
+		return existing, false, err
+	}
+
+	contract, err := findContract(x.db.WithContext(ctx), contractID)
+	deleted := err != nil
+	if deleted {
+		// the contract may be deleted during or after the period
+		usage, uerr := x.GetUsage(ctx, contractID, periodStart, periodEnd)
+		if uerr != nil {
+			return model.Invoice{}, false, uerr
+		}
+		if len(usage) == 0 {
+			return model.Invoice{}, false, fmt.Errorf("%w : deleted contract %s is not used in billing period %s",
+				ErrNotInPeriod, contractID, FormatPeriod(periodStart))
+		}
+	} else if !contract.CreatedAt.Before(periodEnd) {
+		return model.Invoice{}, false, fmt.Errorf("%w : contract %s is created after billing period %s",
+			ErrNotInPeriod, contractID, FormatPeriod(periodStart))
+	}
+	charges, err := x.GetCharges(ctx, contractID, periodStart, periodEnd)
+	if err != nil {
+		return model.Invoice{}, false, err
+	}
+	invoice = model.Invoice{
+		Number:      number,
+		ContractID:  contractID,
+		Kind:        InvoiceKindInvoice,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Currency:    charges.Currency,
+		Lines:       model.InvoiceLines{},
+		Total:       charges.Total,
+	}
+	for _, l := range charges.Lines {
+		invoice.Lines = append(invoice.Lines, model.InvoiceLine{
+			Kind:      l.Kind,
+			Item:      l.Item,
+			PricePlan: l.PricePlan,
+			Quantity:  l.Quantity,
+			UnitPrice: l.UnitPrice,
+			From:      l.From,
+			To:        l.To,
+			Amount:    l.Amount,
+		})
+	}
+
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// the unique number of invoices still prevents a deleted contract from being invoiced twice
+		if !deleted {
+			if err := lockContract(tx, contractID); err != nil {
+				return err
+			}
+		}
+		existing, ok, err := findInvoice(tx, number)
+		if err != nil {
+			return err
+		}
+		if ok {
+			invoice = existing
+			return nil
+		}
+		if res := tx.Create(&invoice); res.Error != nil {
+			return fmt.Errorf("could not create invoice %s : %s", number, res.Error)
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return model.Invoice{}, false, err
+	}
+	if created {
+		log.Info("issued invoice ", number, " of ", invoice.Total, " ", invoice.Currency)
+	}
+	return invoice, created, nil
+}
+
+// CloseReport is the result of closing a billing period for every contract.
+type CloseReport struct {
+	// Issued are the invoices which are newly issued.
+	Issued []model.Invoice
+	// Failed are the errors of contracts whose invoices could not be issued.
+	Failed map[string]error
+}
+
+// CloseAllPeriods issues the invoices of every contract which exists in the billing period starting at
+// periodStart, including contracts which are deleted during or after the period. Contracts which already
+// have the invoices are skipped, and failures of a contract do not stop the others.
+func (x *Accessor) CloseAllPeriods(ctx context.Context, periodStart time.Time, now time.Time) (CloseReport, error) {
+	var ids, deleted []string
+	res := x.db.WithContext(ctx).Model(&model.Contract{}).
+		Where("created_at < ?", periodStart.AddDate(0, 1, 0)).Order("id").Pluck("id", &ids)
+	if res.Error != nil {
+		return CloseReport{}, res.Error
+	}
+	res = x.db.WithContext(ctx).Model(&model.ContractHistory{}).Distinct("contract_id").
+		Where("action = ? AND created_at >= ?", HistoryDeleted, periodStart).
+		Where("contract_id NOT IN (?)", x.db.Model(&model.Contract{}).Select("id")).
+		Order("contract_id").Pluck("contract_id", &deleted)
+	if res.Error != nil {
+		return CloseReport{}, res.Error
+	}
+	report := CloseReport{Failed: map[string]error{}}
+	for _, id := range append(ids, deleted...) {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		invoice, created, err := x.ClosePeriod(ctx, id, periodStart, now)
+		if errors.Is(err, ErrNotInPeriod) {
+			// a deleted contract which is created after the period
+			continue
+		}
+		if err != nil {
+			report.Failed[id] = err
+			continue
+		}
+		if created {
+			report.Issued = append(report.Issued, invoice)
+		}
+	}
+	return report, nil
+}
+
+// IssueCreditNote issues a credit note which credits amount of an invoice for reason. If amount is
+// empty, the remaining balance of the invoice is credited. The credit notes of an invoice never
+// exceed its total.
+func (x *Accessor) IssueCreditNote(ctx context.Context, invoiceID uuid.UUID, amount string, reason string) (model.Invoice, error) {
+	if reason == "" {
+		return model.Invoice{}, fmt.Errorf("reason of credit note must be specified")
+	}
+	var credit *big.Rat
+	if amount != "" {
+		if !priceRegexp.MatchString(amount) {
+			return model.Invoice{}, fmt.Errorf("invalid amount %q", amount)
+		}
+		credit, _ = new(big.Rat).SetString(amount)
+		if credit.Sign() <= 0 {
+			return model.Invoice{}, fmt.Errorf("amount must be positive")
+		}
+	}
+
+	var note model.Invoice
+	err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var invoice model.Invoice
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&invoice, "id = ?", invoiceID)
+		if res.RowsAffected == 0 || res.Error != nil {
+			return fmt.Errorf("Not found invoice %s", invoiceID)
+		}
+		if invoice.Kind != InvoiceKindInvoice {
+			return fmt.Errorf("%s is not an invoice but a %s", invoice.Number, invoice.Kind)
+		}
+		var notes []model.Invoice
+		if res := tx.Where("invoice_id = ?", invoiceID).Find(&notes); res.Error != nil {
+			return res.Error
+		}
+		balance, _ := new(big.Rat).SetString(invoice.Total)
+		for _, n := range notes {
+			total, _ := new(big.Rat).SetString(n.Total)
+			balance.Add(balance, total)
+		}
+		if credit == nil {
+			credit = balance
+		}
+		if credit.Sign() <= 0 || credit.Cmp(balance) > 0 {
+			return fmt.Errorf("%w : %s of %s, balance is %s", ErrCreditExceeded,
+				credit.FloatString(amountPrecision), invoice.Number, balance.FloatString(amountPrecision))
+		}
+
+		negative := new(big.Rat).Neg(credit).FloatString(amountPrecision)
+		note = model.Invoice{
+			Number:      fmt.Sprintf("CN-%s-%s-%d", invoice.PeriodStart.Format("200601"), invoice.ContractID, len(notes)+1),
+			ContractID:  invoice.ContractID,
+			Kind:        InvoiceKindCreditNote,
+			PeriodStart: invoice.PeriodStart,
+			PeriodEnd:   invoice.PeriodEnd,
+			Currency:    invoice.Currency,
+			Lines: model.InvoiceLines{{
+				Kind:      ChargeCredit,
+				Item:      invoice.Number,
+				Quantity:  1,
+				UnitPrice: negative,
+				From:      invoice.PeriodStart,
+				To:        invoice.PeriodEnd,
+				Amount:    negative,
+			}},
+			Total:     negative,
+			InvoiceID: &invoice.ID,
+			Reason:    reason,
+		}
+		if res := tx.Create(&note); res.Error != nil {
+			return fmt.Errorf("could not create credit note of %s : %s", invoice.Number, res.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Invoice{}, err
+	}
+	log.Info("issued credit note ", note.Number, " of ", note.Total, " ", note.Currency)
+	return note, nil
+}
+
+// GetInvoice returns an invoice or a credit note.
+func (x *Accessor) GetInvoice(ctx context.Context, invoiceID uuid.UUID) (model.Invoice, error) {
+	var invoice model.Invoice
+	res := x.db.WithContext(ctx).Limit(1).Find(&invoice, "id = ?", invoiceID)
+	if res.RowsAffected == 0 || res.Error != nil {
+		return model.Invoice{}, fmt.Errorf("Not found invoice %s", invoiceID)
+	}
+	return invoice, nil
+}
+
+// ListInvoices returns the invoices and credit notes ordered by billing period and issue time.
+// If contractID is not empty, only those of the contract are returned, and if periodStart is
+// not zero, only those of the billing period.
+func (x *Accessor) ListInvoices(ctx context.Context, contractID string, periodStart time.Time) ([]model.Invoice, error) {
+	db := x.db.WithContext(ctx).Order("period_start, created_at, number")
+	if contractID != "" {
+		db = db.Where("contract_id = ?", contractID)
+	}
+	if !periodStart.IsZero() {
+		db = db.Where("period_start = ?", periodStart)
+	}
+	var invoices []model.Invoice
+	if res := db.Find(&invoices); res.Error != nil {
+		return nil, res.Error
+	}
+	return invoices, nil
+}
+
+func findInvoice(db *gorm.DB, number string) (model.Invoice, bool, error) {
+	var invoice model.Invoice
+	res := db.Limit(1).Find(&invoice, "number = ?", number)
+	if res.Error != nil {
+		return model.Invoice{}, false, res.Error
+	}
+	return invoice, res.RowsAffected > 0, nil
+}
@@ -0,0 +1,153 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestParsePeriod(t *testing.T) {
+	start, err := contract.ParsePeriod("2026-06")
+	if err != nil || !start.Equal(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected start of period %s, err %v", start, err)
+	}
+	if _, err := contract.ParsePeriod("2026-06-01"); err == nil {
+		t.Errorf("expected an error for a date")
+	}
+	if p := contract.FormatPeriod(time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)); p != "2026-01" {
+		t.Errorf("expected period 2026-01 but got %s", p)
+	}
+	if p := contract.PreviousPeriod(time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)); !p.Equal(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected previous period 2025-12 but got %s", p)
+	}
+}
+
+func TestInvoice(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	planName := "test-" + uuid.New().String()[:8]
+	if _, err := accessor.CreatePricePlan(ctx, model.PricePlan{
+		Name: planName, EffectiveFrom: time.Now().AddDate(-1, 0, 0), Currency: "KRW",
+		ResourcePrices: model.StringMap{"cpu": "3000"}, ServicePrices: model.StringMap{},
+	}); err != nil {
+		t.Fatalf("an error was unexpected while creating price plan %s", err)
+	}
+	contractID, err := accessor.Create(ctx, "invoice", []string{}, &pb.ContractQuota{Cpu: 4}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	if _, err := accessor.SetPricePlan(ctx, contractID, planName); err != nil {
+		t.Fatalf("an error was unexpected while setting price plan %s", err)
+	}
+
+	// close the current period as of a time after it
+	period, _ := contract.ParsePeriod(contract.FormatPeriod(time.Now()))
+	if _, _, err := accessor.ClosePeriod(ctx, contractID, period, time.Now()); !errors.Is(err, contract.ErrPeriodNotOver) {
+		t.Errorf("expected ErrPeriodNotOver but got %v", err)
+	}
+	later := period.AddDate(0, 2, 0)
+	invoice, created, err := accessor.ClosePeriod(ctx, contractID, period, later)
+	if err != nil || !created {
+		t.Fatalf("expected an invoice to be issued, created %v, err %v", created, err)
+	}
+	if invoice.Kind != contract.InvoiceKindInvoice || invoice.Currency != "KRW" || len(invoice.Lines) == 0 || invoice.Total == "0.00" {
+		t.Errorf("unexpected invoice %+v", invoice)
+	}
+	again, created, err := accessor.ClosePeriod(ctx, contractID, period, later)
+	if err != nil || created || again.ID != invoice.ID {
+		t.Errorf("expected the invoice not to be issued again, got %+v, created %v, err %v", again, created, err)
+	}
+
+	if _, err := accessor.IssueCreditNote(ctx, invoice.ID, "1.00", ""); err == nil {
+		t.Errorf("expected an error for a credit note without reason")
+	}
+	note, err := accessor.IssueCreditNote(ctx, invoice.ID, "1.00", "correction")
+	if err != nil || note.Total != "-1.00" || note.InvoiceID == nil || *note.InvoiceID != invoice.ID {
+		t.Fatalf("unexpected credit note %+v, err %v", note, err)
+	}
+	if _, err := accessor.IssueCreditNote(ctx, note.ID, "1.00", "correction"); err == nil {
+		t.Errorf("expected an error for a credit note of a credit note")
+	}
+	if _, err := accessor.IssueCreditNote(ctx, invoice.ID, invoice.Total, "correction"); !errors.Is(err, contract.ErrCreditExceeded) {
+		t.Errorf("expected ErrCreditExceeded but got %v", err)
+	}
+	if _, err := accessor.IssueCreditNote(ctx, invoice.ID, "", "full refund"); err != nil {
+		t.Errorf("an error was unexpected while crediting the balance %s", err)
+	}
+	if _, err := accessor.IssueCreditNote(ctx, invoice.ID, "", "full refund"); !errors.Is(err, contract.ErrCreditExceeded) {
+		t.Errorf("expected ErrCreditExceeded for an invoice without balance but got %v", err)
+	}
+
+	invoices, err := accessor.ListInvoices(ctx, contractID, period)
+	if err != nil || len(invoices) != 3 || invoices[0].ID != invoice.ID {
+		t.Errorf("expected the invoice and 2 credit notes, got %+v, err %v", invoices, err)
+	}
+
+	// a deleted contract is invoiced for its usage until the deletion
+	deletedID, err := accessor.Create(ctx, "invoice-deleted", []string{}, &pb.ContractQuota{Cpu: 4000}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	if _, err := accessor.SetPricePlan(ctx, deletedID, planName); err != nil {
+		t.Fatalf("an error was unexpected while setting price plan %s", err)
+	}
+	if err := accessor.Delete(ctx, deletedID); err != nil {
+		t.Fatalf("an error was unexpected while deleting contract %s", err)
+	}
+	invoice, created, err = accessor.ClosePeriod(ctx, deletedID, period, later)
+	if err != nil || !created || invoice.Total == "0.00" {
+		t.Errorf("expected an invoice of the deleted contract, got %+v, created %v, err %v", invoice, created, err)
+	}
+	if _, _, err := accessor.ClosePeriod(ctx, deletedID, period.AddDate(0, 1, 0), later); !errors.Is(err, contract.ErrNotInPeriod) {
+		t.Errorf("expected ErrNotInPeriod after the deletion but got %v", err)
+	}
+}
+
+func TestInvoiceIssuedInCores(t *testing.T) {
+	db, err := getDB()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	accessor := contract.New(db)
+	ctx := context.Background()
+
+	// an invoice issued before cpu quotas were stored in millicores is kept as it was issued
+	period, _ := contract.ParsePeriod("2020-01")
+	issued := model.Invoice{
+		Number: "INV-202001-" + uuid.New().String()[:8], ContractID: "P0000none", Kind: contract.InvoiceKindInvoice,
+		PeriodStart: period, PeriodEnd: period.AddDate(0, 1, 0), Currency: "KRW", Total: "12000.00",
+		Lines: model.InvoiceLines{
+			{Kind: "resource", Item: "cpu", Quantity: 4, UnitPrice: "3000", Amount: "12000.00"},
+			{Kind: "resource", Item: "memory", Quantity: 16, UnitPrice: "0", Amount: "0.00"},
+		},
+		CpuUnit: model.CpuUnitCores,
+	}
+	if res := db.Create(&issued); res.Error != nil {
+		t.Fatalf("an error was unexpected while creating invoice %s", res.Error)
+	}
+
+	invoice, err := accessor.GetInvoice(ctx, issued.ID)
+	if err != nil {
+		t.Fatalf("an error was unexpected while getting invoice %s", err)
+	}
+	if invoice.Lines[0].Quantity != 4000 || invoice.Lines[1].Quantity != 16 {
+		t.Errorf("expected the cpu quantity in millicores, got %+v", invoice.Lines)
+	}
+	var stored model.Invoice
+	if res := db.Raw("SELECT lines FROM invoices WHERE id = ?", issued.ID).Scan(&stored); res.Error != nil {
+		t.Fatalf("an error was unexpected while reading invoice %s", res.Error)
+	}
+	if stored.Lines[0].Quantity != 4 {
+		t.Errorf("expected the stored lines to be unchanged, got %+v", stored.Lines)
+	}
+}
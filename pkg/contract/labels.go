@@ -0,0 +1,339 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// Labels and annotations follow the syntax of Kubernetes.
+const (
+	maxLabelNameLength   = 63
+	maxLabelPrefixLength = 253
+	maxAnnotationsSize   = 256 * 1024
+	labelNameFmt         = `[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?`
+	labelPrefixFmt       = `[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*`
+)
+
+// Operators of selector requirements.
+const (
+	OpExists       = "exists"
+	OpDoesNotExist = "!"
+	OpEquals       = "="
+	OpNotEquals    = "!="
+	OpIn           = "in"
+	OpNotIn        = "notin"
+)
+
+var (
+	labelNameRegexp   = regexp.MustCompile(`^` + labelNameFmt + `$`)
+	labelPrefixRegexp = regexp.MustCompile(`^` + labelPrefixFmt + `$`)
+	setRequirement    = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// ValidateLabelKey returns an error if key is not a valid label or annotation key,
+// which is a name with an optional DNS subdomain prefix such as example.com/name.
+func ValidateLabelKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) > maxLabelPrefixLength || !labelPrefixRegexp.MatchString(prefix) {
+			return fmt.Errorf("invalid prefix of key %q", key)
+		}
+	}
+	if len(name) > maxLabelNameLength || !labelNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	return nil
+}
+
+// ValidateLabelValue returns an error if value is not a valid label value.
+func ValidateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxLabelNameLength || !labelNameRegexp.MatchString(value) {
+		return fmt.Errorf("invalid label value %q", value)
+	}
+	return nil
+}
+
+// ValidateLabels returns an error if a key or value of labels is invalid.
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if err := ValidateLabelKey(k); err != nil {
+			return err
+		}
+		if err := ValidateLabelValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateAnnotations returns an error if a key of annotations is invalid or they are too large.
+// Values of annotations are free text.
+func ValidateAnnotations(annotations map[string]string) error {
+	size := 0
+	for k, v := range annotations {
+		if err := ValidateLabelKey(k); err != nil {
+			return err
+		}
+		size += len(k) + len(v)
+	}
+	if size > maxAnnotationsSize {
+		return fmt.Errorf("annotations are larger than %d bytes", maxAnnotationsSize)
+	}
+	return nil
+}
+
+// Requirement is a condition on a label of a selector.
+type Requirement struct {
+	Key string
+	// Operator is one of the Op constants.
+	Operator string
+	Values   []string
+}
+
+// Selector selects contracts whose labels satisfy all requirements. An empty selector selects all.
+type Selector []Requirement
+
+// ParseSelector parses a label selector with the syntax of Kubernetes, e.g.
+// "region=kr-central,tier in (gold,silver),!deprecated".
+func ParseSelector(s string) (Selector, error) {
+	var selector Selector
+	for _, part := range splitRequirements(s) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			if strings.TrimSpace(s) == "" {
+				break
+			}
+			return nil, fmt.Errorf("empty requirement in selector %q", s)
+		}
+		r, err := parseRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, r)
+	}
+	return selector, nil
+}
+
+// splitRequirements splits s by commas which are not in parentheses.
+func splitRequirements(s string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func parseRequirement(s string) (Requirement, error) {
+	var r Requirement
+	if m := setRequirement.FindStringSubmatch(s); m != nil {
+		r = Requirement{Key: m[1], Operator: m[2]}
+		for _, v := range strings.Split(m[3], ",") {
+			r.Values = append(r.Values, strings.TrimSpace(v))
+		}
+	} else if strings.HasPrefix(s, "!") && !strings.Contains(s, "=") {
+		r = Requirement{Key: strings.TrimSpace(s[1:]), Operator: OpDoesNotExist}
+	} else if i := strings.Index(s, "!="); i >= 0 {
+		r = Requirement{Key: strings.TrimSpace(s[:i]), Operator: OpNotEquals, Values: []string{strings.TrimSpace(s[i+2:])}}
+	} else if i := strings.Index(s, "=="); i >= 0 {
+		r = Requirement{Key: strings.TrimSpace(s[:i]), Operator: OpEquals, Values: []string{strings.TrimSpace(s[i+2:])}}
+	} else if i := strings.Index(s, "="); i >= 0 {
+		r = Requirement{Key: strings.TrimSpace(s[:i]), Operator: OpEquals, Values: []string{strings.TrimSpace(s[i+1:])}}
+	} else {
+		r = Requirement{Key: s, Operator: OpExists}
+	}
+
+	if err := ValidateLabelKey(r.Key); err != nil {
+		return r, fmt.Errorf("invalid requirement %q : %s", s, err)
+	}
+	for _, v := range r.Values {
+		if err := ValidateLabelValue(v); err != nil {
+			return r, fmt.Errorf("invalid requirement %q : %s", s, err)
+		}
+	}
+	return r, nil
+}
+
+// String returns the selector in the syntax of ParseSelector.
+func (s Selector) String() string {
+	var parts []string
+	for _, r := range s {
+		switch r.Operator {
+		case OpExists:
+			parts = append(parts, r.Key)
+		case OpDoesNotExist:
+			parts = append(parts, "!"+r.Key)
+		case OpIn, OpNotIn:
+			parts = append(parts, fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ",")))
+		default:
+			parts = append(parts, r.Key+r.Operator+r.Values[0])
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Matches returns true if labels satisfy all requirements of s.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		v, ok := labels[r.Key]
+		switch r.Operator {
+		case OpExists:
+			if !ok {
+				return false
+			}
+		case OpDoesNotExist:
+			if ok {
+				return false
+			}
+		case OpEquals, OpIn:
+			if !ok || !contains(r.Values, v) {
+				return false
+			}
+		case OpNotEquals, OpNotIn:
+			if ok && contains(r.Values, v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// apply adds the requirements of s to the conditions of db. The operators are containment (@>)
+// and existence (?) on the labels column, so that the GIN index on it is used.
+func (s Selector) apply(db *gorm.DB) *gorm.DB {
+	for _, r := range s {
+		var values []interface{}
+		var conds []string
+		for _, v := range r.Values {
+			b, _ := json.Marshal(map[string]string{r.Key: v})
+			values = append(values, string(b))
+			conds = append(conds, "contracts.labels @> ?::jsonb")
+		}
+		containsAny := "(" + strings.Join(conds, " OR ") + ")"
+		// The key is validated to have no quotes, and is written as a literal because
+		// the existence operator is the placeholder of gorm.
+		exists := "contracts.labels ? '" + r.Key + "'"
+
+		switch r.Operator {
+		case OpExists:
+			db = db.Clauses(clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: exists}}})
+		case OpDoesNotExist:
+			db = db.Clauses(clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "NOT " + exists}}})
+		case OpEquals, OpIn:
+			db = db.Clauses(clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: containsAny, Vars: values}}})
+		case OpNotEquals, OpNotIn:
+			db = db.Clauses(clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "NOT " + containsAny, Vars: values}}})
+		}
+	}
+	return db
+}
+
+// GetLabels returns the labels and annotations of a contract.
+func (x *Accessor) GetLabels(ctx context.Context, contractID string) (labels map[string]string, annotations map[string]string, err error) {
+	var contract model.Contract
+	res := x.db.WithContext(ctx).Select("labels", "annotations").Limit(1).Find(&contract, "id = ?", contractID)
+	if res.RowsAffected == 0 || res.Error != nil {
+		return nil, nil, fmt.Errorf("Not found contract for %s", contractID)
+	}
+	return contract.Labels, contract.Annotations, nil
+}
+
+// SetLabels sets and removes labels and annotations of a contract, and returns the result.
+func (x *Accessor) SetLabels(ctx context.Context, contractID string, setLabels map[string]string, removeLabels []string,
+	setAnnotations map[string]string, removeAnnotations []string) (labels map[string]string, annotations map[string]string, err error) {
+	if err := ValidateLabels(setLabels); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateAnnotations(setAnnotations); err != nil {
+		return nil, nil, err
+	}
+
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var contract model.Contract
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&contract, "id = ?", contractID)
+		if res.RowsAffected == 0 || res.Error != nil {
+			return fmt.Errorf("Not found contract for %s", contractID)
+		}
+		prev := historyLabels{Labels: copyMap(contract.Labels), Annotations: copyMap(contract.Annotations)}
+		labels = merge(contract.Labels, setLabels, removeLabels)
+		annotations = merge(contract.Annotations, setAnnotations, removeAnnotations)
+		if err := ValidateAnnotations(annotations); err != nil {
+			return err
+		}
+
+		res = tx.Model(&model.Contract{}).Where("id = ?", contractID).Updates(map[string]interface{}{
+			"labels":      model.StringMap(labels),
+			"annotations": model.StringMap(annotations),
+		})
+		if res.Error != nil {
+			return fmt.Errorf("could not update labels for contract id %s: %s", contractID, res.Error)
+		}
+		return recordHistory(tx, contractID, HistoryLabelsUpdated, prev, historyLabels{Labels: labels, Annotations: annotations})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	x.invalidate(ctx, contractID)
+	return labels, annotations, nil
+}
+
+// historyLabels is a snapshot of labels and annotations in history records.
+type historyLabels struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// merge returns a copy of m with set and without the keys of remove.
+func merge(m map[string]string, set map[string]string, remove []string) map[string]string {
+	result := copyMap(m)
+	for _, k := range remove {
+		delete(result, k)
+	}
+	for k, v := range set {
+		result[k] = v
+	}
+	return result
+}
+
+func copyMap(m map[string]string) map[string]string {
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package contract_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+)
+
+func TestParseSelector(t *testing.T) {
+	labels := map[string]string{"region": "kr-central", "tier": "gold", "example.com/owner": "kim"}
+	testCases := []struct {
+		selector string
+		valid    bool
+		matches  bool
+	}{
+		{"", true, true},
+		{"region=kr-central", true, true},
+		{"region==kr-central", true, true},
+		{"region != kr-west", true, true},
+		{"region!=kr-central", true, false},
+		{"tier in (gold, silver)", true, true},
+		{"tier notin (gold,silver)", true, false},
+		{"example.com/owner", true, true},
+		{"!deprecated", true, true},
+		{"region=kr-central,!tier", true, false},
+		{"region=kr-central,tier in (gold,silver),example.com/owner=kim", true, true},
+		{"region=kr central", false, false},
+		{"region=kr-central,", false, false},
+		{"'region'", false, false},
+	}
+	for _, tc := range testCases {
+		selector, err := contract.ParseSelector(tc.selector)
+		if (err == nil) != tc.valid {
+			t.Errorf("selector %q: expected valid %t but got error %v", tc.selector, tc.valid, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := selector.Matches(labels); got != tc.matches {
+			t.Errorf("selector %q: expected match %t but got %t", tc.selector, tc.matches, got)
+		}
+		if again, err := contract.ParseSelector(selector.String()); err != nil || again.String() != selector.String() {
+			t.Errorf("selector %q: expected %q to be parsed again, err %v", tc.selector, selector.String(), err)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	gold, err := accessor.Create(ctx, "labels-gold", []string{}, &pb.ContractQuota{}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	silver, err := accessor.Create(ctx, "labels-silver", []string{}, &pb.ContractQuota{}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+
+	if _, _, err := accessor.SetLabels(ctx, gold, map[string]string{"tier": "gold", "region": "kr-central"}, nil,
+		map[string]string{"salesOwner": "kim"}, nil); err != nil {
+		t.Fatalf("an error was unexpected while setting labels %s", err)
+	}
+	labels, annotations, err := accessor.SetLabels(ctx, silver, map[string]string{"tier": "silver", "region": "kr-west"}, nil, nil, nil)
+	if err != nil || labels["tier"] != "silver" || len(annotations) != 0 {
+		t.Fatalf("unexpected labels %v and annotations %v, err %v", labels, annotations, err)
+	}
+	labels, _, err = accessor.SetLabels(ctx, silver, nil, []string{"region"}, nil, nil)
+	if err != nil || len(labels) != 1 {
+		t.Fatalf("expected region to be removed but got %v, err %v", labels, err)
+	}
+	if _, _, err := accessor.SetLabels(ctx, silver, map[string]string{"bad key": "v"}, nil, nil, nil); err == nil {
+		t.Errorf("expected an error for invalid label key")
+	}
+
+	testCases := []struct {
+		selector string
+		expected []string
+	}{
+		{"tier=gold", []string{gold}},
+		{"tier in (gold,silver)", []string{gold, silver}},
+		{"tier notin (gold),tier", []string{silver}},
+		{"region", []string{gold}},
+		{"tier,!region", []string{silver}},
+		{"region!=kr-central,tier", []string{silver}},
+	}
+	for _, tc := range testCases {
+		selector, err := contract.ParseSelector(tc.selector)
+		if err != nil {
+			t.Fatalf("an error was unexpected while parsing %q: %s", tc.selector, err)
+		}
+		contracts, err := accessor.List(ctx, 0, 100, selector)
+		if err != nil {
+			t.Fatalf("an error was unexpected while listing contracts for %q: %s", tc.selector, err)
+		}
+		var ids []string
+		for _, c := range contracts {
+			if c.ContractId == gold || c.ContractId == silver {
+				ids = append(ids, c.ContractId)
+			}
+		}
+		if len(ids) != len(tc.expected) {
+			t.Errorf("selector %q: expected %v but got %v", tc.selector, tc.expected, ids)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package contract
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract/quantity"
+)
+
+// Labels and annotations of rendered Kubernetes objects.
+const (
+	ManifestContractIdLabel    = "tks.openinfradev.github.io/contract-id"
+	ManifestServicesAnnotation = "tks.openinfradev.github.io/services"
+	manifestManagedByLabel     = "app.kubernetes.io/managed-by"
+	manifestManagedBy          = "tks-contract"
+)
+
+// Files of rendered manifests in the manifest directory of a contract repository.
+const (
+	ResourceQuotaFile = "resource-quota.yaml"
+	LimitRangeFile    = "limit-range.yaml"
+	KustomizationFile = "kustomization.yaml"
+)
+
+// ManifestMapping configures how the quota of a contract is rendered into Kubernetes manifests.
+type ManifestMapping struct {
+	// Resources maps quota dimensions to the resources of ResourceQuota, such as block_ssd to
+	// ssd.storageclass.storage.k8s.io/requests.storage. The quotas of dimensions which are mapped to
+	// the same resource are added up, and dimensions which are not mapped are not rendered.
+	Resources map[string][]string
+	// DefaultLimits and DefaultRequests are the defaults of containers in LimitRange, so that pods
+	// without limits and requests are admitted under ResourceQuota. LimitRange is not rendered if both are empty.
+	DefaultLimits   map[string]string
+	DefaultRequests map[string]string
+	// Directory is the directory of the manifests in the GitOps repository of a contract.
+	Directory string
+}
+
+// Manifest is a rendered Kubernetes manifest.
+type Manifest struct {
+	// Path is the path of the manifest in the GitOps repository of the contract.
+	Path    string
+	Kind    string
+	Content []byte
+}
+
+// ValidateManifestMapping returns an error if a mapping cannot be rendered.
+func ValidateManifestMapping(m ManifestMapping) error {
+	units := map[string]string{}
+	for dimension, resources := range m.Resources {
+		unit, err := quantity.UnitOf(dimension)
+		if err != nil {
+			return err
+		}
+		for _, resource := range resources {
+			if err := ValidateLabelKey(resource); err != nil {
+				return fmt.Errorf("invalid resource %q of %s : %s", resource, dimension, err)
+			}
+			if u, ok := units[resource]; ok && u != unit.Name {
+				return fmt.Errorf("resource %s is mapped from dimensions in %s and %s", resource, u, unit.Name)
+			}
+			units[resource] = unit.Name
+		}
+	}
+	for _, values := range []map[string]string{m.DefaultLimits, m.DefaultRequests} {
+		for resource, v := range values {
+			if err := ValidateLabelKey(resource); err != nil {
+				return fmt.Errorf("invalid resource %q of LimitRange : %s", resource, err)
+			}
+			if err := quantity.Validate(v); err != nil {
+				return fmt.Errorf("invalid default of %s : %s", resource, err)
+			}
+		}
+	}
+	if path.IsAbs(m.Directory) || strings.HasPrefix(path.Clean(m.Directory), "..") {
+		return fmt.Errorf("manifest directory %s must be relative to the repository", m.Directory)
+	}
+	return nil
+}
+
+// k8sObject is a Kubernetes object in a manifest.
+type k8sObject struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   k8sMetadata `yaml:"metadata"`
+	Spec       interface{} `yaml:"spec"`
+}
+
+type k8sMetadata struct {
+	Name        string            `yaml:"name"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type resourceQuotaSpec struct {
+	Hard map[string]string `yaml:"hard"`
+}
+
+type limitRangeSpec struct {
+	Limits []limitRangeItem `yaml:"limits"`
+}
+
+type limitRangeItem struct {
+	Type           string            `yaml:"type"`
+	Default        map[string]string `yaml:"default,omitempty"`
+	DefaultRequest map[string]string `yaml:"defaultRequest,omitempty"`
+}
+
+type kustomization struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Resources  []string `yaml:"resources"`
+}
+
+// RenderManifests renders the quota and services of a contract into ResourceQuota and LimitRange,
+// with a kustomization of them, in the manifest directory of the mapping.
+func RenderManifests(contract *pb.Contract, m ManifestMapping) ([]Manifest, error) {
+	if err := ValidateManifestMapping(m); err != nil {
+		return nil, err
+	}
+	metadata := func(name string) k8sMetadata {
+		md := k8sMetadata{
+			Name: name,
+			Labels: map[string]string{
+				manifestManagedByLabel:  manifestManagedBy,
+				ManifestContractIdLabel: contract.GetContractId(),
+			},
+		}
+		if len(contract.GetAvailableServices()) > 0 {
+			services := append([]string{}, contract.GetAvailableServices()...)
+			sort.Strings(services)
+			md.Annotations = map[string]string{ManifestServicesAnnotation: strings.Join(services, ",")}
+		}
+		return md
+	}
+
+	q := contract.GetQuota()
+	values := map[string]int64{
+		quantity.Cpu:      q.GetCpu(),
+		quantity.Memory:   q.GetMemory(),
+		quantity.Block:    q.GetBlock(),
+		quantity.BlockSsd: q.GetBlockSsd(),
+		quantity.Fs:       q.GetFs(),
+		quantity.FsSsd:    q.GetFsSsd(),
+	}
+	sums := map[string]int64{}
+	dimensions := map[string]string{}
+	for dimension, resources := range m.Resources {
+		for _, resource := range resources {
+			sums[resource] += values[dimension]
+			dimensions[resource] = dimension
+		}
+	}
+	hard := map[string]string{}
+	for resource, v := range sums {
+		hard[resource] = quantity.Format(dimensions[resource], v)
+	}
+
+	type manifestObject struct {
+		file   string
+		object k8sObject
+	}
+	objects := []manifestObject{{
+		file: ResourceQuotaFile,
+		object: k8sObject{
+			APIVersion: "v1", Kind: "ResourceQuota", Metadata: metadata("tks-contract-quota"),
+			Spec: resourceQuotaSpec{Hard: hard},
+		},
+	}}
+	if len(m.DefaultLimits) > 0 || len(m.DefaultRequests) > 0 {
+		objects = append(objects, manifestObject{
+			file: LimitRangeFile,
+			object: k8sObject{
+				APIVersion: "v1", Kind: "LimitRange", Metadata: metadata("tks-contract-limits"),
+				Spec: limitRangeSpec{Limits: []limitRangeItem{{
+					Type: "Container", Default: m.DefaultLimits, DefaultRequest: m.DefaultRequests,
+				}}},
+			},
+		})
+	}
+
+	var manifests []Manifest
+	k := kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+	for _, o := range objects {
+		b, err := encodeManifest(o.object)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, Manifest{Path: path.Join(m.Directory, o.file), Kind: o.object.Kind, Content: b})
+		k.Resources = append(k.Resources, o.file)
+	}
+	b, err := encodeManifest(k)
+	if err != nil {
+		return nil, err
+	}
+	return append(manifests, Manifest{Path: path.Join(m.Directory, KustomizationFile), Kind: k.Kind, Content: b}), nil
+}
+
+func encodeManifest(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,90 @@
+package contract_test
+
+import (
+	"strings"
+	"testing"
+
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+)
+
+func TestRenderManifests(t *testing.T) {
+	mapping := contract.ManifestMapping{
+		Resources: map[string][]string{
+			"cpu":       {"requests.cpu", "limits.cpu"},
+			"memory":    {"requests.memory"},
+			"block":     {"requests.storage"},
+			"block_ssd": {"ssd.storageclass.storage.k8s.io/requests.storage", "requests.storage"},
+		},
+		DefaultRequests: map[string]string{"cpu": "100m", "memory": "128Mi"},
+		Directory:       "quota",
+	}
+	c := &pb.Contract{
+		ContractId:        "P0123abcd",
+		AvailableServices: []string{"servicemesh", "lma"},
+		Quota:             &pb.ContractQuota{Cpu: 64500, Memory: 256, Block: 512, BlockSsd: 512, Fs: 100},
+	}
+	manifests, err := contract.RenderManifests(c, mapping)
+	if err != nil {
+		t.Fatalf("an error was unexpected while rendering manifests %s", err)
+	}
+	if len(manifests) != 3 || manifests[0].Path != "quota/resource-quota.yaml" || manifests[1].Kind != "LimitRange" ||
+		manifests[2].Path != "quota/kustomization.yaml" {
+		t.Fatalf("unexpected manifests %+v", manifests)
+	}
+
+	var quota struct {
+		Metadata struct {
+			Labels      map[string]string `yaml:"labels"`
+			Annotations map[string]string `yaml:"annotations"`
+		} `yaml:"metadata"`
+		Spec struct {
+			Hard map[string]string `yaml:"hard"`
+		} `yaml:"spec"`
+	}
+	if err := yaml.Unmarshal(manifests[0].Content, &quota); err != nil {
+		t.Fatalf("an error was unexpected while decoding ResourceQuota %s", err)
+	}
+	want := map[string]string{
+		"requests.cpu": "64500m", "limits.cpu": "64500m", "requests.memory": "256Gi", "requests.storage": "1Ti",
+		"ssd.storageclass.storage.k8s.io/requests.storage": "512Gi",
+	}
+	for resource, v := range want {
+		if quota.Spec.Hard[resource] != v {
+			t.Errorf("expected %s of %s but got %s", v, resource, quota.Spec.Hard[resource])
+		}
+	}
+	if len(quota.Spec.Hard) != len(want) {
+		t.Errorf("unexpected resources %v", quota.Spec.Hard)
+	}
+	if quota.Metadata.Labels[contract.ManifestContractIdLabel] != "P0123abcd" ||
+		quota.Metadata.Annotations[contract.ManifestServicesAnnotation] != "lma,servicemesh" {
+		t.Errorf("unexpected metadata %+v", quota.Metadata)
+	}
+	if !strings.Contains(string(manifests[2].Content), "- resource-quota.yaml\n  - limit-range.yaml") {
+		t.Errorf("unexpected kustomization %s", manifests[2].Content)
+	}
+
+	// LimitRange is not rendered without defaults
+	manifests, err = contract.RenderManifests(c, contract.ManifestMapping{Resources: mapping.Resources})
+	if err != nil || len(manifests) != 2 || manifests[0].Path != "resource-quota.yaml" {
+		t.Errorf("unexpected manifests %+v, err %v", manifests, err)
+	}
+}
+
+func TestValidateManifestMapping(t *testing.T) {
+	for _, m := range []contract.ManifestMapping{
+		{Resources: map[string][]string{"gpu": {"requests.nvidia.com/gpu"}}},
+		{Resources: map[string][]string{"cpu": {"Requests CPU"}}},
+		{Resources: map[string][]string{"cpu": {"requests.storage"}, "fs": {"requests.storage"}}},
+		{DefaultLimits: map[string]string{"cpu": "a lot"}},
+		{Directory: "../quota"},
+		{Directory: "/quota"},
+	} {
+		if err := contract.ValidateManifestMapping(m); err == nil {
+			t.Errorf("expected an error for %+v", m)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+)
+
+// Role is a role of a member in a contract.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleViewer Role = "viewer"
+)
+
+// ErrLastOwner is returned on removing or demoting the only owner of a contract.
+var ErrLastOwner = errors.New("the only owner of a contract cannot be removed or demoted")
+
+// ValidateRole returns an error if role is not a known contract role.
+func ValidateRole(role Role) error {
+	switch role {
+	case RoleOwner, RoleAdmin, RoleViewer:
+		return nil
+	}
+	return fmt.Errorf("invalid role %s", role)
+}
+
+// AddMember adds a user to a contract or changes the role of an existing member.
+// The only owner of a contract cannot be demoted.
+func (x *Accessor) AddMember(ctx context.Context, contractID string, userID uuid.UUID, role Role) error {
+	if err := ValidateRole(role); err != nil {
+		return err
+	}
+	if userID == uuid.Nil {
+		return fmt.Errorf("user id must be specified")
+	}
+
+	return x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := findContract(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractID); err != nil {
+			return err
+		}
+		if role != RoleOwner {
+			if err := checkLastOwner(tx, contractID, userID); err != nil {
+				return err
+			}
+		}
+		return addMember(tx, contractID, userID, role)
+	})
+}
+
+// RemoveMember removes a user from a contract. The only owner of a contract cannot be removed.
+func (x *Accessor) RemoveMember(ctx context.Context, contractID string, userID uuid.UUID) error {
+	err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := findContract(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractID); err != nil {
+			return err
+		}
+		if err := checkLastOwner(tx, contractID, userID); err != nil {
+			return err
+		}
+		res := tx.Delete(&model.ContractMember{}, "contract_id = ? AND user_id = ?", contractID, userID)
+		if res.Error != nil {
+			return fmt.Errorf("could not delete member %s for contract id %s", userID, contractID)
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("Not found member %s for contract id %s", userID, contractID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("member is removed! contractId : ", contractID, ", userId : ", userID)
+	return nil
+}
+
+// ListMembers returns the members of a contract.
+func (x *Accessor) ListMembers(ctx context.Context, contractID string) ([]model.ContractMember, error) {
+	if _, err := findContract(x.db.WithContext(ctx), contractID); err != nil {
+		return nil, err
+	}
+	var members []model.ContractMember
+	res := x.db.WithContext(ctx).Order("created_at").Find(&members, "contract_id = ?", contractID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return members, nil
+}
+
+// GetMemberRole returns the role of a user in a contract.
+func (x *Accessor) GetMemberRole(ctx context.Context, contractID string, userID uuid.UUID) (Role, error) {
+	var member model.ContractMember
+	res := x.db.WithContext(ctx).Limit(1).Find(&member, "contract_id = ? AND user_id = ?", contractID, userID)
+	if res.RowsAffected == 0 || res.Error != nil {
+		return "", fmt.Errorf("Not found member %s for contract id %s", userID, contractID)
+	}
+	return Role(member.Role), nil
+}
+
+// GetUserContracts returns the contracts which a user belongs to. If selector is not empty,
+// only the contracts whose labels match it are returned.
+func (x *Accessor) GetUserContracts(ctx context.Context, userID uuid.UUID, selector Selector) ([]*pb.Contract, error) {
+	var (
+		contracts       []model.Contract
+		resultContracts []*pb.Contract
+	)
+	res := selector.apply(x.db.WithContext(ctx)).
+		Joins("JOIN contract_members ON contract_members.contract_id = contracts.id").
+		Where("contract_members.user_id = ?", userID).
+		Find(&contracts)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	for _, contract := range contracts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		quota, err := x.GetResourceQuota(ctx, contract.ID)
+		if err != nil {
+			return nil, err
+		}
+		resContract := reflectToPbContract(contract, &quota)
+		resultContracts = append(resultContracts, &resContract)
+	}
+	return resultContracts, nil
+}
+
+// GetUserRoles returns the roles of a user by the ids of the contracts which the user belongs to.
+func (x *Accessor) GetUserRoles(ctx context.Context, userID uuid.UUID) (map[string]Role, error) {
+	var members []model.ContractMember
+	res := x.db.WithContext(ctx).Find(&members, "user_id = ?", userID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	roles := map[string]Role{}
+	for _, m := range members {
+		roles[m.ContractID] = Role(m.Role)
+	}
+	return roles, nil
+}
+
+// checkLastOwner returns ErrLastOwner if the user is the only owner of a contract.
+func checkLastOwner(tx *gorm.DB, contractID string, userID uuid.UUID) error {
+	var owners []model.ContractMember
+	res := tx.Find(&owners, "contract_id = ? AND role = ?", contractID, string(RoleOwner))
+	if res.Error != nil {
+		return res.Error
+	}
+	if len(owners) == 1 && owners[0].UserID == userID {
+		return fmt.Errorf("%w : %s of contract %s", ErrLastOwner, userID, contractID)
+	}
+	return nil
+}
+
+func addMember(tx *gorm.DB, contractID string, userID uuid.UUID, role Role) error {
+	res := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "contract_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(&model.ContractMember{ContractID: contractID, UserID: userID, Role: string(role)})
+	if res.Error != nil {
+		return fmt.Errorf("could not add member %s for contract id %s: %s", userID, contractID, res.Error)
+	}
+	log.Info("member is added! contractId : ", contractID, ", userId : ", userID, ", role : ", role)
+	return nil
+}
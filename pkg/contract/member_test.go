@@ -0,0 +1,113 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+)
+
+func TestContractMembers(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Errorf("an error was unexpected while initilizing database %s", err)
+	}
+	userId := uuid.New()
+
+	if err := accessor.AddMember(context.Background(), contractId, userId, contract.RoleViewer); err != nil {
+		t.Errorf("an error was unexpected while adding member %s", err)
+	}
+	if err := accessor.AddMember(context.Background(), contractId, userId, contract.RoleAdmin); err != nil {
+		t.Errorf("an error was unexpected while changing role of member %s", err)
+	}
+	role, err := accessor.GetMemberRole(context.Background(), contractId, userId)
+	if err != nil || role != contract.RoleAdmin {
+		t.Errorf("expected role %s but got %s (err: %v)", contract.RoleAdmin, role, err)
+	}
+	if err := accessor.AddMember(context.Background(), contractId, userId, "unknown"); err == nil {
+		t.Errorf("an error was expected for unknown role")
+	}
+
+	contracts, err := accessor.GetUserContracts(context.Background(), userId, nil)
+	if err != nil || len(contracts) != 1 || contracts[0].ContractId != contractId {
+		t.Errorf("expected only contract %s for user %s (err: %v)", contractId, userId, err)
+	}
+
+	members, err := accessor.ListMembers(context.Background(), contractId)
+	if err != nil {
+		t.Errorf("an error was unexpected while listing members %s", err)
+	}
+	found := 0
+	for _, m := range members {
+		if m.ContractID != contractId {
+			t.Errorf("unexpected member %+v of another contract", m)
+		}
+		if m.UserID == userId {
+			found++
+			if m.Role != string(contract.RoleAdmin) {
+				t.Errorf("expected member %s to be %s but got %s", userId, contract.RoleAdmin, m.Role)
+			}
+		}
+	}
+	if found != 1 {
+		t.Errorf("expected member %s once but got %d times in %+v", userId, found, members)
+	}
+
+	if err := accessor.RemoveMember(context.Background(), contractId, userId); err != nil {
+		t.Errorf("an error was unexpected while removing member %s", err)
+	}
+	if _, err := accessor.GetMemberRole(context.Background(), contractId, userId); err == nil {
+		t.Errorf("an error was expected for removed member")
+	}
+	remaining, err := accessor.ListMembers(context.Background(), contractId)
+	if err != nil || len(remaining) != len(members)-1 {
+		t.Errorf("expected %d members after removal but got %+v (err: %v)", len(members)-1, remaining, err)
+	}
+	for _, m := range remaining {
+		if m.UserID == userId {
+			t.Errorf("expected member %s to be removed", userId)
+		}
+	}
+}
+
+func TestLastOwner(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	owner, other := uuid.New(), uuid.New()
+	contractID, err := accessor.Create(ctx, "last-owner", []string{}, &pb.ContractQuota{}, owner, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+
+	if err := accessor.RemoveMember(ctx, contractID, owner); !errors.Is(err, contract.ErrLastOwner) {
+		t.Errorf("expected ErrLastOwner on removing the only owner, got %v", err)
+	}
+	if err := accessor.AddMember(ctx, contractID, owner, contract.RoleViewer); !errors.Is(err, contract.ErrLastOwner) {
+		t.Errorf("expected ErrLastOwner on demoting the only owner, got %v", err)
+	}
+
+	// the owner can leave once another owner is added
+	if err := accessor.AddMember(ctx, contractID, other, contract.RoleOwner); err != nil {
+		t.Fatalf("an error was unexpected while adding member %s", err)
+	}
+	if err := accessor.AddMember(ctx, contractID, owner, contract.RoleViewer); err != nil {
+		t.Errorf("an error was unexpected while demoting owner %s", err)
+	}
+	roles, err := accessor.GetUserRoles(ctx, owner)
+	if err != nil || roles[contractID] != contract.RoleViewer {
+		t.Errorf("expected role %s but got %+v (err: %v)", contract.RoleViewer, roles, err)
+	}
+	if err := accessor.RemoveMember(ctx, contractID, owner); err != nil {
+		t.Errorf("an error was unexpected while removing member %s", err)
+	}
+	if _, err := accessor.ListMembers(ctx, "P00000000"); err == nil {
+		t.Errorf("expected an error for members of unknown contract")
+	}
+}
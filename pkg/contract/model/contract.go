@@ -17,11 +17,29 @@ type Contract struct {
 	AvailableServices pq.StringArray `gorm:"type:text[]"`
 	Creator           uuid.UUID
 	Description       string
-	UpdatedAt         time.Time
-	CreatedAt         time.Time
+	// Profile of the contractor, which is changed with Accessor.UpdateContract.
+	BusinessRegistrationNumber string `gorm:"not null;default:''"`
+	BillingAddress             string `gorm:"not null;default:''"`
+	ContactEmail               string `gorm:"not null;default:''"`
+	ContactPhone               string `gorm:"not null;default:''"`
+	// ParentID is the id of the parent contract whose quota covers the quota of this contract.
+	ParentID       *string   `gorm:"index"`
+	Labels         StringMap `gorm:"type:jsonb;not null;default:'{}';index:idx_contracts_labels,type:gin"`
+	Annotations    StringMap `gorm:"type:jsonb;not null;default:'{}'"`
+	PricePlan      string    `gorm:"not null;default:standard"`
+	EffectiveFrom  *time.Time
+	ExpiresAt      *time.Time `gorm:"index"`
+	State          string     `gorm:"not null;default:active"`
+	ExpiryWarnedAt *time.Time
+	// IsDefault marks the default contract. At most one contract is the default.
+	IsDefault bool `gorm:"not null;default:false;uniqueIndex:idx_default_contract,where:is_default"`
+	UpdatedAt time.Time
+	CreatedAt time.Time
 }
 
 func (c *Contract) BeforeCreate(tx *gorm.DB) (err error) {
-	c.ID = helper.GenerateContractId()
+	if c.ID == "" {
+		c.ID = helper.GenerateContractId()
+	}
 	return nil
 }
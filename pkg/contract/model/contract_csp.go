@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	uuid "github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ContractCsp represents a CSP account of tks-info which is attached to a contract with a role.
+type ContractCsp struct {
+	ID         uuid.UUID `gorm:"primarykey;type:uuid;default:uuid_generate_v4()"`
+	ContractID string    `gorm:"uniqueIndex:idx_contract_csp"`
+	// CspID is the id of the CSP info in tks-info.
+	CspID     uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_contract_csp"`
+	CspName   string
+	Role      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (c *ContractCsp) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	uuid "github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ContractHistory represents a change of a contract. Previous and Current hold JSON
+// snapshots of the changed values.
+type ContractHistory struct {
+	ID         uuid.UUID `gorm:"primarykey;type:uuid;default:uuid_generate_v4()"`
+	ContractID string    `gorm:"index:idx_contract_history"`
+	Action     string
+	Actor      uuid.UUID `gorm:"type:uuid"`
+	Previous   string
+	Current    string
+	CreatedAt  time.Time `gorm:"index:idx_contract_history"`
+}
+
+func (h *ContractHistory) BeforeCreate(tx *gorm.DB) (err error) {
+	h.ID = uuid.New()
+	return nil
+}
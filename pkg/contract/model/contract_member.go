@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	uuid "github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ContractMember represents a user who belongs to a contract with a role.
+type ContractMember struct {
+	ID         uuid.UUID `gorm:"primarykey;type:uuid;default:uuid_generate_v4()"`
+	ContractID string    `gorm:"uniqueIndex:idx_contract_member"`
+	UserID     uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_contract_member;index"`
+	Role       string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (m *ContractMember) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	uuid "github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/openinfradev/tks-contract/pkg/contract/quantity"
+)
+
+// Invoice represents an invoice of a contract for a billing period, or a credit note which
+// corrects an invoice. Invoices are never changed once they are created.
+type Invoice struct {
+	ID uuid.UUID `gorm:"primarykey;type:uuid;default:uuid_generate_v4()"`
+	// Number is unique, such as INV-202606-P0123abcd, or CN-202606-P0123abcd-1 for a credit note.
+	Number     string `gorm:"uniqueIndex"`
+	ContractID string `gorm:"index:idx_invoice_period"`
+	// Kind is invoice or credit_note.
+	Kind        string
+	PeriodStart time.Time `gorm:"index:idx_invoice_period"`
+	PeriodEnd   time.Time
+	Currency    string
+	Lines       InvoiceLines `gorm:"type:jsonb;not null;default:'[]'"`
+	// Total is a decimal string, which is negative for credit notes.
+	Total string
+	// InvoiceID is the corrected invoice of a credit note.
+	InvoiceID *uuid.UUID `gorm:"type:uuid;index"`
+	Reason    string
+	// CpuUnit is cores for invoices which were issued while cpu quotas were stored in cores, and
+	// empty for the others whose cpu quantities are in millicores.
+	CpuUnit   string `gorm:"not null;default:''"`
+	CreatedAt time.Time
+}
+
+// CpuUnitCores is the cpu unit of invoices which were issued before cpu quotas were stored in
+// millicores.
+const CpuUnitCores = "cores"
+
+func (i *Invoice) BeforeCreate(tx *gorm.DB) (err error) {
+	i.ID = uuid.New()
+	return nil
+}
+
+// AfterFind returns the cpu quantities of invoices issued in cores in millicores. The stored
+// lines of issued invoices are never rewritten.
+func (i *Invoice) AfterFind(tx *gorm.DB) (err error) {
+	if i.CpuUnit != CpuUnitCores {
+		return nil
+	}
+	for n, l := range i.Lines {
+		if l.Kind == "resource" && l.Item == quantity.Cpu {
+			i.Lines[n].Quantity = l.Quantity * quantity.MillicoresPerCore
+		}
+	}
+	i.CpuUnit = ""
+	return nil
+}
+
+// InvoiceLine is a line item of an invoice.
+type InvoiceLine struct {
+	Kind      string    `json:"kind"`
+	Item      string    `json:"item"`
+	PricePlan string    `json:"pricePlan,omitempty"`
+	Quantity  int64     `json:"quantity"`
+	UnitPrice string    `json:"unitPrice"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Amount    string    `json:"amount"`
+}
+
+// InvoiceLines are line items which are stored as a JSONB array.
+type InvoiceLines []InvoiceLine
+
+// Value implements driver.Valuer.
+func (l InvoiceLines) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner.
+func (l *InvoiceLines) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*l = InvoiceLines{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into InvoiceLines", value)
+	}
+	result := InvoiceLines{}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return err
+	}
+	*l = result
+	return nil
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	uuid "github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PricePlan represents a version of the unit prices of a plan, which is effective from
+// EffectiveFrom until the next version of the plan. Prices are decimal strings per month,
+// keyed by quota dimension in ResourcePrices and by service name in ServicePrices.
+type PricePlan struct {
+	ID             uuid.UUID `gorm:"primarykey;type:uuid;default:uuid_generate_v4()"`
+	Name           string    `gorm:"uniqueIndex:idx_price_plan_version"`
+	EffectiveFrom  time.Time `gorm:"uniqueIndex:idx_price_plan_version"`
+	Currency       string
+	ResourcePrices StringMap `gorm:"type:jsonb;not null;default:'{}'"`
+	ServicePrices  StringMap `gorm:"type:jsonb;not null;default:'{}'"`
+	CreatedAt      time.Time
+}
+
+func (p *PricePlan) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID = uuid.New()
+	return nil
+}
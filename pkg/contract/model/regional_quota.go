@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	uuid "github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RegionalQuota represents a slice of the resource quota of a contract which is allocated to
+// a region or an account of a CSP. The slices of a contract never exceed its resource quota.
+type RegionalQuota struct {
+	ID         uuid.UUID `gorm:"primarykey;type:uuid"`
+	ContractID string    `gorm:"uniqueIndex:idx_regional_quota"`
+	// Csp is the CSP or the CSP account of the slice, such as aws.
+	Csp string `gorm:"uniqueIndex:idx_regional_quota"`
+	// Region is the region or the zone of the slice, such as ap-northeast-2. It is empty for the
+	// slice of a whole CSP account.
+	Region    string `gorm:"uniqueIndex:idx_regional_quota"`
+	Cpu       int64
+	Memory    int64
+	Block     int64
+	BlockSsd  int64
+	Fs        int64
+	FsSsd     int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (r *RegionalQuota) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,567 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+	"github.com/openinfradev/tks-contract/pkg/contract/quantity"
+)
+
+// DefaultPricePlan is the price plan of contracts which are not assigned to another plan.
+const DefaultPricePlan = "standard"
+
+// Priced quota dimensions.
+const (
+	PriceCpu      = quantity.Cpu
+	PriceMemory   = quantity.Memory
+	PriceBlock    = quantity.Block
+	PriceBlockSsd = quantity.BlockSsd
+	PriceFs       = quantity.Fs
+	PriceFsSsd    = quantity.FsSsd
+)
+
+// Kinds of charge lines.
+const (
+	ChargeResource = "resource"
+	ChargeService  = "service"
+)
+
+// amountPrecision is the number of decimal places of charged amounts.
+const amountPrecision = 2
+
+// ErrNoPrice is returned if no version of a price plan is effective for a part of a billing period.
+var ErrNoPrice = errors.New("no price is effective")
+
+var (
+	priceRegexp    = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+	currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// ValidatePricePlan returns an error if a version of a price plan is invalid.
+func ValidatePricePlan(plan model.PricePlan) error {
+	if plan.Name == "" || ValidateLabelValue(plan.Name) != nil {
+		return fmt.Errorf("invalid price plan name %q", plan.Name)
+	}
+	if plan.EffectiveFrom.IsZero() {
+		return fmt.Errorf("effective time of price plan %s must be specified", plan.Name)
+	}
+	if !currencyRegexp.MatchString(plan.Currency) {
+		return fmt.Errorf("invalid currency %q, expected an ISO 4217 code such as KRW", plan.Currency)
+	}
+	for k, v := range plan.ResourcePrices {
+		if !isResourceDimension(k) {
+			return fmt.Errorf("unknown quota dimension %q, expected one of %v", k, resourceDimensions)
+		}
+		if !priceRegexp.MatchString(v) {
+			return fmt.Errorf("invalid price %q of %s", v, k)
+		}
+	}
+	for k, v := range plan.ServicePrices {
+		if k == "" {
+			return fmt.Errorf("service name of price must be specified")
+		}
+		if !priceRegexp.MatchString(v) {
+			return fmt.Errorf("invalid price %q of service %s", v, k)
+		}
+	}
+	return nil
+}
+
+var resourceDimensions = []string{PriceCpu, PriceMemory, PriceBlock, PriceBlockSsd, PriceFs, PriceFsSsd}
+
+func isResourceDimension(name string) bool {
+	return contains(resourceDimensions, name)
+}
+
+// CreatePricePlan adds a version of a price plan. Versions of a plan are immutable, and
+// must be in the same currency.
+func (x *Accessor) CreatePricePlan(ctx context.Context, plan model.PricePlan) (model.PricePlan, error) {
+	if err := ValidatePricePlan(plan); err != nil {
+		return model.PricePlan{}, err
+	}
+	created := model.PricePlan{
+		Name:           plan.Name,
+		EffectiveFrom:  plan.EffectiveFrom,
+		Currency:       plan.Currency,
+		ResourcePrices: plan.ResourcePrices,
+		ServicePrices:  plan.ServicePrices,
+	}
+	err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.PricePlan
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", plan.Name).Limit(1).Find(&existing)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 && existing.Currency != plan.Currency {
+			return fmt.Errorf("currency of price plan %s is %s", plan.Name, existing.Currency)
+		}
+		if res := tx.Create(&created); res.Error != nil {
+			return fmt.Errorf("could not create price plan %s effective from %s: %s",
+				plan.Name, plan.EffectiveFrom.Format(time.RFC3339), res.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.PricePlan{}, err
+	}
+	log.Info("created price plan ", plan.Name, " effective from ", plan.EffectiveFrom.Format(time.RFC3339))
+	return created, nil
+}
+
+// ListPricePlans returns the versions of price plans ordered by name and effective time.
+// If names are given, only the versions of the plans are returned.
+func (x *Accessor) ListPricePlans(ctx context.Context, names ...string) ([]model.PricePlan, error) {
+	var plans []model.PricePlan
+	db := x.db.WithContext(ctx).Order("name, effective_from")
+	if len(names) > 0 {
+		db = db.Where("name IN ?", names)
+	}
+	if res := db.Find(&plans); res.Error != nil {
+		return nil, res.Error
+	}
+	return plans, nil
+}
+
+// historyPricePlan is a snapshot of the price plan of a contract in history records.
+type historyPricePlan struct {
+	PricePlan string `json:"pricePlan"`
+}
+
+// SetPricePlan assigns a contract to a price plan which has at least one version, and returns the previous plan.
+func (x *Accessor) SetPricePlan(ctx context.Context, contractID string, name string) (prev string, err error) {
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if res := tx.Model(&model.PricePlan{}).Where("name = ?", name).Count(&n); res.Error != nil {
+			return res.Error
+		}
+		if n == 0 {
+			return fmt.Errorf("Not found price plan %s", name)
+		}
+		var contract model.Contract
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&contract, "id = ?", contractID)
+		if res.RowsAffected == 0 || res.Error != nil {
+			return fmt.Errorf("Not found contract for %s", contractID)
+		}
+		prev = pricePlanOf(contract)
+		res = tx.Model(&model.Contract{}).Where("id = ?", contractID).Update("price_plan", name)
+		if res.Error != nil {
+			return fmt.Errorf("could not update price plan of contract id %s: %s", contractID, res.Error)
+		}
+		return recordHistory(tx, contractID, HistoryPricePlanUpdated, historyPricePlan{PricePlan: prev}, historyPricePlan{PricePlan: name})
+	})
+	if err == nil {
+		x.invalidate(ctx, contractID)
+	}
+	return prev, err
+}
+
+func pricePlanOf(contract model.Contract) string {
+	if contract.PricePlan == "" {
+		return DefaultPricePlan
+	}
+	return contract.PricePlan
+}
+
+// Usage is the quota, services and price plan of a contract during a period.
+type Usage struct {
+	From      time.Time
+	To        time.Time
+	Quota     model.ResourceQuota
+	Services  []string
+	PricePlan string
+}
+
+// GetUsage returns the usage of a contract during [from, to) in order of time, which is built from
+// its history. Changes before history was recorded are not known, and the earliest known values apply.
+// A deleted contract is used until it is deleted.
+func (x *Accessor) GetUsage(ctx context.Context, contractID string, from time.Time, to time.Time) ([]Usage, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("start %s must be before end %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	current, start, err := lastUsage(x.db.WithContext(ctx), contractID, to)
+	if err != nil {
+		return nil, err
+	}
+	var records []model.ContractHistory
+	res := x.db.WithContext(ctx).Where("contract_id = ? AND action IN ?", contractID, []string{
+		HistoryCreated, HistoryImported, HistoryQuotaUpdated, HistoryServicesUpdated, HistoryPricePlanUpdated,
+	}).Order("created_at DESC, id").Find(&records)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	// walk back from the last values, undoing each change with its previous values
+	var usage []Usage
+	for _, r := range records {
+		if !r.CreatedAt.Before(current.To) {
+			if err := undoChange(&current, r); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		u := current
+		u.From = r.CreatedAt
+		usage = append(usage, u)
+		current.To = r.CreatedAt
+		if r.Previous == "" {
+			// the contract is created or imported
+			start = r.CreatedAt
+			current.To = start
+			break
+		}
+		if err := undoChange(&current, r); err != nil {
+			return nil, err
+		}
+	}
+	if start.Before(current.To) {
+		u := current
+		u.From = start
+		usage = append(usage, u)
+	}
+
+	// clip to the period in order of time
+	var clipped []Usage
+	for i := len(usage) - 1; i >= 0; i-- {
+		u := usage[i]
+		if u.From.Before(from) {
+			u.From = from
+		}
+		if u.To.After(to) {
+			u.To = to
+		}
+		if u.From.Before(u.To) {
+			clipped = append(clipped, u)
+		}
+	}
+	return clipped, nil
+}
+
+// lastUsage returns the usage of a contract which ends at to, or at its deletion if it is deleted
+// before to, and the creation time of the contract. The usage of a deleted contract is taken from
+// its deletion record, and its creation time is zero since it is known only from its history.
+func lastUsage(db *gorm.DB, contractID string, to time.Time) (Usage, time.Time, error) {
+	contract, err := findContract(db, contractID)
+	if err == nil {
+		quota, err := findQuota(db, contractID)
+		if err != nil {
+			return Usage{}, time.Time{}, err
+		}
+		return Usage{
+			To:        to,
+			Quota:     quotaValues(quota),
+			Services:  contract.AvailableServices,
+			PricePlan: pricePlanOf(contract),
+		}, contract.CreatedAt, nil
+	}
+
+	deletion, ok, derr := findDeletion(db, contractID)
+	if derr != nil {
+		return Usage{}, time.Time{}, derr
+	}
+	if !ok {
+		return Usage{}, time.Time{}, err
+	}
+	var prev historyContract
+	if err := json.Unmarshal([]byte(deletion.Previous), &prev); err != nil {
+		return Usage{}, time.Time{}, fmt.Errorf("invalid history record %s : %s", deletion.ID, err)
+	}
+	u := Usage{
+		To:        to,
+		Services:  prev.AvailableServices,
+		PricePlan: prev.PricePlan,
+	}
+	if deletion.CreatedAt.Before(to) {
+		u.To = deletion.CreatedAt
+	}
+	if prev.Quota != nil {
+		u.Quota = quotaFromHistory(*prev.Quota)
+	}
+	if u.PricePlan == "" {
+		u.PricePlan = DefaultPricePlan
+	}
+	return u, time.Time{}, nil
+}
+
+// findDeletion returns the last deletion record of a contract which does not exist.
+func findDeletion(db *gorm.DB, contractID string) (model.ContractHistory, bool, error) {
+	var record model.ContractHistory
+	res := db.Where("contract_id = ? AND action = ?", contractID, HistoryDeleted).
+		Order("created_at DESC, id").Limit(1).Find(&record)
+	if res.Error != nil {
+		return model.ContractHistory{}, false, res.Error
+	}
+	return record, res.RowsAffected > 0, nil
+}
+
+// undoChange sets the values of u before the change of a history record.
+func undoChange(u *Usage, r model.ContractHistory) error {
+	if r.Previous == "" {
+		return nil
+	}
+	var prev interface{}
+	switch r.Action {
+	case HistoryQuotaUpdated:
+		prev = &historyQuota{}
+	case HistoryServicesUpdated:
+		prev = &historyServices{}
+	case HistoryPricePlanUpdated:
+		prev = &historyPricePlan{}
+	case HistoryImported:
+		prev = &historyContract{}
+	default:
+		return nil
+	}
+	if err := json.Unmarshal([]byte(r.Previous), prev); err != nil {
+		return fmt.Errorf("invalid history record %s : %s", r.ID, err)
+	}
+	switch p := prev.(type) {
+	case *historyQuota:
+		u.Quota = quotaFromHistory(*p)
+	case *historyServices:
+		u.Services = p.AvailableServices
+	case *historyPricePlan:
+		u.PricePlan = p.PricePlan
+	case *historyContract:
+		if p.Quota != nil {
+			u.Quota = quotaFromHistory(*p.Quota)
+		}
+		u.Services = p.AvailableServices
+		if p.PricePlan != "" {
+			u.PricePlan = p.PricePlan
+		}
+	}
+	return nil
+}
+
+func quotaFromHistory(q historyQuota) model.ResourceQuota {
+	return model.ResourceQuota{Cpu: q.Cpu, Memory: q.Memory, Block: q.Block, BlockSsd: q.BlockSsd, Fs: q.Fs, FsSsd: q.FsSsd}
+}
+
+// ChargeLine is the charge of an item during a period in which its quantity and unit price do not change.
+type ChargeLine struct {
+	Kind string
+	// Item is a quota dimension for ChargeResource, or a service name for ChargeService.
+	Item      string
+	PricePlan string
+	// Quantity is in the unit of quotas, which is millicores for cpu.
+	Quantity int64
+	// UnitPrice is the price of a unit per month, which is a core for cpu.
+	UnitPrice string
+	From      time.Time
+	To        time.Time
+	// Amount is prorated by the part of the month which the line covers.
+	Amount string
+}
+
+// Charges are the charges of a contract for a billing period.
+type Charges struct {
+	ContractID string
+	Currency   string
+	From       time.Time
+	To         time.Time
+	Lines      []ChargeLine
+	// Total is the sum of the amounts of lines.
+	Total string
+}
+
+// GetCharges returns the charges of a contract for [from, to), prorated by its usage.
+func (x *Accessor) GetCharges(ctx context.Context, contractID string, from time.Time, to time.Time) (Charges, error) {
+	usage, err := x.GetUsage(ctx, contractID, from, to)
+	if err != nil {
+		return Charges{}, err
+	}
+	var names []string
+	for _, u := range usage {
+		if !contains(names, u.PricePlan) {
+			names = append(names, u.PricePlan)
+		}
+	}
+	var plans []model.PricePlan
+	if len(names) > 0 {
+		if plans, err = x.ListPricePlans(ctx, names...); err != nil {
+			return Charges{}, err
+		}
+	}
+	charges, err := ComputeCharges(usage, plans)
+	if err != nil {
+		return Charges{}, err
+	}
+	charges.ContractID = contractID
+	charges.From = from
+	charges.To = to
+	return charges, nil
+}
+
+// ComputeCharges returns the charges of usage with the versions of price plans. Each usage is split by
+// calendar months in UTC and by versions of its plan, and charged for the part of the month it covers.
+func ComputeCharges(usage []Usage, plans []model.PricePlan) (Charges, error) {
+	var (
+		charges Charges
+		lines   []ChargeLine
+		amounts []*big.Rat
+	)
+	for _, u := range usage {
+		versions := versionsOf(plans, u.PricePlan)
+		for _, p := range splitUsage(u, versions) {
+			version := effectiveVersion(versions, p.From)
+			if version == nil {
+				return Charges{}, fmt.Errorf("%w : plan %s at %s", ErrNoPrice, u.PricePlan, p.From.Format(time.RFC3339))
+			}
+			if charges.Currency == "" {
+				charges.Currency = version.Currency
+			} else if charges.Currency != version.Currency {
+				return Charges{}, fmt.Errorf("charges are in different currencies %s and %s", charges.Currency, version.Currency)
+			}
+			fraction := monthFraction(p.From, p.To)
+
+			items := []chargeItem{
+				{ChargeResource, PriceCpu, u.Quota.Cpu},
+				{ChargeResource, PriceMemory, u.Quota.Memory},
+				{ChargeResource, PriceBlock, u.Quota.Block},
+				{ChargeResource, PriceBlockSsd, u.Quota.BlockSsd},
+				{ChargeResource, PriceFs, u.Quota.Fs},
+				{ChargeResource, PriceFsSsd, u.Quota.FsSsd},
+			}
+			for _, svc := range u.Services {
+				items = append(items, chargeItem{ChargeService, svc, 1})
+			}
+
+			for _, item := range items {
+				unitPrice := version.ResourcePrices[item.name]
+				if item.kind == ChargeService {
+					unitPrice = version.ServicePrices[item.name]
+				}
+				if item.quantity == 0 || unitPrice == "" {
+					continue
+				}
+				price, _ := new(big.Rat).SetString(unitPrice)
+				units := new(big.Rat).SetInt64(item.quantity)
+				if item.kind == ChargeResource {
+					// prices are per core and GiB, while cpu is in millicores
+					units = quantity.Whole(item.name, item.quantity)
+				}
+				amount := new(big.Rat).Mul(price, units)
+				amount.Mul(amount, fraction)
+
+				// merge with the line of the item which ends where this one starts in the same month
+				merged := false
+				for i := range lines {
+					l := &lines[i]
+					if l.Kind == item.kind && l.Item == item.name && l.PricePlan == u.PricePlan &&
+						l.Quantity == item.quantity && l.UnitPrice == unitPrice && l.To.Equal(p.From) &&
+						monthStart(l.From).Equal(monthStart(p.From)) {
+						l.To = p.To
+						amounts[i].Add(amounts[i], amount)
+						merged = true
+						break
+					}
+				}
+				if !merged {
+					lines = append(lines, ChargeLine{
+						Kind:      item.kind,
+						Item:      item.name,
+						PricePlan: u.PricePlan,
+						Quantity:  item.quantity,
+						UnitPrice: unitPrice,
+						From:      p.From,
+						To:        p.To,
+					})
+					amounts = append(amounts, amount)
+				}
+			}
+		}
+	}
+
+	total := new(big.Rat)
+	for i := range lines {
+		lines[i].Amount = amounts[i].FloatString(amountPrecision)
+		rounded, _ := new(big.Rat).SetString(lines[i].Amount)
+		total.Add(total, rounded)
+	}
+	charges.Lines = lines
+	charges.Total = total.FloatString(amountPrecision)
+	return charges, nil
+}
+
+// chargeItem is a quota dimension or a service charged in a part of usage.
+type chargeItem struct {
+	kind     string
+	name     string
+	quantity int64
+}
+
+// versionsOf returns the versions of a plan in order of effective time.
+func versionsOf(plans []model.PricePlan, name string) []model.PricePlan {
+	var versions []model.PricePlan
+	for _, p := range plans {
+		if p.Name == name {
+			versions = append(versions, p)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].EffectiveFrom.Before(versions[j].EffectiveFrom)
+	})
+	return versions
+}
+
+// effectiveVersion returns the latest version which is effective at t.
+func effectiveVersion(versions []model.PricePlan, t time.Time) *model.PricePlan {
+	var effective *model.PricePlan
+	for i := range versions {
+		if !versions[i].EffectiveFrom.After(t) {
+			effective = &versions[i]
+		}
+	}
+	return effective
+}
+
+// splitUsage splits u at the start of each month and each version in it.
+func splitUsage(u Usage, versions []model.PricePlan) []Usage {
+	var cuts []time.Time
+	for t := monthStart(u.From).AddDate(0, 1, 0); t.Before(u.To); t = t.AddDate(0, 1, 0) {
+		cuts = append(cuts, t)
+	}
+	for _, v := range versions {
+		if v.EffectiveFrom.After(u.From) && v.EffectiveFrom.Before(u.To) {
+			cuts = append(cuts, v.EffectiveFrom)
+		}
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].Before(cuts[j]) })
+
+	var parts []Usage
+	from := u.From
+	for _, t := range append(cuts, u.To) {
+		if !t.After(from) {
+			continue
+		}
+		p := u
+		p.From = from
+		p.To = t
+		parts = append(parts, p)
+		from = t
+	}
+	return parts
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthFraction returns the part of the month of from which [from, to) covers.
+func monthFraction(from time.Time, to time.Time) *big.Rat {
+	start := monthStart(from)
+	month := start.AddDate(0, 1, 0).Sub(start)
+	return big.NewRat(int64(to.Sub(from)), int64(month))
+}
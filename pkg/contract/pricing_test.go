@@ -0,0 +1,152 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestComputeCharges(t *testing.T) {
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	plans := []model.PricePlan{
+		{
+			Name: "standard", EffectiveFrom: date(1, 1), Currency: "KRW",
+			ResourcePrices: model.StringMap{"cpu": "3000", "memory": "1000.5"},
+			ServicePrices:  model.StringMap{"lma": "300"},
+		},
+		{
+			Name: "standard", EffectiveFrom: date(7, 1), Currency: "KRW",
+			ResourcePrices: model.StringMap{"cpu": "6000"},
+		},
+	}
+
+	// cpu 4 cores for June, raised to 8 cores from June 16, and lma for the whole period
+	usage := []contract.Usage{
+		{From: date(6, 1), To: date(6, 16), Quota: model.ResourceQuota{Cpu: 4000}, Services: []string{"lma"}, PricePlan: "standard"},
+		{From: date(6, 16), To: date(7, 16), Quota: model.ResourceQuota{Cpu: 8000}, Services: []string{"lma"}, PricePlan: "standard"},
+	}
+	charges, err := contract.ComputeCharges(usage, plans)
+	if err != nil {
+		t.Fatalf("an error was unexpected while computing charges %s", err)
+	}
+	expected := []struct {
+		item     string
+		quantity int64
+		from     time.Time
+		to       time.Time
+		amount   string
+	}{
+		{"cpu", 4000, date(6, 1), date(6, 16), "6000.00"},  // 3000 * 4 * 15/30
+		{"lma", 1, date(6, 1), date(7, 1), "300.00"},       // merged over the change of cpu
+		{"cpu", 8000, date(6, 16), date(7, 1), "12000.00"}, // 3000 * 8 * 15/30
+		{"cpu", 8000, date(7, 1), date(7, 16), "23225.81"}, // 6000 * 8 * 15/31
+	}
+	if len(charges.Lines) != len(expected) {
+		t.Fatalf("expected %d lines but got %+v", len(expected), charges.Lines)
+	}
+	for i, e := range expected {
+		l := charges.Lines[i]
+		if l.Item != e.item || l.Quantity != e.quantity || !l.From.Equal(e.from) || !l.To.Equal(e.to) || l.Amount != e.amount {
+			t.Errorf("expected line %+v but got %+v", e, l)
+		}
+	}
+	if charges.Currency != "KRW" || charges.Total != "41525.81" {
+		t.Errorf("unexpected total %s %s", charges.Total, charges.Currency)
+	}
+
+	// no version of the plan is effective before January
+	usage[0].From = date(1, 1).Add(-time.Hour)
+	if _, err := contract.ComputeCharges(usage, plans); !errors.Is(err, contract.ErrNoPrice) {
+		t.Errorf("expected ErrNoPrice but got %v", err)
+	}
+}
+
+func TestValidatePricePlan(t *testing.T) {
+	valid := model.PricePlan{
+		Name: "standard", EffectiveFrom: time.Now(), Currency: "KRW",
+		ResourcePrices: model.StringMap{"cpu": "3000", "block_ssd": "0.25"},
+		ServicePrices:  model.StringMap{"lma": "300"},
+	}
+	if err := contract.ValidatePricePlan(valid); err != nil {
+		t.Errorf("an error was unexpected for a valid price plan %s", err)
+	}
+	for _, invalid := range []func(p *model.PricePlan){
+		func(p *model.PricePlan) { p.Name = "" },
+		func(p *model.PricePlan) { p.EffectiveFrom = time.Time{} },
+		func(p *model.PricePlan) { p.Currency = "won" },
+		func(p *model.PricePlan) { p.ResourcePrices = model.StringMap{"gpu": "1"} },
+		func(p *model.PricePlan) { p.ResourcePrices = model.StringMap{"cpu": "-1"} },
+		func(p *model.PricePlan) { p.ServicePrices = model.StringMap{"lma": "1/3"} },
+	} {
+		p := valid
+		invalid(&p)
+		if err := contract.ValidatePricePlan(p); err == nil {
+			t.Errorf("expected an error for an invalid price plan %+v", p)
+		}
+	}
+}
+
+func TestCharges(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	planName := "test-" + uuid.New().String()[:8]
+	if _, err := accessor.CreatePricePlan(ctx, model.PricePlan{
+		Name: planName, EffectiveFrom: time.Now().Add(-365 * 24 * time.Hour), Currency: "KRW",
+		ResourcePrices: model.StringMap{"cpu": "3000"}, ServicePrices: model.StringMap{"lma": "300"},
+	}); err != nil {
+		t.Fatalf("an error was unexpected while creating price plan %s", err)
+	}
+	if _, err := accessor.CreatePricePlan(ctx, model.PricePlan{
+		Name: planName, EffectiveFrom: time.Now(), Currency: "USD", ResourcePrices: model.StringMap{},
+		ServicePrices: model.StringMap{},
+	}); err == nil {
+		t.Errorf("expected an error for a version in another currency")
+	}
+
+	contractID, err := accessor.Create(ctx, "charges", []string{"lma"}, &pb.ContractQuota{Cpu: 4}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	if _, err := accessor.SetPricePlan(ctx, contractID, "unknown-"+planName); err == nil {
+		t.Errorf("expected an error for an unknown price plan")
+	}
+	prev, err := accessor.SetPricePlan(ctx, contractID, planName)
+	if err != nil || prev != contract.DefaultPricePlan {
+		t.Fatalf("unexpected previous plan %s, err %v", prev, err)
+	}
+	if _, _, err := accessor.UpdateResourceQuota(ctx, contractID, &pb.ContractQuota{Cpu: 8}); err != nil {
+		t.Fatalf("an error was unexpected while updating quota %s", err)
+	}
+
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	usage, err := accessor.GetUsage(ctx, contractID, from, to)
+	if err != nil || len(usage) == 0 {
+		t.Fatalf("unexpected usage %+v, err %v", usage, err)
+	}
+	if first := usage[0]; first.Quota.Cpu != 4 || first.PricePlan != contract.DefaultPricePlan {
+		t.Errorf("expected the first usage with cpu 4 in the default plan, got %+v", first)
+	}
+	if last := usage[len(usage)-1]; last.Quota.Cpu != 8 || last.PricePlan != planName || !last.To.Equal(to) {
+		t.Errorf("expected the last usage with cpu 8 in plan %s, got %+v", planName, last)
+	}
+
+	charges, err := accessor.GetCharges(ctx, contractID, usage[len(usage)-1].From, to)
+	if err != nil {
+		t.Fatalf("an error was unexpected while getting charges %s", err)
+	}
+	if charges.Currency != "KRW" || len(charges.Lines) < 2 {
+		t.Errorf("unexpected charges %+v", charges)
+	}
+}
@@ -0,0 +1,194 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// Limits of profile fields, which follow the columns of the contracts table.
+const (
+	maxContractorNameLength = 50
+	maxDescriptionLength    = 100
+	maxBillingAddressLength = 200
+	maxContactEmailLength   = 254
+)
+
+var (
+	// businessRegistrationNumberRegexp matches a Korean business registration number of ten
+	// digits, with or without dashes.
+	businessRegistrationNumberRegexp = regexp.MustCompile(`^([0-9]{3})-?([0-9]{2})-?([0-9]{5})$`)
+	// contactPhoneRegexp matches a phone number of digits separated by dashes or spaces, with an
+	// optional country code.
+	contactPhoneRegexp = regexp.MustCompile(`^\+?[0-9]+([- ][0-9]+)*$`)
+)
+
+var (
+	// ErrInvalidProfile is returned if an updated profile is invalid.
+	ErrInvalidProfile = errors.New("invalid profile")
+	// ErrContractorNameTaken is returned on renaming a contract to the name of another contract.
+	ErrContractorNameTaken = errors.New("contractor name is taken by another contract")
+)
+
+// Profile is the contractor profile of a contract.
+type Profile struct {
+	ContractorName string `json:"contractorName"`
+	Description    string `json:"description"`
+	// BusinessRegistrationNumber is formatted as 123-45-67890.
+	BusinessRegistrationNumber string `json:"businessRegistrationNumber"`
+	BillingAddress             string `json:"billingAddress"`
+	ContactEmail               string `json:"contactEmail"`
+	ContactPhone               string `json:"contactPhone"`
+}
+
+// ProfileUpdate is a change of a profile. Fields which are nil are left unchanged, and the other
+// fields except the contractor name are cleared with an empty string.
+type ProfileUpdate struct {
+	ContractorName             *string
+	Description                *string
+	BusinessRegistrationNumber *string
+	BillingAddress             *string
+	ContactEmail               *string
+	ContactPhone               *string
+}
+
+func newProfile(contract model.Contract) Profile {
+	return Profile{
+		ContractorName:             contract.ContractorName,
+		Description:                contract.Description,
+		BusinessRegistrationNumber: contract.BusinessRegistrationNumber,
+		BillingAddress:             contract.BillingAddress,
+		ContactEmail:               contract.ContactEmail,
+		ContactPhone:               contract.ContactPhone,
+	}
+}
+
+// apply returns a copy of p with the changes of u, with surrounding spaces trimmed.
+func (u ProfileUpdate) apply(p Profile) Profile {
+	for _, f := range []struct {
+		value *string
+		field *string
+	}{
+		{u.ContractorName, &p.ContractorName},
+		{u.Description, &p.Description},
+		{u.BusinessRegistrationNumber, &p.BusinessRegistrationNumber},
+		{u.BillingAddress, &p.BillingAddress},
+		{u.ContactEmail, &p.ContactEmail},
+		{u.ContactPhone, &p.ContactPhone},
+	} {
+		if f.value != nil {
+			*f.field = strings.TrimSpace(*f.value)
+		}
+	}
+	if m := businessRegistrationNumberRegexp.FindStringSubmatch(p.BusinessRegistrationNumber); m != nil {
+		p.BusinessRegistrationNumber = m[1] + "-" + m[2] + "-" + m[3]
+	}
+	return p
+}
+
+// ValidateProfile returns an error if a field of a profile is invalid. Only the contractor name
+// is required.
+func ValidateProfile(p Profile) error {
+	if p.ContractorName == "" {
+		return fmt.Errorf("contractor name must be specified")
+	}
+	if utf8.RuneCountInString(p.ContractorName) > maxContractorNameLength {
+		return fmt.Errorf("contractor name is longer than %d characters", maxContractorNameLength)
+	}
+	if utf8.RuneCountInString(p.Description) > maxDescriptionLength {
+		return fmt.Errorf("description is longer than %d characters", maxDescriptionLength)
+	}
+	if p.BusinessRegistrationNumber != "" && !businessRegistrationNumberRegexp.MatchString(p.BusinessRegistrationNumber) {
+		return fmt.Errorf("invalid business registration number %s", p.BusinessRegistrationNumber)
+	}
+	if utf8.RuneCountInString(p.BillingAddress) > maxBillingAddressLength {
+		return fmt.Errorf("billing address is longer than %d characters", maxBillingAddressLength)
+	}
+	if p.ContactEmail != "" {
+		addr, err := mail.ParseAddress(p.ContactEmail)
+		if err != nil || addr.Address != p.ContactEmail || len(p.ContactEmail) > maxContactEmailLength {
+			return fmt.Errorf("invalid contact email %s", p.ContactEmail)
+		}
+	}
+	if p.ContactPhone != "" {
+		digits := 0
+		for _, r := range p.ContactPhone {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if !contactPhoneRegexp.MatchString(p.ContactPhone) || digits < 7 || digits > 15 {
+			return fmt.Errorf("invalid contact phone %s", p.ContactPhone)
+		}
+	}
+	return nil
+}
+
+// GetProfile returns the contractor profile of a contract.
+func (x *Accessor) GetProfile(ctx context.Context, contractID string) (Profile, error) {
+	contract, err := findContract(x.db.WithContext(ctx), contractID)
+	if err != nil {
+		return Profile{}, err
+	}
+	return newProfile(contract), nil
+}
+
+// UpdateContract changes the contractor profile of a contract, and returns the previous and
+// current profiles. The contractor name must not be taken by another contract.
+func (x *Accessor) UpdateContract(ctx context.Context, contractID string, update ProfileUpdate) (prev Profile, curr Profile, err error) {
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		contract, err := findContract(tx.Clauses(clause.Locking{Strength: "UPDATE"}), contractID)
+		if err != nil {
+			return err
+		}
+		prev = newProfile(contract)
+		curr = update.apply(prev)
+		if err := ValidateProfile(curr); err != nil {
+			return fmt.Errorf("%w : %s", ErrInvalidProfile, err)
+		}
+		if curr == prev {
+			return nil
+		}
+		if curr.ContractorName != prev.ContractorName {
+			var count int64
+			res := tx.Model(&model.Contract{}).Where("contractor_name = ? AND id <> ?", curr.ContractorName, contractID).Count(&count)
+			if res.Error != nil {
+				return res.Error
+			}
+			if count > 0 {
+				return fmt.Errorf("%w : %s", ErrContractorNameTaken, curr.ContractorName)
+			}
+		}
+
+		res := tx.Model(&model.Contract{}).Where("id = ?", contractID).Updates(map[string]interface{}{
+			"contractor_name":              curr.ContractorName,
+			"description":                  curr.Description,
+			"business_registration_number": curr.BusinessRegistrationNumber,
+			"billing_address":              curr.BillingAddress,
+			"contact_email":                curr.ContactEmail,
+			"contact_phone":                curr.ContactPhone,
+		})
+		if res.Error != nil {
+			return fmt.Errorf("could not update profile for contract id %s: %s", contractID, res.Error)
+		}
+		return recordHistory(tx, contractID, HistoryProfileUpdated, prev, curr)
+	})
+	if err != nil {
+		return Profile{}, Profile{}, err
+	}
+	if curr != prev {
+		x.invalidate(ctx, contractID)
+		log.Info("updated profile of contract id ", contractID)
+	}
+	return prev, curr, nil
+}
@@ -0,0 +1,97 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+)
+
+func TestValidateProfile(t *testing.T) {
+	valid := contract.Profile{
+		ContractorName:             "acme",
+		BusinessRegistrationNumber: "123-45-67890",
+		ContactEmail:               "billing@acme.example",
+		ContactPhone:               "+82 2-1234-5678",
+	}
+	if err := contract.ValidateProfile(valid); err != nil {
+		t.Errorf("expected %+v to be valid, but got %s", valid, err)
+	}
+	testCases := []struct {
+		name   string
+		modify func(p *contract.Profile)
+	}{
+		{"empty name", func(p *contract.Profile) { p.ContractorName = "" }},
+		{"long name", func(p *contract.Profile) { p.ContractorName = strings.Repeat("가", 51) }},
+		{"long description", func(p *contract.Profile) { p.Description = strings.Repeat("a", 101) }},
+		{"business registration number", func(p *contract.Profile) { p.BusinessRegistrationNumber = "123-456-7890" }},
+		{"long billing address", func(p *contract.Profile) { p.BillingAddress = strings.Repeat("a", 201) }},
+		{"email with name", func(p *contract.Profile) { p.ContactEmail = "Acme <billing@acme.example>" }},
+		{"email", func(p *contract.Profile) { p.ContactEmail = "billing" }},
+		{"phone", func(p *contract.Profile) { p.ContactPhone = "02-CALL-ACME" }},
+		{"short phone", func(p *contract.Profile) { p.ContactPhone = "1234" }},
+	}
+	for _, tc := range testCases {
+		p := valid
+		tc.modify(&p)
+		if err := contract.ValidateProfile(p); err == nil {
+			t.Errorf("%s: expected an error for %+v", tc.name, p)
+		}
+	}
+}
+
+func TestUpdateContract(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	suffix := uuid.New().String()[:8]
+	contractID, err := accessor.Create(ctx, "profile-"+suffix, []string{}, &pb.ContractQuota{}, uuid.Nil, "before")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+	if _, err := accessor.Create(ctx, "profile-taken-"+suffix, []string{}, &pb.ContractQuota{}, uuid.Nil, ""); err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+
+	name, number, email := "profile-renamed-"+suffix, " 1234567890 ", "billing@acme.example"
+	prev, curr, err := accessor.UpdateContract(ctx, contractID, contract.ProfileUpdate{
+		ContractorName: &name, BusinessRegistrationNumber: &number, ContactEmail: &email,
+	})
+	if err != nil {
+		t.Fatalf("an error was unexpected while updating contract %s", err)
+	}
+	if prev.ContractorName != "profile-"+suffix || curr.ContractorName != name || curr.Description != "before" ||
+		curr.BusinessRegistrationNumber != "123-45-67890" || curr.ContactEmail != email {
+		t.Errorf("unexpected profiles %+v to %+v", prev, curr)
+	}
+	got, err := accessor.GetContract(ctx, contractID)
+	if err != nil || got.ContractorName != name {
+		t.Errorf("expected the contract to be renamed to %s, but got %+v (%v)", name, got, err)
+	}
+	histories, err := accessor.GetHistory(ctx, contractID, 0, 10)
+	if err != nil {
+		t.Fatalf("an error was unexpected while querying history %s", err)
+	}
+	if len(histories) == 0 || histories[0].Action != contract.HistoryProfileUpdated {
+		t.Errorf("expected the latest history to be %s, but got %+v", contract.HistoryProfileUpdated, histories)
+	}
+
+	taken := "profile-taken-" + suffix
+	if _, _, err := accessor.UpdateContract(ctx, contractID, contract.ProfileUpdate{ContractorName: &taken}); !errors.Is(err, contract.ErrContractorNameTaken) {
+		t.Errorf("expected %s, but got %v", contract.ErrContractorNameTaken, err)
+	}
+	phone := "call me"
+	if _, _, err := accessor.UpdateContract(ctx, contractID, contract.ProfileUpdate{ContactPhone: &phone}); !errors.Is(err, contract.ErrInvalidProfile) {
+		t.Errorf("expected %s, but got %v", contract.ErrInvalidProfile, err)
+	}
+	if _, _, err := accessor.UpdateContract(ctx, "P0000none", contract.ProfileUpdate{ContactEmail: &email}); err == nil {
+		t.Errorf("expected an error for a contract which does not exist")
+	}
+}
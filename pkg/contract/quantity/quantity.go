@@ -0,0 +1,177 @@
+// Package quantity defines the units in which contract quotas are stored, and parses and formats
+// quotas as Kubernetes-style quantities such as 4, 500m, 16Gi and 1Ti.
+//
+// Quotas are stored as integers in the unit of their dimension: millicores for cpu, and GiB for
+// memory and storage. A quantity without a suffix is taken in cores for cpu as in Kubernetes, and in
+// GiB for memory and storage; unlike Kubernetes, a memory quantity of 128 is 128Gi, not 128 bytes.
+package quantity
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+)
+
+// Quota dimensions, named as in price plans.
+const (
+	Cpu      = "cpu"
+	Memory   = "memory"
+	Block    = "block"
+	BlockSsd = "block_ssd"
+	Fs       = "fs"
+	FsSsd    = "fs_ssd"
+)
+
+// Unit is the unit in which the quota of a dimension is stored.
+type Unit struct {
+	// Name is the name of the unit in messages, such as millicores or GiB.
+	Name string
+	// Suffix is the quantity suffix of the unit.
+	Suffix string
+	// base is the size of the unit in cores or bytes.
+	base *big.Rat
+	// whole is the size of a quantity without a suffix in the unit, which is also the unit of prices.
+	whole int64
+}
+
+// MillicoresPerCore is the number of millicores in a core, which is the cpu unit of
+// pb.ContractQuota on the gRPC API.
+const MillicoresPerCore = 1000
+
+var (
+	// Millicores is the unit of cpu quotas.
+	Millicores = Unit{Name: "millicores", Suffix: "m", base: big.NewRat(1, 1000), whole: 1000}
+	// GiB is the unit of memory and storage quotas.
+	GiB = Unit{Name: "GiB", Suffix: "Gi", base: big.NewRat(1<<30, 1), whole: 1}
+)
+
+var units = map[string]Unit{
+	Cpu:      Millicores,
+	Memory:   GiB,
+	Block:    GiB,
+	BlockSsd: GiB,
+	Fs:       GiB,
+	FsSsd:    GiB,
+}
+
+// Dimensions returns the quota dimensions in the order of pb.ContractQuota.
+func Dimensions() []string {
+	return []string{Cpu, Memory, Block, BlockSsd, Fs, FsSsd}
+}
+
+// UnitOf returns the unit of a quota dimension.
+func UnitOf(dimension string) (Unit, error) {
+	u, ok := units[dimension]
+	if !ok {
+		return Unit{}, fmt.Errorf("unknown quota dimension %q", dimension)
+	}
+	return u, nil
+}
+
+// suffixes maps quantity suffixes to their multipliers.
+var suffixes = map[string]*big.Rat{
+	"n":  big.NewRat(1, 1000000000),
+	"u":  big.NewRat(1, 1000000),
+	"m":  big.NewRat(1, 1000),
+	"k":  big.NewRat(1000, 1),
+	"M":  new(big.Rat).SetInt64(1000000),
+	"G":  new(big.Rat).SetInt64(1000000000),
+	"T":  new(big.Rat).SetInt64(1000000000000),
+	"P":  new(big.Rat).SetInt64(1000000000000000),
+	"E":  new(big.Rat).SetInt64(1000000000000000000),
+	"Ki": new(big.Rat).SetInt64(1 << 10),
+	"Mi": new(big.Rat).SetInt64(1 << 20),
+	"Gi": new(big.Rat).SetInt64(1 << 30),
+	"Ti": new(big.Rat).SetInt64(1 << 40),
+	"Pi": new(big.Rat).SetInt64(1 << 50),
+	"Ei": new(big.Rat).SetInt64(1 << 60),
+}
+
+var quantityRegexp = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)([a-zA-Z]*)$`)
+
+// Parse converts a quantity of a dimension to the unit of the dimension. The quantity must be
+// a whole number of the unit which is not negative, so that 0.5m cpu or 512Mi memory is an error.
+func Parse(dimension string, s string) (int64, error) {
+	u, err := UnitOf(dimension)
+	if err != nil {
+		return 0, err
+	}
+	v, suffixed, err := parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s quantity : %w", dimension, err)
+	}
+	if suffixed {
+		// the value is in cores or bytes, which is converted to the unit
+		v.Quo(v, u.base)
+	} else {
+		v.Mul(v, new(big.Rat).SetInt64(u.whole))
+	}
+	if !v.IsInt() {
+		return 0, fmt.Errorf("%s quantity %q is not a whole number of %s", dimension, s, u.Name)
+	}
+	if !v.Num().IsInt64() {
+		return 0, fmt.Errorf("%s quantity %q is too large", dimension, s)
+	}
+	return v.Num().Int64(), nil
+}
+
+// Validate returns an error if s is not a quantity which is not negative.
+func Validate(s string) error {
+	_, _, err := parse(s)
+	return err
+}
+
+// parse returns the value of a quantity multiplied by its suffix, and whether it has a suffix.
+func parse(s string) (*big.Rat, bool, error) {
+	m := quantityRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return nil, false, fmt.Errorf("%q is not a quantity", s)
+	}
+	v, ok := new(big.Rat).SetString(m[1])
+	if !ok {
+		return nil, false, fmt.Errorf("%q is not a quantity", s)
+	}
+	if m[2] == "" {
+		return v, false, nil
+	}
+	mul, ok := suffixes[m[2]]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown suffix %q of quantity %q", m[2], s)
+	}
+	return v.Mul(v, mul), true, nil
+}
+
+// Format returns a quota of a dimension as a quantity, in whole cores if possible for cpu, and
+// in the largest binary suffix which represents it exactly for memory and storage.
+func Format(dimension string, v int64) string {
+	u, ok := units[dimension]
+	if !ok || v == 0 {
+		return strconv.FormatInt(v, 10)
+	}
+	if u.whole > 1 {
+		if v%u.whole == 0 {
+			return strconv.FormatInt(v/u.whole, 10)
+		}
+		return strconv.FormatInt(v, 10) + u.Suffix
+	}
+	suffix := u.Suffix
+	for _, next := range []string{"Ti", "Pi", "Ei"} {
+		if v%1024 != 0 {
+			break
+		}
+		v /= 1024
+		suffix = next
+	}
+	return strconv.FormatInt(v, 10) + suffix
+}
+
+// Whole returns a quota of a dimension in cores for cpu and in GiB for memory and storage,
+// which are the units of prices.
+func Whole(dimension string, v int64) *big.Rat {
+	u, ok := units[dimension]
+	if !ok {
+		return new(big.Rat).SetInt64(v)
+	}
+	return big.NewRat(v, u.whole)
+}
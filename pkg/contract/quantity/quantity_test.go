@@ -0,0 +1,82 @@
+package quantity_test
+
+import (
+	"testing"
+
+	"github.com/openinfradev/tks-contract/pkg/contract/quantity"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		dimension string
+		in        string
+		want      int64
+		wantErr   bool
+	}{
+		{quantity.Cpu, "4", 4000, false},
+		{quantity.Cpu, "4000m", 4000, false},
+		{quantity.Cpu, "2k", 2000000, false},
+		{quantity.Cpu, "500m", 500, false},
+		{quantity.Cpu, "1.5", 1500, false},
+		{quantity.Cpu, "0.5m", 0, true},
+		{quantity.Memory, "128", 128, false},
+		{quantity.Memory, "16Gi", 16, false},
+		{quantity.Memory, "1Ti", 1024, false},
+		{quantity.Memory, "0.5Ti", 512, false},
+		{quantity.Memory, "2048Mi", 2, false},
+		{quantity.Memory, "512Mi", 0, true},
+		{quantity.Memory, "16G", 0, true},
+		{quantity.BlockSsd, "1Pi", 1 << 20, false},
+		{quantity.Fs, "-1", 0, true},
+		{quantity.Fs, "1Xi", 0, true},
+		{quantity.Fs, "", 0, true},
+		{quantity.FsSsd, "9223372036854775808", 0, true},
+		{"gpu", "1", 0, true},
+	}
+	for _, tc := range testCases {
+		got, err := quantity.Parse(tc.dimension, tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("expected an error for %s %q but got %d", tc.dimension, tc.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("expected %d for %s %q but got %d, err %v", tc.want, tc.dimension, tc.in, got, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		dimension string
+		in        int64
+		want      string
+	}{
+		{quantity.Cpu, 64000, "64"},
+		{quantity.Cpu, 500, "500m"},
+		{quantity.Cpu, 1500, "1500m"},
+		{quantity.Memory, 0, "0"},
+		{quantity.Memory, 16, "16Gi"},
+		{quantity.Memory, 1024, "1Ti"},
+		{quantity.Block, 1536, "1536Gi"},
+		{quantity.Fs, 3 << 20, "3Pi"},
+	}
+	for _, tc := range testCases {
+		if got := quantity.Format(tc.dimension, tc.in); got != tc.want {
+			t.Errorf("expected %s for %s %d but got %s", tc.want, tc.dimension, tc.in, got)
+		}
+		if v, err := quantity.Parse(tc.dimension, quantity.Format(tc.dimension, tc.in)); err != nil || v != tc.in {
+			t.Errorf("expected %d after formatting and parsing %s %d but got %d, err %v", tc.in, tc.dimension, tc.in, v, err)
+		}
+	}
+}
+
+func TestWhole(t *testing.T) {
+	if got := quantity.Whole(quantity.Cpu, 1500); got.FloatString(1) != "1.5" {
+		t.Errorf("expected 1.5 cores for 1500 millicores but got %s", got.FloatString(1))
+	}
+	if got := quantity.Whole(quantity.Memory, 16); got.FloatString(0) != "16" {
+		t.Errorf("expected 16 GiB but got %s", got.FloatString(0))
+	}
+}
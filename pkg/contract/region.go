@@ -0,0 +1,231 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+var (
+	// ErrRegionalQuotaExceeded is returned if the regional quotas of a contract would exceed its quota.
+	ErrRegionalQuotaExceeded = errors.New("regional quotas exceed quota of contract")
+	// ErrNoRegionalQuota is returned on releasing a regional quota which is not allocated.
+	ErrNoRegionalQuota = errors.New("no regional quota")
+)
+
+// historyRegionalQuotas is a snapshot of the regional quotas of a contract in history records.
+type historyRegionalQuotas struct {
+	RegionalQuotas []historyRegionalQuota `json:"regionalQuotas"`
+}
+
+type historyRegionalQuota struct {
+	Csp    string `json:"csp"`
+	Region string `json:"region,omitempty"`
+	historyQuota
+}
+
+func newHistoryRegionalQuotas(quotas []model.RegionalQuota) *historyRegionalQuotas {
+	h := &historyRegionalQuotas{RegionalQuotas: []historyRegionalQuota{}}
+	for _, q := range quotas {
+		h.RegionalQuotas = append(h.RegionalQuotas, historyRegionalQuota{
+			Csp:    q.Csp,
+			Region: q.Region,
+			historyQuota: historyQuota{
+				Cpu: q.Cpu, Memory: q.Memory, Block: q.Block, BlockSsd: q.BlockSsd, Fs: q.Fs, FsSsd: q.FsSsd,
+			},
+		})
+	}
+	return h
+}
+
+// ValidateRegionalQuota returns an error if a regional quota has an invalid CSP or region, or a negative quota.
+func ValidateRegionalQuota(q model.RegionalQuota) error {
+	if q.Csp == "" || ValidateLabelValue(q.Csp) != nil {
+		return fmt.Errorf("invalid CSP %q of regional quota", q.Csp)
+	}
+	if err := ValidateLabelValue(q.Region); err != nil {
+		return fmt.Errorf("invalid region %q of regional quota", q.Region)
+	}
+	if q.Cpu < 0 || q.Memory < 0 || q.Block < 0 || q.BlockSsd < 0 || q.Fs < 0 || q.FsSsd < 0 {
+		return fmt.Errorf("regional quota of %s must not be negative", regionName(q.Csp, q.Region))
+	}
+	return nil
+}
+
+// ValidateRegionalQuotas returns an error if any of regional quotas is invalid, or if two of them
+// are of the same CSP and region.
+func ValidateRegionalQuotas(quotas []model.RegionalQuota) error {
+	seen := map[string]bool{}
+	for _, q := range quotas {
+		if err := ValidateRegionalQuota(q); err != nil {
+			return err
+		}
+		name := regionName(q.Csp, q.Region)
+		if seen[name] {
+			return fmt.Errorf("duplicate regional quota for %s", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// ListRegionalQuotas returns the regional quotas of a contract ordered by CSP and region.
+func (x *Accessor) ListRegionalQuotas(ctx context.Context, contractID string) ([]model.RegionalQuota, error) {
+	if _, err := findContract(x.db.WithContext(ctx), contractID); err != nil {
+		return nil, err
+	}
+	return findRegionalQuotas(x.db.WithContext(ctx), contractID)
+}
+
+// AllocateRegionalQuota allocates a slice of the quota of a contract to a region, replacing the
+// regional quota of the same CSP and region if any.
+func (x *Accessor) AllocateRegionalQuota(ctx context.Context, contractID string, quota model.RegionalQuota) (
+	prev []model.RegionalQuota, curr []model.RegionalQuota, err error) {
+	return x.changeRegionalQuotas(ctx, contractID, func(quotas []model.RegionalQuota) ([]model.RegionalQuota, error) {
+		for i := range quotas {
+			if quotas[i].Csp == quota.Csp && quotas[i].Region == quota.Region {
+				quotas[i] = quota
+				return quotas, nil
+			}
+		}
+		return append(quotas, quota), nil
+	})
+}
+
+// ReleaseRegionalQuota releases the regional quota of a CSP and region back to the contract.
+func (x *Accessor) ReleaseRegionalQuota(ctx context.Context, contractID string, csp string, region string) (
+	prev []model.RegionalQuota, curr []model.RegionalQuota, err error) {
+	return x.changeRegionalQuotas(ctx, contractID, func(quotas []model.RegionalQuota) ([]model.RegionalQuota, error) {
+		for i := range quotas {
+			if quotas[i].Csp == csp && quotas[i].Region == region {
+				return append(quotas[:i], quotas[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("%w for %s of contract %s", ErrNoRegionalQuota, regionName(csp, region), contractID)
+	})
+}
+
+// RebalanceRegionalQuotas replaces all the regional quotas of a contract at once, so that quotas
+// can be moved between regions. Regions which are not in quotas are released.
+func (x *Accessor) RebalanceRegionalQuotas(ctx context.Context, contractID string, quotas []model.RegionalQuota) (
+	prev []model.RegionalQuota, curr []model.RegionalQuota, err error) {
+	return x.changeRegionalQuotas(ctx, contractID, func([]model.RegionalQuota) ([]model.RegionalQuota, error) {
+		return append([]model.RegionalQuota{}, quotas...), nil
+	})
+}
+
+// changeRegionalQuotas replaces the regional quotas of a contract with the result of change, and
+// records the change in history. The quota of the contract is locked while checking the regional quotas against it.
+func (x *Accessor) changeRegionalQuotas(ctx context.Context, contractID string,
+	change func([]model.RegionalQuota) ([]model.RegionalQuota, error)) (prev []model.RegionalQuota, curr []model.RegionalQuota, err error) {
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var quota model.ResourceQuota
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&quota, "contract_id = ?", contractID)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return fmt.Errorf("Not found quota for contract id %s", contractID)
+		}
+		if prev, err = findRegionalQuotas(tx, contractID); err != nil {
+			return err
+		}
+		next, err := change(append([]model.RegionalQuota{}, prev...))
+		if err != nil {
+			return err
+		}
+
+		if err := ValidateRegionalQuotas(next); err != nil {
+			return err
+		}
+		ids := map[string]model.RegionalQuota{}
+		for _, q := range prev {
+			ids[regionName(q.Csp, q.Region)] = q
+		}
+		for i := range next {
+			name := regionName(next[i].Csp, next[i].Region)
+			next[i].ContractID = contractID
+			next[i].ID = ids[name].ID
+			next[i].CreatedAt = ids[name].CreatedAt
+		}
+
+		if res := tx.Delete(&model.RegionalQuota{}, "contract_id = ?", contractID); res.Error != nil {
+			return res.Error
+		}
+		if len(next) > 0 {
+			if res := tx.Create(&next); res.Error != nil {
+				return res.Error
+			}
+		}
+		if err := checkRegionalQuotas(tx, contractID); err != nil {
+			return err
+		}
+		if curr, err = findRegionalQuotas(tx, contractID); err != nil {
+			return err
+		}
+		return recordHistory(tx, contractID, HistoryRegionalQuotasUpdated,
+			newHistoryRegionalQuotas(prev), newHistoryRegionalQuotas(curr))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return prev, curr, nil
+}
+
+// SumRegionalQuotas returns the sum of regional quotas.
+func SumRegionalQuotas(quotas []model.RegionalQuota) model.ResourceQuota {
+	var sum model.ResourceQuota
+	for _, q := range quotas {
+		sum.Cpu += q.Cpu
+		sum.Memory += q.Memory
+		sum.Block += q.Block
+		sum.BlockSsd += q.BlockSsd
+		sum.Fs += q.Fs
+		sum.FsSsd += q.FsSsd
+	}
+	return sum
+}
+
+// checkRegionalQuotas returns an error if the sum of the regional quotas of a contract exceeds
+// its quota in any resource.
+func checkRegionalQuotas(tx *gorm.DB, contractID string) error {
+	quota, err := findQuota(tx, contractID)
+	if err != nil {
+		return err
+	}
+	quotas, err := findRegionalQuotas(tx, contractID)
+	if err != nil {
+		return err
+	}
+	if name, q, a, ok := exceeded(quota, SumRegionalQuotas(quotas)); ok {
+		return fmt.Errorf("%w : %s of regional quotas %d exceeds %d of contract %s",
+			ErrRegionalQuotaExceeded, name, a, q, contractID)
+	}
+	return nil
+}
+
+func findRegionalQuotas(db *gorm.DB, contractID string) ([]model.RegionalQuota, error) {
+	var quotas []model.RegionalQuota
+	res := db.Where("contract_id = ?", contractID).Find(&quotas)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	sort.Slice(quotas, func(i, j int) bool {
+		if quotas[i].Csp != quotas[j].Csp {
+			return quotas[i].Csp < quotas[j].Csp
+		}
+		return quotas[i].Region < quotas[j].Region
+	})
+	return quotas, nil
+}
+
+// regionName returns the name of a regional quota in messages, such as aws/ap-northeast-2.
+func regionName(csp string, region string) string {
+	if region == "" {
+		return csp
+	}
+	return csp + "/" + region
+}
@@ -0,0 +1,83 @@
+package contract_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestRegionalQuotas(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	contractID, err := accessor.Create(ctx, "regional", []string{}, &pb.ContractQuota{Cpu: 100, Memory: 400}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+
+	seoul := model.RegionalQuota{Csp: "aws", Region: "ap-northeast-2", Cpu: 60, Memory: 200}
+	if _, _, err := accessor.AllocateRegionalQuota(ctx, contractID, seoul); err != nil {
+		t.Fatalf("an error was unexpected while allocating regional quota %s", err)
+	}
+	tokyo := model.RegionalQuota{Csp: "aws", Region: "ap-northeast-1", Cpu: 50}
+	if _, _, err := accessor.AllocateRegionalQuota(ctx, contractID, tokyo); !errors.Is(err, contract.ErrRegionalQuotaExceeded) {
+		t.Errorf("expected ErrRegionalQuotaExceeded for cpu 110 of 100, got %v", err)
+	}
+	tokyo.Cpu = 40
+	prev, curr, err := accessor.AllocateRegionalQuota(ctx, contractID, tokyo)
+	if err != nil || len(prev) != 1 || len(curr) != 2 || curr[0].Region != "ap-northeast-1" {
+		t.Fatalf("unexpected regional quotas %+v, err %v", curr, err)
+	}
+
+	// the total cannot be lowered below the regional quotas
+	if _, _, err := accessor.UpdateResourceQuota(ctx, contractID, &pb.ContractQuota{Cpu: 90}); !errors.Is(err, contract.ErrRegionalQuotaExceeded) {
+		t.Errorf("expected ErrRegionalQuotaExceeded for lowering cpu to 90, got %v", err)
+	}
+
+	// move cpu from Seoul to Tokyo at once
+	seoul.Cpu, tokyo.Cpu = 30, 70
+	if _, curr, err = accessor.RebalanceRegionalQuotas(ctx, contractID, []model.RegionalQuota{seoul, tokyo}); err != nil {
+		t.Fatalf("an error was unexpected while rebalancing regional quotas %s", err)
+	}
+	if curr[0].Cpu != 70 || curr[1].Cpu != 30 {
+		t.Errorf("unexpected regional quotas %+v", curr)
+	}
+	if _, _, err := accessor.RebalanceRegionalQuotas(ctx, contractID, []model.RegionalQuota{seoul, seoul}); err == nil {
+		t.Errorf("expected an error for duplicate regional quotas")
+	}
+	if _, _, err := accessor.AllocateRegionalQuota(ctx, contractID, model.RegionalQuota{Csp: "", Cpu: 1}); err == nil {
+		t.Errorf("expected an error for a regional quota without CSP")
+	}
+
+	if _, _, err := accessor.ReleaseRegionalQuota(ctx, contractID, "gcp", ""); !errors.Is(err, contract.ErrNoRegionalQuota) {
+		t.Errorf("expected ErrNoRegionalQuota, got %v", err)
+	}
+	if _, curr, err = accessor.ReleaseRegionalQuota(ctx, contractID, "aws", "ap-northeast-1"); err != nil || len(curr) != 1 {
+		t.Errorf("unexpected regional quotas %+v, err %v", curr, err)
+	}
+	if _, _, err := accessor.UpdateResourceQuota(ctx, contractID, &pb.ContractQuota{Cpu: 30}); err != nil {
+		t.Errorf("an error was unexpected while updating quota %s", err)
+	}
+
+	records, err := accessor.GetHistory(ctx, contractID, 0, 10)
+	if err != nil || len(records) == 0 {
+		t.Fatalf("unexpected history %+v, err %v", records, err)
+	}
+	var n int
+	for _, r := range records {
+		if r.Action == contract.HistoryRegionalQuotasUpdated {
+			n++
+		}
+	}
+	if n != 4 {
+		t.Errorf("expected 4 changes of regional quotas in history but got %d", n)
+	}
+}
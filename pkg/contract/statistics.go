@@ -0,0 +1,50 @@
+package contract
+
+import (
+	"context"
+
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// Statistics represents aggregated values across all contracts.
+type Statistics struct {
+	Contracts int64
+	Cpu       int64
+	Memory    int64
+	Block     int64
+	BlockSsd  int64
+	Fs        int64
+	FsSsd     int64
+}
+
+// GetStatistics returns the number of contracts and the total quota allocated to them.
+// The quotas of sub-contracts are included in the quotas of their top-level ancestors.
+func (x *Accessor) GetStatistics(ctx context.Context) (Statistics, error) {
+	var (
+		stats Statistics
+		total model.ResourceQuota
+	)
+	if res := x.db.WithContext(ctx).Model(&model.Contract{}).Count(&stats.Contracts); res.Error != nil {
+		return Statistics{}, res.Error
+	}
+
+	// the quotas of sub-contracts are carved out of the quotas of their parents, so only
+	// the quotas of top-level contracts are summed.
+	res := x.db.WithContext(ctx).Model(&model.ResourceQuota{}).
+		Select("COALESCE(SUM(resource_quota.cpu), 0) AS cpu, COALESCE(SUM(resource_quota.memory), 0) AS memory, " +
+			"COALESCE(SUM(resource_quota.block), 0) AS block, COALESCE(SUM(resource_quota.block_ssd), 0) AS block_ssd, " +
+			"COALESCE(SUM(resource_quota.fs), 0) AS fs, COALESCE(SUM(resource_quota.fs_ssd), 0) AS fs_ssd").
+		Joins("JOIN contracts ON contracts.id = resource_quota.contract_id").
+		Where("contracts.parent_id IS NULL").
+		Scan(&total)
+	if res.Error != nil {
+		return Statistics{}, res.Error
+	}
+	stats.Cpu = total.Cpu
+	stats.Memory = total.Memory
+	stats.Block = total.Block
+	stats.BlockSsd = total.BlockSsd
+	stats.Fs = total.Fs
+	stats.FsSsd = total.FsSsd
+	return stats, nil
+}
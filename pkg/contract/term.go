@@ -0,0 +1,210 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// State is the state of a contract, which is changed by the expiry of its term.
+type State string
+
+const (
+	// StateActive is the state of a contract whose term is not over.
+	StateActive State = "active"
+	// StateReadOnly is the state of an expired contract which can be read but not changed.
+	StateReadOnly State = "read_only"
+	// StateSuspended is the state of an expired contract whose services are to be stopped.
+	StateSuspended State = "suspended"
+)
+
+// ValidateExpiredState returns an error if state is not a state for expired contracts.
+func ValidateExpiredState(state State) error {
+	switch state {
+	case StateReadOnly, StateSuspended:
+		return nil
+	}
+	return fmt.Errorf("invalid state %q for expired contracts, must be %s or %s", state, StateReadOnly, StateSuspended)
+}
+
+// Term is the term of a contract and the state which its expiry leads to.
+type Term struct {
+	EffectiveFrom time.Time
+	// ExpiresAt is nil if the contract does not expire.
+	ExpiresAt *time.Time
+	State     State
+	// ExpiryWarnedAt is when the contract was flagged as nearing expiry in the current term.
+	ExpiryWarnedAt *time.Time
+}
+
+// historyTerm is a snapshot of a term in history records.
+type historyTerm struct {
+	EffectiveFrom time.Time  `json:"effectiveFrom"`
+	ExpiresAt     *time.Time `json:"expiresAt"`
+	State         State      `json:"state"`
+}
+
+func newTerm(contract model.Contract) Term {
+	term := Term{
+		EffectiveFrom:  contract.CreatedAt,
+		ExpiresAt:      contract.ExpiresAt,
+		State:          State(contract.State),
+		ExpiryWarnedAt: contract.ExpiryWarnedAt,
+	}
+	if contract.EffectiveFrom != nil {
+		term.EffectiveFrom = *contract.EffectiveFrom
+	}
+	if term.State == "" {
+		term.State = StateActive
+	}
+	return term
+}
+
+func newHistoryTerm(term Term) historyTerm {
+	return historyTerm{EffectiveFrom: term.EffectiveFrom, ExpiresAt: term.ExpiresAt, State: term.State}
+}
+
+// GetTerm returns the term of a contract.
+func (x *Accessor) GetTerm(ctx context.Context, contractID string) (Term, error) {
+	var contract model.Contract
+	res := x.db.WithContext(ctx).Limit(1).Find(&contract, "id = ?", contractID)
+	if res.RowsAffected == 0 || res.Error != nil {
+		return Term{}, fmt.Errorf("Not found contract for %s", contractID)
+	}
+	return newTerm(contract), nil
+}
+
+// Renew extends the term of a contract to expiresAt and makes it active again. The new expiry must
+// be later than now and the current expiry. If effectiveFrom is not nil, the term starts from it.
+func (x *Accessor) Renew(ctx context.Context, contractID string, effectiveFrom *time.Time, expiresAt time.Time) (prev Term, curr Term, err error) {
+	if !expiresAt.After(time.Now()) {
+		return Term{}, Term{}, fmt.Errorf("expiry %s must be in the future", expiresAt.Format(time.RFC3339))
+	}
+
+	err = x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var contract model.Contract
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(&contract, "id = ?", contractID)
+		if res.RowsAffected == 0 || res.Error != nil {
+			return fmt.Errorf("Not found contract for %s", contractID)
+		}
+		prev = newTerm(contract)
+		if prev.ExpiresAt != nil && !expiresAt.After(*prev.ExpiresAt) {
+			return fmt.Errorf("expiry %s must be later than the current expiry %s",
+				expiresAt.Format(time.RFC3339), prev.ExpiresAt.Format(time.RFC3339))
+		}
+		curr = Term{EffectiveFrom: prev.EffectiveFrom, ExpiresAt: &expiresAt, State: StateActive}
+		if effectiveFrom != nil {
+			curr.EffectiveFrom = *effectiveFrom
+		}
+		if !expiresAt.After(curr.EffectiveFrom) {
+			return fmt.Errorf("expiry %s must be later than the start %s",
+				expiresAt.Format(time.RFC3339), curr.EffectiveFrom.Format(time.RFC3339))
+		}
+
+		res = tx.Model(&model.Contract{}).Where("id = ?", contractID).Updates(map[string]interface{}{
+			"effective_from":   curr.EffectiveFrom,
+			"expires_at":       expiresAt,
+			"state":            string(StateActive),
+			"expiry_warned_at": nil,
+		})
+		if res.Error != nil {
+			return fmt.Errorf("could not renew contract id %s: %s", contractID, res.Error)
+		}
+		return recordHistory(tx, contractID, HistoryRenewed, newHistoryTerm(prev), newHistoryTerm(curr))
+	})
+	if err != nil {
+		return Term{}, Term{}, err
+	}
+	x.invalidate(ctx, contractID)
+	log.Info("renewed contract ID ", contractID, " until ", expiresAt.Format(time.RFC3339))
+	return prev, curr, nil
+}
+
+// WarnExpiring flags active contracts which expire within period from now, and returns those
+// which are newly flagged. A contract is flagged once per term, even if several servers run it.
+func (x *Accessor) WarnExpiring(ctx context.Context, now time.Time, period time.Duration) ([]model.Contract, error) {
+	var candidates []model.Contract
+	res := x.db.WithContext(ctx).
+		Where("state = ? AND expires_at > ? AND expires_at <= ? AND expiry_warned_at IS NULL",
+			string(StateActive), now, now.Add(period)).
+		Order("expires_at").Find(&candidates)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	var warned []model.Contract
+	for _, contract := range candidates {
+		res := x.db.WithContext(ctx).Model(&model.Contract{}).
+			Where("id = ? AND expiry_warned_at IS NULL", contract.ID).
+			Update("expiry_warned_at", now)
+		if res.Error != nil {
+			return warned, res.Error
+		}
+		if res.RowsAffected == 1 {
+			x.invalidate(ctx, contract.ID)
+			contract.ExpiryWarnedAt = &now
+			warned = append(warned, contract)
+		}
+	}
+	return warned, nil
+}
+
+// Expire changes active contracts which expired by now into state, and returns those which are
+// changed. A contract is changed once, even if several servers run it.
+func (x *Accessor) Expire(ctx context.Context, now time.Time, state State) ([]model.Contract, error) {
+	if err := ValidateExpiredState(state); err != nil {
+		return nil, err
+	}
+	var candidates []model.Contract
+	res := x.db.WithContext(ctx).
+		Where("state = ? AND expires_at <= ?", string(StateActive), now).
+		Order("expires_at").Find(&candidates)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	var expired []model.Contract
+	for _, contract := range candidates {
+		prev := newTerm(contract)
+		changed := false
+		err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&model.Contract{}).
+				Where("id = ? AND state = ?", contract.ID, string(StateActive)).
+				Update("state", string(state))
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return nil
+			}
+			changed = true
+			curr := prev
+			curr.State = state
+			return recordHistory(tx, contract.ID, HistoryExpired, newHistoryTerm(prev), newHistoryTerm(curr))
+		})
+		if err != nil {
+			return expired, err
+		}
+		if changed {
+			x.invalidate(ctx, contract.ID)
+			contract.State = string(state)
+			expired = append(expired, contract)
+		}
+	}
+	return expired, nil
+}
+
+// CountExpiring returns the number of active contracts which expire within period from now.
+func (x *Accessor) CountExpiring(ctx context.Context, now time.Time, period time.Duration) (int64, error) {
+	var n int64
+	res := x.db.WithContext(ctx).Model(&model.Contract{}).
+		Where("state = ? AND expires_at > ? AND expires_at <= ?", string(StateActive), now, now.Add(period)).
+		Count(&n)
+	return n, res.Error
+}
@@ -0,0 +1,97 @@
+package contract_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestTerm(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	ctx := context.Background()
+	contractID, err := accessor.Create(ctx, "term", []string{}, &pb.ContractQuota{}, uuid.Nil, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+
+	term, err := accessor.GetTerm(ctx, contractID)
+	if err != nil || term.State != contract.StateActive || term.ExpiresAt != nil || term.EffectiveFrom.IsZero() {
+		t.Fatalf("unexpected term of a new contract %+v, err %v", term, err)
+	}
+
+	now := time.Now()
+	if _, _, err := accessor.Renew(ctx, contractID, nil, now.Add(-time.Hour)); err == nil {
+		t.Errorf("expected an error for an expiry in the past")
+	}
+	expiresAt := now.Add(10 * 24 * time.Hour)
+	prev, curr, err := accessor.Renew(ctx, contractID, nil, expiresAt)
+	if err != nil {
+		t.Fatalf("an error was unexpected while renewing contract %s", err)
+	}
+	if prev.ExpiresAt != nil || curr.ExpiresAt == nil || !curr.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("unexpected terms %+v and %+v", prev, curr)
+	}
+	if _, _, err := accessor.Renew(ctx, contractID, nil, expiresAt.Add(-time.Hour)); err == nil {
+		t.Errorf("expected an error for a renewal which shortens the term")
+	}
+
+	warned, err := accessor.WarnExpiring(ctx, now, 30*24*time.Hour)
+	if err != nil || !containsContract(warned, contractID) {
+		t.Fatalf("expected contract %s to be warned, got %v, err %v", contractID, warned, err)
+	}
+	warned, err = accessor.WarnExpiring(ctx, now, 30*24*time.Hour)
+	if err != nil || containsContract(warned, contractID) {
+		t.Errorf("expected contract %s to be warned once, err %v", contractID, err)
+	}
+
+	if _, err := accessor.Expire(ctx, now, contract.StateActive); err == nil {
+		t.Errorf("expected an error for an invalid expired state")
+	}
+	expired, err := accessor.Expire(ctx, expiresAt.Add(time.Second), contract.StateReadOnly)
+	if err != nil || !containsContract(expired, contractID) {
+		t.Fatalf("expected contract %s to be expired, got %v, err %v", contractID, expired, err)
+	}
+	term, err = accessor.GetTerm(ctx, contractID)
+	if err != nil || term.State != contract.StateReadOnly {
+		t.Errorf("expected state %s but got %s, err %v", contract.StateReadOnly, term.State, err)
+	}
+
+	_, curr, err = accessor.Renew(ctx, contractID, nil, expiresAt.Add(365*24*time.Hour))
+	if err != nil || curr.State != contract.StateActive {
+		t.Fatalf("expected a renewed contract to be active, got %+v, err %v", curr, err)
+	}
+	term, err = accessor.GetTerm(ctx, contractID)
+	if err != nil || term.State != contract.StateActive || term.ExpiryWarnedAt != nil {
+		t.Errorf("unexpected term after renewal %+v, err %v", term, err)
+	}
+
+	records, err := accessor.GetHistory(ctx, contractID, 0, 100)
+	if err != nil {
+		t.Fatalf("an error was unexpected while getting history %s", err)
+	}
+	actions := map[string]int{}
+	for _, r := range records {
+		actions[r.Action]++
+	}
+	if actions[contract.HistoryRenewed] != 2 || actions[contract.HistoryExpired] != 1 {
+		t.Errorf("unexpected history actions %v", actions)
+	}
+}
+
+func containsContract(contracts []model.Contract, id string) bool {
+	for _, c := range contracts {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
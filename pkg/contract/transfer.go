@@ -0,0 +1,456 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+
+	"github.com/openinfradev/tks-common/pkg/helper"
+	"github.com/openinfradev/tks-common/pkg/log"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+// ConflictPolicy decides how an imported contract is handled when a contract with
+// the same id or contractor name already exists.
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictFail      ConflictPolicy = "fail"
+)
+
+// ParseConflictPolicy returns the conflict policy named s. The default is ConflictSkip.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case "":
+		return ConflictSkip, nil
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid conflict policy %s", s)
+}
+
+// Actions of import results.
+const (
+	ImportCreated     = "created"
+	ImportOverwritten = "overwritten"
+	ImportSkipped     = "skipped"
+	ImportFailed      = "failed"
+)
+
+// ContractRecord is a contract with its quota and members, which is exported and imported as a unit.
+type ContractRecord struct {
+	Contract model.Contract
+	Quota    model.ResourceQuota
+	// Members of the contract. On overwrite, nil keeps the existing members.
+	Members []model.ContractMember
+}
+
+// ImportResult is the result of importing a contract record.
+type ImportResult struct {
+	ContractID     string
+	ContractorName string
+	Action         string
+	Error          string
+	// replacedDefault is the default contract which the imported contract replaced.
+	replacedDefault string
+}
+
+// ImportReport is the result of an import. Committed is false for a dry run, or if the import
+// is rolled back because a record failed under ConflictFail.
+type ImportReport struct {
+	DryRun    bool
+	Committed bool
+	Results   []ImportResult
+}
+
+// Count returns the number of results with action.
+func (r ImportReport) Count(action string) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// errRollback rolls back an import transaction without failing the import.
+var errRollback = errors.New("rollback")
+
+// Export returns contracts with their quota and members, ordered by creation time.
+// If ids is empty, all contracts are returned.
+func (x *Accessor) Export(ctx context.Context, ids []string) ([]ContractRecord, error) {
+	var contracts []model.Contract
+	db := x.db.WithContext(ctx).Order("created_at, id")
+	if len(ids) > 0 {
+		db = db.Where("id IN ?", ids)
+	}
+	if res := db.Find(&contracts); res.Error != nil {
+		return nil, res.Error
+	}
+	if len(ids) > 0 {
+		found := map[string]bool{}
+		for _, contract := range contracts {
+			found[contract.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				return nil, fmt.Errorf("Not found contract for %s", id)
+			}
+		}
+	}
+
+	records := make([]ContractRecord, 0, len(contracts))
+	for _, contract := range contracts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		record := ContractRecord{Contract: contract}
+		res := x.db.WithContext(ctx).Limit(1).Find(&record.Quota, "contract_id = ?", contract.ID)
+		if res.RowsAffected == 0 || res.Error != nil {
+			return nil, fmt.Errorf("Not found quota for contract id %s", contract.ID)
+		}
+		members, err := x.ListMembers(ctx, contract.ID)
+		if err != nil {
+			return nil, err
+		}
+		record.Members = members
+		records = append(records, record)
+	}
+	return orderParentsFirst(records), nil
+}
+
+// orderParentsFirst returns records in which a parent comes before its children, so that they are
+// imported in order. Otherwise, the order of records is kept.
+func orderParentsFirst(records []ContractRecord) []ContractRecord {
+	index := map[string]int{}
+	for i, r := range records {
+		index[r.Contract.ID] = i
+	}
+	ordered := make([]ContractRecord, 0, len(records))
+	added := make([]bool, len(records))
+	var add func(i int)
+	add = func(i int) {
+		if added[i] {
+			return
+		}
+		added[i] = true
+		if p := records[i].Contract.ParentID; p != nil {
+			if j, ok := index[*p]; ok {
+				add(j)
+			}
+		}
+		ordered = append(ordered, records[i])
+	}
+	for i := range records {
+		add(i)
+	}
+	return ordered
+}
+
+// Import creates or overwrites contracts from records in a transaction. Each record is imported
+// in a savepoint, so that a failed record does not affect the others unless policy is ConflictFail,
+// which rolls back the whole import. A dry run reports the results and rolls back.
+func (x *Accessor) Import(ctx context.Context, records []ContractRecord, policy ConflictPolicy, dryRun bool) (ImportReport, error) {
+	report := ImportReport{DryRun: dryRun}
+	failed := false
+	err := x.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range records {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			r := &records[i]
+			result := ImportResult{ContractID: r.Contract.ID, ContractorName: r.Contract.ContractorName}
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				return importContract(tx, r, policy, &result)
+			})
+			if err != nil {
+				result.Action = ImportFailed
+				result.Error = err.Error()
+				failed = true
+			}
+			report.Results = append(report.Results, result)
+		}
+		if dryRun || (failed && policy == ConflictFail) {
+			return errRollback
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+	report.Committed = true
+	for _, result := range report.Results {
+		if result.Action == ImportOverwritten {
+			x.invalidate(ctx, result.ContractID)
+		}
+		x.invalidate(ctx, result.replacedDefault)
+	}
+	log.Info("imported contracts. created : ", report.Count(ImportCreated), ", overwritten : ",
+		report.Count(ImportOverwritten), ", skipped : ", report.Count(ImportSkipped), ", failed : ", report.Count(ImportFailed))
+	return report, nil
+}
+
+func validateRecord(r *ContractRecord) error {
+	if r.Contract.ContractorName == "" {
+		return fmt.Errorf("contractor name must be specified")
+	}
+	if r.Contract.ID != "" && !helper.ValidateContractId(r.Contract.ID) {
+		return fmt.Errorf("invalid contract ID %s", r.Contract.ID)
+	}
+	if p := r.Contract.ParentID; p != nil && !helper.ValidateContractId(*p) {
+		return fmt.Errorf("invalid parent contract ID %s", *p)
+	}
+	if err := ValidateLabels(r.Contract.Labels); err != nil {
+		return err
+	}
+	if err := ValidateAnnotations(r.Contract.Annotations); err != nil {
+		return err
+	}
+	switch State(r.Contract.State) {
+	case "", StateActive, StateReadOnly, StateSuspended:
+	default:
+		return fmt.Errorf("invalid state %q", r.Contract.State)
+	}
+	profile := ProfileUpdate{}.apply(newProfile(r.Contract))
+	if err := ValidateProfile(profile); err != nil {
+		return err
+	}
+	r.Contract.BusinessRegistrationNumber = profile.BusinessRegistrationNumber
+	if p := r.Contract.PricePlan; p != "" && ValidateLabelValue(p) != nil {
+		return fmt.Errorf("invalid price plan %q", p)
+	}
+	q := r.Quota
+	if q.Cpu < 0 || q.Memory < 0 || q.Block < 0 || q.BlockSsd < 0 || q.Fs < 0 || q.FsSsd < 0 {
+		return fmt.Errorf("quota must not be negative")
+	}
+	for _, m := range r.Members {
+		if m.UserID == uuid.Nil {
+			return fmt.Errorf("user id of member must be specified")
+		}
+		if err := ValidateRole(Role(m.Role)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importContract imports a record in tx and sets the action and contract id of result.
+func importContract(tx *gorm.DB, r *ContractRecord, policy ConflictPolicy, result *ImportResult) error {
+	if err := validateRecord(r); err != nil {
+		return err
+	}
+
+	var existing []model.Contract
+	db := tx.Where("contractor_name = ?", r.Contract.ContractorName)
+	if r.Contract.ID != "" {
+		db = db.Or("id = ?", r.Contract.ID)
+	}
+	if res := db.Find(&existing); res.Error != nil {
+		return res.Error
+	}
+	if len(existing) == 0 {
+		return createRecord(tx, r, result)
+	}
+
+	switch policy {
+	case ConflictSkip:
+		result.ContractID = existing[0].ID
+		result.Action = ImportSkipped
+		return nil
+	case ConflictFail:
+		return fmt.Errorf("contract %s already exists", existing[0].ID)
+	}
+	if len(existing) > 1 {
+		return fmt.Errorf("contract %s and contractor name %s belong to different contracts",
+			r.Contract.ID, r.Contract.ContractorName)
+	}
+	if r.Contract.ID != "" && r.Contract.ID != existing[0].ID {
+		return fmt.Errorf("contractor name %s is used by contract %s", r.Contract.ContractorName, existing[0].ID)
+	}
+	result.ContractID = existing[0].ID
+	return overwriteRecord(tx, existing[0], r, result)
+}
+
+func createRecord(tx *gorm.DB, r *ContractRecord, result *ImportResult) error {
+	contract := model.Contract{
+		ID:                r.Contract.ID,
+		ContractorName:    r.Contract.ContractorName,
+		AvailableServices: pq.StringArray(r.Contract.AvailableServices),
+		Creator:           r.Contract.Creator,
+		Description:       r.Contract.Description,
+		Labels:            r.Contract.Labels,
+		Annotations:       r.Contract.Annotations,
+		ParentID:          r.Contract.ParentID,
+		EffectiveFrom:     r.Contract.EffectiveFrom,
+		ExpiresAt:         r.Contract.ExpiresAt,
+		State:             string(StateActive),
+		PricePlan:         DefaultPricePlan,
+		CreatedAt:         r.Contract.CreatedAt,
+
+		BusinessRegistrationNumber: r.Contract.BusinessRegistrationNumber,
+		BillingAddress:             r.Contract.BillingAddress,
+		ContactEmail:               r.Contract.ContactEmail,
+		ContactPhone:               r.Contract.ContactPhone,
+	}
+	if r.Contract.State != "" {
+		contract.State = r.Contract.State
+	}
+	if r.Contract.PricePlan != "" {
+		contract.PricePlan = r.Contract.PricePlan
+	}
+	if contract.ParentID != nil {
+		if err := lockContract(tx, *contract.ParentID); err != nil {
+			return err
+		}
+	}
+	if res := tx.Create(&contract); res.Error != nil {
+		return res.Error
+	}
+	quota := quotaValues(r.Quota)
+	quota.ContractID = contract.ID
+	if res := tx.Create(&quota); res.Error != nil {
+		return res.Error
+	}
+	if err := checkQuotaEnvelope(tx, contract.ID); err != nil {
+		return err
+	}
+	for _, m := range r.Members {
+		if err := addMember(tx, contract.ID, m.UserID, Role(m.Role)); err != nil {
+			return err
+		}
+	}
+	pbQuota := reflectToPbQuota(quota)
+	if err := recordHistory(tx, contract.ID, HistoryImported, nil, newHistoryContract(contract, &pbQuota)); err != nil {
+		return err
+	}
+	if r.Contract.IsDefault {
+		prev, err := replaceDefault(tx, contract.ID)
+		if err != nil {
+			return err
+		}
+		result.replacedDefault = prev
+	}
+	result.ContractID = contract.ID
+	result.Action = ImportCreated
+	return nil
+}
+
+func overwriteRecord(tx *gorm.DB, contract model.Contract, r *ContractRecord, result *ImportResult) error {
+	var prevQuota model.ResourceQuota
+	if res := tx.Limit(1).Find(&prevQuota, "contract_id = ?", contract.ID); res.Error != nil {
+		return res.Error
+	}
+	pbPrevQuota := reflectToPbQuota(prevQuota)
+	prev := newHistoryContract(contract, &pbPrevQuota)
+
+	contract.ContractorName = r.Contract.ContractorName
+	contract.AvailableServices = pq.StringArray(r.Contract.AvailableServices)
+	contract.Description = r.Contract.Description
+	state := r.Contract.State
+	if state == "" {
+		state = string(StateActive)
+	}
+	contract.PricePlan = r.Contract.PricePlan
+	if contract.PricePlan == "" {
+		contract.PricePlan = DefaultPricePlan
+	}
+	if p := r.Contract.ParentID; p != nil {
+		if err := lockContract(tx, *p); err != nil {
+			return err
+		}
+		if err := checkCycle(tx, contract.ID, *p); err != nil {
+			return err
+		}
+	}
+	res := tx.Model(&model.Contract{}).Where("id = ?", contract.ID).Updates(map[string]interface{}{
+		"contractor_name":    contract.ContractorName,
+		"available_services": contract.AvailableServices,
+		"description":        contract.Description,
+		"labels":             r.Contract.Labels,
+		"annotations":        r.Contract.Annotations,
+		"effective_from":     r.Contract.EffectiveFrom,
+		"expires_at":         r.Contract.ExpiresAt,
+		"state":              state,
+		"parent_id":          r.Contract.ParentID,
+		"price_plan":         contract.PricePlan,
+
+		"business_registration_number": r.Contract.BusinessRegistrationNumber,
+		"billing_address":              r.Contract.BillingAddress,
+		"contact_email":                r.Contract.ContactEmail,
+		"contact_phone":                r.Contract.ContactPhone,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	quota := quotaValues(r.Quota)
+	res = tx.Model(&model.ResourceQuota{}).Where("contract_id = ?", contract.ID).Updates(map[string]interface{}{
+		"cpu":       quota.Cpu,
+		"memory":    quota.Memory,
+		"block":     quota.Block,
+		"block_ssd": quota.BlockSsd,
+		"fs":        quota.Fs,
+		"fs_ssd":    quota.FsSsd,
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		quota.ContractID = contract.ID
+		if res := tx.Create(&quota); res.Error != nil {
+			return res.Error
+		}
+	}
+
+	if err := checkQuotaEnvelope(tx, contract.ID); err != nil {
+		return err
+	}
+
+	if r.Members != nil {
+		if res := tx.Delete(&model.ContractMember{}, "contract_id = ?", contract.ID); res.Error != nil {
+			return fmt.Errorf("could not delete members for contractId %s", contract.ID)
+		}
+		for _, m := range r.Members {
+			if err := addMember(tx, contract.ID, m.UserID, Role(m.Role)); err != nil {
+				return err
+			}
+		}
+	}
+
+	pbQuota := reflectToPbQuota(quota)
+	if err := recordHistory(tx, contract.ID, HistoryImported, prev, newHistoryContract(contract, &pbQuota)); err != nil {
+		return err
+	}
+	// a contract is not unflagged by a record, since there must be a default contract
+	if r.Contract.IsDefault && !contract.IsDefault {
+		prev, err := replaceDefault(tx, contract.ID)
+		if err != nil {
+			return err
+		}
+		result.replacedDefault = prev
+	}
+	result.Action = ImportOverwritten
+	return nil
+}
+
+// quotaValues returns a new resource quota with the values of quota.
+func quotaValues(quota model.ResourceQuota) model.ResourceQuota {
+	return model.ResourceQuota{
+		Cpu:      quota.Cpu,
+		Memory:   quota.Memory,
+		Block:    quota.Block,
+		BlockSsd: quota.BlockSsd,
+		Fs:       quota.Fs,
+		FsSsd:    quota.FsSsd,
+	}
+}
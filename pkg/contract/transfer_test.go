@@ -0,0 +1,110 @@
+package contract_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	pb "github.com/openinfradev/tks-proto/tks_pb"
+
+	"github.com/openinfradev/tks-contract/pkg/contract"
+	model "github.com/openinfradev/tks-contract/pkg/contract/model"
+)
+
+func TestExportImport(t *testing.T) {
+	accessor, err := getAccessor()
+	if err != nil {
+		t.Fatalf("an error was unexpected while initilizing database %s", err)
+	}
+	owner := uuid.New()
+	id, err := accessor.Create(context.Background(), "transfer", []string{"lma"}, &pb.ContractQuota{Cpu: 8, Memory: 32}, owner, "")
+	if err != nil {
+		t.Fatalf("an error was unexpected while creating new contract: %s", err)
+	}
+
+	number, email := "1234567890", "billing@transfer.example"
+	_, profile, err := accessor.UpdateContract(context.Background(), id,
+		contract.ProfileUpdate{BusinessRegistrationNumber: &number, ContactEmail: &email})
+	if err != nil {
+		t.Fatalf("an error was unexpected while updating the profile %s", err)
+	}
+
+	records, err := accessor.Export(context.Background(), []string{id})
+	if err != nil {
+		t.Fatalf("an error was unexpected while exporting contracts %s", err)
+	}
+	if len(records) != 1 || records[0].Quota.Memory != 32 || len(records[0].Members) != 1 ||
+		records[0].Contract.BusinessRegistrationNumber != "123-45-67890" || records[0].Contract.ContactEmail != email {
+		t.Fatalf("unexpected exported records %+v", records)
+	}
+	if _, err := accessor.Export(context.Background(), []string{id, "P00000000"}); err == nil {
+		t.Errorf("expected an error for unknown contract")
+	}
+
+	records[0].Quota.Cpu = 16
+	newRecord := contract.ContractRecord{
+		Contract: model.Contract{ContractorName: "transfer-new", AvailableServices: []string{"servicemesh"}},
+		Quota:    model.ResourceQuota{Cpu: 4},
+	}
+	invalid := contract.ContractRecord{Contract: model.Contract{ContractorName: ""}}
+
+	testCases := []struct {
+		name      string
+		records   []contract.ContractRecord
+		policy    contract.ConflictPolicy
+		dryRun    bool
+		actions   []string
+		committed bool
+		cpu       int64
+	}{
+		{"skip", []contract.ContractRecord{records[0]}, contract.ConflictSkip, false,
+			[]string{contract.ImportSkipped}, true, 8},
+		{"fail", []contract.ContractRecord{records[0], newRecord}, contract.ConflictFail, false,
+			[]string{contract.ImportFailed, contract.ImportCreated}, false, 8},
+		{"dry run", []contract.ContractRecord{records[0]}, contract.ConflictOverwrite, true,
+			[]string{contract.ImportOverwritten}, false, 8},
+		{"overwrite", []contract.ContractRecord{records[0], invalid}, contract.ConflictOverwrite, false,
+			[]string{contract.ImportOverwritten, contract.ImportFailed}, true, 16},
+	}
+	for _, tc := range testCases {
+		report, err := accessor.Import(context.Background(), tc.records, tc.policy, tc.dryRun)
+		if err != nil {
+			t.Fatalf("%s: an error was unexpected while importing contracts %s", tc.name, err)
+		}
+		if report.Committed != tc.committed || len(report.Results) != len(tc.actions) {
+			t.Fatalf("%s: unexpected report %+v", tc.name, report)
+		}
+		for i, action := range tc.actions {
+			if report.Results[i].Action != action {
+				t.Errorf("%s: expected %s for record %d but got %+v", tc.name, action, i, report.Results[i])
+			}
+		}
+		quota, err := accessor.GetResourceQuota(context.Background(), id)
+		if err != nil || quota.Cpu != tc.cpu {
+			t.Errorf("%s: expected cpu %d but got %d, err %v", tc.name, tc.cpu, quota.Cpu, err)
+		}
+	}
+
+	if _, err := accessor.Export(context.Background(), []string{id}); err != nil {
+		t.Errorf("an error was unexpected while exporting contracts %s", err)
+	}
+	history, err := accessor.GetHistory(context.Background(), id, 0, 1)
+	if err != nil || len(history) != 1 || history[0].Action != contract.HistoryImported {
+		t.Errorf("expected an imported history record but got %+v, err %v", history, err)
+	}
+
+	// A contract which is deleted is imported again with the same id.
+	if err := accessor.Delete(context.Background(), id); err != nil {
+		t.Fatalf("an error was unexpected while deleting contract %s", err)
+	}
+	report, err := accessor.Import(context.Background(), records, contract.ConflictFail, false)
+	if err != nil || !report.Committed || report.Results[0].ContractID != id {
+		t.Fatalf("expected contract %s to be created but got %+v, err %v", id, report, err)
+	}
+	if role, err := accessor.GetMemberRole(context.Background(), id, owner); err != nil || role != contract.RoleOwner {
+		t.Errorf("expected the owner to be imported but got %s, err %v", role, err)
+	}
+	if got, err := accessor.GetProfile(context.Background(), id); err != nil || got != profile {
+		t.Errorf("expected the profile %+v to be imported but got %+v, err %v", profile, got, err)
+	}
+}
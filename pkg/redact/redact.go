@@ -0,0 +1,76 @@
+package redact
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Mask replaces sensitive values.
+const Mask = "********"
+
+// sensitiveNames are substrings of field names which hold sensitive values.
+var sensitiveNames = []string{"password", "secret", "token", "auth", "credential"}
+
+// IsSensitive returns true if a field with the name holds a sensitive value.
+func IsSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns Mask for a non-empty value.
+func String(value string) string {
+	if value == "" {
+		return ""
+	}
+	return Mask
+}
+
+// Message returns a copy of m whose sensitive string fields are masked, including nested messages.
+func Message(m proto.Message) proto.Message {
+	if m == nil {
+		return nil
+	}
+	c := proto.Clone(m)
+	redactMessage(c.ProtoReflect())
+	return c
+}
+
+func redactMessage(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Kind() == protoreflect.StringKind && IsSensitive(string(fd.Name())):
+			if fd.IsList() {
+				list := v.List()
+				for i := 0; i < list.Len(); i++ {
+					list.Set(i, protoreflect.ValueOfString(String(list.Get(i).String())))
+				}
+			} else if !fd.IsMap() {
+				m.Set(fd, protoreflect.ValueOfString(String(v.String())))
+			}
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			if fd.IsList() {
+				list := v.List()
+				for i := 0; i < list.Len(); i++ {
+					redactMessage(list.Get(i).Message())
+				}
+			} else if fd.IsMap() {
+				v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+					if fd.MapValue().Kind() == protoreflect.MessageKind {
+						redactMessage(mv.Message())
+					}
+					return true
+				})
+			} else {
+				redactMessage(v.Message())
+			}
+		}
+		return true
+	})
+}
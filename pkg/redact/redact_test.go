@@ -0,0 +1,70 @@
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/openinfradev/tks-contract/pkg/redact"
+)
+
+func TestIsSensitive(t *testing.T) {
+	require.True(t, redact.IsSensitive("csp_auth"))
+	require.True(t, redact.IsSensitive("DBPassword"))
+	require.True(t, redact.IsSensitive("access_token"))
+	require.False(t, redact.IsSensitive("contractor_name"))
+}
+
+func TestString(t *testing.T) {
+	require.Equal(t, redact.Mask, redact.String("password"))
+	require.Equal(t, "", redact.String(""))
+}
+
+func TestMessage(t *testing.T) {
+	md := createRequestDescriptor(t)
+	in := dynamicpb.NewMessage(md)
+	in.Set(md.Fields().ByName("csp_name"), protoreflect.ValueOfString("aws"))
+	in.Set(md.Fields().ByName("csp_auth"), protoreflect.ValueOfString("{'token':'csp_auth_token'}"))
+
+	out := redact.Message(in).ProtoReflect()
+	require.Equal(t, "aws", out.Get(md.Fields().ByName("csp_name")).String())
+	require.Equal(t, redact.Mask, out.Get(md.Fields().ByName("csp_auth")).String())
+
+	// the original message is not changed
+	require.Equal(t, "{'token':'csp_auth_token'}", in.Get(md.Fields().ByName("csp_auth")).String())
+}
+
+func createRequestDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("redact_test.proto"),
+		Package: proto.String("redact.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("CreateContractRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("csp_name"),
+					Number:   proto.Int32(1),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					JsonName: proto.String("cspName"),
+				},
+				{
+					Name:     proto.String("csp_auth"),
+					Number:   proto.Int32(2),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					JsonName: proto.String("cspAuth"),
+				},
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	require.NoError(t, err)
+	return fd.Messages().Get(0)
+}
@@ -7,11 +7,28 @@ CREATE TABLE contracts
     available_services character varying(50)[] COLLATE pg_catalog."default",
     creator uuid,
     description character varying(100) COLLATE pg_catalog."default",
+    business_registration_number text NOT NULL DEFAULT '',
+    billing_address text NOT NULL DEFAULT '',
+    contact_email text NOT NULL DEFAULT '',
+    contact_phone text NOT NULL DEFAULT '',
+    parent_id character varying(10) COLLATE pg_catalog."default",
+    labels jsonb NOT NULL DEFAULT '{}',
+    annotations jsonb NOT NULL DEFAULT '{}',
+    effective_from timestamp with time zone,
+    expires_at timestamp with time zone,
+    state text NOT NULL DEFAULT 'active',
+    expiry_warned_at timestamp with time zone,
+    is_default boolean NOT NULL DEFAULT false,
+    price_plan text NOT NULL DEFAULT 'standard',
     updated_at timestamp with time zone,
     created_at timestamp with time zone
 );
 CREATE UNIQUE INDEX idx_contractor_name ON contracts(contractor_name);
 ALTER TABLE contracts CLUSTER ON idx_contractor_name;
+CREATE INDEX idx_contracts_labels ON contracts USING gin(labels);
+CREATE INDEX idx_contracts_expires_at ON contracts(expires_at);
+CREATE INDEX idx_contracts_parent_id ON contracts(parent_id);
+CREATE UNIQUE INDEX idx_default_contract ON contracts(is_default) WHERE is_default;
 INSERT INTO contracts(
 	contractor_name, id, available_services, updated_at, created_at)
 	VALUES ('tester', 'Pedcaa975', ARRAY['lma'], '2021-05-01'::timestamp, '2021-05-01'::timestamp);
@@ -29,3 +46,96 @@ CREATE TABLE resource_quota
     updated_at timestamp with time zone,
     created_at timestamp with time zone
 );
+
+CREATE TABLE contract_csps
+(
+    id uuid primary key,
+    contract_id character varying(10) COLLATE pg_catalog."default",
+    csp_id uuid,
+    csp_name text,
+    role text,
+    updated_at timestamp with time zone,
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX idx_contract_csp ON contract_csps(contract_id, csp_id);
+
+CREATE TABLE regional_quotas
+(
+    id uuid primary key,
+    contract_id character varying(10) COLLATE pg_catalog."default",
+    csp text,
+    region text,
+    cpu bigint,
+    memory bigint,
+    block bigint,
+    block_ssd bigint,
+    fs bigint,
+    fs_ssd bigint,
+    updated_at timestamp with time zone,
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX idx_regional_quota ON regional_quotas(contract_id, csp, region);
+
+CREATE TABLE contract_members
+(
+    id uuid primary key,
+    contract_id character varying(10) COLLATE pg_catalog."default",
+    user_id uuid,
+    role character varying(20) COLLATE pg_catalog."default",
+    updated_at timestamp with time zone,
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX idx_contract_member ON contract_members(contract_id, user_id);
+CREATE INDEX idx_contract_members_user_id ON contract_members(user_id);
+
+CREATE TABLE contract_histories
+(
+    id uuid primary key,
+    contract_id character varying(10) COLLATE pg_catalog."default",
+    action character varying(30) COLLATE pg_catalog."default",
+    actor uuid,
+    previous text,
+    current text,
+    created_at timestamp with time zone
+);
+CREATE INDEX idx_contract_history ON contract_histories(contract_id, created_at);
+
+CREATE TABLE price_plans
+(
+    id uuid primary key,
+    name text,
+    effective_from timestamp with time zone,
+    currency text,
+    resource_prices jsonb NOT NULL DEFAULT '{}',
+    service_prices jsonb NOT NULL DEFAULT '{}',
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX idx_price_plan_version ON price_plans(name, effective_from);
+
+CREATE TABLE invoices
+(
+    id uuid primary key,
+    number text,
+    contract_id character varying(10),
+    kind text,
+    period_start timestamp with time zone,
+    period_end timestamp with time zone,
+    currency text,
+    lines jsonb NOT NULL DEFAULT '[]',
+    total text,
+    invoice_id uuid,
+    reason text,
+    cpu_unit text NOT NULL DEFAULT '',
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX idx_invoices_number ON invoices(number);
+CREATE INDEX idx_invoice_period ON invoices(contract_id, period_start);
+CREATE INDEX idx_invoices_invoice_id ON invoices(invoice_id);
+
+CREATE TABLE schema_migrations
+(
+    version text primary key,
+    applied_at timestamp with time zone
+);
+-- the tables above are created in the current schema, to which upgrade.sql must not migrate them
+INSERT INTO schema_migrations (version, applied_at) VALUES ('cpu_millicores', now());
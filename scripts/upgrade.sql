@@ -0,0 +1,156 @@
+-- Upgrades the database of an existing deployment which was initialized by an earlier script.sql.
+-- Every statement is idempotent, so the script can be applied to a database of any earlier version
+-- and applied again.
+\c tks;
+
+-- contracts
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS business_registration_number text NOT NULL DEFAULT '';
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS billing_address text NOT NULL DEFAULT '';
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS contact_email text NOT NULL DEFAULT '';
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS contact_phone text NOT NULL DEFAULT '';
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS parent_id character varying(10) COLLATE pg_catalog."default";
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS labels jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS annotations jsonb NOT NULL DEFAULT '{}';
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS effective_from timestamp with time zone;
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS expires_at timestamp with time zone;
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS state text NOT NULL DEFAULT 'active';
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS expiry_warned_at timestamp with time zone;
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS is_default boolean NOT NULL DEFAULT false;
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS price_plan text NOT NULL DEFAULT 'standard';
+CREATE INDEX IF NOT EXISTS idx_contracts_labels ON contracts USING gin(labels);
+CREATE INDEX IF NOT EXISTS idx_contracts_expires_at ON contracts(expires_at);
+CREATE INDEX IF NOT EXISTS idx_contracts_parent_id ON contracts(parent_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_default_contract ON contracts(is_default) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS contract_csps
+(
+    id uuid primary key,
+    contract_id character varying(10) COLLATE pg_catalog."default",
+    csp_id uuid,
+    csp_name text,
+    role text,
+    updated_at timestamp with time zone,
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contract_csp ON contract_csps(contract_id, csp_id);
+
+CREATE TABLE IF NOT EXISTS regional_quotas
+(
+    id uuid primary key,
+    contract_id character varying(10) COLLATE pg_catalog."default",
+    csp text,
+    region text,
+    cpu bigint,
+    memory bigint,
+    block bigint,
+    block_ssd bigint,
+    fs bigint,
+    fs_ssd bigint,
+    updated_at timestamp with time zone,
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_regional_quota ON regional_quotas(contract_id, csp, region);
+
+CREATE TABLE IF NOT EXISTS contract_members
+(
+    id uuid primary key,
+    contract_id character varying(10) COLLATE pg_catalog."default",
+    user_id uuid,
+    role character varying(20) COLLATE pg_catalog."default",
+    updated_at timestamp with time zone,
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contract_member ON contract_members(contract_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_contract_members_user_id ON contract_members(user_id);
+
+CREATE TABLE IF NOT EXISTS contract_histories
+(
+    id uuid primary key,
+    contract_id character varying(10) COLLATE pg_catalog."default",
+    action character varying(30) COLLATE pg_catalog."default",
+    actor uuid,
+    previous text,
+    current text,
+    created_at timestamp with time zone
+);
+CREATE INDEX IF NOT EXISTS idx_contract_history ON contract_histories(contract_id, created_at);
+
+CREATE TABLE IF NOT EXISTS price_plans
+(
+    id uuid primary key,
+    name text,
+    effective_from timestamp with time zone,
+    currency text,
+    resource_prices jsonb NOT NULL DEFAULT '{}',
+    service_prices jsonb NOT NULL DEFAULT '{}',
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_plan_version ON price_plans(name, effective_from);
+
+CREATE TABLE IF NOT EXISTS invoices
+(
+    id uuid primary key,
+    number text,
+    contract_id character varying(10),
+    kind text,
+    period_start timestamp with time zone,
+    period_end timestamp with time zone,
+    currency text,
+    lines jsonb NOT NULL DEFAULT '[]',
+    total text,
+    invoice_id uuid,
+    reason text,
+    cpu_unit text NOT NULL DEFAULT '',
+    created_at timestamp with time zone
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_number ON invoices(number);
+CREATE INDEX IF NOT EXISTS idx_invoice_period ON invoices(contract_id, period_start);
+CREATE INDEX IF NOT EXISTS idx_invoices_invoice_id ON invoices(invoice_id);
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS cpu_unit text NOT NULL DEFAULT '';
+
+-- Migrations which are not idempotent by themselves are recorded in schema_migrations, and are
+-- applied only once.
+CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version text primary key,
+    applied_at timestamp with time zone
+);
+
+-- cpu_millicores: cpu quotas were stored in cores, and are stored in millicores.
+CREATE OR REPLACE FUNCTION pg_temp.cpu_to_millicores(quota jsonb) RETURNS jsonb AS $$
+    SELECT CASE WHEN jsonb_typeof(quota->'cpu') = 'number'
+        THEN jsonb_set(quota, '{cpu}', to_jsonb((quota->>'cpu')::bigint * 1000))
+        ELSE quota END;
+$$ LANGUAGE sql;
+
+CREATE OR REPLACE FUNCTION pg_temp.history_to_millicores(action text, snapshot text) RETURNS text AS $$
+    SELECT CASE
+        WHEN snapshot IS NULL OR snapshot = '' THEN snapshot
+        WHEN action = 'quota_updated' THEN pg_temp.cpu_to_millicores(snapshot::jsonb)::text
+        WHEN action IN ('created', 'deleted', 'imported') AND jsonb_typeof(snapshot::jsonb->'quota') = 'object'
+            THEN jsonb_set(snapshot::jsonb, '{quota}', pg_temp.cpu_to_millicores(snapshot::jsonb->'quota'))::text
+        WHEN action = 'regional_quotas_updated' AND jsonb_typeof(snapshot::jsonb->'regionalQuotas') = 'array'
+            THEN jsonb_set(snapshot::jsonb, '{regionalQuotas}', (
+                SELECT coalesce(jsonb_agg(pg_temp.cpu_to_millicores(q) ORDER BY i), '[]')
+                FROM jsonb_array_elements(snapshot::jsonb->'regionalQuotas') WITH ORDINALITY AS e(q, i)))::text
+        ELSE snapshot END;
+$$ LANGUAGE sql;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM schema_migrations WHERE version = 'cpu_millicores') THEN
+        RETURN;
+    END IF;
+
+    UPDATE resource_quota SET cpu = cpu * 1000;
+    UPDATE regional_quotas SET cpu = cpu * 1000;
+    UPDATE contract_histories
+        SET previous = pg_temp.history_to_millicores(action, previous),
+            current = pg_temp.history_to_millicores(action, current)
+        WHERE action IN ('quota_updated', 'created', 'deleted', 'imported', 'regional_quotas_updated');
+    -- issued invoices are never changed, so that their cpu quantities are read as cores
+    UPDATE invoices SET cpu_unit = 'cores';
+
+    INSERT INTO schema_migrations (version, applied_at) VALUES ('cpu_millicores', now());
+END
+$$;